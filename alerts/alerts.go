@@ -16,18 +16,10 @@ func (t Type) GetColor() string {
 	case Warning:
 		return "light_yellow"
 	default:
-		panic(fmt.Sprintf("unexpected alert type %v", t))
+		panic(fmt.Sprintf("unexpected alert type %d", int(t)))
 	}
 }
 
-/*
-TODO: add fix snippet
-"fix": {
-      "insert": "number",
-      "where": "before"
-    }
-*/
-
 type Alert interface {
 	Message() string
 	SnippetSpecifier() Snippet
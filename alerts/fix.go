@@ -0,0 +1,164 @@
+package alerts
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"hybroid/tokens"
+)
+
+// A location in a source. Lines and columns start at 1, like the ones of tokens
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Replaces the source from Start up to End (excluded) with NewText
+type TextEdit struct {
+	Start   Position
+	End     Position
+	NewText string
+}
+
+// A change of the source resolving an alert
+type Fix struct {
+	Title string
+	Edits []TextEdit
+}
+
+// Implemented by the alerts with a "fix" in their JSON. They only give one when they were
+// given what it needs by the stage that raised them
+type Fixable interface {
+	Alert
+	Fix() (Fix, bool)
+}
+
+// Returns the fixes of the alerts that have one, in the order of the alerts
+func FixesOf(alertsList []Alert) []Fix {
+	fixes := make([]Fix, 0)
+	for _, alert := range alertsList {
+		fixable, ok := alert.(Fixable)
+		if !ok {
+			continue
+		}
+		if fix, ok := fixable.Fix(); ok {
+			fixes = append(fixes, fix)
+		}
+	}
+	return fixes
+}
+
+// Applies the fixes to the source and returns it with the amount of fixes applied. A fix
+// overlapping one applied before it is left out, as is a fix that was already applied
+func ApplyFixes(source string, fixes []Fix) (string, int) {
+	lineStarts := []int{0}
+	for i := range len(source) {
+		if source[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	offset := func(position Position) int {
+		if position.Line < 1 {
+			return 0
+		}
+		if position.Line > len(lineStarts) {
+			return len(source)
+		}
+		return min(lineStarts[position.Line-1]+max(position.Column-1, 0), len(source))
+	}
+
+	type change struct {
+		start, end int
+		text       string
+	}
+	// insertions at the same place are all kept, in the order of their fixes
+	conflicts := func(a, b change) bool {
+		if a.start == b.start {
+			return a.start != a.end || b.start != b.end
+		}
+		return a.start < b.end && b.start < a.end
+	}
+	accepted := make([]change, 0)
+	applied := 0
+	for _, fix := range fixes {
+		changes := make([]change, 0, len(fix.Edits))
+		for _, edit := range fix.Edits {
+			changes = append(changes, change{offset(edit.Start), offset(edit.End), edit.NewText})
+		}
+		// the same fix can be given by several alerts, like adding a use statement
+		if !slices.ContainsFunc(changes, func(c change) bool { return !slices.Contains(accepted, c) }) {
+			continue
+		}
+		if slices.ContainsFunc(changes, func(c change) bool {
+			return slices.ContainsFunc(accepted, func(other change) bool { return conflicts(c, other) })
+		}) {
+			continue
+		}
+		accepted = append(accepted, changes...)
+		applied++
+	}
+
+	// applied from the end, the offsets of the changes before stay valid
+	slices.Reverse(accepted)
+	slices.SortStableFunc(accepted, func(a, b change) int {
+		return b.start - a.start
+	})
+	result := source
+	for _, change := range accepted {
+		result = result[:change.start] + change.text + result[change.end:]
+	}
+	return result, applied
+}
+
+// Returns the first and last tokens of what a fix is placed at
+func spanOf(at any) (tokens.Token, tokens.Token) {
+	switch at := at.(type) {
+	case tokens.Token:
+		return at, at
+	case Snippet:
+		toks := at.GetTokens()
+		return toks[0], toks[len(toks)-1]
+	}
+	return tokens.Token{}, tokens.Token{}
+}
+
+func isZero(value any) bool {
+	return reflect.ValueOf(value).IsZero()
+}
+
+func startOf(token tokens.Token) Position {
+	return Position{Line: token.Line, Column: token.Column.Start}
+}
+
+func endOf(token tokens.Token) Position {
+	// the column of the end of a token spanning lines is on its last line
+	return Position{Line: token.Line + strings.Count(token.Lexeme, "\n"), Column: token.Column.End}
+}
+
+func insertBefore(start, _ tokens.Token, text string) TextEdit {
+	return TextEdit{Start: startOf(start), End: startOf(start), NewText: text}
+}
+
+func insertAfter(_, end tokens.Token, text string) TextEdit {
+	return TextEdit{Start: endOf(end), End: endOf(end), NewText: text}
+}
+
+func replace(start, end tokens.Token, text string) TextEdit {
+	return TextEdit{Start: startOf(start), End: endOf(end), NewText: text}
+}
+
+// Inserts the text at the start of the line of the first token
+func insertLineBefore(start, _ tokens.Token, text string) TextEdit {
+	position := Position{Line: start.Line, Column: 1}
+	return TextEdit{Start: position, End: position, NewText: text}
+}
+
+// Replaces the whole lines the tokens are on
+func replaceLines(start, end tokens.Token, text string) TextEdit {
+	return TextEdit{
+		Start:   Position{Line: start.Line, Column: 1},
+		End:     Position{Line: endOf(end).Line + 1, Column: 1},
+		NewText: text,
+	}
+}
@@ -829,3 +829,135 @@ func (imk *InvalidMapKey) ID() string {
 func (imk *InvalidMapKey) AlertType() Type {
 	return Error
 }
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type UnknownMacro struct {
+	Specifier Snippet
+	Name      string
+}
+
+func (um *UnknownMacro) Message() string {
+	return fmt.Sprintf("unknown macro '%s'", um.Name)
+}
+
+func (um *UnknownMacro) SnippetSpecifier() Snippet {
+	return um.Specifier
+}
+
+func (um *UnknownMacro) Note() string {
+	return "macros must be declared before they are used"
+}
+
+func (um *UnknownMacro) ID() string {
+	return "hyb033P"
+}
+
+func (um *UnknownMacro) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type MacroArgumentCountMismatch struct {
+	Specifier Snippet
+	Name      string
+	Expected  int
+	Given     int
+}
+
+func (macm *MacroArgumentCountMismatch) Message() string {
+	return fmt.Sprintf("macro '%s' expects %d argument(s), but got %d", macm.Name, macm.Expected, macm.Given)
+}
+
+func (macm *MacroArgumentCountMismatch) SnippetSpecifier() Snippet {
+	return macm.Specifier
+}
+
+func (macm *MacroArgumentCountMismatch) Note() string {
+	return ""
+}
+
+func (macm *MacroArgumentCountMismatch) ID() string {
+	return "hyb034P"
+}
+
+func (macm *MacroArgumentCountMismatch) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type MacroExpansionFailed struct {
+	Specifier Snippet
+	Name      string
+	Line      int
+}
+
+func (mef *MacroExpansionFailed) Message() string {
+	return fmt.Sprintf("failed to expand macro '%s'", mef.Name)
+}
+
+func (mef *MacroExpansionFailed) SnippetSpecifier() Snippet {
+	return mef.Specifier
+}
+
+func (mef *MacroExpansionFailed) Note() string {
+	return fmt.Sprintf("the macro is declared at line %d", mef.Line)
+}
+
+func (mef *MacroExpansionFailed) ID() string {
+	return "hyb035P"
+}
+
+func (mef *MacroExpansionFailed) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type InterfaceMethodWithBody struct {
+	Specifier Snippet
+	Method    string
+}
+
+func (imwb *InterfaceMethodWithBody) Message() string {
+	return fmt.Sprintf("interface method '%s' cannot have a body", imwb.Method)
+}
+
+func (imwb *InterfaceMethodWithBody) SnippetSpecifier() Snippet {
+	return imwb.Specifier
+}
+
+func (imwb *InterfaceMethodWithBody) Note() string {
+	return "the classes and entities that implement the interface give the body of the method"
+}
+
+func (imwb *InterfaceMethodWithBody) ID() string {
+	return "hyb036P"
+}
+
+func (imwb *InterfaceMethodWithBody) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type ExpectedCallAfterStart struct {
+	Specifier Snippet
+}
+
+func (ecas *ExpectedCallAfterStart) Message() string {
+	return "expected a call after 'start'"
+}
+
+func (ecas *ExpectedCallAfterStart) SnippetSpecifier() Snippet {
+	return ecas.Specifier
+}
+
+func (ecas *ExpectedCallAfterStart) Note() string {
+	return "only calls to tasks can be started, like 'start Task()'"
+}
+
+func (ecas *ExpectedCallAfterStart) ID() string {
+	return "hyb037P"
+}
+
+func (ecas *ExpectedCallAfterStart) AlertType() Type {
+	return Error
+}
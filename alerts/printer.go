@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"hybroid/tokens"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -116,10 +117,18 @@ func (p *Printer) StageAlerts(sourcePath string, alerts []Alert) {
 	p.alertsByFile[sourcePath] = fileAlerts
 }
 
-func (p *Printer) PrintAlerts() error {
+// Prints the staged alerts, the paths of their sources being relative to the root
+func (p *Printer) PrintAlerts(root string, format Format) error {
+	if format != TextFormat {
+		return writeReport(os.Stdout, format, p.alertsByFile)
+	}
+
 	warningsCount, errorsCount := 0, 0
 	for sourcePath, alerts := range p.alertsByFile {
-		sourceFile, err := os.OpenFile(sourcePath, os.O_RDONLY, os.ModePerm)
+		if len(alerts) == 0 {
+			continue
+		}
+		sourceFile, err := os.OpenFile(filepath.Join(root, sourcePath), os.O_RDONLY, os.ModePerm)
 		if err != nil {
 			return err
 		}
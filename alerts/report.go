@@ -0,0 +1,257 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// How the printer writes the alerts
+type Format string
+
+const (
+	// Colored messages with the code snippets, for people
+	TextFormat Format = "text"
+	// A single JSON object listing the alerts
+	JSONFormat Format = "json"
+	// A SARIF 2.1.0 log, for code scanning tools
+	SARIFFormat Format = "sarif"
+	// Workflow commands annotating the sources in GitHub Actions
+	GitHubFormat Format = "github"
+)
+
+var Formats = []Format{TextFormat, JSONFormat, SARIFFormat, GitHubFormat}
+
+func ParseFormat(name string) (Format, error) {
+	format := Format(name)
+	if !slices.Contains(Formats, format) {
+		return "", fmt.Errorf("unknown alert format '%s', expected one of text, json, sarif or github", name)
+	}
+	return format, nil
+}
+
+func (t Type) String() string {
+	switch t {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		panic(fmt.Sprintf("unexpected alert type %d", int(t)))
+	}
+}
+
+// The part of a source an alert is about, from the start of its first token to the end of its last one
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func SpanOf(alert Alert) Span {
+	tokens := alert.SnippetSpecifier().GetTokens()
+	return Span{Start: startOf(tokens[0]), End: endOf(tokens[len(tokens)-1])}
+}
+
+type reportPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type reportSpan struct {
+	Start reportPosition `json:"start"`
+	End   reportPosition `json:"end"`
+}
+
+type reportAlert struct {
+	ID       string     `json:"id"`
+	Severity string     `json:"severity"`
+	Message  string     `json:"message"`
+	Note     string     `json:"note,omitempty"`
+	File     string     `json:"file"`
+	Span     reportSpan `json:"span"`
+}
+
+type report struct {
+	Alerts   []reportAlert `json:"alerts"`
+	Errors   int           `json:"errors"`
+	Warnings int           `json:"warnings"`
+}
+
+// Returns the alerts of every file, the files sorted by path and their alerts by position
+func sortedAlerts(alertsByFile map[string][]Alert) []reportAlert {
+	paths := make([]string, 0, len(alertsByFile))
+	for path := range alertsByFile {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	reported := make([]reportAlert, 0)
+	for _, path := range paths {
+		for _, alert := range alertsByFile[path] {
+			span := SpanOf(alert)
+			reported = append(reported, reportAlert{
+				ID:       alert.ID(),
+				Severity: alert.AlertType().String(),
+				Message:  strings.TrimSpace(alert.Message()),
+				Note:     alert.Note(),
+				File:     path,
+				Span: reportSpan{
+					Start: reportPosition{span.Start.Line, span.Start.Column},
+					End:   reportPosition{span.End.Line, span.End.Column},
+				},
+			})
+		}
+	}
+	return reported
+}
+
+// Writes the alerts in a format meant for other programs, which cannot be the text one
+func writeReport(out io.Writer, format Format, alertsByFile map[string][]Alert) error {
+	reported := sortedAlerts(alertsByFile)
+
+	switch format {
+	case JSONFormat:
+		result := report{Alerts: reported}
+		for _, alert := range reported {
+			if alert.Severity == Error.String() {
+				result.Errors++
+			} else {
+				result.Warnings++
+			}
+		}
+		return json.NewEncoder(out).Encode(result)
+	case SARIFFormat:
+		return json.NewEncoder(out).Encode(sarifLog(reported))
+	case GitHubFormat:
+		for _, alert := range reported {
+			_, err := fmt.Fprintf(out, "::%s file=%s,line=%d,col=%d,endLine=%d,endColumn=%d,title=%s::%s\n",
+				alert.Severity,
+				escapeGitHubProperty(alert.File),
+				alert.Span.Start.Line, alert.Span.Start.Column,
+				alert.Span.End.Line, alert.Span.End.Column,
+				escapeGitHubProperty(alert.ID),
+				escapeGitHubData(messageWithNote(alert)),
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("alerts cannot be reported in the %s format", format)
+}
+
+func messageWithNote(alert reportAlert) string {
+	if alert.Note == "" {
+		return alert.Message
+	}
+	return alert.Message + "\nnote: " + alert.Note
+}
+
+func escapeGitHubData(data string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(data)
+}
+
+func escapeGitHubProperty(property string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(property)
+}
+
+// The parts of the SARIF 2.1.0 schema the alerts fill in
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarif struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+func sarifLog(reported []reportAlert) sarif {
+	rules := make([]sarifRule, 0)
+	results := make([]sarifResult, 0, len(reported))
+	for _, alert := range reported {
+		if !slices.ContainsFunc(rules, func(rule sarifRule) bool { return rule.ID == alert.ID }) {
+			rules = append(rules, sarifRule{ID: alert.ID})
+		}
+		results = append(results, sarifResult{
+			RuleID:  alert.ID,
+			Level:   alert.Severity,
+			Message: sarifMessage{Text: messageWithNote(alert)},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: strings.ReplaceAll(alert.File, "\\", "/")},
+					Region: sarifRegion{
+						StartLine:   alert.Span.Start.Line,
+						StartColumn: alert.Span.Start.Column,
+						EndLine:     alert.Span.End.Line,
+						EndColumn:   alert.Span.End.Column,
+					},
+				},
+			}},
+		})
+	}
+	slices.SortFunc(rules, func(a, b sarifRule) int { return strings.Compare(a.ID, b.ID) })
+
+	return sarif{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "hybroid",
+				InformationURI: "https://github.com/pewpewlive/hybroid",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+}
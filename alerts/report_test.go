@@ -0,0 +1,62 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"hybroid/tokens"
+	"testing"
+)
+
+func reportedAlerts() map[string][]Alert {
+	token := func(line, start int, lexeme string) tokens.Token {
+		return tokens.NewToken(tokens.String, lexeme, "", tokens.NewLocation(line, start, start+len(lexeme)))
+	}
+	return map[string][]Alert{
+		"level.hyb":      {&UnterminatedString{Specifier: NewSingle(token(3, 9, `"a,b`))}},
+		"dir/shared.hyb": {&UnterminatedString{Specifier: NewMulti(token(1, 1, "x"), token(2, 4, "yz"))}},
+	}
+}
+
+func TestJSONReport(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeReport(&out, JSONFormat, reportedAlerts()); err != nil {
+		t.Fatal(err)
+	}
+
+	var result report
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if result.Errors != 2 || result.Warnings != 0 || len(result.Alerts) != 2 {
+		t.Fatalf("unexpected report: %+v", result)
+	}
+	first := result.Alerts[0]
+	expected := reportSpan{Start: reportPosition{1, 1}, End: reportPosition{2, 6}}
+	if first.File != "dir/shared.hyb" || first.ID != (&UnterminatedString{}).ID() || first.Severity != "error" || first.Span != expected {
+		t.Errorf("unexpected alert: %+v", first)
+	}
+}
+
+func TestGitHubReport(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeReport(&out, GitHubFormat, map[string][]Alert{"level.hyb": reportedAlerts()["level.hyb"]}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "::error file=level.hyb,line=3,col=9,endLine=3,endColumn=13,title=hyb002L::unterminated string\n"
+	if out.String() != expected {
+		t.Errorf("unexpected output:\n%q\nexpected:\n%q", out.String(), expected)
+	}
+	if escaped := escapeGitHubProperty("a,b:c%\n"); escaped != "a%2Cb%3Ac%25%0A" {
+		t.Errorf("unexpected escaping: %s", escaped)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat("sarif"); err != nil || format != SARIFFormat {
+		t.Errorf("expected the sarif format, got %q (%v)", format, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected an unknown format to be refused")
+	}
+}
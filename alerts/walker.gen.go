@@ -4,6 +4,7 @@ package alerts
 
 import (
 	"fmt"
+	"hybroid/tokens"
 	"strings"
 )
 
@@ -539,8 +540,10 @@ func (tmeg *TooManyElementsGiven) AlertType() Type {
 
 // AUTO-GENERATED, DO NOT MANUALLY MODIFY!
 type ExplicitTypeRequiredInDeclaration struct {
-	Specifier Snippet
-	Context   string
+	Specifier    Snippet
+	Context      string
+	Keyword      tokens.Token
+	InferredType string
 }
 
 func (etrid *ExplicitTypeRequiredInDeclaration) Message() string {
@@ -563,6 +566,14 @@ func (etrid *ExplicitTypeRequiredInDeclaration) AlertType() Type {
 	return Error
 }
 
+func (etrid *ExplicitTypeRequiredInDeclaration) Fix() (Fix, bool) {
+	if isZero(etrid.InferredType) {
+		return Fix{}, false
+	}
+	start, end := spanOf(etrid.Keyword)
+	return Fix{Title: fmt.Sprintf("declare it as '%s'", etrid.InferredType), Edits: []TextEdit{replace(start, end, fmt.Sprintf("%s", etrid.InferredType))}}, true
+}
+
 // AUTO-GENERATED, DO NOT MANUALLY MODIFY!
 type ExplicitTypeMismatch struct {
 	Specifier    Snippet
@@ -647,6 +658,8 @@ type UndeclaredVariableAccess struct {
 	Specifier Snippet
 	Var       string
 	Context   string
+	Use       string
+	UseAfter  tokens.Token
 }
 
 func (uva *UndeclaredVariableAccess) Message() string {
@@ -669,6 +682,14 @@ func (uva *UndeclaredVariableAccess) AlertType() Type {
 	return Error
 }
 
+func (uva *UndeclaredVariableAccess) Fix() (Fix, bool) {
+	if isZero(uva.Use) {
+		return Fix{}, false
+	}
+	start, end := spanOf(uva.UseAfter)
+	return Fix{Title: fmt.Sprintf("add 'use %s'", uva.Use), Edits: []TextEdit{insertAfter(start, end, fmt.Sprintf("\nuse %s", uva.Use))}}, true
+}
+
 // AUTO-GENERATED, DO NOT MANUALLY MODIFY!
 type ConstValueAssignment struct {
 	Specifier Snippet
@@ -797,6 +818,11 @@ func (uc *UnreachableCode) AlertType() Type {
 	return Warning
 }
 
+func (uc *UnreachableCode) Fix() (Fix, bool) {
+	start, end := spanOf(uc.Specifier)
+	return Fix{Title: "remove the unreachable code", Edits: []TextEdit{replaceLines(start, end, "")}}, true
+}
+
 // AUTO-GENERATED, DO NOT MANUALLY MODIFY!
 type InvalidUseOfExitStmt struct {
 	Specifier Snippet
@@ -1269,7 +1295,10 @@ func (ic *InsufficientCases) AlertType() Type {
 
 // AUTO-GENERATED, DO NOT MANUALLY MODIFY!
 type DefaultCaseMissing struct {
-	Specifier Snippet
+	Specifier     Snippet
+	ClosingBrace  tokens.Token
+	Indentation   string
+	DefaultValues string
 }
 
 func (dcm *DefaultCaseMissing) Message() string {
@@ -1292,6 +1321,14 @@ func (dcm *DefaultCaseMissing) AlertType() Type {
 	return Error
 }
 
+func (dcm *DefaultCaseMissing) Fix() (Fix, bool) {
+	if isZero(dcm.DefaultValues) {
+		return Fix{}, false
+	}
+	start, end := spanOf(dcm.ClosingBrace)
+	return Fix{Title: "add an 'else' case", Edits: []TextEdit{insertLineBefore(start, end, fmt.Sprintf("%selse => %s\n", dcm.Indentation, dcm.DefaultValues))}}, true
+}
+
 // AUTO-GENERATED, DO NOT MANUALLY MODIFY!
 type InvalidCaseType struct {
 	Specifier      Snippet
@@ -2100,3 +2137,508 @@ func (unie *UnallowedNumberInEnvironment) ID() string {
 func (unie *UnallowedNumberInEnvironment) AlertType() Type {
 	return Error
 }
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type ErrorInMacroExpansion struct {
+	Specifier Snippet
+	Name      string
+	Line      int
+}
+
+func (eime *ErrorInMacroExpansion) Message() string {
+	return fmt.Sprintf("errors found in the expansion of macro '%s'", eime.Name)
+}
+
+func (eime *ErrorInMacroExpansion) SnippetSpecifier() Snippet {
+	return eime.Specifier
+}
+
+func (eime *ErrorInMacroExpansion) Note() string {
+	return fmt.Sprintf("the macro is declared at line %d", eime.Line)
+}
+
+func (eime *ErrorInMacroExpansion) ID() string {
+	return "hyb081W"
+}
+
+func (eime *ErrorInMacroExpansion) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type ListIndexPastLength struct {
+	Specifier Snippet
+	Index     string
+	Length    int
+}
+
+func (lipl *ListIndexPastLength) Message() string {
+	return fmt.Sprintf("list index %s is past the end of the list", lipl.Index)
+}
+
+func (lipl *ListIndexPastLength) SnippetSpecifier() Snippet {
+	return lipl.Specifier
+}
+
+func (lipl *ListIndexPastLength) Note() string {
+	return fmt.Sprintf("the list has a length of %d", lipl.Length)
+}
+
+func (lipl *ListIndexPastLength) ID() string {
+	return "hyb082W"
+}
+
+func (lipl *ListIndexPastLength) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type ConstantDivisionByZero struct {
+	Specifier Snippet
+}
+
+func (cdbz *ConstantDivisionByZero) Message() string {
+	return "division by zero in a constant expression"
+}
+
+func (cdbz *ConstantDivisionByZero) SnippetSpecifier() Snippet {
+	return cdbz.Specifier
+}
+
+func (cdbz *ConstantDivisionByZero) Note() string {
+	return ""
+}
+
+func (cdbz *ConstantDivisionByZero) ID() string {
+	return "hyb083W"
+}
+
+func (cdbz *ConstantDivisionByZero) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type ConstantOverflow struct {
+	Specifier Snippet
+	Type      string
+	Range     string
+}
+
+func (co *ConstantOverflow) Message() string {
+	return fmt.Sprintf("the constant expression overflows the range of %s", co.Type)
+}
+
+func (co *ConstantOverflow) SnippetSpecifier() Snippet {
+	return co.Specifier
+}
+
+func (co *ConstantOverflow) Note() string {
+	return fmt.Sprintf("%s values range from %s", co.Type, co.Range)
+}
+
+func (co *ConstantOverflow) ID() string {
+	return "hyb084W"
+}
+
+func (co *ConstantOverflow) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type PossiblyNilAccess struct {
+	Specifier Snippet
+	Type      string
+	Context   string
+}
+
+func (pna *PossiblyNilAccess) Message() string {
+	return fmt.Sprintf("value of type '%s' can be nil, so it cannot be %s", pna.Type, pna.Context)
+}
+
+func (pna *PossiblyNilAccess) SnippetSpecifier() Snippet {
+	return pna.Specifier
+}
+
+func (pna *PossiblyNilAccess) Note() string {
+	return "check it with 'if let', compare it to nil or give it a fallback value with '??'"
+}
+
+func (pna *PossiblyNilAccess) ID() string {
+	return "hyb085W"
+}
+
+func (pna *PossiblyNilAccess) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type UnwrapWithOrCondition struct {
+	Specifier Snippet
+}
+
+func (uwoc *UnwrapWithOrCondition) Message() string {
+	return "cannot unwrap an optional with an 'or' condition"
+}
+
+func (uwoc *UnwrapWithOrCondition) SnippetSpecifier() Snippet {
+	return uwoc.Specifier
+}
+
+func (uwoc *UnwrapWithOrCondition) Note() string {
+	return "the unwrapped value would be nil when only the other side of the 'or' is true"
+}
+
+func (uwoc *UnwrapWithOrCondition) ID() string {
+	return "hyb086W"
+}
+
+func (uwoc *UnwrapWithOrCondition) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type NotAnInterface struct {
+	Specifier Snippet
+	Type      string
+}
+
+func (nai *NotAnInterface) Message() string {
+	return fmt.Sprintf("'%s' is not an interface, so it cannot be implemented", nai.Type)
+}
+
+func (nai *NotAnInterface) SnippetSpecifier() Snippet {
+	return nai.Specifier
+}
+
+func (nai *NotAnInterface) Note() string {
+	return ""
+}
+
+func (nai *NotAnInterface) ID() string {
+	return "hyb087W"
+}
+
+func (nai *NotAnInterface) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type MissingInterfaceMethod struct {
+	Specifier Snippet
+	Type      string
+	Method    string
+	Interface string
+	Signature string
+}
+
+func (mim *MissingInterfaceMethod) Message() string {
+	return fmt.Sprintf("'%s' is missing the method '%s' of interface '%s'", mim.Type, mim.Method, mim.Interface)
+}
+
+func (mim *MissingInterfaceMethod) SnippetSpecifier() Snippet {
+	return mim.Specifier
+}
+
+func (mim *MissingInterfaceMethod) Note() string {
+	return fmt.Sprintf("the method has to be declared with the signature '%s'", mim.Signature)
+}
+
+func (mim *MissingInterfaceMethod) ID() string {
+	return "hyb088W"
+}
+
+func (mim *MissingInterfaceMethod) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type InterfaceMethodMismatch struct {
+	Specifier Snippet
+	Method    string
+	Interface string
+	Expected  string
+	Got       string
+}
+
+func (imm *InterfaceMethodMismatch) Message() string {
+	return fmt.Sprintf("method '%s' does not match its signature in interface '%s'", imm.Method, imm.Interface)
+}
+
+func (imm *InterfaceMethodMismatch) SnippetSpecifier() Snippet {
+	return imm.Specifier
+}
+
+func (imm *InterfaceMethodMismatch) Note() string {
+	return fmt.Sprintf("expected '%s', got '%s'", imm.Expected, imm.Got)
+}
+
+func (imm *InterfaceMethodMismatch) ID() string {
+	return "hyb089W"
+}
+
+func (imm *InterfaceMethodMismatch) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type WaitOutsideTask struct {
+	Specifier Snippet
+}
+
+func (wot *WaitOutsideTask) Message() string {
+	return "cannot wait outside of a task"
+}
+
+func (wot *WaitOutsideTask) SnippetSpecifier() Snippet {
+	return wot.Specifier
+}
+
+func (wot *WaitOutsideTask) Note() string {
+	return "only the bodies of functions declared with 'task fn' can wait, not the functions or tick bodies inside them"
+}
+
+func (wot *WaitOutsideTask) ID() string {
+	return "hyb090W"
+}
+
+func (wot *WaitOutsideTask) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type TaskCallOutsideTask struct {
+	Specifier Snippet
+	Task      string
+}
+
+func (tcot *TaskCallOutsideTask) Message() string {
+	return fmt.Sprintf("task '%s' can only be called from another task", tcot.Task)
+}
+
+func (tcot *TaskCallOutsideTask) SnippetSpecifier() Snippet {
+	return tcot.Specifier
+}
+
+func (tcot *TaskCallOutsideTask) Note() string {
+	return "use 'start' to run it alongside the code calling it"
+}
+
+func (tcot *TaskCallOutsideTask) ID() string {
+	return "hyb091W"
+}
+
+func (tcot *TaskCallOutsideTask) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type NotATask struct {
+	Specifier Snippet
+	Type      string
+}
+
+func (nat *NotATask) Message() string {
+	return fmt.Sprintf("value of type '%s' is not a task, so it cannot be started", nat.Type)
+}
+
+func (nat *NotATask) SnippetSpecifier() Snippet {
+	return nat.Specifier
+}
+
+func (nat *NotATask) Note() string {
+	return "declare the function with 'task fn'"
+}
+
+func (nat *NotATask) ID() string {
+	return "hyb092W"
+}
+
+func (nat *NotATask) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type InvalidSoundLink struct {
+	Specifier Snippet
+	Reason    string
+}
+
+func (isl *InvalidSoundLink) Message() string {
+	return fmt.Sprintf("cannot parse the sound link: %s", isl.Reason)
+}
+
+func (isl *InvalidSoundLink) SnippetSpecifier() Snippet {
+	return isl.Specifier
+}
+
+func (isl *InvalidSoundLink) Note() string {
+	return "links are copied from JFXR, as in 'https://jfxr.frozenfractal.com/#%7B...%7D'"
+}
+
+func (isl *InvalidSoundLink) ID() string {
+	return "hyb093W"
+}
+
+func (isl *InvalidSoundLink) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type UnknownSoundField struct {
+	Specifier Snippet
+	Field     string
+}
+
+func (usf *UnknownSoundField) Message() string {
+	return fmt.Sprintf("'%s' is not a field of sounds, so it is left out", usf.Field)
+}
+
+func (usf *UnknownSoundField) SnippetSpecifier() Snippet {
+	return usf.Specifier
+}
+
+func (usf *UnknownSoundField) Note() string {
+	return ""
+}
+
+func (usf *UnknownSoundField) ID() string {
+	return "hyb094W"
+}
+
+func (usf *UnknownSoundField) AlertType() Type {
+	return Warning
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type InvalidSoundValue struct {
+	Specifier Snippet
+	Field     string
+	Value     string
+	Expected  string
+}
+
+func (isv *InvalidSoundValue) Message() string {
+	return fmt.Sprintf("sound field '%s' cannot be %s", isv.Field, isv.Value)
+}
+
+func (isv *InvalidSoundValue) SnippetSpecifier() Snippet {
+	return isv.Specifier
+}
+
+func (isv *InvalidSoundValue) Note() string {
+	return fmt.Sprintf("expected %s", isv.Expected)
+}
+
+func (isv *InvalidSoundValue) ID() string {
+	return "hyb095W"
+}
+
+func (isv *InvalidSoundValue) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type SegmentIndexOutOfRange struct {
+	Specifier Snippet
+	Index     string
+	Count     int
+}
+
+func (sioor *SegmentIndexOutOfRange) Message() string {
+	return fmt.Sprintf("segment index %s is out of range for a mesh with %d vertexes", sioor.Index, sioor.Count)
+}
+
+func (sioor *SegmentIndexOutOfRange) SnippetSpecifier() Snippet {
+	return sioor.Specifier
+}
+
+func (sioor *SegmentIndexOutOfRange) Note() string {
+	return "segments index the vertexes from 0"
+}
+
+func (sioor *SegmentIndexOutOfRange) ID() string {
+	return "hyb096W"
+}
+
+func (sioor *SegmentIndexOutOfRange) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type MeshColorCountMismatch struct {
+	Specifier Snippet
+	Colors    int
+	Vertexes  int
+}
+
+func (mccm *MeshColorCountMismatch) Message() string {
+	return fmt.Sprintf("mesh has %d colors for %d vertexes", mccm.Colors, mccm.Vertexes)
+}
+
+func (mccm *MeshColorCountMismatch) SnippetSpecifier() Snippet {
+	return mccm.Specifier
+}
+
+func (mccm *MeshColorCountMismatch) Note() string {
+	return "every vertex needs exactly one color"
+}
+
+func (mccm *MeshColorCountMismatch) ID() string {
+	return "hyb097W"
+}
+
+func (mccm *MeshColorCountMismatch) AlertType() Type {
+	return Error
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type MixedVertexDimensions struct {
+	Specifier  Snippet
+	Dimensions int
+	Expected   int
+}
+
+func (mvd *MixedVertexDimensions) Message() string {
+	return fmt.Sprintf("vertex has %d coordinates, but the first vertex of the mesh has %d", mvd.Dimensions, mvd.Expected)
+}
+
+func (mvd *MixedVertexDimensions) SnippetSpecifier() Snippet {
+	return mvd.Specifier
+}
+
+func (mvd *MixedVertexDimensions) Note() string {
+	return "the vertexes of a mesh are either all 2D or all 3D"
+}
+
+func (mvd *MixedVertexDimensions) ID() string {
+	return "hyb098W"
+}
+
+func (mvd *MixedVertexDimensions) AlertType() Type {
+	return Warning
+}
+
+// AUTO-GENERATED, DO NOT MANUALLY MODIFY!
+type UnwrapOutsideIfCondition struct {
+	Specifier Snippet
+}
+
+func (uoic *UnwrapOutsideIfCondition) Message() string {
+	return "an optional can only be unwrapped with 'let' in the condition of an if"
+}
+
+func (uoic *UnwrapOutsideIfCondition) SnippetSpecifier() Snippet {
+	return uoic.Specifier
+}
+
+func (uoic *UnwrapOutsideIfCondition) Note() string {
+	return "use 'if let' or 'else if let', or give the value a fallback with '??'"
+}
+
+func (uoic *UnwrapOutsideIfCondition) ID() string {
+	return "hyb099W"
+}
+
+func (uoic *UnwrapOutsideIfCondition) AlertType() Type {
+	return Error
+}
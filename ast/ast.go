@@ -16,6 +16,8 @@ const (
 	AliasDeclaration          NodeType = "aliasDeclaration"
 	EntityDeclaration         NodeType = "entityDeclaration"
 	EntityFunctionDeclaration NodeType = "entityFunctionDeclaration"
+	InterfaceDeclaration      NodeType = "interfaceDeclaration"
+	TestDeclaration           NodeType = "testDeclaration"
 
 	DestroyStatement    NodeType = "destroyStatement"
 	AssignmentStatement NodeType = "assignmentStatement"
@@ -23,6 +25,7 @@ const (
 	WhileStatement      NodeType = "whileStatement"
 	ForStatement        NodeType = "forStatement"
 	TickStatement       NodeType = "tickStatement"
+	WaitStatement       NodeType = "waitStatement"
 	IfStatement         NodeType = "ifStatement"
 	UseStatement        NodeType = "useStatement"
 	AddStatement        NodeType = "addStatement"
@@ -50,6 +53,9 @@ const (
 	MethodExpression            NodeType = "methodExpression"
 	MacroCallExpression         NodeType = "macroCallExpression"
 	MatchExpression             NodeType = "matchExpression"
+	FindExpression              NodeType = "findExpression"
+	NilCoalescingExpression     NodeType = "nilCoalescingExpression"
+	LetUnwrapExpression         NodeType = "letUnwrapExpression"
 	FieldExpression             NodeType = "fieldExpression"
 	MemberExpression            NodeType = "memberExpression"
 	ParentExpression            NodeType = "parentExpression"
@@ -58,6 +64,7 @@ const (
 	SelfExpression              NodeType = "selfExpression"
 	NewExpession                NodeType = "newExpession"
 	SpawnExpression             NodeType = "spawnExpression"
+	StartExpression             NodeType = "startExpression"
 	EntityEvaluationExpression  NodeType = "entityEvaluationExpression"
 
 	EntityAccessExpression NodeType = "entityAccessExpression"
@@ -80,11 +87,14 @@ const (
 	Func          PrimitiveValueType = "func"
 	Entity        PrimitiveValueType = "entity"
 	Class         PrimitiveValueType = "class"
+	Interface     PrimitiveValueType = "interface"
 	Struct        PrimitiveValueType = "struct"
 	Ident         PrimitiveValueType = "ident"
 	Enum          PrimitiveValueType = "enum"
 	Path          PrimitiveValueType = "path"
 	Generic       PrimitiveValueType = "generic"
+	Optional      PrimitiveValueType = "optional"
+	Nil           PrimitiveValueType = "nil"
 	Invalid       PrimitiveValueType = "invalid"
 	Uninitialized PrimitiveValueType = "uninitialized"
 )
@@ -104,6 +114,7 @@ type MethodCallType int
 const (
 	ClassMethod MethodCallType = iota
 	EntityMethod
+	InterfaceMethod
 )
 
 type MacroType int
@@ -28,6 +28,8 @@ type MacroDecl struct {
 	Params    []*IdentifierExpr
 	MacroType MacroType
 	Tokens    []tokens.Token
+	IsPub     bool
+	Globals   []bool // The tokens of the body naming the variables declared globally in the file of the macro
 }
 
 func (md *MacroDecl) GetType() NodeType                { return MacroDeclaration }
@@ -54,7 +56,9 @@ type EntityDecl struct {
 	Destroyer     *EntityFunctionDecl
 	Callbacks     []*EntityFunctionDecl
 	Methods       []MethodDecl
+	Impls         []*TypeExpr
 	IsPub         bool
+	Doc           string
 }
 
 func (ed *EntityDecl) GetType() NodeType                { return EntityDeclaration }
@@ -80,12 +84,28 @@ type EnumDecl struct {
 	Name   tokens.Token
 	Fields []*IdentifierExpr
 	IsPub  bool
+	Doc    string
+	// the doc comments of the fields, empty for the ones without
+	FieldDocs []string
 }
 
 func (ed *EnumDecl) GetType() NodeType                { return EnumDeclaration }
 func (ed *EnumDecl) GetToken() tokens.Token           { return ed.Name }
 func (ed *EnumDecl) GetValueType() PrimitiveValueType { return Invalid }
 
+// A test block, which only the test bundles run
+type TestDecl struct {
+	Body
+
+	Token tokens.Token
+	// the string literal naming the test
+	Name tokens.Token
+}
+
+func (td *TestDecl) GetType() NodeType                { return TestDeclaration }
+func (td *TestDecl) GetToken() tokens.Token           { return td.Token }
+func (td *TestDecl) GetValueType() PrimitiveValueType { return Invalid }
+
 type ConstructorDecl struct {
 	Body
 
@@ -112,6 +132,10 @@ type FunctionDecl struct {
 	Generics []*IdentifierExpr
 	Params   []FunctionParam
 	Returns  []*TypeExpr
+	IsTask   bool
+	Doc      string
+	// Named by a macro of the file, so it has to be reachable from the files expanding it
+	MacroUsed bool
 }
 
 func (fd *FunctionDecl) GetType() NodeType                { return FunctionDeclaration }
@@ -127,6 +151,8 @@ type MethodDecl struct {
 	Params   []FunctionParam
 	Generics []*IdentifierExpr
 	IsPub    bool
+	IsTask   bool
+	Doc      string
 }
 
 func (md *MethodDecl) GetType() NodeType                { return MethodDeclaration }
@@ -140,6 +166,9 @@ type VariableDecl struct {
 	IsPub       bool
 	IsConst     bool
 	Token       tokens.Token
+	Doc         string
+	// Named by a macro of the file, so it has to be reachable from the files expanding it
+	MacroUsed bool
 }
 
 func (vd *VariableDecl) GetType() NodeType                { return VariableDeclaration }
@@ -153,9 +182,24 @@ type ClassDecl struct {
 	Fields        []VariableDecl
 	Methods       []MethodDecl
 	GenericParams []*IdentifierExpr
+	Impls         []*TypeExpr
 	IsPub         bool
+	Doc           string
 }
 
 func (cd *ClassDecl) GetType() NodeType                { return ClassDeclaration }
 func (cd *ClassDecl) GetToken() tokens.Token           { return cd.Token }
 func (cd *ClassDecl) GetValueType() PrimitiveValueType { return Invalid }
+
+// The methods of an interface have no body, only their signature
+type InterfaceDecl struct {
+	Token   tokens.Token
+	Name    tokens.Token
+	Methods []MethodDecl
+	IsPub   bool
+	Doc     string
+}
+
+func (id *InterfaceDecl) GetType() NodeType                { return InterfaceDeclaration }
+func (id *InterfaceDecl) GetToken() tokens.Token           { return id.Token }
+func (id *InterfaceDecl) GetValueType() PrimitiveValueType { return Invalid }
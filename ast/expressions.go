@@ -28,17 +28,23 @@ func (epe *EnvPathExpr) Combine(token tokens.Token) {
 type EnvAccessExpr struct {
 	PathExpr *EnvPathExpr
 	Accessed *IdentifierExpr
+	// Qualified by the expansion of a macro, which can access the private variables of its environment
+	FromMacro bool
 }
 
 func (eae *EnvAccessExpr) GetType() NodeType      { return EnvironmentAccessExpression }
 func (eae *EnvAccessExpr) GetToken() tokens.Token { return eae.Accessed.GetToken() }
 
-// type MacroCallExpr struct {
-// 	Caller *CallExpr
-// }
+type MacroCallExpr struct {
+	Token     tokens.Token
+	Caller    *CallExpr
+	Macro     *MacroDecl
+	Expansion Node // Set for expression expansions
+	Body      Body // Set for program expansions
+}
 
-// func (mce *MacroCallExpr) GetType() NodeType                { return MacroCallExpression }
-// func (mce *MacroCallExpr) GetToken() tokens.Token           { return mce.Caller.GetToken() }
+func (mce *MacroCallExpr) GetType() NodeType      { return MacroCallExpression }
+func (mce *MacroCallExpr) GetToken() tokens.Token { return mce.Token }
 
 type EntityAccessExpr struct {
 	Expr       Node
@@ -73,6 +79,10 @@ type TypeExpr struct {
 	Returns      []*TypeExpr
 	Fields       []FunctionParam
 	IsVariadic   bool
+	// `T?`, the type of the values that can also be nil
+	IsOptional bool
+	// `task fn()`, the type of the functions that can wait
+	IsTask bool
 }
 
 func (te *TypeExpr) GetType() NodeType      { return TypeExpression }
@@ -108,6 +118,27 @@ type BinaryExpr struct {
 func (be *BinaryExpr) GetType() NodeType      { return BinaryExpression }
 func (be *BinaryExpr) GetToken() tokens.Token { return be.Operator }
 
+// `maybe ?? fallback`
+type NilCoalescingExpr struct {
+	Left, Right Node
+	Operator    tokens.Token
+	// set by the walker when the value is a bool, which Lua's `or` cannot tell apart from nil
+	IsBool bool
+}
+
+func (nce *NilCoalescingExpr) GetType() NodeType      { return NilCoalescingExpression }
+func (nce *NilCoalescingExpr) GetToken() tokens.Token { return nce.Operator }
+
+// `let name = maybe`, the condition of an if statement that unwraps an optional value
+type LetUnwrapExpr struct {
+	Name  tokens.Token
+	Expr  Node
+	Token tokens.Token
+}
+
+func (lue *LetUnwrapExpr) GetType() NodeType      { return LetUnwrapExpression }
+func (lue *LetUnwrapExpr) GetToken() tokens.Token { return lue.Token }
+
 type CallNode interface {
 	GetReturnAmount() int
 }
@@ -181,6 +212,16 @@ type MatchExpr struct {
 func (me *MatchExpr) GetType() NodeType      { return MatchExpression }
 func (me *MatchExpr) GetToken() tokens.Token { return me.MatchStmt.GetToken() }
 
+type FindExpr struct {
+	Token     tokens.Token
+	Value     Node
+	Container Node
+	InMap     bool // Set by the walker when the container is a map
+}
+
+func (fe *FindExpr) GetType() NodeType      { return FindExpression }
+func (fe *FindExpr) GetToken() tokens.Token { return fe.Token }
+
 type SelfExpr struct {
 	Token      tokens.Token
 	EntityName string
@@ -220,6 +261,15 @@ func (ne *SpawnExpr) GetGenerics() []*TypeExpr { return ne.GenericArgs }
 func (ne *SpawnExpr) GetCaller() Node          { return ne.Type }
 func (ne *SpawnExpr) GetArgs() []Node          { return ne.Args }
 
+// Runs the call to a task alongside the code starting it
+type StartExpr struct {
+	Call  Node
+	Token tokens.Token
+}
+
+func (se *StartExpr) GetType() NodeType      { return StartExpression }
+func (se *StartExpr) GetToken() tokens.Token { return se.Token }
+
 type IdentifierType int
 
 const (
@@ -48,10 +48,11 @@ func (ms *CaseStmt) GetType() NodeType      { return CaseStatement }
 func (ms *CaseStmt) GetToken() tokens.Token { return ms.Expressions[0].GetToken() }
 
 type MatchStmt struct {
-	Token       tokens.Token
-	ExprToMatch Node
-	Cases       []*CaseStmt
-	HasDefault  bool
+	Token        tokens.Token
+	ExprToMatch  Node
+	Cases        []*CaseStmt
+	HasDefault   bool
+	ClosingBrace tokens.Token
 }
 
 func (ms *MatchStmt) GetType() NodeType      { return MatchStatement }
@@ -107,6 +108,16 @@ type TickStmt struct {
 func (ts *TickStmt) GetType() NodeType      { return TickStatement }
 func (ts *TickStmt) GetToken() tokens.Token { return ts.Token }
 
+// `wait N` waits N ticks, `wait until cond` waits for the tick the condition is true on
+type WaitStmt struct {
+	Ticks Node
+	Until Node
+	Token tokens.Token
+}
+
+func (ws *WaitStmt) GetType() NodeType      { return WaitStatement }
+func (ws *WaitStmt) GetToken() tokens.Token { return ws.Token }
+
 type ReturnStmt struct {
 	Args  []Node
 	Token tokens.Token
@@ -147,9 +158,10 @@ func (as *AddStmt) GetType() NodeType      { return AddStatement }
 func (as *AddStmt) GetToken() tokens.Token { return as.Token }
 
 type RemoveStmt struct {
-	Value      Node
-	Identifier string
-	Token      tokens.Token
+	Token     tokens.Token
+	Index     Node
+	Container Node
+	InMap     bool // Set by the walker when the container is a map
 }
 
 func (rs *RemoveStmt) GetType() NodeType      { return RemoveStatement }
@@ -20,11 +20,15 @@ func RunApp() {
 			commands.Initialize(),
 			commands.Watch(),
 			commands.Lsp(),
+			commands.Trace(),
+			commands.Run(),
+			commands.Test(),
+			commands.Format(),
 		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		fmt.Printf("Error: %v", err)
-		return
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 }
@@ -1,12 +1,26 @@
 package commands
 
-import "github.com/urfave/cli/v2"
+import (
+	"fmt"
+	"hybroid/core"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/urfave/cli/v2"
+)
 
 func Add() *cli.Command {
 	return &cli.Command{
-		Name:    "add",
-		Aliases: []string{"a"},
-		Usage:   "Installs packages from the PewPew Marketplace",
+		Name:      "add",
+		Aliases:   []string{"a"},
+		Usage:     "Installs packages from the PewPew Marketplace",
+		ArgsUsage: "[name[@version]...]",
+		Description: "Resolves the given packages from the package registry and vendors their sources into the project's packages folder. " +
+			"Without any arguments, every package listed in the config file is installed again",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "registry", Usage: "The registry directory or file:// index to use, overriding the config file"},
+		},
 		Action: func(ctx *cli.Context) error {
 			return add(ctx)
 		},
@@ -14,5 +28,99 @@ func Add() *cli.Command {
 }
 
 func add(ctx *cli.Context) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed getting current working directory: %v", err)
+	}
+
+	configPath := filepath.Join(cwd, "hybconfig.toml")
+	configFile, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed reading Hybroid Live config file: %v", err)
+	}
+	config := core.HybroidConfig{}
+	if err := toml.Unmarshal(configFile, &config); err != nil {
+		return fmt.Errorf("failed parsing Hybroid Live config file: %v", err)
+	}
+	if config.Packages == nil {
+		config.Packages = make(map[string]string)
+	}
+
+	lock := core.PackageLock{}
+	lockPath := filepath.Join(cwd, core.LockFileName)
+	if lockFile, err := os.ReadFile(lockPath); err == nil {
+		if err := toml.Unmarshal(lockFile, &lock); err != nil {
+			return fmt.Errorf("failed parsing lock file: %v", err)
+		}
+	}
+
+	location := config.Project.Registry
+	if ctx.IsSet("registry") {
+		location = ctx.String("registry")
+	}
+	registry, err := core.OpenRegistry(location, cwd)
+	if err != nil {
+		return err
+	}
+
+	requested := make([][2]string, 0)
+	if ctx.NArg() == 0 {
+		for name, version := range config.Packages {
+			requested = append(requested, [2]string{name, version})
+		}
+	}
+	for _, spec := range ctx.Args().Slice() {
+		name, version, err := core.ParsePackageSpec(spec)
+		if err != nil {
+			return err
+		}
+		requested = append(requested, [2]string{name, version})
+	}
+
+	for _, pkg := range requested {
+		name := pkg[0]
+		version, source, err := registry.Resolve(name, pkg[1])
+		if err != nil {
+			return err
+		}
+		hash, err := core.HashPackage(source)
+		if err != nil {
+			return fmt.Errorf("failed hashing package '%s': %v", name, err)
+		}
+		// the same version of a package must never change its contents
+		if locked, found := lock.Find(name); found && locked.Version == version && locked.Hash != hash {
+			return fmt.Errorf("package '%s@%s' does not match the hash in %s (expected %s, got %s)", name, version, core.LockFileName, locked.Hash, hash)
+		}
+
+		if err := core.VendorPackage(source, filepath.Join(cwd, core.PackagesDirectory, name)); err != nil {
+			return fmt.Errorf("failed to vendor package '%s': %v", name, err)
+		}
+
+		config.Packages[name] = version
+		lock.Set(core.LockedPackage{
+			Name:    name,
+			Version: version,
+			Source:  location,
+			Hash:    hash,
+		})
+		fmt.Printf("Added %s@%s\n", name, version)
+	}
+
+	configFile, err = toml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed generating Hybroid Live config file: %v", err)
+	}
+	if err = os.WriteFile(configPath, configFile, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to write the Hybroid Live config file to disk: %v", err)
+	}
+
+	lockFile, err := toml.Marshal(lock)
+	if err != nil {
+		return fmt.Errorf("failed generating lock file: %v", err)
+	}
+	if err = os.WriteFile(lockPath, lockFile, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to write the lock file to disk: %v", err)
+	}
+
 	return nil
 }
@@ -3,8 +3,10 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
+	"hybroid/alerts"
 	"hybroid/core"
 	"hybroid/evaluator"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -19,13 +21,47 @@ func Build() *cli.Command {
 		Aliases:     []string{"b"},
 		Usage:       "Builds a Hybroid Live project",
 		Description: "This will take the current project in the location the command was ran, and will transpile the project into its destination folder, based on the config file",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "fix",
+				Usage: "apply the fixes of the alerts to the sources before building",
+			},
+			&cli.BoolFlag{
+				Name:  "release",
+				Usage: "leave out the unused code and builtins, minify the Lua and report the size of each file",
+			},
+			formatFlag(),
+		},
 		Action: func(ctx *cli.Context) error {
-			return Build_()
+			format, err := alerts.ParseFormat(ctx.String("format"))
+			if err != nil {
+				return err
+			}
+			if ctx.Bool("fix") {
+				// the output of the other formats is read by programs, the fixes are reported aside
+				out := io.Writer(os.Stdout)
+				if format != alerts.TextFormat {
+					out = os.Stderr
+				}
+				if err := fixSources(out); err != nil {
+					return err
+				}
+			}
+			return Build_(format, ctx.Bool("release"))
 		},
 	}
 }
 
-func runEvaluator(config core.HybroidConfig, filesToBuild []core.File, cwd string) error {
+func formatFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:  "format",
+		Value: string(alerts.TextFormat),
+		Usage: "print the alerts as text, json, sarif or github (workflow commands)",
+	}
+}
+
+// Builds the project with a new evaluator, which is returned for the builds that follow
+func runEvaluator(config core.HybroidConfig, format alerts.Format, release bool, filesToBuild []core.File, cwd string) (*evaluator.Evaluator, error) {
 	outputDir := config.Project.OutputDirectory
 
 	if outputDir != "" {
@@ -55,7 +91,7 @@ func runEvaluator(config core.HybroidConfig, filesToBuild []core.File, cwd strin
 		return color
 	}))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	manifestConfig := config.Level
@@ -65,49 +101,163 @@ func runEvaluator(config core.HybroidConfig, filesToBuild []core.File, cwd strin
 	if len(filesToBuild) == 0 {
 		files, filesErr := core.CollectFiles(cwd)
 		if filesErr != nil {
-			return filesErr
-
+			return nil, filesErr
 		}
 		filesToBuild = append(filesToBuild, files...)
 	}
 
 	evaluator := evaluator.NewEvaluator(filesToBuild)
+	evaluator.SetFormat(format)
+	evaluator.SetRelease(release)
 	err = evaluator.Action(cwd, outputDir)
 	if err != nil {
-		return err
+		return evaluator, err
+	}
+	if release {
+		// the output of the other formats is read by programs, the report is written aside
+		out := io.Writer(os.Stdout)
+		if format != alerts.TextFormat {
+			out = os.Stderr
+		}
+		printSizeReport(out, evaluator.OutputSizes())
 	}
 
 	manifest, manifestErr := json.MarshalIndent(manifestConfig, "", "  ")
 	if manifestErr != nil {
-		return fmt.Errorf("failed creating level manifest file: %v", manifestErr)
-
+		return evaluator, fmt.Errorf("failed creating level manifest file: %v", manifestErr)
 	}
 	os.WriteFile(filepath.Join(cwd, outputDir, "/manifest.json"), manifest, os.ModePerm)
 
+	return evaluator, deployOutput(config, cwd)
+}
+
+// Mirrors the output directory into the deploy directory of the project, if it has one
+func deployOutput(config core.HybroidConfig, cwd string) error {
+	deployDir := config.Project.DeployDirectory
+	if deployDir == "" {
+		return nil
+	}
+	if !filepath.IsAbs(deployDir) {
+		deployDir = filepath.Join(cwd, deployDir)
+	}
+
+	// the files the output does not have are removed, so it has to be the directory of this level
+	if entries, err := os.ReadDir(deployDir); err == nil && len(entries) != 0 {
+		if _, err := os.Stat(filepath.Join(deployDir, "manifest.json")); err != nil {
+			return fmt.Errorf("deploy directory %s is not empty and has no manifest.json, so it may not be the directory of the level", deployDir)
+		}
+	}
+	if err := core.Mirror(filepath.Join(cwd, config.Project.OutputDirectory), deployDir); err != nil {
+		return fmt.Errorf("failed deploying the level: %v", err)
+	}
 	return nil
 }
 
-func Build_(filesToBuild ...core.File) error {
+// The fixes of one round can reveal others, like an added use statement making a declaration analyzable
+const maxFixRounds = 5
+
+// Applies the fixes of the alerts of the project to its sources
+func fixSources(out io.Writer) error {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("failed getting current working directory: %v", err)
 	}
-	cwd += "/"
 
-	configFile, err := os.ReadFile(cwd + "hybconfig.toml")
+	files, err := core.CollectFiles(cwd)
 	if err != nil {
-		return fmt.Errorf("failed reading Hybroid Live config file: %v", err)
+		return err
 	}
 
-	config := core.HybroidConfig{}
-	if err := toml.Unmarshal(configFile, &config); err != nil {
-		return fmt.Errorf("failed parsing Hybroid Live config file: %v", err)
+	for range maxFixRounds {
+		eval := evaluator.NewEvaluator(files)
+		if err := eval.ParseAll(cwd); err != nil {
+			return err
+		}
+		eval.RunAnalysis()
+
+		applied := 0
+		for _, file := range files {
+			fixes := alerts.FixesOf(eval.GetAlerts(file.Path()))
+			if len(fixes) == 0 {
+				continue
+			}
+
+			sourcePath := filepath.Join(cwd, file.Path())
+			source, err := os.ReadFile(sourcePath)
+			if err != nil {
+				return fmt.Errorf("failed reading %s: %v", file.Path(), err)
+			}
+			fixed, count := alerts.ApplyFixes(string(source), fixes)
+			if count == 0 {
+				continue
+			}
+			if err := os.WriteFile(sourcePath, []byte(fixed), os.ModePerm); err != nil {
+				return fmt.Errorf("failed writing %s: %v", file.Path(), err)
+			}
+			fmt.Fprintf(out, "Applied %d fix(es) to %s\n", count, file.Path())
+			applied += count
+		}
+		if applied == 0 {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Prints the size of each file of a release build, and how much minifying took off
+func printSizeReport(out io.Writer, sizes []evaluator.OutputSize) {
+	total, minifiedTotal := 0, 0
+	for _, size := range sizes {
+		fmt.Fprintf(out, "%s: %s (%s before minifying, -%d%%)\n", size.Path, formatSize(size.MinifiedSize), formatSize(size.Size), reduction(size.Size, size.MinifiedSize))
+		total += size.Size
+		minifiedTotal += size.MinifiedSize
+	}
+	fmt.Fprintf(out, "total: %s (%s before minifying, -%d%%)\n", formatSize(minifiedTotal), formatSize(total), reduction(total, minifiedTotal))
+}
+
+func formatSize(bytes int) string {
+	if bytes < 1024 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	return fmt.Sprintf("%.1f KiB", float64(bytes)/1024)
+}
+
+func reduction(size, minifiedSize int) int {
+	if size == 0 {
+		return 0
 	}
+	return (size - minifiedSize) * 100 / size
+}
 
-	err = runEvaluator(config, filesToBuild, cwd)
+func Build_(format alerts.Format, release bool, filesToBuild ...core.File) error {
+	cwd, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("build failed: %v", err)
+		return fmt.Errorf("failed getting current working directory: %v", err)
+	}
+	cwd += "/"
+
+	config, err := readConfig(cwd)
+	if err != nil {
+		return err
+	}
+
+	_, err = runEvaluator(config, format, release, filesToBuild, cwd)
+	if err != nil {
+		return fmt.Errorf("build failed: %w", err)
 	}
 
 	return nil
 }
+
+func readConfig(cwd string) (core.HybroidConfig, error) {
+	config := core.HybroidConfig{}
+	configFile, err := os.ReadFile(filepath.Join(cwd, "hybconfig.toml"))
+	if err != nil {
+		return config, fmt.Errorf("failed reading Hybroid Live config file: %v", err)
+	}
+	if err := toml.Unmarshal(configFile, &config); err != nil {
+		return config, fmt.Errorf("failed parsing Hybroid Live config file: %v", err)
+	}
+	return config, nil
+}
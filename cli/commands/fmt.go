@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"hybroid/formatter"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v2"
+)
+
+func Format() *cli.Command {
+	return &cli.Command{
+		Name:        "fmt",
+		Aliases:     []string{"f"},
+		Usage:       "Formats Hybroid sources",
+		ArgsUsage:   "[paths]",
+		Description: "Rewrites the given files, or the Hybroid files in the given directories (the current directory by default), in the canonical layout. Comments are kept and files with syntax errors are left untouched",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "check",
+				Usage: "only list the files that are not formatted, and fail if there are any",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			return format(ctx)
+		},
+	}
+}
+
+func format(ctx *cli.Context) error {
+	paths := ctx.Args().Slice()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := collectSources(paths)
+	if err != nil {
+		return err
+	}
+
+	check := ctx.Bool("check")
+	unformatted, failed := 0, 0
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("failed reading %s: %v", file, err)
+		}
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed reading %s: %v", file, err)
+		}
+		formatted, err := formatter.Format(string(source))
+		if err != nil {
+			fmt.Printf("%s: %v\n", file, err)
+			failed++
+			continue
+		}
+		if formatted == string(source) {
+			continue
+		}
+
+		unformatted++
+		if check {
+			fmt.Println(file)
+			continue
+		}
+		if err := os.WriteFile(file, []byte(formatted), info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed writing %s: %v", file, err)
+		}
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d file(s) could not be formatted", failed)
+	}
+	if check && unformatted != 0 {
+		return fmt.Errorf("%d file(s) are not formatted", unformatted)
+	}
+	return nil
+}
+
+// Collects the given files, and the Hybroid files in the given directories
+func collectSources(paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading %s: %v", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".hyb" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed walking %s: %v", path, err)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"fmt"
+	"hybroid/alerts"
+	"hybroid/core"
+	"hybroid/simulator"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/urfave/cli/v2"
+)
+
+func Run() *cli.Command {
+	return &cli.Command{
+		Name:        "run",
+		Aliases:     []string{"r"},
+		Usage:       "Builds a Hybroid Live project and simulates its level without the game",
+		Description: "Builds the project, then runs the generated Lua with a stand-in of the PewPew API: the fixedpoint math is the one of the game, the players and their inputs are fake, and the update callbacks are called 30 times per simulated second. Prints what the level printed and the entities alive at the end, and fails if the level raised an error, which makes it usable in CI",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "ticks",
+				Value: 60 * simulator.TicksPerSecond,
+				Usage: "the number of ticks to simulate, there are 30 per second",
+			},
+			&cli.Uint64Flag{
+				Name:  "seed",
+				Usage: "the seed of the random numbers",
+			},
+			&cli.IntFlag{
+				Name:  "players",
+				Value: 1,
+				Usage: "the number of players",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			return run(ctx)
+		},
+	}
+}
+
+func run(ctx *cli.Context) error {
+	if err := Build_(alerts.TextFormat, false); err != nil {
+		return err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed getting current working directory: %v", err)
+	}
+	configFile, err := os.ReadFile(filepath.Join(cwd, "hybconfig.toml"))
+	if err != nil {
+		return fmt.Errorf("failed reading Hybroid Live config file: %v", err)
+	}
+	config := core.HybroidConfig{}
+	if err := toml.Unmarshal(configFile, &config); err != nil {
+		return fmt.Errorf("failed parsing Hybroid Live config file: %v", err)
+	}
+
+	outputPath := filepath.Join(cwd, config.Project.OutputDirectory)
+	report, err := simulator.Run(outputPath, simulator.Options{
+		Ticks:   ctx.Int("ticks"),
+		Seed:    ctx.Uint64("seed"),
+		Players: ctx.Int("players"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed running the level: %v", err)
+	}
+
+	for _, print := range report.Prints {
+		fmt.Printf("[tick %d] %s\n", print.Tick, print.Text)
+	}
+
+	fmt.Printf("Simulated %d tick(s)", report.Ticks)
+	if report.Stopped {
+		fmt.Print(", the game was stopped")
+	}
+	fmt.Println()
+	for _, kind := range simulator.SortedKinds(report.EntityCounts) {
+		fmt.Printf("  %s: %d alive (%d spawned)\n", kind, report.EntityCounts[kind], report.Spawned[kind])
+	}
+	for i, score := range report.Scores {
+		fmt.Printf("  player %d score: %d\n", i, score)
+	}
+
+	if report.Error == nil {
+		return nil
+	}
+	// the error refers to the generated Lua, the source maps lead back to the Hybroid sources
+	files, err := collectSourceMaps(outputPath)
+	if err != nil {
+		files = nil
+	}
+	lines := strings.Split(report.Error.Message+"\n"+report.Error.Traceback, "\n")
+	for i, line := range lines {
+		lines[i] = rewriteTraceLine(line, files, cwd)
+	}
+	return fmt.Errorf("the level raised an error at tick %d: %s", report.Error.Tick, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"hybroid/core"
+	"hybroid/evaluator"
+	"hybroid/simulator"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/urfave/cli/v2"
+)
+
+func Test() *cli.Command {
+	return &cli.Command{
+		Name:        "test",
+		Usage:       "Runs the test blocks of a Hybroid Live project",
+		Description: "Builds a separate bundle of the project that keeps the `test \"name\" { ... }` blocks, which normal builds leave out, then runs every test with a stand-in of the PewPew API and reports which passed and which failed, with the Hybroid file and line they are at. Fails if a test failed, which makes it usable in CI",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "filter",
+				Usage: "only runs the tests whose name contains the text",
+			},
+		},
+		Action: func(ctx *cli.Context) error {
+			return test(ctx)
+		},
+	}
+}
+
+func test(ctx *cli.Context) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed getting current working directory: %v", err)
+	}
+	cwd += "/"
+
+	configFile, err := os.ReadFile(cwd + "hybconfig.toml")
+	if err != nil {
+		return fmt.Errorf("failed reading Hybroid Live config file: %v", err)
+	}
+	config := core.HybroidConfig{}
+	if err := toml.Unmarshal(configFile, &config); err != nil {
+		return fmt.Errorf("failed parsing Hybroid Live config file: %v", err)
+	}
+
+	files, err := core.CollectFiles(cwd)
+	if err != nil {
+		return err
+	}
+
+	// the bundle is apart from the output directory, so the build of the level stays untouched
+	bundlePath, err := os.MkdirTemp(cwd, ".hybroid-test-")
+	if err != nil {
+		return fmt.Errorf("failed creating the test bundle directory: %v", err)
+	}
+	defer os.RemoveAll(bundlePath)
+
+	eval := evaluator.NewEvaluator(files)
+	eval.SetTests(true)
+	if err := eval.Action(cwd, filepath.Base(bundlePath)); err != nil {
+		return fmt.Errorf("build failed: %w", err)
+	}
+
+	testFiles := make([]simulator.TestFile, 0)
+	for _, file := range eval.TestFiles() {
+		testFiles = append(testFiles, simulator.TestFile{Source: file.Source, Module: file.Module})
+	}
+	if len(testFiles) == 0 {
+		fmt.Println("No tests found")
+		return nil
+	}
+
+	results := simulator.RunTests(bundlePath, testFiles, ctx.String("filter"))
+
+	// the errors refer to the generated Lua, the source maps lead back to the Hybroid sources
+	sourceMaps, err := collectSourceMaps(bundlePath)
+	if err != nil {
+		sourceMaps = nil
+	}
+
+	passed, failed := 0, 0
+	for _, result := range results {
+		location := result.Source
+		if result.Line != 0 {
+			location = fmt.Sprintf("%s:%d", result.Source, result.Line)
+		}
+		if result.Error == nil {
+			passed++
+			fmt.Printf("PASS %s (%s)\n", result.Name, location)
+			continue
+		}
+
+		failed++
+		fmt.Printf("FAIL %s (%s)\n", result.Name, location)
+		for _, print := range result.Prints {
+			fmt.Printf("    %s\n", print)
+		}
+		lines := strings.Split(result.Error.Message+"\n"+result.Error.Traceback, "\n")
+		for _, line := range lines {
+			if line != "" {
+				fmt.Printf("    %s\n", rewriteTraceLine(line, sourceMaps, cwd))
+			}
+		}
+	}
+
+	fmt.Printf("%d passed, %d failed\n", passed, failed)
+	if failed != 0 {
+		return errors.New("some tests failed")
+	}
+	return nil
+}
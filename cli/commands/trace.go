@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"hybroid/core"
+	"hybroid/generator"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/urfave/cli/v2"
+)
+
+func Trace() *cli.Command {
+	return &cli.Command{
+		Name:        "trace",
+		Aliases:     []string{"t"},
+		Usage:       "Maps a Lua stack trace back to the Hybroid sources",
+		ArgsUsage:   "[file]",
+		Description: "Reads a Lua error or stack trace from the given file (or the standard input), and rewrites every reference to a transpiled Lua file into a reference to the Hybroid file and line it was generated from, using the source maps in the output directory",
+		Action: func(ctx *cli.Context) error {
+			return trace(ctx)
+		},
+	}
+}
+
+// Matches references like `/dynamic/enemies/pylon.lua:12` and `[string "/dynamic/enemies/pylon.lua"]:12`
+var luaReferenceRegex = regexp.MustCompile(`(?:\[string ")?/?((?:[\w\-.]+/)*[\w\-]+)\.lua(?:"\])?:(\d+)`)
+
+type tracedFile struct {
+	dir   string // the directory of the source map
+	lines []*generator.OriginalPosition
+}
+
+func trace(ctx *cli.Context) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed getting current working directory: %v", err)
+	}
+
+	configFile, err := os.ReadFile(filepath.Join(cwd, "hybconfig.toml"))
+	if err != nil {
+		return fmt.Errorf("failed reading Hybroid Live config file: %v", err)
+	}
+	config := core.HybroidConfig{}
+	if err := toml.Unmarshal(configFile, &config); err != nil {
+		return fmt.Errorf("failed parsing Hybroid Live config file: %v", err)
+	}
+
+	outputPath := filepath.Join(cwd, config.Project.OutputDirectory)
+	files, err := collectSourceMaps(outputPath)
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = os.Stdin
+	if ctx.NArg() != 0 {
+		traceFile, err := os.Open(ctx.Args().First())
+		if err != nil {
+			return fmt.Errorf("failed opening trace file: %v", err)
+		}
+		defer traceFile.Close()
+		input = traceFile
+	}
+
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		fmt.Println(rewriteTraceLine(scanner.Text(), files, cwd))
+	}
+	return scanner.Err()
+}
+
+// Collects the source maps in the output directory, keyed by the path of their Lua file (without the extension)
+func collectSourceMaps(outputPath string) (map[string]tracedFile, error) {
+	files := make(map[string]tracedFile)
+	err := filepath.WalkDir(outputPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".lua.map") {
+			return nil
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sourceMap, err := generator.ParseSourceMap(contents)
+		if err != nil {
+			return fmt.Errorf("failed parsing source map '%s': %v", path, err)
+		}
+		lines, err := sourceMap.Lines()
+		if err != nil {
+			return fmt.Errorf("failed parsing source map '%s': %v", path, err)
+		}
+		rel, err := filepath.Rel(outputPath, strings.TrimSuffix(path, ".lua.map"))
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = tracedFile{dir: filepath.Dir(path), lines: lines}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed reading source maps, make sure the project was built: %v", err)
+	}
+	return files, nil
+}
+
+func rewriteTraceLine(line string, files map[string]tracedFile, cwd string) string {
+	return luaReferenceRegex.ReplaceAllStringFunc(line, func(reference string) string {
+		match := luaReferenceRegex.FindStringSubmatch(reference)
+		path, lineNumber := match[1], match[2]
+
+		// the trace can refer to the file from anywhere (/dynamic/, out/...), so the longest known suffix wins
+		var file *tracedFile
+		longest := -1
+		for luaPath, tracedFile := range files {
+			if (path == luaPath || strings.HasSuffix(path, "/"+luaPath)) && len(luaPath) > longest {
+				file, longest = &tracedFile, len(luaPath)
+			}
+		}
+		if file == nil {
+			return reference
+		}
+
+		luaLine, _ := strconv.Atoi(lineNumber)
+		if luaLine < 1 || luaLine > len(file.lines) || file.lines[luaLine-1] == nil {
+			return reference
+		}
+		position := file.lines[luaLine-1]
+		source, err := filepath.Rel(cwd, filepath.Join(file.dir, filepath.FromSlash(position.Source)))
+		if err != nil {
+			source = position.Source
+		}
+		return fmt.Sprintf("%s:%d:%d", filepath.ToSlash(source), position.Line, position.Column)
+	})
+}
@@ -1,16 +1,20 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"hybroid/alerts"
 	"hybroid/core"
+	"hybroid/evaluator"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
-	"github.com/pelletier/go-toml/v2"
 	"github.com/urfave/cli/v2"
 )
 
@@ -19,25 +23,38 @@ func Watch() *cli.Command {
 		Name:        "watch",
 		Aliases:     []string{"w"},
 		Usage:       "Starts a watcher process",
-		Description: "The Hybroid Live watcher will keep track of the project files and will automatically build them when they are updated, to remove the need for running the transpiler every time",
+		Description: "The Hybroid Live watcher will keep track of the project files and will automatically build them when they are updated, to remove the need for running the transpiler every time. Builds also mirror the output into the deploy_directory of the [project] table, if it is set",
+		Flags: []cli.Flag{
+			formatFlag(),
+		},
 		Action: func(ctx *cli.Context) error {
 			return watch(ctx)
 		},
 	}
 }
 
-func watch(ctx *cli.Context) error {
-	cwd, _ := os.Getwd()
+// The builds of a watcher, which keep the analysis of the project between them so that
+// only the files that changed and their dependents are walked again
+type watchSession struct {
+	cwd     string
+	format  alerts.Format
+	config  core.HybroidConfig
+	watcher *fsnotify.Watcher
+	// nil until the first build, and after a change that needs the project built from scratch
+	evaluator *evaluator.Evaluator
+}
 
-	configFile, err := os.ReadFile(cwd + "/hybconfig.toml")
+func watch(ctx *cli.Context) error {
+	format, err := alerts.ParseFormat(ctx.String("format"))
 	if err != nil {
-		return fmt.Errorf("failed reading Hybroid Live config file: %v", err)
+		return err
 	}
-	config := core.HybroidConfig{}
-	if err := toml.Unmarshal(configFile, &config); err != nil {
-		return fmt.Errorf("failed parsing Hybroid Live config file: %v", err)
+
+	cwd, _ := os.Getwd()
+	config, err := readConfig(cwd)
+	if err != nil {
+		return err
 	}
-	outputDirAbs, _ := filepath.Abs(filepath.Join(cwd, config.Project.OutputDirectory))
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -45,43 +62,137 @@ func watch(ctx *cli.Context) error {
 	}
 	defer watcher.Close()
 
-	// Start listening for events.
-	go func() {
-		var pending *time.Timer
-		for {
-			select {
-			case event, ok := <-watcher.Events:
-				if !ok {
-					return
-				}
-				log.Println("event:", event)
-				abs, _ := filepath.Abs(event.Name)
-				if strings.HasPrefix(abs, outputDirAbs) || strings.Contains(event.Name, ".lua") {
-					continue
-				}
-				if pending != nil {
-					pending.Stop()
-				}
-				pending = time.AfterFunc(150*time.Millisecond, func() {
-					Build_()
-				})
-			case err, ok := <-watcher.Errors:
-				if !ok {
-					return
+	session := &watchSession{cwd: cwd + "/", format: format, config: config, watcher: watcher}
+	if err := session.watchDirectory(cwd); err != nil {
+		return fmt.Errorf("failed to start a watcher process: %s", err)
+	}
+	session.build(nil)
+
+	changes := make(map[string]fsnotify.Op)
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			abs, _ := filepath.Abs(event.Name)
+			if session.ignored(abs) {
+				continue
+			}
+			log.Println("event:", event)
+			// the directories created later are watched as well, with the files they came with
+			if stat, err := os.Stat(abs); err == nil && stat.IsDir() && event.Has(fsnotify.Create) {
+				if err := session.watchDirectory(abs); err != nil {
+					log.Println("error:", err)
 				}
-				log.Println("error:", err)
 			}
+			changes[abs] |= event.Op
+			debounce.Reset(150 * time.Millisecond)
+		case <-debounce.C:
+			session.build(changes)
+			changes = make(map[string]fsnotify.Op)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Println("error:", err)
 		}
-	}()
+	}
+}
 
-	// Add a path.
-	err = watcher.Add(cwd)
-	if err != nil {
-		return fmt.Errorf("failed to start a watcher process: %s", err)
+// Watches a directory and every directory under it
+func (s *watchSession) watchDirectory(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && (strings.HasPrefix(d.Name(), ".") || s.ignored(path)) {
+			return filepath.SkipDir
+		}
+		return s.watcher.Add(path)
+	})
+}
+
+// Whether a change to the path is left out, like the ones the builds make themselves
+func (s *watchSession) ignored(path string) bool {
+	ignoredDirs := []string{s.config.Project.OutputDirectory}
+	if deployDir := s.config.Project.DeployDirectory; deployDir != "" {
+		ignoredDirs = append(ignoredDirs, deployDir)
+	}
+	for _, dir := range ignoredDirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(s.cwd, dir)
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") && dir != filepath.Clean(s.cwd) {
+			return true
+		}
 	}
+	return filepath.Ext(path) == ".lua"
+}
 
-	// Block main goroutine forever.
-	<-make(chan struct{})
+// Builds the project after the changes to the paths. The changed sources are given to the
+// evaluator of the last build, a fresh one only builds it again when a source or a directory
+// is removed or the config changes
+func (s *watchSession) build(changes map[string]fsnotify.Op) {
+	for path, op := range changes {
+		if s.evaluator == nil {
+			break
+		}
+		if filepath.Base(path) == "hybconfig.toml" {
+			config, err := readConfig(s.cwd)
+			if err != nil {
+				log.Println("error:", err)
+				return
+			}
+			s.config = config
+			s.evaluator = nil
+			break
+		}
 
-	return nil
+		removed := op.Has(fsnotify.Remove) || op.Has(fsnotify.Rename)
+		stat, err := os.Stat(path)
+		if err != nil {
+			if removed && (filepath.Ext(path) == ".hyb" || slices.Contains(s.watcher.WatchList(), path)) {
+				s.evaluator = nil
+			}
+			continue
+		}
+		if stat.IsDir() {
+			// the sources of a new directory are not known to the evaluator yet
+			if op.Has(fsnotify.Create) {
+				s.evaluator = nil
+			}
+			continue
+		}
+		if filepath.Ext(path) != ".hyb" {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			log.Println("error:", err)
+			continue
+		}
+		rel, err := filepath.Rel(s.cwd, path)
+		if err != nil {
+			continue
+		}
+		s.evaluator.UpdateFileContent(filepath.ToSlash(rel), string(content))
+	}
+
+	var err error
+	if s.evaluator == nil {
+		s.evaluator, err = runEvaluator(s.config, s.format, false, nil, s.cwd)
+	} else if err = s.evaluator.Rebuild(s.cwd, s.config.Project.OutputDirectory); err == nil {
+		err = deployOutput(s.config, s.cwd)
+	}
+	// the alerts were already printed, a failed build just waits for the next change
+	if err != nil && !errors.Is(err, evaluator.ErrCompilationFailed) {
+		log.Println("error:", err)
+	}
 }
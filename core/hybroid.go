@@ -14,12 +14,14 @@ type LevelManifest struct {
 type ProjectConfig struct {
 	Name            string `toml:"name"` // should be kebab-case
 	OutputDirectory string `toml:"output_directory"`
+	Registry        string `toml:"registry,omitempty"`         // a directory or a file:// index
+	DeployDirectory string `toml:"deploy_directory,omitempty"` // mirrors the output, like the level in the custom levels of PewPew
 }
 
 type HybroidConfig struct {
-	Level   LevelManifest `toml:"level"`
-	Project ProjectConfig `toml:"project"`
-	//Packages        []PackageConfig `toml:"packages"`
+	Level    LevelManifest     `toml:"level"`
+	Project  ProjectConfig     `toml:"project"`
+	Packages map[string]string `toml:"packages,omitempty"` // package name to version
 }
 
 type File struct {
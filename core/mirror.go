@@ -0,0 +1,60 @@
+package core
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Mirror makes dst a copy of src. Only the files whose contents differ are written, and the
+// files and directories of dst that src does not have are removed
+func Mirror(src, dst string) error {
+	kept := map[string]bool{".": true}
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		kept[rel] = true
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if existing, err := os.ReadFile(target); err == nil && bytes.Equal(existing, contents) {
+			return nil
+		}
+		return os.WriteFile(target, contents, os.ModePerm)
+	})
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(dst, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil {
+			return err
+		}
+		if kept[rel] {
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
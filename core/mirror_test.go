@@ -0,0 +1,41 @@
+package core_test
+
+import (
+	"hybroid/core"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMirror(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(src, "level.lua"), "level")
+	writeFile(t, filepath.Join(src, "enemies", "crate.lua"), "crate")
+	writeFile(t, filepath.Join(dst, "level.lua"), "old level")
+	writeFile(t, filepath.Join(dst, "enemies", "crate.lua"), "crate")
+	writeFile(t, filepath.Join(dst, "enemies", "removed.lua"), "removed")
+	writeFile(t, filepath.Join(dst, "misc", "removed.lua"), "removed")
+
+	// the unchanged file keeps its old time, as it is not written again
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(filepath.Join(dst, "enemies", "crate.lua"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := core.Mirror(src, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if contents, _ := os.ReadFile(filepath.Join(dst, "level.lua")); string(contents) != "level" {
+		t.Errorf("expected the changed file to be copied, got %q", contents)
+	}
+	if stat, err := os.Stat(filepath.Join(dst, "enemies", "crate.lua")); err != nil || !stat.ModTime().Equal(old) {
+		t.Errorf("expected the unchanged file to be left alone")
+	}
+	for _, path := range []string{filepath.Join("enemies", "removed.lua"), "misc"} {
+		if _, err := os.Stat(filepath.Join(dst, path)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed", path)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const LockFileName = "hybroid.lock"
+const PackagesDirectory = "packages"
+
+var packageNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+var packageVersionRegex = regexp.MustCompile(`^[0-9A-Za-z.+-]+$`)
+
+type LockedPackage struct {
+	Name    string `toml:"name"`
+	Version string `toml:"version"`
+	Source  string `toml:"source"`
+	Hash    string `toml:"hash"`
+}
+
+type PackageLock struct {
+	Packages []LockedPackage `toml:"package"`
+}
+
+func (pl *PackageLock) Find(name string) (LockedPackage, bool) {
+	for _, pkg := range pl.Packages {
+		if pkg.Name == name {
+			return pkg, true
+		}
+	}
+	return LockedPackage{}, false
+}
+
+// Adds the package to the lock, replacing the previous entry with the same name
+func (pl *PackageLock) Set(pkg LockedPackage) {
+	for i := range pl.Packages {
+		if pl.Packages[i].Name == pkg.Name {
+			pl.Packages[i] = pkg
+			return
+		}
+	}
+	pl.Packages = append(pl.Packages, pkg)
+	slices.SortFunc(pl.Packages, func(a, b LockedPackage) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// Splits a `name@version` package specifier. The version is empty if it was omitted
+func ParsePackageSpec(spec string) (string, string, error) {
+	name, version, hasVersion := strings.Cut(spec, "@")
+	if !packageNameRegex.MatchString(name) {
+		return "", "", fmt.Errorf("invalid package name '%s'", name)
+	}
+	if hasVersion && !packageVersionRegex.MatchString(version) {
+		return "", "", fmt.Errorf("invalid version '%s' for package '%s'", version, name)
+	}
+	return name, version, nil
+}
+
+// Compares two dot separated versions, numerically where possible
+func CompareVersions(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		if i >= len(aParts) {
+			return -1
+		}
+		if i >= len(bParts) {
+			return 1
+		}
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		if aErr == nil && bErr == nil {
+			if aNum != bNum {
+				return aNum - bNum
+			}
+			continue
+		}
+		if cmp := strings.Compare(aParts[i], bParts[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// A registry is either a directory laid out as `<name>/<version>/`, or a JSON index file
+// mapping package names to versions to directories (relative to the index)
+type Registry struct {
+	root  string
+	index map[string]map[string]string
+}
+
+// Opens the registry at the given location, which may be a path or a file:// URL.
+// Relative paths are resolved from base
+func OpenRegistry(location, base string) (*Registry, error) {
+	if location == "" {
+		return nil, fmt.Errorf("no package registry configured, set `registry` in the [project] table or pass --registry")
+	}
+	path := strings.TrimPrefix(location, "file://")
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening package registry '%s': %v", location, err)
+	}
+	if stat.IsDir() {
+		return &Registry{root: path}, nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading package registry index '%s': %v", location, err)
+	}
+	registry := &Registry{root: filepath.Dir(path)}
+	if err := json.Unmarshal(contents, &registry.index); err != nil {
+		return nil, fmt.Errorf("failed parsing package registry index '%s': %v", location, err)
+	}
+	return registry, nil
+}
+
+func (r *Registry) Versions(name string) []string {
+	versions := make([]string, 0)
+	if r.index != nil {
+		for version := range r.index[name] {
+			versions = append(versions, version)
+		}
+	} else {
+		entries, _ := os.ReadDir(filepath.Join(r.root, name))
+		for _, entry := range entries {
+			if entry.IsDir() {
+				versions = append(versions, entry.Name())
+			}
+		}
+	}
+	slices.SortFunc(versions, CompareVersions)
+	return versions
+}
+
+// Returns the resolved version and the directory of the package sources.
+// An empty version resolves to the latest one
+func (r *Registry) Resolve(name, version string) (string, string, error) {
+	versions := r.Versions(name)
+	if len(versions) == 0 {
+		return "", "", fmt.Errorf("package '%s' was not found in the registry", name)
+	}
+	if version == "" {
+		version = versions[len(versions)-1]
+	} else if !slices.Contains(versions, version) {
+		return "", "", fmt.Errorf("package '%s' has no version '%s' (available: %s)", name, version, strings.Join(versions, ", "))
+	}
+
+	dir := filepath.Join(r.root, name, version)
+	if r.index != nil {
+		entry := filepath.FromSlash(r.index[name][version])
+		if filepath.IsAbs(entry) {
+			return "", "", fmt.Errorf("package '%s' %s has an absolute path in the registry index", name, version)
+		}
+		dir = filepath.Join(r.root, entry)
+	}
+	// the sources of a package can only come from inside the registry
+	if rel, err := filepath.Rel(r.root, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("package '%s' %s is outside of the registry", name, version)
+	}
+	return version, dir, nil
+}
+
+func collectPackageSources(dir string) ([]string, error) {
+	sources := make([]string, 0)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".hyb" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, filepath.ToSlash(rel))
+		return nil
+	})
+	slices.Sort(sources)
+	return sources, err
+}
+
+// Hashes the .hyb sources of a package, including their paths, so that renames change the hash as well
+func HashPackage(dir string) (string, error) {
+	sources, err := collectPackageSources(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(sources) == 0 {
+		return "", fmt.Errorf("package at '%s' contains no .hyb files", dir)
+	}
+
+	hash := sha256.New()
+	for _, source := range sources {
+		contents, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(source)))
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(source))
+		hash.Write([]byte{0})
+		hash.Write(contents)
+		hash.Write([]byte{0})
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Copies the .hyb sources of a package into dst, replacing anything that was there before
+func VendorPackage(src, dst string) error {
+	sources, err := collectPackageSources(src)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	for _, source := range sources {
+		contents, err := os.ReadFile(filepath.Join(src, filepath.FromSlash(source)))
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, filepath.FromSlash(source))
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, contents, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
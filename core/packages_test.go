@@ -0,0 +1,126 @@
+package core_test
+
+import (
+	"hybroid/core"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistryResolve(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "helpers", "1.2.0", "helpers.hyb"), "env Helpers as Shared")
+	writeFile(t, filepath.Join(root, "helpers", "1.10.0", "helpers.hyb"), "env Helpers as Shared")
+	writeFile(t, filepath.Join(root, "index.json"), `{"helpers": {"0.1.0": "helpers/1.2.0"}}`)
+
+	registry, err := core.OpenRegistry("file://"+root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, dir, err := registry.Resolve("helpers", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.10.0" || dir != filepath.Join(root, "helpers", "1.10.0") {
+		t.Errorf("expected the latest version to resolve, got %s at %s", version, dir)
+	}
+	if _, _, err := registry.Resolve("helpers", "2.0.0"); err == nil {
+		t.Errorf("expected a missing version to fail")
+	}
+
+	index, err := core.OpenRegistry("index.json", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, dir, err = index.Resolve("helpers", "0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "0.1.0" || dir != filepath.Join(root, "helpers", "1.2.0") {
+		t.Errorf("expected the index entry to resolve, got %s at %s", version, dir)
+	}
+}
+
+func TestRegistryOutsidePaths(t *testing.T) {
+	root := t.TempDir()
+	registryDir := filepath.Join(root, "registry")
+	writeFile(t, filepath.Join(root, "secret", "secret.hyb"), "env Secret as Shared")
+	writeFile(t, filepath.Join(registryDir, "index.json"), `{"escape": {"1.0.0": "../secret"}, "absolute": {"1.0.0": "`+filepath.ToSlash(filepath.Join(root, "secret"))+`"}}`)
+
+	registry, err := core.OpenRegistry("index.json", registryDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"escape", "absolute"} {
+		if _, dir, err := registry.Resolve(name, "1.0.0"); err == nil {
+			t.Errorf("%s: expected the path outside of the registry to be rejected, got %s", name, dir)
+		}
+	}
+
+	dirRegistry, err := core.OpenRegistry(registryDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, dir, err := dirRegistry.Resolve("..", "secret"); err == nil {
+		t.Errorf("expected a name leaving the registry to be rejected, got %s", dir)
+	}
+}
+
+func TestVendorPackage(t *testing.T) {
+	src, dst := t.TempDir(), filepath.Join(t.TempDir(), "helpers")
+	writeFile(t, filepath.Join(src, "helpers.hyb"), "env Helpers as Shared")
+	writeFile(t, filepath.Join(src, "nested", "more.hyb"), "env More as Shared")
+	writeFile(t, filepath.Join(src, "README.md"), "not vendored")
+	writeFile(t, filepath.Join(dst, "stale.hyb"), "env Stale as Shared")
+
+	if err := core.VendorPackage(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"helpers.hyb", "nested/more.hyb"} {
+		if _, err := os.Stat(filepath.Join(dst, path)); err != nil {
+			t.Errorf("expected %s to be vendored: %v", path, err)
+		}
+	}
+	for _, path := range []string{"README.md", "stale.hyb"} {
+		if _, err := os.Stat(filepath.Join(dst, path)); err == nil {
+			t.Errorf("expected %s to not be in the vendored package", path)
+		}
+	}
+
+	srcHash, err := core.HashPackage(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstHash, _ := core.HashPackage(dst)
+	if srcHash != dstHash {
+		t.Errorf("expected the vendored package to hash the same as its source")
+	}
+
+	writeFile(t, filepath.Join(dst, "nested", "more.hyb"), "env Changed as Shared")
+	if changedHash, _ := core.HashPackage(dst); changedHash == srcHash {
+		t.Errorf("expected a changed source to change the hash")
+	}
+}
+
+func TestParsePackageSpec(t *testing.T) {
+	name, version, err := core.ParsePackageSpec("helpers@1.0.0")
+	if err != nil || name != "helpers" || version != "1.0.0" {
+		t.Errorf("unexpected result: %s, %s, %v", name, version, err)
+	}
+	if _, version, _ := core.ParsePackageSpec("helpers"); version != "" {
+		t.Errorf("expected an empty version, got %s", version)
+	}
+	if _, _, err := core.ParsePackageSpec("../helpers@1"); err == nil {
+		t.Errorf("expected an invalid name to fail")
+	}
+}
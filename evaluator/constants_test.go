@@ -0,0 +1,25 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"testing"
+)
+
+func TestConstantAlerts(t *testing.T) {
+	cases := []struct {
+		name, source string
+		expected     alerts.Alert
+	}{
+		{"integer division", "const A = 1 \\ 0", &alerts.ConstantDivisionByZero{}},
+		{"float division", "const A = 1 / (2 - 2)", &alerts.ConstantDivisionByZero{}},
+		{"fixedpoint division", "const A = 1f / 0fx", &alerts.ConstantDivisionByZero{}},
+		{"integer overflow", "const A = 4611686018427387904 * 2", &alerts.ConstantOverflow{}},
+		{"fixedpoint overflow", "const A = 2000000000000000f * 2000f", &alerts.ConstantOverflow{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectAlert(t, analyze(t, "env Level as Level", c.source), c.expected)
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package evaluator
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"hybroid/alerts"
 	"hybroid/ast"
@@ -12,6 +15,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 )
@@ -25,7 +29,23 @@ type Evaluator struct {
 	programs     map[string][]ast.Node
 	parseAlerts  map[string][]alerts.Alert
 	fileContents map[string]string
-	printer      alerts.Printer
+	// the macros each file declares, the public ones being callable from the files using its environment
+	macros  map[string]map[string]*ast.MacroDecl
+	printer alerts.Printer
+	// format is how Action and EmitLua print the alerts
+	format alerts.Format
+	// release builds leave out the unused code and minify the Lua they write
+	release     bool
+	outputSizes []OutputSize
+	// test bundles have the test blocks that the other builds leave out
+	tests bool
+	// analyzed is set once every walker went through a full analysis, after which
+	// only the files in changed and the environments depending on them are walked again
+	analyzed bool
+	changed  map[string]bool
+	// the files the last build wrote in outputPath, which the next one replaces only where they changed
+	outputPath string
+	written    map[string]bool
 }
 
 func NewEvaluator(files []core.File) *Evaluator {
@@ -36,7 +56,10 @@ func NewEvaluator(files []core.File) *Evaluator {
 		programs:     make(map[string][]ast.Node),
 		parseAlerts:  make(map[string][]alerts.Alert),
 		fileContents: make(map[string]string),
+		macros:       make(map[string]map[string]*ast.MacroDecl),
 		printer:      alerts.NewPrinter(),
+		format:       alerts.TextFormat,
+		changed:      make(map[string]bool),
 	}
 
 	for _, file := range evaluator.files {
@@ -53,6 +76,61 @@ func NewEvaluator(files []core.File) *Evaluator {
 	return evaluator
 }
 
+// Sets how the alerts are printed once the project is built
+func (e *Evaluator) SetFormat(format alerts.Format) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.format = format
+}
+
+// Sets whether EmitLua writes a release build
+func (e *Evaluator) SetRelease(release bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.release = release
+}
+
+// Sets whether EmitLua writes a test bundle, with the test blocks of the files
+func (e *Evaluator) SetTests(tests bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tests = tests
+}
+
+// A file with test blocks
+type TestFile struct {
+	Source string
+	// the path the Lua of the file is required with
+	Module string
+}
+
+// Returns the files of the last analysis that have test blocks, in the order of the files
+func (e *Evaluator) TestFiles() []TestFile {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	files := make([]TestFile, 0)
+	for i, w := range e.walkerList {
+		if slices.ContainsFunc(w.Program(), func(node ast.Node) bool { return node.GetType() == ast.TestDeclaration }) {
+			files = append(files, TestFile{Source: e.files[i].Path(), Module: e.files[i].NewPath("/dynamic", ".lua")})
+		}
+	}
+	return files
+}
+
+// The size of a Lua file of a release build, before and after minifying it
+type OutputSize struct {
+	Path         string
+	Size         int
+	MinifiedSize int
+}
+
+// Returns the sizes of the files the last release build wrote, in the order they were written
+func (e *Evaluator) OutputSizes() []OutputSize {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.outputSizes
+}
+
 func (e *Evaluator) GetAlerts(sourcePath string) []alerts.Alert {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -145,6 +223,7 @@ func (e *Evaluator) ensureFile(path string) string {
 	w := walker.NewWalker(sourcePath, fi.NewPath("/dynamic", ".lua"))
 	e.walkerList = append(e.walkerList, w)
 	e.files = append(e.files, fi)
+	e.analyzed = false
 
 	if absPath != "" {
 		e.walkers[absPath] = w
@@ -162,6 +241,7 @@ func (e *Evaluator) ParseAll(cwd string) error {
 }
 
 func (e *Evaluator) parseAll(cwd string) error {
+	e.analyzed = false
 	for i, w := range e.walkerList {
 		sourcePath := e.files[i].Path()
 		sourceFile, err := os.OpenFile(filepath.Join(cwd, sourcePath), os.O_RDONLY, os.ModePerm)
@@ -178,6 +258,7 @@ func (e *Evaluator) parseAll(cwd string) error {
 		e.fileContents[sourcePath] = content
 		e.parseFromContent(sourcePath, content, w)
 	}
+	e.resolveMacros()
 	return nil
 }
 
@@ -190,6 +271,11 @@ func (e *Evaluator) RunAnalysis() {
 
 func (e *Evaluator) runAnalysis() {
 	walker.SetupLibraryEnvironments()
+	if e.analyzed && e.runIncrementalAnalysis() {
+		e.changed = make(map[string]bool)
+		return
+	}
+
 	e.reparseCached()
 	e.printer = alerts.NewPrinter() // Clear previous alerts
 
@@ -234,8 +320,126 @@ func (e *Evaluator) runAnalysis() {
 		w.PostWalk()
 		e.printer.StageAlerts(e.files[i].Path(), w.GetAlerts())
 	}
+
+	e.analyzed = true
+	e.changed = make(map[string]bool)
 }
 
+// Walks again only the changed files and the environments that depend on them, keeping
+// the walks of every other environment. Returns false without changing anything if
+// a full analysis is needed instead, which is when an environment changes its name
+// or shares it with another, as that changes which environments depend on which
+func (e *Evaluator) runIncrementalAnalysis() bool {
+	if len(e.changed) == 0 {
+		return true
+	}
+
+	dirty := make(map[*walker.Walker]bool)
+	names := make([]string, 0)
+	for path := range e.changed {
+		w, ok := e.walkers[path]
+		if !ok {
+			return false
+		}
+		name := w.Env().Name
+		if environmentName(w.Program()) != name {
+			return false
+		}
+		if name != "" {
+			for _, other := range e.walkerList {
+				if other != w && other.Env().Name == name {
+					return false
+				}
+			}
+			names = append(names, name)
+		}
+		dirty[w] = true
+	}
+
+	// Pass 0: Find the transitive dependents of the changed environments
+	for i := 0; i < len(names); i++ {
+		for _, w := range e.walkerList {
+			if dirty[w] || !w.DependsOn(names[i]) {
+				continue
+			}
+			if _, ok := e.fileContents[w.Env().HybroidPath()]; !ok {
+				return false
+			}
+			dirty[w] = true
+			if w.Env().Name != "" {
+				names = append(names, w.Env().Name)
+			}
+		}
+	}
+
+	// The walk of the dependents mutated their AST, so they are parsed again as well
+	for _, w := range e.walkerList {
+		path := w.Env().HybroidPath()
+		if dirty[w] && !e.changed[path] {
+			e.parseFromContent(path, e.fileContents[path], w)
+		}
+	}
+
+	e.printer = alerts.NewPrinter()
+	for _, file := range e.files {
+		sourcePath := file.Path()
+		if parseAlerts, ok := e.parseAlerts[sourcePath]; ok {
+			e.printer.StageAlerts(sourcePath, parseAlerts)
+		}
+	}
+
+	// Pass 1: Bring the kept walks back to how they were right after walking,
+	// including the usages they made of each other
+	for _, w := range e.walkerList {
+		if !dirty[w] {
+			w.Rewind()
+		}
+	}
+	for _, w := range e.walkerList {
+		if !dirty[w] {
+			w.ReplayUsages()
+		}
+	}
+
+	// Pass 2: PreWalk and Walk the dirty walkers, their environment names stay the same
+	for _, w := range e.walkerList {
+		if dirty[w] {
+			w.Reset()
+			w.PreWalk(e.walkers)
+			if w.Env().Name != "" {
+				e.walkers[w.Env().Name] = w
+			}
+		}
+	}
+	for _, w := range e.walkerList {
+		if dirty[w] && !w.Walked {
+			w.Walk()
+		}
+	}
+
+	// Pass 3: PostWalk everything, as the usages of every environment may have changed
+	for i, w := range e.walkerList {
+		w.PostWalk()
+		e.printer.StageAlerts(e.files[i].Path(), w.GetAlerts())
+	}
+
+	return true
+}
+
+// Returns the name the environment declaration of the program gives, the same way PreWalk does
+func environmentName(program []ast.Node) string {
+	if len(program) == 0 {
+		return ""
+	}
+	if envDecl, ok := program[0].(*ast.EnvironmentDecl); ok {
+		return envDecl.Env.Path.Lexeme
+	}
+	return ""
+}
+
+// Returned by Action when the project has errors, after they were printed
+var ErrCompilationFailed = errors.New("compilation failed with errors")
+
 // Action maintains the exact same build process as before, but uses the refactored phases.
 func (e *Evaluator) Action(cwd, outputDir string) error {
 	e.mu.Lock()
@@ -246,11 +450,26 @@ func (e *Evaluator) Action(cwd, outputDir string) error {
 		return err
 	}
 
+	return e.build(cwd, outputDir)
+}
+
+// Rebuild is Action for a project the evaluator already built: instead of reading every file
+// again, only the files given to UpdateFileContent since and their dependents are walked again
+func (e *Evaluator) Rebuild(cwd, outputDir string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.build(cwd, outputDir)
+}
+
+func (e *Evaluator) build(cwd, outputDir string) error {
 	e.runAnalysis()
 
 	if e.hasErrors() {
-		e.printer.PrintAlerts()
-		return nil
+		if err := e.printer.PrintAlerts(cwd, e.format); err != nil {
+			return err
+		}
+		return ErrCompilationFailed
 	}
 
 	return e.emitLua(cwd, outputDir)
@@ -276,27 +495,59 @@ func (e *Evaluator) EmitLua(cwd, outputDir string) error {
 
 func (e *Evaluator) emitLua(cwd, outputDir string) error {
 	outputPath := filepath.Join(cwd, outputDir)
-	if outputDir != "" {
-		if stat, err := os.Lstat(outputPath); err == nil && stat.IsDir() {
-			os.RemoveAll(outputPath)
+	// the first build starts from an empty output directory, the next ones only write the files that changed
+	if outputPath != e.outputPath {
+		e.written = nil
+		if outputDir != "" {
+			if stat, err := os.Lstat(outputPath); err == nil && stat.IsDir() {
+				os.RemoveAll(outputPath)
+			}
 		}
 	}
+	written := make(map[string]bool)
 
 	gen := generator.NewGenerator()
 	for _, w := range e.walkerList {
 		gen.SetUniqueEnvName(w.Env().Name)
 	}
 
+	// the builtins of every level environment are defined once, by level.lua
+	levelBuiltins := make([]string, 0)
+	for _, builtin := range []string{"ParseSound", "ToString", "assert", "assert_eq", "HybroidCall", "HybroidStart"} {
+		for _, w := range e.walkerList {
+			if w.Env().Type == ast.LevelEnv && slices.Contains(e.usedBuiltins(w), builtin) {
+				levelBuiltins = append(levelBuiltins, builtin)
+				break
+			}
+		}
+	}
+
+	e.outputSizes = make([]OutputSize, 0)
 	for i, w := range e.walkerList {
+		if e.tests {
+			gen.EnableTests(e.files[i].Path())
+		}
 		gen.SetEnv(w.Env().Name, w.Env().Type)
 		gen.GenerateUsedLibraries(w.Env().UsedLibraries)
 
+		program := w.Program()
+		if e.release {
+			program = w.PrunedProgram()
+		}
 		if e.files[i].FileName == "level" {
-			gen.GenerateWithBuiltins(w.Program())
-		} else if w.Env().Type != ast.LevelEnv {
-			gen.Generate(w.Program(), w.Env().UsedBuiltinVars)
+			if e.release {
+				gen.Generate(program, levelBuiltins)
+			} else {
+				// ParseSound and ToString are always there, the asserts only when they are used
+				gen.GenerateWithBuiltins(program, slices.DeleteFunc(slices.Clone(levelBuiltins), func(builtin string) bool {
+					return builtin == "ParseSound" || builtin == "ToString"
+				})...)
+			}
+		} else if w.Env().Type != ast.LevelEnv || e.tests {
+			// the test bundles run their files without level.lua, which defines the builtins otherwise
+			gen.Generate(program, e.usedBuiltins(w))
 		} else {
-			gen.Generate(w.Program(), []string{})
+			gen.Generate(program, []string{})
 		}
 
 		e.printer.StageAlerts(e.files[i].Path(), gen.GetAlerts())
@@ -308,17 +559,78 @@ func (e *Evaluator) emitLua(cwd, outputDir string) error {
 
 		// Fix: .lua extension logic from original
 		luaPath := e.files[i].NewPath(outputPath, ".lua")
-		err = os.WriteFile(luaPath, []byte(gen.GetSrc()), os.ModePerm)
+		src := gen.GetSrc()
+		if e.release {
+			minified, err := generator.Minify(src)
+			if err != nil {
+				return fmt.Errorf("failed to minify %s: %v", e.files[i].Path(), err)
+			}
+			e.outputSizes = append(e.outputSizes, OutputSize{
+				Path:         filepath.ToSlash(filepath.Join(outputDir, e.files[i].NewPath("", ".lua"))),
+				Size:         len(src),
+				MinifiedSize: len(minified),
+			})
+			src = minified
+		}
+		err = writeOutput(written, luaPath, []byte(src))
 		if err != nil {
 			return fmt.Errorf("failed to write transpiled file to destination: %v", err)
 		}
 
+		// the source maps point into the code before it is minified, so release builds have none
+		if !e.release {
+			// The source is relative to the map, as the output directory can be moved around with it
+			source, err := filepath.Rel(filepath.Dir(luaPath), filepath.Join(cwd, e.files[i].Path()))
+			if err != nil {
+				source = e.files[i].Path()
+			}
+			sourceMap, err := json.Marshal(gen.GetSourceMap(filepath.Base(luaPath), filepath.ToSlash(source)))
+			if err != nil {
+				return fmt.Errorf("failed to create source map: %v", err)
+			}
+			err = writeOutput(written, luaPath+".map", sourceMap)
+			if err != nil {
+				return fmt.Errorf("failed to write source map to destination: %v", err)
+			}
+		}
+
 		gen = generator.NewGenerator()
 	}
 
-	e.printer.PrintAlerts()
+	// the files of the sources removed since the last build
+	for path := range e.written {
+		if !written[path] {
+			os.Remove(path)
+		}
+	}
+	e.outputPath = outputPath
+	e.written = written
+
 	generator.ResetGlobalGeneratorValues()
-	return nil
+	return e.printer.PrintAlerts(cwd, e.format)
+}
+
+// Writes a file of the output, unless it already has the same contents
+func writeOutput(written map[string]bool, path string, contents []byte) error {
+	written[path] = true
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, contents) {
+		return nil
+	}
+	return os.WriteFile(path, contents, os.ModePerm)
+}
+
+// Returns the builtins the code of the walker uses, including the ones of its test blocks in test bundles
+func (e *Evaluator) usedBuiltins(w *walker.Walker) []string {
+	if !e.tests {
+		return w.Env().UsedBuiltinVars
+	}
+	builtins := slices.Clone(w.Env().UsedBuiltinVars)
+	for _, builtin := range w.Env().TestBuiltinVars {
+		if !slices.Contains(builtins, builtin) {
+			builtins = append(builtins, builtin)
+		}
+	}
+	return builtins
 }
 
 // UpdateFileContent parses a specific file from a string (in-memory) instead of disk.
@@ -331,7 +643,21 @@ func (e *Evaluator) UpdateFileContent(path string, content string) error {
 func (e *Evaluator) updateFileContent(path string, content string) error {
 	path = e.ensureFile(path)
 	e.fileContents[path] = content
+	previous := publicMacros(e.macros[path])
 	e.parseFromContent(path, content, nil)
+	e.changed[path] = true
+
+	// The files using the environment expand its public macros, so they are parsed again when those change
+	env := environmentName(e.programs[path])
+	if env == "" || publicMacros(e.macros[path]) == previous {
+		return nil
+	}
+	for other, otherContent := range e.fileContents {
+		if other != path && usesEnvironment(e.programs[other], env) {
+			e.parseFromContent(other, otherContent, nil)
+			e.changed[other] = true
+		}
+	}
 	return nil
 }
 
@@ -339,6 +665,96 @@ func (e *Evaluator) reparseCached() {
 	for path, content := range e.fileContents {
 		e.parseFromContent(path, content, nil)
 	}
+	e.resolveMacros()
+}
+
+// Parses again the files calling macros that were unknown when they were parsed, as the
+// environments declaring them may have been parsed after them. Stops once a pass resolves none
+func (e *Evaluator) resolveMacros() {
+	for range e.fileContents {
+		resolved := false
+		for path, content := range e.fileContents {
+			before := unknownMacros(e.parseAlerts[path])
+			if before == 0 {
+				continue
+			}
+			e.parseFromContent(path, content, nil)
+			if unknownMacros(e.parseAlerts[path]) < before {
+				resolved = true
+			}
+		}
+		if !resolved {
+			return
+		}
+	}
+}
+
+// Returns the macros declared in the files of the environment
+func (e *Evaluator) environmentMacros(env string) map[string]*ast.MacroDecl {
+	var macros map[string]*ast.MacroDecl
+	for path, fileMacros := range e.macros {
+		if environmentName(e.programs[path]) != env {
+			continue
+		}
+		if macros == nil {
+			macros = make(map[string]*ast.MacroDecl)
+		}
+		for name, macro := range fileMacros {
+			macros[name] = macro
+		}
+	}
+	return macros
+}
+
+func unknownMacros(fileAlerts []alerts.Alert) int {
+	count := 0
+	for _, alert := range fileAlerts {
+		if _, ok := alert.(*alerts.UnknownMacro); ok {
+			count++
+		}
+	}
+	return count
+}
+
+// Returns whether the program has a use statement of the environment
+func usesEnvironment(program []ast.Node, env string) bool {
+	for _, node := range program {
+		if use, ok := node.(*ast.UseStmt); ok && use.PathExpr.Path.Lexeme == env {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the public macros as text, to tell whether they changed between two parses of a file
+func publicMacros(macros map[string]*ast.MacroDecl) string {
+	names := make([]string, 0, len(macros))
+	for name, macro := range macros {
+		if macro.IsPub {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		macro := macros[name]
+		builder.WriteString(name)
+		for _, param := range macro.Params {
+			builder.WriteString(" " + param.Name.Lexeme)
+		}
+		builder.WriteString(" {")
+		for i, token := range macro.Tokens {
+			// the globals are qualified with the environment in the expansions
+			if macro.Globals != nil && macro.Globals[i] {
+				builder.WriteString(" :" + token.Lexeme)
+				continue
+			}
+			builder.WriteString(" " + token.Lexeme)
+		}
+		builder.WriteString(" }\n")
+	}
+	return builder.String()
 }
 
 func (e *Evaluator) parseFromContent(path, content string, w *walker.Walker) {
@@ -349,11 +765,14 @@ func (e *Evaluator) parseFromContent(path, content string, w *walker.Walker) {
 	e.parseAlerts[path] = fileAlerts
 	e.printer.StageAlerts(path, fileAlerts)
 	if tokenizeErr != nil {
+		delete(e.macros, path)
 		return
 	}
 
 	p := parser.NewParser(tokens)
+	p.SetMacroLookup(e.environmentMacros)
 	program := p.Parse()
+	e.macros[path] = p.Macros()
 	fileAlerts = append(fileAlerts, p.GetAlerts()...)
 	e.parseAlerts[path] = fileAlerts
 	e.printer.StageAlerts(path, fileAlerts)
@@ -475,10 +894,12 @@ func (e *Evaluator) RemoveFile(path string) bool {
 		delete(e.programs, sp)
 		delete(e.parseAlerts, sp)
 		delete(e.fileContents, sp)
+		delete(e.macros, sp)
 	}
 	for _, abs := range matchedAbs {
 		delete(e.walkers, abs)
 	}
+	e.analyzed = false
 
 	return true
 }
@@ -488,8 +909,7 @@ func (e *Evaluator) AnalyzeFile(path string) *walker.Walker {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.ensureFile(path)
-	// For now, we re-run full project analysis to ensure cross-file consistency.
-	// This can be optimized later to be incremental.
+	// Only the files changed since the last analysis and their dependents are walked again
 	e.runAnalysis()
 
 	canonical := e.canonicalPath(path)
@@ -3,6 +3,7 @@ package evaluator
 import (
 	"hybroid/alerts"
 	"hybroid/core"
+	"hybroid/generator"
 	"os"
 	"strings"
 	"testing"
@@ -115,3 +116,60 @@ func TestExpressions(t *testing.T) {
 	newEval(t)
 	check(t)
 }
+
+func TestMacros(t *testing.T) {
+	testFolderName = "macros"
+
+	newEval(t)
+	check(t)
+}
+
+func TestConstants(t *testing.T) {
+	testFolderName = "constants"
+
+	newEval(t)
+	check(t)
+}
+
+func TestSourceMap(t *testing.T) {
+	testFolderName = "statements"
+
+	newEval(t)
+	mapFile, err := os.ReadFile(cwd + testsFolder + testFolderName + "/test.lua.map")
+	if err != nil {
+		t.Fatalf("failed reading source map: %v", err)
+	}
+	sourceMap, err := generator.ParseSourceMap(mapFile)
+	if err != nil {
+		t.Fatalf("failed parsing source map: %v", err)
+	}
+	positions, err := sourceMap.Lines()
+	if err != nil {
+		t.Fatalf("failed decoding source map: %v", err)
+	}
+
+	luaLines := strings.Split(readFile(t), "\n")
+	if len(positions) != len(luaLines) {
+		t.Fatalf("expected %d mapped lines, got %d", len(luaLines), len(positions))
+	}
+	// Lua line -> Hybroid line
+	expected := map[string]int{
+		"local function E_thing()": 4,
+		"\treturn E_a":             5,
+		"elseif E_thing() then":    7,
+		"E_thing2(E_data)":         45,
+	}
+	for i, line := range luaLines {
+		hybLine, found := expected[line]
+		if !found {
+			continue
+		}
+		delete(expected, line)
+		if positions[i] == nil || positions[i].Source != "test.hyb" || positions[i].Line != hybLine {
+			t.Errorf("expected Lua line %d (%q) to map to test.hyb:%d, got %+v", i+1, line, hybLine, positions[i])
+		}
+	}
+	for line := range expected {
+		t.Errorf("expected Lua line %q was not generated", line)
+	}
+}
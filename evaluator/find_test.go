@@ -0,0 +1,53 @@
+package evaluator
+
+import (
+	"hybroid/core"
+	"hybroid/simulator"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindInLoopCondition(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "level.hyb"), []byte(`env Level as Level
+
+test "find in a loop condition" {
+    let items = [1, 3, 2, 3]
+    let passes = 0
+    while find 3 in items != nil {
+        if let index = find 3 in items {
+            items[index] = 0
+        }
+        passes += 1
+    }
+    assert_eq(passes, 2)
+}
+
+test "find without a match" {
+    let counts = {"" = 1, "a" = 2}
+    assert_eq(find 1 in counts ?? "none", "")
+    assert_eq(find 3 in counts ?? "none", "none")
+    assert_eq(find 3 in [1, 2] ?? 0, 0)
+}
+`), 0644)
+
+	e := NewEvaluator([]core.File{{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"}})
+	e.SetTests(true)
+	if err := e.Action(root+"/", "bundle"); err != nil {
+		t.Fatal(err)
+	}
+	if list := e.GetAlerts("level.hyb"); len(list) != 0 {
+		t.Fatalf("unexpected alerts %v", alertIDs(list))
+	}
+
+	results := simulator.RunTests(filepath.Join(root, "bundle"), []simulator.TestFile{{Source: "level.hyb", Module: "/dynamic/level.lua"}}, "")
+	if len(results) != 2 {
+		t.Fatalf("expected 2 tests to run, got %+v", results)
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			t.Errorf("expected %q to pass, got %v", result.Name, result.Error)
+		}
+	}
+}
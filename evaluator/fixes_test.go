@@ -0,0 +1,147 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"hybroid/core"
+	"testing"
+)
+
+// Analyzes the level next to the helpers and returns it with the fixes of its alerts applied
+func fixLevel(t *testing.T, source string) (string, int) {
+	t.Helper()
+	files := []core.File{
+		{DirectoryPath: ".", FileName: "helpers", FileExtension: ".hyb"},
+		{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"},
+	}
+	e := NewEvaluator(files)
+	e.UpdateFileContent("helpers.hyb", `env Helpers as Shared
+
+pub fn Triple(number x) -> number {
+	return x * 3
+}
+`)
+	e.UpdateFileContent("level.hyb", source)
+	e.RunAnalysis()
+
+	return alerts.ApplyFixes(source, alerts.FixesOf(e.GetAlerts("level.hyb")))
+}
+
+func TestFixes(t *testing.T) {
+	cases := []struct {
+		name, source, expected string
+	}{
+		{
+			name: "use statements",
+			source: `env Level as Level
+
+use Pewpew
+
+Print(ToString(Triple(2)))
+Print(ToString(Triple(3)))
+`,
+			expected: `env Level as Level
+
+use Pewpew
+use Helpers
+
+Print(ToString(Triple(2)))
+Print(ToString(Triple(3)))
+`,
+		},
+		{
+			name: "default cases",
+			source: `env Level as Level
+
+use Pewpew
+
+fn Name(number x) -> text {
+    let name = match x {
+        1 => "one"
+        2 => "two"
+    }
+    return name
+}
+Print(Name(1))
+`,
+			expected: `env Level as Level
+
+use Pewpew
+
+fn Name(number x) -> text {
+    let name = match x {
+        1 => "one"
+        2 => "two"
+        else => ""
+    }
+    return name
+}
+Print(Name(1))
+`,
+		},
+		{
+			name: "unreachable code",
+			source: `env Level as Level
+
+use Pewpew
+
+fn Get() -> number {
+	return 1
+	Print("never")
+	Print(
+		"printed")
+}
+Print(ToString(Get()))
+`,
+			expected: `env Level as Level
+
+use Pewpew
+
+fn Get() -> number {
+	return 1
+}
+Print(ToString(Get()))
+`,
+		},
+		{
+			name: "explicit types",
+			source: `env Level as Level
+
+use Pewpew
+
+let a, b = 1
+Print(ToString(a + b))
+`,
+			expected: `env Level as Level
+
+use Pewpew
+
+number a, b = 1
+Print(ToString(a + b))
+`,
+		},
+	}
+
+	for _, c := range cases {
+		fixed, applied := fixLevel(t, c.source)
+		if applied == 0 {
+			t.Errorf("%s: no fix was applied", c.name)
+		}
+		if fixed != c.expected {
+			t.Errorf("%s: unexpected result:\n%s\nexpected:\n%s", c.name, fixed, c.expected)
+		}
+	}
+}
+
+func TestApplyFixesSkipsOverlaps(t *testing.T) {
+	source := "abcdef\n"
+	fixes := []alerts.Fix{
+		{Title: "first", Edits: []alerts.TextEdit{{Start: alerts.Position{Line: 1, Column: 2}, End: alerts.Position{Line: 1, Column: 4}, NewText: "X"}}},
+		{Title: "overlapping", Edits: []alerts.TextEdit{{Start: alerts.Position{Line: 1, Column: 3}, End: alerts.Position{Line: 1, Column: 5}, NewText: "Y"}}},
+		{Title: "same as first", Edits: []alerts.TextEdit{{Start: alerts.Position{Line: 1, Column: 2}, End: alerts.Position{Line: 1, Column: 4}, NewText: "X"}}},
+		{Title: "insertion", Edits: []alerts.TextEdit{{Start: alerts.Position{Line: 1, Column: 6}, End: alerts.Position{Line: 1, Column: 6}, NewText: "Z"}}},
+	}
+	fixed, applied := alerts.ApplyFixes(source, fixes)
+	if fixed != "aXdeZf\n" || applied != 2 {
+		t.Errorf("unexpected result %q with %d fix(es)", fixed, applied)
+	}
+}
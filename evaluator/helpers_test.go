@@ -0,0 +1,37 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"hybroid/core"
+	"testing"
+)
+
+// Analyzes a file made of the environment header and the source, and returns its alerts
+func analyze(t *testing.T, envHeader, source string) []alerts.Alert {
+	t.Helper()
+	e := NewEvaluator([]core.File{{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"}})
+	e.UpdateFileContent("level.hyb", envHeader+"\n\n"+source+"\n")
+	e.RunAnalysis()
+	return e.GetAlerts("level.hyb")
+}
+
+// Fails the test unless the list has an alert of the same kind as the expected one
+func expectAlert(t *testing.T, list []alerts.Alert, expected alerts.Alert) {
+	t.Helper()
+	for _, alert := range list {
+		if alert.ID() == expected.ID() {
+			return
+		}
+	}
+	t.Errorf("expected %s, got %v", expected.ID(), alertIDs(list))
+}
+
+// Fails the test for every error of the list, warnings are allowed
+func expectNoErrors(t *testing.T, list []alerts.Alert) {
+	t.Helper()
+	for _, alert := range list {
+		if alert.AlertType() == alerts.Error {
+			t.Errorf("unexpected %s: %s", alert.ID(), alert.Message())
+		}
+	}
+}
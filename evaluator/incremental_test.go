@@ -0,0 +1,281 @@
+package evaluator
+
+import (
+	"fmt"
+	"hybroid/alerts"
+	"hybroid/core"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var incrementalFiles = []core.File{
+	{DirectoryPath: ".", FileName: "helpers", FileExtension: ".hyb"},
+	{DirectoryPath: ".", FileName: "enemies", FileExtension: ".hyb"},
+	{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"},
+	{DirectoryPath: ".", FileName: "unrelated", FileExtension: ".hyb"},
+}
+
+var incrementalSources = map[string]string{
+	"helpers.hyb": `env Helpers as Shared
+
+pub let speed = 10
+pub let unused = 2
+
+pub fn Double(number x) -> number {
+	return x * 2
+}
+
+pub class Counter {
+	number count
+
+	new() {
+		self.count = 0
+	}
+}
+`,
+	"enemies.hyb": `env Enemies as Shared
+
+use Helpers
+
+pub fn Speed() -> number {
+	let counter = new Counter()
+	return Double(speed) + counter.count
+}
+`,
+	"level.hyb": `env Level as Level
+
+use Pewpew
+use Enemies
+
+Pewpew:Print(Speed() .. "")
+`,
+	"unrelated.hyb": `env Unrelated as Shared
+
+let lonely = 1
+`,
+}
+
+// Returns the alerts of every file, in a form that does not depend on the order they were reported in
+func alertsOf(t *testing.T, e *Evaluator) map[string][]string {
+	t.Helper()
+	result := make(map[string][]string)
+	for _, file := range e.files {
+		fileAlerts := make([]string, 0)
+		for _, alert := range e.GetAlerts(file.Path()) {
+			fileAlerts = append(fileAlerts, fmt.Sprintf("%s %s %v", alert.ID(), alert.Message(), alert.SnippetSpecifier()))
+		}
+		slices.Sort(fileAlerts)
+		result[file.Path()] = fileAlerts
+	}
+	return result
+}
+
+func fullAnalysis(t *testing.T, sources map[string]string) map[string][]string {
+	t.Helper()
+	e := NewEvaluator(incrementalFiles)
+	for path, source := range sources {
+		e.UpdateFileContent(path, source)
+	}
+	e.RunAnalysis()
+	return alertsOf(t, e)
+}
+
+func TestIncrementalAnalysis(t *testing.T) {
+	sources := make(map[string]string)
+	for path, source := range incrementalSources {
+		sources[path] = source
+	}
+
+	e := NewEvaluator(incrementalFiles)
+	for path, source := range sources {
+		e.UpdateFileContent(path, source)
+	}
+	e.RunAnalysis()
+	if got, expected := alertsOf(t, e), fullAnalysis(t, sources); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("the first analysis differs from a full one:\n%v\n%v", got, expected)
+	}
+
+	edits := []struct {
+		name, path, source string
+		rewalked           []string
+	}{
+		{
+			name: "unused variable gets used by a dependent",
+			path: "level.hyb",
+			source: `env Level as Level
+
+use Pewpew
+use Enemies
+use Helpers
+
+Pewpew:Print(Speed() .. "" .. unused)
+`,
+			rewalked: []string{"level.hyb"},
+		},
+		{
+			name: "dependency stops using a class",
+			path: "enemies.hyb",
+			source: `env Enemies as Shared
+
+use Helpers
+
+pub fn Speed() -> number {
+	return Double(speed)
+}
+`,
+			rewalked: []string{"enemies.hyb", "level.hyb"},
+		},
+		{
+			name: "type error in the root of the graph",
+			path: "helpers.hyb",
+			source: `env Helpers as Shared
+
+pub let speed = "fast"
+pub let unused = 2
+
+pub fn Double(number x) -> number {
+	return x * 2
+}
+
+pub class Counter {
+	number count
+
+	new() {
+		self.count = 0
+	}
+}
+`,
+			rewalked: []string{"helpers.hyb", "enemies.hyb", "level.hyb"},
+		},
+		{
+			name:     "parse error in an unrelated file",
+			path:     "unrelated.hyb",
+			source:   "env Unrelated as Shared\n\nlet lonely = (1\n",
+			rewalked: []string{"unrelated.hyb"},
+		},
+		{
+			name:     "environment renamed",
+			path:     "helpers.hyb",
+			source:   "env Helping as Shared\n\npub let speed = 10\n",
+			rewalked: []string{"helpers.hyb", "enemies.hyb", "level.hyb", "unrelated.hyb"},
+		},
+		{
+			name:     "environment renamed back",
+			path:     "helpers.hyb",
+			source:   incrementalSources["helpers.hyb"],
+			rewalked: []string{"helpers.hyb", "enemies.hyb", "level.hyb", "unrelated.hyb"},
+		},
+	}
+
+	for _, edit := range edits {
+		programs := make(map[string]any)
+		for _, w := range e.WalkerList() {
+			programs[w.Env().HybroidPath()] = &w.Program()[0]
+		}
+
+		sources[edit.path] = edit.source
+		e.UpdateFileContent(edit.path, edit.source)
+		e.AnalyzeFile(edit.path)
+
+		if got, expected := alertsOf(t, e), fullAnalysis(t, sources); fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("%s: the incremental analysis differs from a full one:\n%v\n%v", edit.name, got, expected)
+		}
+		for _, w := range e.WalkerList() {
+			path := w.Env().HybroidPath()
+			reparsed := programs[path] != &w.Program()[0]
+			if reparsed != slices.Contains(edit.rewalked, path) {
+				t.Errorf("%s: expected %s to be walked again to be %v", edit.name, path, !reparsed)
+			}
+		}
+	}
+}
+
+func TestIncrementalAnalysisWithoutChanges(t *testing.T) {
+	e := NewEvaluator(incrementalFiles)
+	for path, source := range incrementalSources {
+		e.UpdateFileContent(path, source)
+	}
+	e.RunAnalysis()
+	expected := alertsOf(t, e)
+	program := &e.WalkerList()[0].Program()[0]
+
+	e.AnalyzeFile("level.hyb")
+	if got := alertsOf(t, e); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected the alerts to be kept:\n%v\n%v", got, expected)
+	}
+	if program != &e.WalkerList()[0].Program()[0] {
+		t.Errorf("expected nothing to be walked again")
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	root := t.TempDir()
+	sources := maps.Clone(incrementalSources)
+	sources["level.hyb"] = strings.Replace(sources["level.hyb"], `Speed() .. ""`, "ToString(Speed())", 1)
+	for path, source := range sources {
+		os.WriteFile(filepath.Join(root, path), []byte(source), 0644)
+	}
+
+	e := NewEvaluator(incrementalFiles)
+	if err := e.Action(root+"/", "out"); err != nil {
+		t.Fatal(err)
+	}
+	// the files of the environments that do not change keep their old time, as they are not written again
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	unrelated := filepath.Join(root, "out", "unrelated.lua")
+	os.Chtimes(unrelated, old, old)
+
+	changed := strings.Replace(sources["helpers.hyb"], "speed = 10", "speed = 20", 1)
+	os.WriteFile(filepath.Join(root, "helpers.hyb"), []byte(changed), 0644)
+	e.UpdateFileContent("helpers.hyb", changed)
+	if err := e.Rebuild(root+"/", "out"); err != nil {
+		t.Fatal(err)
+	}
+
+	if stat, err := os.Stat(unrelated); err != nil || !stat.ModTime().Equal(old) {
+		t.Errorf("expected unrelated.lua to be left alone")
+	}
+
+	// the rebuild writes the same files a new build does
+	if err := NewEvaluator(incrementalFiles).Action(root+"/", "fresh"); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range incrementalFiles {
+		for _, extension := range []string{".lua", ".lua.map"} {
+			rebuilt, _ := os.ReadFile(file.NewPath(filepath.Join(root, "out"), extension))
+			fresh, _ := os.ReadFile(file.NewPath(filepath.Join(root, "fresh"), extension))
+			if string(rebuilt) != string(fresh) {
+				t.Errorf("%s: expected the rebuild to write\n%s\ngot\n%s", file.NewPath("", extension), fresh, rebuilt)
+			}
+		}
+	}
+}
+
+func TestIncrementalInterfaceConformance(t *testing.T) {
+	files := []core.File{
+		{DirectoryPath: ".", FileName: "shapes", FileExtension: ".hyb"},
+		{DirectoryPath: ".", FileName: "crates", FileExtension: ".hyb"},
+	}
+	sources := map[string]string{
+		"shapes.hyb": "env Shapes as Shared\n\npub interface Named {\n\tfn Name() -> text\n}\n",
+		"crates.hyb": "env Crates as Shared\n\npub class Crate impl Shapes:Named {\n\tnew() {}\n\n\tfn Name() -> text {\n\t\treturn \"crate\"\n\t}\n}\n",
+	}
+
+	e := NewEvaluator(files)
+	for path, source := range sources {
+		e.UpdateFileContent(path, source)
+	}
+	e.RunAnalysis()
+	expectNoErrors(t, e.GetAlerts("crates.hyb"))
+
+	// the implementor has to be checked again against the changed interface
+	sources["shapes.hyb"] = "env Shapes as Shared\n\npub interface Named {\n\tfn Name() -> text\n\tfn Size() -> number\n}\n"
+	e.UpdateFileContent("shapes.hyb", sources["shapes.hyb"])
+	e.AnalyzeFile("shapes.hyb")
+	expectAlert(t, e.GetAlerts("crates.hyb"), &alerts.MissingInterfaceMethod{})
+}
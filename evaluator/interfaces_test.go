@@ -0,0 +1,133 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"hybroid/core"
+	"hybroid/simulator"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const interfacesPrelude = `interface Named {
+    fn Name() -> text
+}
+
+`
+
+func TestInterfaceAlerts(t *testing.T) {
+	cases := []struct {
+		name, source string
+		expected     alerts.Alert
+	}{
+		{"missing method", "class A impl Named {\n    new() {}\n}", &alerts.MissingInterfaceMethod{}},
+		{"mismatched method", "class A impl Named {\n    new() {}\n\n    fn Name() -> number {\n        return 1\n    }\n}", &alerts.InterfaceMethodMismatch{}},
+		{"not an interface", "class B {\n    new() {}\n}\n\nclass A impl B {\n    new() {}\n}", &alerts.NotAnInterface{}},
+		{"method with a body", "interface Sized {\n    fn Size() -> number {\n        return 1\n    }\n}", &alerts.InterfaceMethodWithBody{}},
+		{"missing entity method", "entity E impl Named {\n    spawn(fixed x, fixed y) {}\n\n    destroy() {}\n}", &alerts.MissingInterfaceMethod{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectAlert(t, analyze(t, "env Level as Level", interfacesPrelude+c.source), c.expected)
+		})
+	}
+}
+
+func TestInterfaceConformance(t *testing.T) {
+	source := `class A impl Named {
+    new() {}
+
+    fn Name() -> text {
+        return "a"
+    }
+}
+
+fn Greet(Named n) -> text {
+    return n.Name()
+}
+
+Named n = new A()
+list<Named> all = [new A()]
+let greeting = Greet(n) .. Greet(all[1])`
+
+	expectNoErrors(t, analyze(t, "env Level as Level", interfacesPrelude+source))
+}
+
+func TestInterfacesAtRuntime(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "level.hyb"), []byte(`env Level as Level
+
+use Pewpew
+
+interface Damageable {
+    fn Damage(number amount) -> number
+    fn Name() -> text
+}
+
+class Crate impl Damageable {
+    number hp
+
+    new(number hp) {
+        self.hp = hp
+    }
+
+    fn Damage(number amount) -> number {
+        hp -= amount
+        return hp
+    }
+
+    fn Name() -> text {
+        return "crate"
+    }
+}
+
+entity Pylon impl Damageable {
+    number hp = 10
+
+    spawn(fixed x, fixed y) {
+    }
+
+    destroy() {
+        DestroyEntity(self)
+    }
+
+    fn Damage(number amount) -> number {
+        hp -= amount * 2
+        return hp
+    }
+
+    fn Name() -> text {
+        return "pylon"
+    }
+}
+
+list<Damageable> things = [new Crate(5)]
+Table:Insert(things, spawn Pylon(0f, 0f))
+for _, thing in things {
+    Print(thing.Name() .. " " .. ToString(thing.Damage(3)))
+}
+`), 0644)
+
+	e := NewEvaluator([]core.File{{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"}})
+	if err := e.Action(root+"/", "out"); err != nil {
+		t.Fatal(err)
+	}
+	report, err := simulator.Run(filepath.Join(root, "out"), simulator.Options{Ticks: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Error != nil {
+		t.Fatalf("unexpected error: %s", report.Error.Message)
+	}
+
+	prints := []string{"crate 2", "pylon 4"}
+	if len(report.Prints) != len(prints) {
+		t.Fatalf("expected the prints %v, got %v", prints, report.Prints)
+	}
+	for i, print := range prints {
+		if report.Prints[i].Text != print {
+			t.Errorf("expected the print %q, got %q", print, report.Prints[i].Text)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"hybroid/core"
+	"hybroid/simulator"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var macroFiles = []core.File{
+	{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"},
+	{DirectoryPath: ".", FileName: "helpers", FileExtension: ".hyb"},
+}
+
+const macroHelpers = `env Helpers as Shared
+
+pub macro Double(x) => x * 2
+
+macro Triple(x) => x * 3
+
+pub macro Sextuple(x) => @Triple(@Double(x))
+`
+
+// Builds the test bundle of the sources and runs its test blocks, failing on any alert
+func runMacroTests(t *testing.T, files []core.File, sources map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	testFiles := make([]simulator.TestFile, 0)
+	for _, file := range files {
+		os.WriteFile(filepath.Join(root, file.Path()), []byte(sources[file.Path()]), 0644)
+	}
+
+	e := NewEvaluator(files)
+	e.SetTests(true)
+	if err := e.Action(root+"/", "bundle"); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if list := e.GetAlerts(file.Path()); len(list) != 0 {
+			t.Fatalf("unexpected alerts in %s: %v", file.Path(), alertIDs(list))
+		}
+	}
+	for _, file := range e.TestFiles() {
+		testFiles = append(testFiles, simulator.TestFile{Source: file.Source, Module: file.Module})
+	}
+
+	results := simulator.RunTests(filepath.Join(root, "bundle"), testFiles, "")
+	if len(results) == 0 {
+		t.Fatal("expected the tests to run")
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			t.Errorf("expected %q to pass, got %v", result.Name, result.Error)
+		}
+	}
+}
+
+func TestPublicMacros(t *testing.T) {
+	// the level is parsed before the environment declaring the macros it calls
+	runMacroTests(t, macroFiles, map[string]string{
+		"helpers.hyb": macroHelpers,
+		"level.hyb": `env Level as Level
+
+use Helpers
+
+test "macros of a used environment" {
+    assert_eq(@Double(2), 4)
+    assert_eq(@Sextuple(1), 6)
+}
+`,
+	})
+}
+
+func TestMacroGlobals(t *testing.T) {
+	// the private helper of the macro is not captured by the function of the level with the same name
+	runMacroTests(t, macroFiles, map[string]string{
+		"helpers.hyb": `env Helpers as Shared
+
+fn offset() -> number {
+    return 10
+}
+
+let scale = 3
+
+pub macro Shifted(x) => x * scale + offset()
+`,
+		"level.hyb": `env Level as Level
+
+use Helpers
+
+fn offset() -> number {
+    return 100
+}
+
+test "globals of a macro from another environment" {
+    assert_eq(@Shifted(1), 13)
+    assert_eq(@Shifted(offset()), 310)
+}
+`,
+	})
+}
+
+func TestUnsharedMacros(t *testing.T) {
+	cases := []struct {
+		name, level, helpers string
+	}{
+		{
+			name:    "private macro",
+			level:   "env Level as Level\n\nuse Helpers\n\nlet x = @Triple(1)\n",
+			helpers: macroHelpers,
+		},
+		{
+			name:    "environment not used",
+			level:   "env Level as Level\n\nlet x = @Double(1)\n",
+			helpers: macroHelpers,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := NewEvaluator(macroFiles)
+			e.UpdateFileContent("level.hyb", c.level)
+			e.UpdateFileContent("helpers.hyb", c.helpers)
+			e.RunAnalysis()
+			expectAlert(t, e.GetAlerts("level.hyb"), &alerts.UnknownMacro{})
+		})
+	}
+}
+
+func TestPublicMacrosChanged(t *testing.T) {
+	e := NewEvaluator(macroFiles)
+	e.UpdateFileContent("helpers.hyb", macroHelpers)
+	e.UpdateFileContent("level.hyb", "env Level as Level\n\nuse Helpers\n\nlet x = @Double(1)\n")
+	e.RunAnalysis()
+	expectNoErrors(t, e.GetAlerts("level.hyb"))
+
+	e.UpdateFileContent("helpers.hyb", "env Helpers as Shared\n\nmacro Double(x) => x * 2\n")
+	e.RunAnalysis()
+	expectAlert(t, e.GetAlerts("level.hyb"), &alerts.UnknownMacro{})
+}
+
+func TestMacroFieldNames(t *testing.T) {
+	runMacroTests(t, macroFiles[:1], map[string]string{
+		"level.hyb": `env Level as Level
+
+macro Check(n) {
+    let value = n
+    let box = struct{ value = value + 1 }
+    struct{ number value } typed = struct{ value = value + 2 }
+    assert_eq(box.value, n + 1)
+    assert_eq(typed.value, n + 2)
+}
+
+macro Wrapped(value) => struct{ value = value }
+
+test "fields named like the variables of a macro" {
+    @Check(2)
+    let wrapped = @Wrapped(5)
+    assert_eq(wrapped.value, 5)
+}
+`,
+	})
+}
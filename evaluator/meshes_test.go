@@ -0,0 +1,43 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"testing"
+)
+
+func TestMeshAlerts(t *testing.T) {
+	cases := []struct {
+		name, source string
+		expected     alerts.Alert
+	}{
+		{"index past the vertexes", "pub meshes = [struct{\n    vertexes = [[0, 0], [1, 0]],\n    segments = [[0, 2]]\n}]", &alerts.SegmentIndexOutOfRange{}},
+		{"negative index", "pub meshes = [struct{\n    vertexes = [[0, 0], [1, 0]],\n    segments = [[-1, 0]]\n}]", &alerts.SegmentIndexOutOfRange{}},
+		{"fractional index", "pub meshes = [struct{\n    vertexes = [[0, 0], [1, 0]],\n    segments = [[0, 0.5]]\n}]", &alerts.SegmentIndexOutOfRange{}},
+		{"too few colors", "pub meshes = [struct{\n    vertexes = [[0, 0], [1, 0]],\n    segments = [[0, 1]],\n    colors = [0xffffffff]\n}]", &alerts.MeshColorCountMismatch{}},
+		{"mixed dimensions", "pub meshes = [struct{\n    vertexes = [[0, 0], [1, 0, 1]],\n    segments = [[0, 1]]\n}]", &alerts.MixedVertexDimensions{}},
+		{"mesh variable", "Mesh m = struct{\n    vertexes = [[0, 0], [1, 0]],\n    segments = [[0, 3]]\n}\npub meshes = [m]", &alerts.SegmentIndexOutOfRange{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectAlert(t, analyze(t, "env M as Mesh", c.source), c.expected)
+		})
+	}
+}
+
+func TestValidMeshes(t *testing.T) {
+	cases := []struct {
+		name, source string
+	}{
+		{"2D", "pub meshes = [struct{\n    vertexes = [[0, 0], [1, 0], [1, 1]],\n    segments = [[0, 1, 2, 0]],\n    colors = [0xffffffff, 0xff0000ff, 0x00ff00ff]\n}]"},
+		{"3D without colors", "pub meshes = [struct{\n    vertexes = [[0, 0, 0], [1, 0, 1]],\n    segments = [[0, 1]]\n}]"},
+		{"mixed dimensions only warn", "pub meshes = [struct{\n    vertexes = [[0, 0], [1, 0, 1]],\n    segments = [[0, 1]]\n}]"},
+		{"indices from variables", "let last = 5\npub meshes = [struct{\n    vertexes = [[0, 0], [1, 0]],\n    segments = [[0, last]]\n}]"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectNoErrors(t, analyze(t, "env M as Mesh", c.source))
+		})
+	}
+}
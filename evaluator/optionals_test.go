@@ -0,0 +1,122 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"hybroid/core"
+	"hybroid/simulator"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const optionalsPrelude = `class Point {
+    number x
+
+    new(number x) {
+        self.x = x
+    }
+}
+
+let points = {"a" = new Point(1)}
+
+fn Show(number n) {}
+fn Check(bool b) {}
+`
+
+func TestOptionalAlerts(t *testing.T) {
+	cases := []struct {
+		name, source string
+		expected     alerts.Alert
+	}{
+		{"map lookup access", "let x = points[\"a\"].x", &alerts.PossiblyNilAccess{}},
+		{"optional call", "alias Callback = fn()\nCallback? callback\ncallback()", &alerts.PossiblyNilAccess{}},
+		{"optional compound assignment", "number? n\nn += 1", &alerts.PossiblyNilAccess{}},
+		{"map compound assignment", "let counts = {\"a\" = 1}\ncounts[\"a\"] += 1", &alerts.PossiblyNilAccess{}},
+		{"optional map value compound assignment", "map<number?> counts = {}\ncounts[\"a\"] += 1", &alerts.PossiblyNilAccess{}},
+		{"untyped nil", "let x = nil", &alerts.InvalidType{}},
+		{"nil to non optional", "number x = nil", &alerts.ExplicitTypeMismatch{}},
+		{"coalescing non optional", "let x = 1 ?? 2", &alerts.TypeMismatch{}},
+		{"wrong fallback", "let x = points[\"a\"] ?? 2", &alerts.TypeMismatch{}},
+		{"if let non optional", "if let x = 1 {\n    Show(x)\n}", &alerts.TypeMismatch{}},
+		{"if let with or", "if let p = points[\"b\"] or true {\n    Show(p.x)\n}", &alerts.UnwrapWithOrCondition{}},
+		{"let in a while", "Point? o\nwhile let z = o {\n    o = nil\n}", &alerts.UnwrapOutsideIfCondition{}},
+		{"let as a value", "Point? o\nlet k = let y = o", &alerts.UnwrapOutsideIfCondition{}},
+		{"let as an argument", "Point? o\nif Check(let y = o) {\n}", &alerts.UnwrapOutsideIfCondition{}},
+		{"unwrapped outside the if", "if let p = points[\"b\"] {\n    Show(p.x)\n}\nShow(p.x)", &alerts.UndeclaredVariableAccess{}},
+		{"assigned after the check", "fn F(Point? p) {\n    if p != nil {\n        p = nil\n        Show(p.x)\n    }\n}", &alerts.PossiblyNilAccess{}},
+		{"checked in a closure", "Point? p\nif p != nil {\n    let f = fn() {\n        Show(p.x)\n    }\n    f()\n}", &alerts.PossiblyNilAccess{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectAlert(t, analyze(t, "env Level as Level", optionalsPrelude+c.source), c.expected)
+		})
+	}
+}
+
+func TestOptionalNarrowing(t *testing.T) {
+	cases := []struct {
+		name, source string
+	}{
+		{"if let", "if let p = points[\"a\"] {\n    Show(p.x)\n}"},
+		{"else if let", "if false == true {\n    Show(1)\n} else if let p = points[\"a\"] {\n    Show(p.x)\n}"},
+		{"not nil", "fn F(Point? p) {\n    if p != nil {\n        Show(p.x)\n    }\n}"},
+		{"nil else", "fn F(Point? p) {\n    if p == nil {\n        Show(0)\n    } else {\n        Show(p.x)\n    }\n}"},
+		{"guard", "fn F(Point? p) -> number {\n    if p == nil {\n        return 0\n    }\n    return p.x\n}"},
+		{"and chain", "fn F(Point? p) {\n    if p != nil and p.x > 0 {\n        Show(p.x)\n    }\n}"},
+		{"or chain", "fn F(Point? p) -> bool {\n    return p == nil or p.x > 0\n}"},
+		{"coalescing", "let p = points[\"a\"] ?? new Point(0)\nShow(p.x)"},
+		{"coalescing chain", "Point? other\nlet p = points[\"a\"] ?? other ?? new Point(0)\nShow(p.x)"},
+		{"optional arguments", "fn F(Point? p) {\n    Check(p == nil)\n}\nF(nil)\nF(new Point(1))\nF(points[\"a\"])"},
+		{"optional returns", "fn F(text key) -> Point? {\n    if key == \"\" {\n        return nil\n    }\n    return points[key]\n}\nCheck(F(\"a\") == nil)"},
+		{"map element with a fallback", "let counts = {\"a\" = 1}\ncounts[\"a\"] = (counts[\"a\"] ?? 0) + 1"},
+		{"map assignment", "points[\"b\"] = new Point(2)\npoints[\"a\"] = nil"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectNoErrors(t, analyze(t, "env Level as Level", optionalsPrelude+c.source))
+		})
+	}
+}
+
+func TestOptionalsAtRuntime(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "level.hyb"), []byte(`env Level as Level
+
+let names = {"a" = "first"}
+bool? flag = false
+let kept = flag ?? true
+Pewpew:Print((names["b"] ?? "none") .. " " .. (names["a"] ?? "none"))
+if kept == false {
+    Pewpew:Print("false is kept")
+}
+if let name = names["b"] {
+    Pewpew:Print(name)
+} else if let name = names["a"] {
+    Pewpew:Print("found " .. name)
+}
+`), 0644)
+
+	e := NewEvaluator([]core.File{{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"}})
+	if err := e.Action(root+"/", "out"); err != nil {
+		t.Fatal(err)
+	}
+	report, err := simulator.Run(filepath.Join(root, "out"), simulator.Options{Ticks: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Error != nil {
+		t.Fatalf("unexpected error: %s", report.Error.Message)
+	}
+
+	prints := []string{"none first", "false is kept", "found first"}
+	if len(report.Prints) != len(prints) {
+		t.Fatalf("expected the prints %v, got %v", prints, report.Prints)
+	}
+	for i, print := range prints {
+		if report.Prints[i].Text != print {
+			t.Errorf("expected the print %q, got %q", print, report.Prints[i].Text)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package evaluator
+
+import (
+	"hybroid/core"
+	"hybroid/simulator"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOperatorPrecedence(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "level.hyb"), []byte(`env Level as Level
+
+test "operator precedence" {
+    // the numbers are read from a list so that they are not folded while compiling
+    let n = [1, 2, 3, 4, 10]
+    assert_eq("a" .. "b" == "ab", true)
+    assert_eq(-n[2] ^ n[2], -4)
+    assert_eq(n[2] ^ n[3] ^ n[2], 512)
+    assert_eq(n[5] - n[4] - n[3], 3)
+    assert_eq(n[5] - n[2] * n[3], 4)
+    assert_eq(n[1] < n[2] == true, true)
+    assert_eq(n[1] == n[1] or n[1] == n[2] and false, true)
+}
+`), 0644)
+
+	e := NewEvaluator([]core.File{{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"}})
+	e.SetTests(true)
+	if err := e.Action(root+"/", "bundle"); err != nil {
+		t.Fatal(err)
+	}
+	if list := e.GetAlerts("level.hyb"); len(list) != 0 {
+		t.Fatalf("unexpected alerts %v", alertIDs(list))
+	}
+
+	results := simulator.RunTests(filepath.Join(root, "bundle"), []simulator.TestFile{{Source: "level.hyb", Module: "/dynamic/level.lua"}}, "")
+	if len(results) != 1 || results[0].Error != nil {
+		t.Fatalf("expected the test to pass, got %+v", results)
+	}
+}
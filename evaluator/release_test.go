@@ -0,0 +1,65 @@
+package evaluator
+
+import (
+	"hybroid/core"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReleaseBuild(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "level.hyb"), []byte(`env Level as Level
+
+use Pewpew
+
+fn Unused() {
+    Print("never called")
+}
+
+class Point {
+    number x
+
+    new() {
+        self.x = 1
+    }
+}
+
+fn Greet(text name) {
+    Print("Hello " .. name)
+}
+
+Greet(ToString(2))
+`), 0644)
+
+	e := NewEvaluator([]core.File{{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"}})
+	e.SetRelease(true)
+	if err := e.Action(root+"/", "out"); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := os.ReadFile(filepath.Join(root, "out", "level.lua"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lua := string(output)
+	for _, pruned := range []string{"never called", "HC", "ParseSound", "\n\t"} {
+		if strings.Contains(lua, pruned) {
+			t.Errorf("expected the release build to leave out %q, got\n%s", pruned, lua)
+		}
+	}
+	for _, kept := range []string{"function ToString(", `"Hello "`} {
+		if !strings.Contains(lua, kept) {
+			t.Errorf("expected the release build to keep %q, got\n%s", kept, lua)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "out", "level.lua.map")); err == nil {
+		t.Error("expected the release build to write no source map")
+	}
+
+	sizes := e.OutputSizes()
+	if len(sizes) != 1 || sizes[0].Path != "out/level.lua" || sizes[0].MinifiedSize != len(lua) || sizes[0].Size <= len(lua) {
+		t.Errorf("unexpected sizes %+v", sizes)
+	}
+}
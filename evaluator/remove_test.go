@@ -0,0 +1,50 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"testing"
+)
+
+func TestRemoveAlerts(t *testing.T) {
+	cases := []struct {
+		name, source string
+		expected     alerts.Alert
+	}{
+		{"past a literal", "remove 3 from [1, 2]", &alerts.ListIndexPastLength{}},
+		{"past a constant", "const fruits = [\"kiwi\", \"apple\"]\nremove 3 from fruits", &alerts.ListIndexPastLength{}},
+		{"past a variable", "let fruits = [\"banana\", \"kiwi\", \"apple\"]\nremove 9 from fruits", &alerts.ListIndexPastLength{}},
+		{"past a variable removed from", "let fruits = [\"banana\", \"kiwi\", \"apple\"]\nremove 1 from fruits\nremove 3 from fruits", &alerts.ListIndexPastLength{}},
+		{"zero", "let fruits = [\"kiwi\"]\nremove 0 from fruits", &alerts.ListIndexOutOfBounds{}},
+		{"fraction", "let fruits = [\"kiwi\"]\nremove 1.5 from fruits", &alerts.InvalidListIndex{}},
+		{"text index", "let fruits = [\"kiwi\"]\nremove \"kiwi\" from fruits", &alerts.TypeMismatch{}},
+		{"number key", "let inventory = {\"kiwis\" = 10}\nremove 1 from inventory", &alerts.TypeMismatch{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectAlert(t, analyze(t, "env Level as Level", c.source), c.expected)
+		})
+	}
+}
+
+func TestRemoveFromChangedLists(t *testing.T) {
+	cases := []struct {
+		name, source string
+	}{
+		{"within the length", "let fruits = [\"banana\", \"kiwi\", \"apple\"]\nremove 3 from fruits\nremove 2 from fruits"},
+		{"after an assignment", "let fruits = [\"banana\"]\nfruits = [\"kiwi\", \"apple\", \"pear\"]\nremove 3 from fruits"},
+		{"after an index assignment", "let fruits = [\"banana\"]\nfruits[2] = \"kiwi\"\nremove 2 from fruits"},
+		{"after being passed", "fn Fill(list<text> l) {\n    l[2] = \"kiwi\"\n}\nlet fruits = [\"banana\"]\nFill(fruits)\nremove 2 from fruits"},
+		{"in a loop", "let fruits = [\"banana\"]\nrepeat 2 {\n    fruits[2] = \"kiwi\"\n    remove 2 from fruits\n}"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for _, alert := range analyze(t, "env Level as Level", c.source) {
+				if alert.ID() == (&alerts.ListIndexPastLength{}).ID() {
+					t.Errorf("unexpected %s: %s", alert.ID(), alert.Message())
+				}
+			}
+		})
+	}
+}
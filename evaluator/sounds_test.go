@@ -0,0 +1,90 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"hybroid/core"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func jfxrLink(sound string) string {
+	return "https://jfxr.frozenfractal.com/#" + url.PathEscape(sound)
+}
+
+func TestSoundAlerts(t *testing.T) {
+	cases := []struct {
+		name, sound string
+		expected    alerts.Alert
+	}{
+		{"unknown field", `{"attack":0,"loudness":3}`, &alerts.UnknownSoundField{}},
+		{"out of range", `{"attack":0,"frequency":5}`, &alerts.InvalidSoundValue{}},
+		{"text for a number", `{"attack":"slow"}`, &alerts.InvalidSoundValue{}},
+		{"number for a bool", `{"normalization":1}`, &alerts.InvalidSoundValue{}},
+		{"unknown waveform", `{"waveform":"noise"}`, &alerts.InvalidSoundValue{}},
+		{"not an object", `[1, 2]`, &alerts.InvalidSoundLink{}},
+		{"broken json", `{"attack":`, &alerts.InvalidSoundLink{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectAlert(t, analyze(t, "env S as Sound", "pub sounds = [ParseSound(\""+jfxrLink(c.sound)+"\")]"), c.expected)
+		})
+	}
+
+	list := analyze(t, "env S as Sound", "pub sounds = [ParseSound(\"https://jfxr.frozenfractal.com/\")]")
+	if len(list) != 1 || list[0].ID() != (&alerts.InvalidSoundLink{}).ID() {
+		t.Errorf("link without a sound: expected %s, got %v", (&alerts.InvalidSoundLink{}).ID(), alertIDs(list))
+	}
+}
+
+func TestSoundsAtCompileTime(t *testing.T) {
+	sound := `{"_version":1,"_name":"Blip","_locked":[],"sampleRate":44100,"attack":0,"frequency":440,"waveform":"square","normalization":true,"amplification":50}`
+	cases := []struct {
+		name, source string
+		contains     []string
+		helper       bool
+	}{
+		{
+			"literal links",
+			"pub sounds = [ParseSound(\"" + jfxrLink(sound) + "\")]",
+			[]string{"sampleRate = 44100", "frequency = 440", `waveform = "square"`, "normalization = true", "amplification = 0.5"},
+			false,
+		},
+		{
+			"links from variables",
+			"let link = \"" + jfxrLink(sound) + "\"\npub sounds = [ParseSound(\"" + jfxrLink(sound) + "\"), ParseSound(link)]",
+			[]string{"frequency = 440", "ParseSound(E_link)"},
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		root := t.TempDir()
+		os.WriteFile(filepath.Join(root, "sound.hyb"), []byte("env S as Sound\n\n"+c.source+"\n"), 0644)
+
+		e := NewEvaluator([]core.File{{DirectoryPath: ".", FileName: "sound", FileExtension: ".hyb"}})
+		if err := e.Action(root+"/", "out"); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		output, err := os.ReadFile(filepath.Join(root, "out", "sound.lua"))
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		lua := string(output)
+
+		for _, part := range c.contains {
+			if !strings.Contains(lua, part) {
+				t.Errorf("%s: expected %q in\n%s", c.name, part, lua)
+			}
+		}
+		if !c.helper && strings.Contains(lua, "_version") {
+			t.Errorf("%s: expected the JFXR metadata to be left out of\n%s", c.name, lua)
+		}
+		if helper := strings.Contains(lua, "function ParseSound"); helper != c.helper {
+			t.Errorf("%s: expected the ParseSound helper to be emitted: %v, got\n%s", c.name, c.helper, lua)
+		}
+	}
+}
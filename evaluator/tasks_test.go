@@ -0,0 +1,129 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"hybroid/core"
+	"hybroid/simulator"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const tasksPrelude = "bool ready = false\n\n"
+
+func TestTaskAlerts(t *testing.T) {
+	cases := []struct {
+		name, source string
+		expected     alerts.Alert
+	}{
+		{"wait at the top level", "wait 1", &alerts.WaitOutsideTask{}},
+		{"wait in a function", "fn F() {\n    wait 1\n}", &alerts.WaitOutsideTask{}},
+		{"wait in a closure of a task", "task fn T() {\n    let f = fn() {\n        wait 1\n    }\n    f()\n}", &alerts.WaitOutsideTask{}},
+		{"wait in a tick of a task", "task fn T() {\n    tick {\n        wait 1\n    }\n}", &alerts.WaitOutsideTask{}},
+		{"task called outside of a task", "task fn T() {}\nT()", &alerts.TaskCallOutsideTask{}},
+		{"start of a function", "fn F() {}\nstart F()", &alerts.NotATask{}},
+		{"start without a call", "task fn T() {}\nstart T", &alerts.ExpectedCallAfterStart{}},
+		{"wait for text", "task fn T() {\n    wait \"long\"\n}", &alerts.TypeMismatch{}},
+		{"wait until a number", "task fn T() {\n    wait until 1\n}", &alerts.TypeMismatch{}},
+		{"function for a task", "fn F() {}\ntask fn() t = F", &alerts.ExplicitTypeMismatch{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectAlert(t, analyze(t, "env Level as Level", tasksPrelude+c.source), c.expected)
+		})
+	}
+}
+
+func TestTasks(t *testing.T) {
+	cases := []struct {
+		name, source string
+	}{
+		{"wait", "task fn T() {\n    wait 1\n    wait until ready\n}\nstart T()"},
+		{"task from a task", "task fn A() {\n    wait 1\n}\ntask fn B() {\n    A()\n}\nstart B()"},
+		{"task value", "task fn A() {\n    wait 1\n}\ntask fn() t = A\nstart t()"},
+		{"task parameter", "task fn Then(task fn() next) {\n    wait 1\n    next()\n}\ntask fn A() {}\nstart Then(A)"},
+		{"method", "class C {\n    new() {}\n\n    task fn Run() {\n        wait 1\n    }\n}\nlet c = new C()\nstart c.Run()"},
+		{"wait in a loop", "task fn T() {\n    repeat 3 {\n        wait 2\n    }\n}\nstart T()"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectNoErrors(t, analyze(t, "env Level as Level", tasksPrelude+c.source))
+		})
+	}
+}
+
+func TestTasksAtRuntime(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "level.hyb"), []byte(`env Level as Level
+
+use Pewpew
+
+number counter = 0
+
+task fn Flash() {
+    wait 1
+    Print("flash")
+}
+
+task fn Countdown(text name, number ticks) {
+    repeat 3 with i {
+        Print(name .. " " .. ToString(i))
+        wait ticks
+    }
+    Flash()
+    wait until counter >= 10
+    Print(name .. " done")
+}
+
+class Blinker {
+    number times = 0
+
+    new() {}
+
+    task fn Blink() {
+        wait 2
+        times += 1
+        Print("blink " .. ToString(times))
+    }
+}
+
+tick {
+    counter += 1
+}
+
+start Countdown("a", 2)
+let b = new Blinker()
+start b.Blink()
+`), 0644)
+
+	e := NewEvaluator([]core.File{{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"}})
+	if err := e.Action(root+"/", "out"); err != nil {
+		t.Fatal(err)
+	}
+	report, err := simulator.Run(filepath.Join(root, "out"), simulator.Options{Ticks: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Error != nil {
+		t.Fatalf("unexpected error: %s", report.Error.Message)
+	}
+
+	prints := []simulator.Print{
+		{Tick: 0, Text: "a 1"},
+		{Tick: 2, Text: "a 2"},
+		{Tick: 2, Text: "blink 1"},
+		{Tick: 4, Text: "a 3"},
+		{Tick: 7, Text: "flash"},
+		{Tick: 10, Text: "a done"},
+	}
+	if len(report.Prints) != len(prints) {
+		t.Fatalf("expected the prints %v, got %v", prints, report.Prints)
+	}
+	for i, print := range prints {
+		if report.Prints[i] != print {
+			t.Errorf("expected the print %v, got %v", print, report.Prints[i])
+		}
+	}
+}
@@ -0,0 +1,22 @@
+local pewpew = pewpew
+
+
+
+
+
+
+
+
+
+
+
+
+local E_speed = 1fx
+local E_moved = E_speed * 8fx + 4fx
+local E_turned = 3.578fx - E_speed
+local E_power = 4.0
+local E_wrapped = 2
+local E_ratio = 7.0
+pewpew.print("wave one" .. ToString(true) .. ToString(true))
+pewpew.print(ToString(E_moved + E_turned + 1.2048fx))
+pewpew.print(ToString(E_power + E_wrapped + E_ratio + 519.0))
@@ -48,7 +48,39 @@ HEE_Entity_Destroy(E_k, 2)
 local E_po = {
 	["thing"] = E_l
 }
-HEE_Entity[E_po["thing"][2]][1]()
-HEE_Entity_method1(E_po["thing"][2])
+local E_things = E_po["thing"]
+if E_things ~= nil then
+	HEE_Entity[E_things[2]][1]()
+	HEE_Entity_method1(E_things[2])
 
+end
 local _ = ToString(2)
+local E_fruits = {"banana", "kiwi", "apple"}
+local E_inventory = {
+	["bananas"] = 2,
+	["kiwis"] = 10
+}
+local _ = (function(H_, H0)
+	for H1, H2 in ipairs(H0) do
+		if H2 == H_ then
+			return H1
+		end
+	end
+	return nil
+end)("apple", E_fruits)
+local _ = (((function(H3, H4)
+	for H5, H6 in pairs(H4) do
+		if H6 == H3 then
+			return H5
+		end
+	end
+	return nil
+end)(10, E_inventory) or "none")) .. "!"
+local _ = (function(H7, H8)
+	for H9, Ha in ipairs(H8) do
+		if Ha == H7 then
+			return H9
+		end
+	end
+	return nil
+end)("pear", {"pear"}) == nil
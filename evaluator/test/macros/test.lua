@@ -0,0 +1,28 @@
+local pewpew = pewpew
+
+
+
+
+
+
+
+local E_tmp, E_other = 6, 4
+do
+	local E___Swap_tmp = E_tmp
+	E_tmp = E_other
+	E_other = E___Swap_tmp
+end
+pewpew.print(("Hello John!"))
+local function E_Sum()
+	local E_sum = 0
+	do
+		for E___Times_i = 1, E_tmp, 1 do
+			(function(E_i)
+				E_sum = E_sum + (E_i)
+			end)(E___Times_i)
+			::GL_::
+		end
+	end
+	return E_sum + E_other
+end
+local _ = E_Sum()
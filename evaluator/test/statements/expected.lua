@@ -10,7 +10,6 @@ end
 local function E_thing2(E_data)
 	local E_a = E_data["numbers"]
 	local E_b = function()
-
 		if #E_a < 3 then
 			return 1
 		else 
@@ -32,7 +31,6 @@ local function E_thing2(E_data)
 		::GL_::
 	end
 	return 0, function()
-
 		return -1
 	end
 end
@@ -41,3 +39,10 @@ local E_data = {
 	booleans = {false}
 }
 E_thing2(E_data)
+local E_fruits = {"banana", "kiwi", "apple", "pear"}
+table.remove(E_fruits, 4)
+local E_inventory = {
+	["bananas"] = 2,
+	["cherries"] = 12
+}
+E_inventory["cherries"] = nil
@@ -39,3 +39,10 @@ local E_data = {
 	booleans = {false}
 }
 E_thing2(E_data)
+local E_fruits = {"banana", "kiwi", "apple", "pear"}
+table.remove(E_fruits, 4)
+local E_inventory = {
+	["bananas"] = 2,
+	["cherries"] = 12
+}
+E_inventory["cherries"] = nil
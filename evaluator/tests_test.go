@@ -0,0 +1,103 @@
+package evaluator
+
+import (
+	"hybroid/alerts"
+	"hybroid/core"
+	"hybroid/simulator"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testedSource = `env Helpers as Level
+
+pub fn Double(number x) -> number {
+    return x * 2
+}
+
+test "doubles" {
+    assert_eq(Double(2), 4)
+}
+
+test "fails" {
+    assert(Double(2) == 5, "two doubled is not five")
+}
+`
+
+func writeTestedProject(t *testing.T) (string, []core.File) {
+	t.Helper()
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "level.hyb"), []byte("env Level as Level\n\nuse Helpers\n\nPewpew:Print(ToString(Double(1)))\n"), 0644)
+	os.WriteFile(filepath.Join(root, "helpers.hyb"), []byte(testedSource), 0644)
+	return root, []core.File{
+		{DirectoryPath: ".", FileName: "level", FileExtension: ".hyb"},
+		{DirectoryPath: ".", FileName: "helpers", FileExtension: ".hyb"},
+	}
+}
+
+func TestBuildsLeaveTestsOut(t *testing.T) {
+	for _, release := range []bool{false, true} {
+		root, files := writeTestedProject(t)
+		e := NewEvaluator(files)
+		e.SetRelease(release)
+		if err := e.Action(root+"/", "out"); err != nil {
+			t.Fatal(err)
+		}
+
+		// the asserts of the test blocks would replace the assert of Lua in the level
+		for _, file := range []string{"level.lua", "helpers.lua"} {
+			output, err := os.ReadFile(filepath.Join(root, "out", file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, left := range []string{"HybroidTests", "doubles", "assert"} {
+				if strings.Contains(string(output), left) {
+					t.Errorf("expected the build of %s (release: %v) to leave out %q, got\n%s", file, release, left, output)
+				}
+			}
+		}
+	}
+}
+
+func TestTestBundle(t *testing.T) {
+	root, files := writeTestedProject(t)
+	e := NewEvaluator(files)
+	e.SetTests(true)
+	if err := e.Action(root+"/", "bundle"); err != nil {
+		t.Fatal(err)
+	}
+
+	testFiles := e.TestFiles()
+	if len(testFiles) != 1 || testFiles[0].Source != "helpers.hyb" || testFiles[0].Module != "/dynamic/helpers.lua" {
+		t.Fatalf("unexpected test files %+v", testFiles)
+	}
+
+	results := simulator.RunTests(filepath.Join(root, "bundle"), []simulator.TestFile{{Source: testFiles[0].Source, Module: testFiles[0].Module}}, "")
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if results[0].Name != "doubles" || results[0].Source != "helpers.hyb" || results[0].Line != 7 || results[0].Error != nil {
+		t.Errorf("expected the first test to pass at helpers.hyb:7, got %+v", results[0])
+	}
+	if results[1].Name != "fails" || results[1].Error == nil || !strings.Contains(results[1].Error.Message, "two doubled is not five") {
+		t.Errorf("expected the second test to fail with its message, got %+v", results[1])
+	}
+}
+
+func TestTestBlockAlerts(t *testing.T) {
+	cases := []struct {
+		name, source string
+		expected     alerts.Alert
+	}{
+		{"duplicate name", "test \"a\" {}\ntest \"a\" {}", &alerts.DuplicateElement{}},
+		{"nested", "fn F() {\n    test \"a\" {}\n}", &alerts.InvalidStmtInLocalBlock{}},
+		{"wrong assert", "test \"a\" {\n    assert(1)\n}", &alerts.InvalidArgumentType{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expectAlert(t, analyze(t, "env Level as Level", c.source), c.expected)
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package formatter
+
+import (
+	"fmt"
+	"hybroid/ast"
+	"hybroid/tokens"
+	"slices"
+)
+
+func (p *printer) node(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.EnvironmentDecl:
+		p.write("env ")
+		p.token(node.Env.Path)
+		p.write(" as ")
+		p.token(node.EnvType.Token)
+	case *ast.VariableDecl:
+		p.variableDeclaration(node)
+	case *ast.FunctionDecl:
+		p.pub(node.IsPub)
+		p.task(node.IsTask)
+		p.write("fn ")
+		p.token(node.Name)
+		p.signature(node.Generics, node.Params, node.Returns)
+		p.body(node.Body)
+	case *ast.AliasDecl:
+		p.pub(node.IsPub)
+		p.write("alias ")
+		p.token(node.Name)
+		p.write(" = ")
+		p.typeExpr(node.Type)
+	case *ast.EnumDecl:
+		p.enumDeclaration(node)
+	case *ast.ClassDecl:
+		p.pub(node.IsPub)
+		p.write("class ")
+		p.token(node.Name)
+		p.genericParams(node.GenericParams)
+		p.impls(node.Impls)
+		members := make([]ast.Node, 0)
+		for i := range node.Fields {
+			members = append(members, &node.Fields[i])
+		}
+		if node.Constructor != nil {
+			members = append(members, node.Constructor)
+		}
+		for i := range node.Methods {
+			members = append(members, &node.Methods[i])
+		}
+		p.members(members)
+	case *ast.EntityDecl:
+		p.pub(node.IsPub)
+		p.write("entity ")
+		p.token(node.Name)
+		p.genericParams(node.GenericParams)
+		p.impls(node.Impls)
+		members := make([]ast.Node, 0)
+		for i := range node.Fields {
+			members = append(members, &node.Fields[i])
+		}
+		if node.Spawner != nil {
+			members = append(members, node.Spawner)
+		}
+		if node.Destroyer != nil {
+			members = append(members, node.Destroyer)
+		}
+		for _, callback := range node.Callbacks {
+			members = append(members, callback)
+		}
+		for i := range node.Methods {
+			members = append(members, &node.Methods[i])
+		}
+		p.members(members)
+	case *ast.InterfaceDecl:
+		p.pub(node.IsPub)
+		p.write("interface ")
+		p.token(node.Name)
+		members := make([]ast.Node, 0)
+		for i := range node.Methods {
+			members = append(members, &node.Methods[i])
+		}
+		p.membersWith(members, func(member ast.Node) {
+			method := member.(*ast.MethodDecl)
+			p.write("fn ")
+			p.token(method.Name)
+			p.signature(method.Generics, method.Params, method.Returns)
+		})
+	case *ast.ConstructorDecl:
+		p.token(node.Token)
+		p.signature(node.Generics, node.Params, nil)
+		p.body(node.Body)
+	case *ast.EntityFunctionDecl:
+		p.token(node.Token)
+		p.signature(node.Generics, node.Params, node.Returns)
+		p.body(node.Body)
+	case *ast.MethodDecl:
+		// methods cannot be public, IsPub is inherited from the declaration holding them
+		p.task(node.IsTask)
+		p.write("fn ")
+		p.token(node.Name)
+		p.signature(node.Generics, node.Params, node.Returns)
+		p.body(node.Body)
+	case *ast.MacroDecl:
+		p.macroDeclaration(node)
+	case *ast.TestDecl:
+		p.token(node.Token)
+		p.write(" ")
+		p.token(node.Name)
+		p.body(node.Body)
+	default:
+		if !p.statement(node) {
+			p.expression(node)
+		}
+	}
+}
+
+func (p *printer) pub(isPub bool) {
+	if isPub {
+		p.write("pub ")
+	}
+}
+
+func (p *printer) task(isTask bool) {
+	if isTask {
+		p.write("task ")
+	}
+}
+
+func (p *printer) variableDeclaration(decl *ast.VariableDecl) {
+	switch {
+	case decl.IsPub:
+		p.write("pub ")
+		if decl.IsConst {
+			p.write("const ")
+		}
+	case decl.IsConst:
+		p.write("const ")
+	case decl.Type == nil:
+		p.write("let ")
+	}
+	if decl.Type != nil {
+		p.typeExpr(decl.Type)
+		p.write(" ")
+	}
+	for i, ident := range decl.Identifiers {
+		if i != 0 {
+			p.write(", ")
+		}
+		p.token(ident.Name)
+	}
+	if len(decl.Expressions) != 0 {
+		p.write(" = ")
+		p.expressions(decl.Expressions)
+	}
+}
+
+func (p *printer) enumDeclaration(decl *ast.EnumDecl) {
+	p.pub(decl.IsPub)
+	p.write("enum ")
+	p.token(decl.Name)
+	p.write(" ")
+	open := p.find(tokens.LeftBrace, p.cursor)
+	starts := make([]int, len(decl.Fields))
+	for i, field := range decl.Fields {
+		starts[i] = p.index(field.Name)
+	}
+	// unlike literals, enums always get a line for every variant
+	if len(starts) == 0 && !p.hasComments(open, p.closing(open)) {
+		p.write("{}")
+		p.advanceTo(p.closing(open))
+		return
+	}
+	p.spread(open, "{", "}", starts, func(p *printer, i int) {
+		p.token(decl.Fields[i].Name)
+	})
+}
+
+func (p *printer) impls(impls []*ast.TypeExpr) {
+	if len(impls) == 0 {
+		return
+	}
+	p.write(" impl ")
+	p.types(impls)
+}
+
+// Members are printed in the order of the source, the declarations only keep them by kind
+func (p *printer) members(members []ast.Node) {
+	p.membersWith(members, p.node)
+}
+
+func (p *printer) membersWith(members []ast.Node, print func(ast.Node)) {
+	slices.SortFunc(members, func(a, b ast.Node) int {
+		return p.start(a) - p.start(b)
+	})
+	open := p.find(tokens.LeftBrace, p.cursor)
+	close := p.closing(open)
+	p.cursor = open
+	p.write(" {")
+	if len(members) == 0 && !p.hasComments(open, close) {
+		p.write("}")
+		p.cursor = close
+		return
+	}
+	p.indent++
+	for i, member := range members {
+		p.lineBefore(p.start(member), i != 0)
+		print(member)
+	}
+	p.indent--
+	p.lineBeforeClosing(close)
+	p.write("}")
+	p.cursor = close
+}
+
+// Macros are kept as they were written, their body only gets parsed when they are called
+func (p *printer) macroDeclaration(decl *ast.MacroDecl) {
+	start := p.start(decl)
+	end := p.index(decl.Name)
+	if decl.MacroType == ast.ProgramExpansion {
+		end = p.closing(p.find(tokens.LeftBrace, end))
+	} else if len(decl.Tokens) != 0 {
+		end = p.index(decl.Tokens[len(decl.Tokens)-1])
+	}
+	p.write(p.sourceText(start, end))
+	p.skipComments(end)
+	p.cursor = end
+}
+
+func (p *printer) signature(generics []*ast.IdentifierExpr, params []ast.FunctionParam, returns []*ast.TypeExpr) {
+	p.genericParams(generics)
+	p.write("(")
+	p.params(params)
+	p.write(")")
+	p.returns(returns)
+}
+
+func (p *printer) genericParams(generics []*ast.IdentifierExpr) {
+	if len(generics) == 0 {
+		return
+	}
+	p.write("<")
+	for i, generic := range generics {
+		if i != 0 {
+			p.write(", ")
+		}
+		p.token(generic.Name)
+	}
+	p.write(">")
+}
+
+// Parameters sharing the type of the previous one were written without it, as in `fixed x, y`
+func (p *printer) params(params []ast.FunctionParam) {
+	for i, param := range params {
+		if i != 0 {
+			p.write(", ")
+		}
+		if i == 0 || param.Type != params[i-1].Type {
+			p.typeExpr(param.Type)
+			p.write(" ")
+		}
+		p.token(param.Name)
+	}
+}
+
+func (p *printer) returns(returns []*ast.TypeExpr) {
+	switch len(returns) {
+	case 0:
+		return
+	case 1:
+		p.write(" -> ")
+		p.typeExpr(returns[0])
+	default:
+		p.write(" -> (")
+		p.types(returns)
+		p.write(")")
+	}
+}
+
+func (p *printer) unsupported(node ast.Node) {
+	if p.err == nil {
+		p.err = fmt.Errorf("cannot format %s at %d:%d", node.GetType(), node.GetToken().Line, node.GetToken().Column.Start)
+	}
+}
@@ -0,0 +1,310 @@
+package formatter
+
+import (
+	"hybroid/ast"
+	"hybroid/tokens"
+	"strings"
+)
+
+func (p *printer) expression(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.LiteralExpr:
+		p.token(node.Token)
+		// the lexeme of a number does not hold its postfix
+		switch node.Token.Type {
+		case tokens.Fixed:
+			p.write("f")
+		case tokens.FixedPoint:
+			p.write("fx")
+		case tokens.Radian:
+			p.write("r")
+		case tokens.Degree:
+			p.write("d")
+		}
+	case *ast.IdentifierExpr:
+		p.token(node.Name)
+	case *ast.SelfExpr:
+		p.token(node.Token)
+	case *ast.EnvAccessExpr:
+		p.token(node.PathExpr.Path)
+		p.write(":")
+		p.token(node.Accessed.Name)
+	case *ast.BinaryExpr:
+		p.expression(node.Left)
+		p.write(" ")
+		p.token(node.Operator)
+		p.write(" ")
+		p.expression(node.Right)
+	case *ast.NilCoalescingExpr:
+		p.expression(node.Left)
+		p.write(" ")
+		p.token(node.Operator)
+		p.write(" ")
+		p.expression(node.Right)
+	case *ast.LetUnwrapExpr:
+		p.token(node.Token)
+		p.write(" ")
+		p.token(node.Name)
+		p.write(" = ")
+		p.expression(node.Expr)
+	case *ast.UnaryExpr:
+		p.token(node.Operator)
+		if isWord(node.Operator.Lexeme) {
+			p.write(" ")
+		}
+		p.expression(node.Value)
+	case *ast.GroupExpr:
+		p.token(node.Token)
+		p.expression(node.Expr)
+		p.write(")")
+	case *ast.CallExpr:
+		p.expression(node.Caller)
+		p.genericArgs(node.GenericArgs)
+		p.args(node.Args)
+	case *ast.MacroCallExpr:
+		p.token(node.Token)
+		p.expression(node.Caller)
+	case *ast.AccessExpr:
+		p.expression(node.Start)
+		for _, accessed := range node.Accessed {
+			switch accessed := accessed.(type) {
+			case *ast.FieldExpr:
+				p.write(".")
+				p.expression(accessed.Field)
+			case *ast.MemberExpr:
+				p.write("[")
+				p.expression(accessed.Member)
+				p.write("]")
+			}
+		}
+	case *ast.NewExpr:
+		p.token(node.Token)
+		p.genericArgs(node.GenericArgs)
+		p.write(" ")
+		p.typeExpr(node.Type)
+		p.args(node.Args)
+	case *ast.SpawnExpr:
+		p.token(node.Token)
+		p.genericArgs(node.GenericArgs)
+		p.write(" ")
+		p.typeExpr(node.Type)
+		p.args(node.Args)
+	case *ast.StartExpr:
+		p.token(node.Token)
+		p.write(" ")
+		p.expression(node.Call)
+	case *ast.FindExpr:
+		p.token(node.Token)
+		p.write(" ")
+		p.expression(node.Value)
+		p.write(" in ")
+		p.expression(node.Container)
+	case *ast.EntityEvaluationExpr:
+		if node.ConvertedVarName != nil {
+			p.token(node.Token)
+			p.write(" ")
+			p.token(*node.ConvertedVarName)
+			p.write(" = ")
+		}
+		p.expression(node.Expr)
+		p.write(" ")
+		p.token(node.Operator)
+		p.write(" ")
+		p.typeExpr(node.Type)
+	case *ast.FunctionExpr:
+		p.token(node.Token)
+		p.signature(node.Generics, node.Params, node.Returns)
+		p.body(node.Body)
+	case *ast.MatchExpr:
+		p.matchStatement(&node.MatchStmt)
+	case *ast.ListExpr:
+		p.listExpression(node)
+	case *ast.MapExpr:
+		p.mapExpression(node)
+	case *ast.StructExpr:
+		p.token(node.Token)
+		p.write(" ")
+		starts := make([]int, len(node.Fields))
+		for i, field := range node.Fields {
+			starts[i] = p.index(field.Name)
+		}
+		p.elements(p.find(tokens.LeftBrace, p.index(node.Token)), "{", "}", starts, func(p *printer, i int) {
+			p.token(node.Fields[i].Name)
+			p.write(" = ")
+			p.expression(node.Expressions[i])
+		})
+	case *ast.TypeExpr:
+		p.typeExpr(node)
+	default:
+		p.unsupported(node)
+	}
+}
+
+func (p *printer) listExpression(list *ast.ListExpr) {
+	if list.Type != nil {
+		p.typeExpr(list.Type)
+	}
+	starts := make([]int, len(list.List))
+	for i, element := range list.List {
+		starts[i] = p.start(element)
+	}
+	p.elements(p.index(list.Token), "[", "]", starts, func(p *printer, i int) {
+		p.expression(list.List[i])
+	})
+}
+
+func (p *printer) mapExpression(m *ast.MapExpr) {
+	if m.Type != nil {
+		p.typeExpr(m.Type)
+		p.write(" ")
+	}
+	starts := make([]int, len(m.KeyValueList))
+	for i, property := range m.KeyValueList {
+		starts[i] = p.start(property.Key)
+	}
+	p.elements(p.index(m.Token), "{", "}", starts, func(p *printer, i int) {
+		p.expression(m.KeyValueList[i].Key)
+		p.write(" = ")
+		p.expression(m.KeyValueList[i].Expr)
+	})
+}
+
+// Prints the elements of a literal between its delimiters. They stay on one line when they fit and no comment
+// is between them, otherwise every element gets its own line
+func (p *printer) elements(open int, left, right string, starts []int, element func(*printer, int)) {
+	close := p.closing(open)
+	p.advanceTo(open)
+	padding := ""
+	if left == "{" {
+		padding = " "
+	}
+	if len(starts) == 0 && !p.hasComments(open, close) {
+		p.write(left + right)
+		p.advanceTo(close)
+		return
+	}
+
+	flat := p.measure(func(fp *printer) {
+		fp.write(left + padding)
+		for i := range starts {
+			if i != 0 {
+				fp.write(", ")
+			}
+			element(fp, i)
+		}
+		fp.write(padding + right)
+	})
+	if p.flat || p.fits(flat) && !p.hasComments(open, close) {
+		p.write(left + padding)
+		for i := range starts {
+			if i != 0 {
+				p.write(", ")
+			}
+			element(p, i)
+		}
+		p.write(padding + right)
+		p.advanceTo(close)
+		return
+	}
+
+	p.spread(open, left, right, starts, element)
+}
+
+// Prints every element of a literal on its own line
+func (p *printer) spread(open int, left, right string, starts []int, element func(*printer, int)) {
+	close := p.closing(open)
+	p.write(left)
+	p.indent++
+	for i, start := range starts {
+		p.lineBefore(start, i != 0)
+		element(p, i)
+		if i != len(starts)-1 {
+			p.write(",")
+		}
+	}
+	p.indent--
+	p.lineBeforeClosing(close)
+	p.write(right)
+	p.advanceTo(close)
+}
+
+func (p *printer) expressions(nodes []ast.Node) {
+	for i, node := range nodes {
+		if i != 0 {
+			p.write(", ")
+		}
+		p.expression(node)
+	}
+}
+
+func (p *printer) args(args []ast.Node) {
+	p.write("(")
+	p.expressions(args)
+	p.write(")")
+}
+
+func (p *printer) genericArgs(args []*ast.TypeExpr) {
+	if len(args) == 0 {
+		return
+	}
+	p.write("<")
+	p.types(args)
+	p.write(">")
+}
+
+func (p *printer) types(types []*ast.TypeExpr) {
+	for i, typ := range types {
+		if i != 0 {
+			p.write(", ")
+		}
+		p.typeExpr(typ)
+	}
+}
+
+func (p *printer) typeExpr(typ *ast.TypeExpr) {
+	switch name := typ.Name.(type) {
+	case *ast.IdentifierExpr:
+		if typ.IsTask {
+			p.write("task ")
+		}
+		p.token(name.Name)
+		switch name.Name.Type {
+		case tokens.Fn:
+			p.write("(")
+			p.types(typ.Params)
+			p.write(")")
+			p.returns(typ.Returns)
+		case tokens.Struct:
+			p.write(" ")
+			// fields sharing a type stay together, as in `fixed x, y`
+			groups := make([][]ast.FunctionParam, 0)
+			starts := make([]int, 0)
+			for i, field := range typ.Fields {
+				if i == 0 || field.Type != typ.Fields[i-1].Type {
+					groups = append(groups, nil)
+					starts = append(starts, p.start(field.Type))
+				}
+				groups[len(groups)-1] = append(groups[len(groups)-1], field)
+			}
+			p.elements(p.index(name.Name)+1, "{", "}", starts, func(p *printer, i int) {
+				p.params(groups[i])
+			})
+		default:
+			p.genericArgs(typ.WrappedTypes)
+		}
+	default:
+		p.expression(name)
+	}
+	if typ.IsOptional {
+		p.write("?")
+	}
+	if typ.IsVariadic {
+		p.write("...")
+	}
+}
+
+func isWord(lexeme string) bool {
+	return strings.IndexFunc(lexeme, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+	}) == -1
+}
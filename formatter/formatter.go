@@ -0,0 +1,409 @@
+package formatter
+
+import (
+	"fmt"
+	"hybroid/alerts"
+	"hybroid/ast"
+	"hybroid/lexer"
+	"hybroid/parser"
+	"hybroid/tokens"
+	"slices"
+	"strings"
+)
+
+const indentation = "    "
+
+// Literals and enums are kept on one line as long as they fit in this many columns
+const maxWidth = 100
+
+// Returned when the source cannot be formatted because it does not parse
+type SyntaxError struct {
+	Alerts []alerts.Alert
+}
+
+func (se *SyntaxError) Error() string {
+	alert := se.Alerts[0]
+	location := ""
+	if toks := alert.SnippetSpecifier().GetTokens(); len(toks) != 0 {
+		location = fmt.Sprintf(" at %d:%d", toks[0].Line, toks[0].Column.Start)
+	}
+	return fmt.Sprintf("the source has %d syntax error(s), the first one%s: %s", len(se.Alerts), location, alert.Message())
+}
+
+// Returns the canonical layout of a Hybroid source. Comments are kept, either on their own
+// lines or at the end of the line of the code they followed
+func Format(source string) (string, error) {
+	lex := lexer.NewLexer(strings.NewReader(source))
+	toks, err := lex.Tokenize()
+	if err != nil {
+		return "", err
+	}
+	if errs := errorsOf(lex.GetAlerts()); len(errs) != 0 {
+		return "", &SyntaxError{Alerts: errs}
+	}
+	p := parser.NewParser(toks)
+	program := p.Parse()
+	if errs := errorsOf(p.GetAlerts()); len(errs) != 0 {
+		return "", &SyntaxError{Alerts: errs}
+	}
+
+	// doc comments are printed with the other comments
+	code := slices.DeleteFunc(slices.Clone(toks), func(t tokens.Token) bool { return t.Type == tokens.DocComment })
+	pr := newPrinter(source, code, lex.Comments())
+	pr.program(program)
+	if pr.err != nil {
+		return "", pr.err
+	}
+	return pr.out.String(), nil
+}
+
+func errorsOf(all []alerts.Alert) []alerts.Alert {
+	errs := make([]alerts.Alert, 0)
+	for _, alert := range all {
+		if alert.AlertType() == alerts.Error {
+			errs = append(errs, alert)
+		}
+	}
+	return errs
+}
+
+type position struct {
+	line, column int
+}
+
+type comment struct {
+	tokens.Token
+	trailing bool // whether it follows code (or another trailing comment) on its line
+	gap      bool // whether a blank line separates it from what is before it
+}
+
+func (c comment) endLine() int {
+	return c.Line + strings.Count(c.Lexeme, "\n")
+}
+
+type printer struct {
+	out    strings.Builder
+	indent int
+	err    error
+
+	source    string
+	lineStart []int // the offset of every line of the source
+
+	tokens   []tokens.Token
+	indices  map[position]int // the index of a token by its location
+	comments []comment
+	next     int // the index of the next comment to print
+
+	// The index of the last token printed, the brace of a body is the first one after it
+	cursor int
+	// Literals are printed on one line, used to measure them
+	flat bool
+}
+
+func newPrinter(source string, toks []tokens.Token, comments []tokens.Token) *printer {
+	p := &printer{
+		source:    source,
+		lineStart: []int{0},
+		tokens:    toks,
+		indices:   make(map[position]int, len(toks)),
+		comments:  make([]comment, 0, len(comments)),
+		cursor:    -1,
+	}
+	for i, char := range []byte(source) {
+		if char == '\n' {
+			p.lineStart = append(p.lineStart, i+1)
+		}
+	}
+	for i, token := range toks {
+		p.indices[position{token.Line, token.Column.Start}] = i
+	}
+
+	// what a comment follows is the last token or comment before it
+	index := 0
+	for i, token := range comments {
+		for index < len(toks) && before(toks[index], token) {
+			index++
+		}
+		previousLine, trailing := 0, false
+		if index > 0 {
+			previousLine = toks[index-1].Line
+			trailing = previousLine == token.Line
+		}
+		if i > 0 && (index == 0 || before(toks[index-1], comments[i-1])) {
+			previousLine = p.comments[i-1].endLine()
+			trailing = previousLine == token.Line
+		}
+		p.comments = append(p.comments, comment{
+			Token:    token,
+			trailing: trailing,
+			gap:      previousLine != 0 && token.Line > previousLine+1,
+		})
+	}
+	return p
+}
+
+func (p *printer) program(program []ast.Node) {
+	for i, node := range program {
+		p.lineBefore(p.start(node), i != 0)
+		p.node(node)
+	}
+	p.commentsBefore(len(p.tokens)-1, true)
+	p.newline()
+}
+
+// Returns a copy of the printer that prints everything on one line into its own output
+func (p *printer) measure(print func(*printer)) string {
+	flat := &printer{
+		source:    p.source,
+		lineStart: p.lineStart,
+		tokens:    p.tokens,
+		indices:   p.indices,
+		indent:    p.indent,
+		cursor:    p.cursor,
+		flat:      true,
+	}
+	print(flat)
+	return flat.out.String()
+}
+
+// Whether the flat form of a construct can be printed at the current column
+func (p *printer) fits(flat string) bool {
+	return !strings.Contains(flat, "\n") && p.column()+len(flat) <= maxWidth
+}
+
+func (p *printer) write(text string) {
+	if p.atLineStart() && text != "\n" {
+		p.out.WriteString(strings.Repeat(indentation, p.indent))
+	}
+	p.out.WriteString(text)
+}
+
+func (p *printer) token(token tokens.Token) {
+	p.write(token.Lexeme)
+	p.advanceTo(p.index(token))
+}
+
+func (p *printer) atLineStart() bool {
+	out := p.out.String()
+	return len(out) == 0 || out[len(out)-1] == '\n'
+}
+
+func (p *printer) column() int {
+	out := p.out.String()
+	return len(out) - (strings.LastIndexByte(out, '\n') + 1)
+}
+
+func (p *printer) newline() {
+	if !p.atLineStart() {
+		p.out.WriteString("\n")
+	}
+}
+
+func (p *printer) blankLine() {
+	p.newline()
+	if out := p.out.String(); len(out) != 0 && !strings.HasSuffix(out, "\n\n") {
+		p.out.WriteString("\n")
+	}
+}
+
+// Starts the line of the construct beginning at the given token. The comments before it are printed first,
+// trailing ones at the end of the current line. A single blank line of the source is kept when allowed
+func (p *printer) lineBefore(index int, allowBlank bool) {
+	if p.flat {
+		return
+	}
+	lastLine, allowBlank := p.commentsBefore(index, allowBlank)
+	p.startLine(allowBlank && p.tokens[index].Line > lastLine+1)
+}
+
+// Starts the line of the brace or bracket closing a body, the comments before it are still in the body
+func (p *printer) lineBeforeClosing(index int) {
+	if p.flat {
+		return
+	}
+	p.indent++
+	p.commentsBefore(index, true)
+	p.indent--
+	p.newline()
+}
+
+// Prints the comments before the given token, returns the line the last thing before the token ends on
+// and whether a blank line can separate it from the token
+func (p *printer) commentsBefore(index int, allowBlank bool) (int, bool) {
+	token := p.tokens[index]
+	lastLine := 0
+	if index > 0 {
+		lastLine = p.tokens[index-1].Line
+	}
+	for p.next < len(p.comments) && before(p.comments[p.next].Token, token) {
+		comment := p.comments[p.next]
+		p.next++
+		if comment.trailing && !p.atLineStart() {
+			p.write(" " + comment.Lexeme)
+		} else {
+			p.startLine(allowBlank && comment.gap)
+			p.write(comment.Lexeme)
+		}
+		if index == 0 || !before(comment.Token, p.tokens[index-1]) {
+			lastLine = max(lastLine, comment.endLine())
+		}
+		// comments at the start of a body can be separated from what follows them
+		allowBlank = true
+	}
+	return lastLine, allowBlank
+}
+
+func (p *printer) startLine(blank bool) {
+	if blank && p.out.Len() != 0 {
+		p.blankLine()
+	} else {
+		p.newline()
+	}
+}
+
+func before(comment, token tokens.Token) bool {
+	if comment.Line != token.Line {
+		return comment.Line < token.Line
+	}
+	return comment.Column.Start < token.Column.Start
+}
+
+// Whether a comment is between the tokens of the given indices
+func (p *printer) hasComments(from, to int) bool {
+	for _, comment := range p.comments[p.next:] {
+		if !before(comment.Token, p.tokens[from]) && before(comment.Token, p.tokens[to]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Skips the comments before the token at the given index, when they were printed with the source
+func (p *printer) skipComments(index int) {
+	for p.next < len(p.comments) && before(p.comments[p.next].Token, p.tokens[index]) {
+		p.next++
+	}
+}
+
+func (p *printer) index(token tokens.Token) int {
+	if index, ok := p.indices[position{token.Line, token.Column.Start}]; ok {
+		return index
+	}
+	return p.cursor
+}
+
+func (p *printer) advanceTo(index int) {
+	p.cursor = max(p.cursor, index)
+}
+
+// Returns the index of the first token of the given type after the given index
+func (p *printer) find(tokenType tokens.TokenType, after int) int {
+	for i := after + 1; i < len(p.tokens); i++ {
+		if p.tokens[i].Type == tokenType {
+			return i
+		}
+	}
+	return len(p.tokens) - 1
+}
+
+// Returns the index of the token closing the brace, bracket or parenthesis at the given index
+func (p *printer) closing(open int) int {
+	var closer tokens.TokenType
+	switch p.tokens[open].Type {
+	case tokens.LeftBrace:
+		closer = tokens.RightBrace
+	case tokens.LeftBracket:
+		closer = tokens.RightBracket
+	case tokens.LeftParen:
+		closer = tokens.RightParen
+	default:
+		return open
+	}
+	depth := 0
+	for i := open; i < len(p.tokens); i++ {
+		switch p.tokens[i].Type {
+		case p.tokens[open].Type:
+			depth++
+		case closer:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(p.tokens) - 1
+}
+
+// Returns the text of the source between the starts of the tokens at the given indices, the last one included
+func (p *printer) sourceText(from, to int) string {
+	offset := func(token tokens.Token) int {
+		return p.lineStart[token.Line-1] + token.Column.Start - 1
+	}
+	return p.source[offset(p.tokens[from]) : offset(p.tokens[to])+len(p.tokens[to].Lexeme)]
+}
+
+// Returns the index of the first token of a node, including the keywords before the one it holds
+func (p *printer) start(node ast.Node) int {
+	index := p.index(first(node))
+	for index > 0 {
+		switch p.tokens[index-1].Type {
+		case tokens.Pub, tokens.Let, tokens.Const, tokens.Fn, tokens.Macro, tokens.Env:
+			index--
+			continue
+		}
+		break
+	}
+	return index
+}
+
+// Returns the leftmost token held by a node
+func first(node ast.Node) tokens.Token {
+	switch node := node.(type) {
+	case *ast.EnvironmentDecl:
+		return node.Env.Path
+	case *ast.VariableDecl:
+		switch node.Token.Type {
+		case tokens.Pub, tokens.Let, tokens.Const:
+			return node.Token
+		}
+		if node.Type != nil {
+			return first(node.Type)
+		}
+		return node.Identifiers[0].Name
+	case *ast.EnumDecl:
+		return node.Token
+	case *ast.AssignmentStmt:
+		return first(node.Identifiers[0])
+	case *ast.CaseStmt:
+		return first(node.Expressions[0])
+	case *ast.BinaryExpr:
+		return first(node.Left)
+	case *ast.NilCoalescingExpr:
+		return first(node.Left)
+	case *ast.UnaryExpr:
+		return node.Operator
+	case *ast.CallExpr:
+		return first(node.Caller)
+	case *ast.AccessExpr:
+		return first(node.Start)
+	case *ast.ListExpr:
+		if node.Type != nil {
+			return first(node.Type)
+		}
+	case *ast.MapExpr:
+		if node.Type != nil {
+			return first(node.Type)
+		}
+	case *ast.MatchExpr:
+		return node.MatchStmt.Token
+	case *ast.EntityEvaluationExpr:
+		if node.ConvertedVarName == nil {
+			return first(node.Expr)
+		}
+	case *ast.EnvAccessExpr:
+		return node.PathExpr.Path
+	case *ast.TypeExpr:
+		return first(node.Name)
+	}
+	return node.GetToken()
+}
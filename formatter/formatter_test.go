@@ -0,0 +1,364 @@
+package formatter
+
+import (
+	"hybroid/lexer"
+	"hybroid/tokens"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		name, source, expected string
+	}{
+		{
+			name: "braces and spacing",
+			source: `env Test as Level
+let a=1+2
+fn f(number x,y)->number{
+return x*y
+}
+if a>2{
+f(1,2)
+}else if a<0 {
+
+
+
+}else{a=3}
+`,
+			expected: `env Test as Level
+let a = 1 + 2
+fn f(number x, y) -> number {
+    return x * y
+}
+if a > 2 {
+    f(1, 2)
+} else if a < 0 {} else {
+    a = 3
+}
+`,
+		},
+		{
+			name: "literals fit on one line or get a line per element",
+			source: `env Test as Level
+let s = struct{
+	x = 1,
+	y = 2
+}
+let m = {"a"=[1,2,3]}
+let long = struct{first = "aaaaaaaaaaaaaaaaaaaa", second = "bbbbbbbbbbbbbbbbbbbbbb", third = "cccccccccccccccccccccc"}
+enum Direction { Up, Down }
+`,
+			expected: `env Test as Level
+let s = struct { x = 1, y = 2 }
+let m = { "a" = [1, 2, 3] }
+let long = struct {
+    first = "aaaaaaaaaaaaaaaaaaaa",
+    second = "bbbbbbbbbbbbbbbbbbbbbb",
+    third = "cccccccccccccccccccccc"
+}
+enum Direction {
+    Up,
+    Down
+}
+`,
+		},
+		{
+			name: "comments are kept",
+			source: `// The level
+env Test as Level // trailing the environment
+
+
+// about a
+let a = 1 /* inline */ // and trailing
+fn f() {
+	// only a comment
+}
+fn g() {
+
+	a = 2 // set a
+
+	// at the end
+}
+let s = struct {
+	x = 1, // the x
+	y = 2
+}
+/* block
+   comment */
+// last
+`,
+			expected: `// The level
+env Test as Level // trailing the environment
+
+// about a
+let a = 1 /* inline */ // and trailing
+fn f() {
+    // only a comment
+}
+fn g() {
+    a = 2 // set a
+
+    // at the end
+}
+let s = struct {
+    x = 1, // the x
+    y = 2
+}
+/* block
+   comment */
+// last
+`,
+		},
+		{
+			name: "members keep their order",
+			source: `env Test as Level
+entity E {
+	fixed x, y
+	Update() {}
+	spawn(fixed x, y) {
+		self.x, self.y = x, y
+	}
+	fn Get() -> (fixed, fixed) => x, y
+}
+`,
+			expected: `env Test as Level
+entity E {
+    fixed x, y
+    Update() {}
+    spawn(fixed x, y) {
+        self.x, self.y = x, y
+    }
+    fn Get() -> (fixed, fixed) => x, y
+}
+`,
+		},
+		{
+			name: "single statement bodies and match cases stay inline",
+			source: `env Test as Level
+fn f(number x) -> number {
+	if x > 2 return 1
+	let y = match x {
+		1 => 2
+		else => {
+			yield 3
+		}
+	}
+	match x {
+		1 => f(2)
+	}
+	return y
+}
+`,
+			expected: `env Test as Level
+fn f(number x) -> number {
+    if x > 2 return 1
+    let y = match x {
+        1 => 2
+        else => {
+            yield 3
+        }
+    }
+    match x {
+        1 => f(2)
+    }
+    return y
+}
+`,
+		},
+		{
+			name: "test blocks",
+			source: `env Test as Level
+test   "adds"{
+assert_eq(1+1,2)
+}
+`,
+			expected: `env Test as Level
+test "adds" {
+    assert_eq(1 + 1, 2)
+}
+`,
+		},
+		{
+			name: "optionals",
+			source: `env Test as Level
+number ?  maybe=nil
+let value=maybe??0
+if let found=maybe{
+print(found)
+}
+`,
+			expected: `env Test as Level
+number? maybe = nil
+let value = maybe ?? 0
+if let found = maybe {
+    print(found)
+}
+`,
+		},
+		{
+			name: "interfaces",
+			source: `env Test as Level
+pub interface Shape{
+// the area
+fn Area()->fixed
+fn Scale(fixed factor)
+}
+class Square impl Shape,Other{
+fixed side
+new(fixed side){
+self.side=side
+}
+fn Area()->fixed{
+return side*side
+}
+fn Scale(fixed factor){
+side*=factor
+}
+}
+`,
+			expected: `env Test as Level
+pub interface Shape {
+    // the area
+    fn Area() -> fixed
+    fn Scale(fixed factor)
+}
+class Square impl Shape, Other {
+    fixed side
+    new(fixed side) {
+        self.side = side
+    }
+    fn Area() -> fixed {
+        return side * side
+    }
+    fn Scale(fixed factor) {
+        side *= factor
+    }
+}
+`,
+		},
+		{
+			name: "tasks",
+			source: `env Test as Level
+pub task fn Intro(task fn() after){
+wait 30
+wait until   ready
+start after()
+}
+class Door{
+new(){}
+task fn Open(){
+wait 2
+}
+}
+`,
+			expected: `env Test as Level
+pub task fn Intro(task fn() after) {
+    wait 30
+    wait until ready
+    start after()
+}
+class Door {
+    new() {}
+    task fn Open() {
+        wait 2
+    }
+}
+`,
+		},
+	}
+
+	for _, c := range cases {
+		formatted, err := Format(c.source)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if formatted != c.expected {
+			t.Errorf("%s: unexpected layout:\n%s\nexpected:\n%s", c.name, formatted, c.expected)
+		}
+	}
+}
+
+func TestFormatRejectsSyntaxErrors(t *testing.T) {
+	_, err := Format("env Test as Level\nlet a = (1\n")
+	if _, ok := err.(*SyntaxError); !ok {
+		t.Errorf("expected a syntax error, got %v", err)
+	}
+}
+
+// The tokens of a source that the layout does not change
+func significantTokens(t *testing.T, source string) ([]string, []string) {
+	t.Helper()
+	lex := lexer.NewLexer(strings.NewReader(source))
+	toks, err := lex.Tokenize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make([]string, 0, len(toks))
+	for i, token := range toks {
+		// trailing commas are dropped
+		if token.Type == tokens.Comma && i+1 < len(toks) && (toks[i+1].Type == tokens.RightBracket || toks[i+1].Type == tokens.RightBrace) {
+			continue
+		}
+		result = append(result, token.Type.String()+" "+token.Lexeme)
+	}
+	comments := make([]string, 0)
+	for _, comment := range lex.Comments() {
+		comments = append(comments, strings.TrimSpace(comment.Lexeme))
+	}
+	return result, comments
+}
+
+func TestFormatKeepsSources(t *testing.T) {
+	paths := make([]string, 0)
+	for _, root := range []string{"../examples", "../evaluator/test", "../parser/tests"} {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && filepath.Ext(path) == ".hyb" && !strings.HasSuffix(path, "invalid.hyb") {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+	}
+	if len(paths) == 0 {
+		t.Fatal("found no sources to format")
+	}
+
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted, err := Format(string(source))
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		again, err := Format(formatted)
+		if err != nil {
+			t.Errorf("%s: the formatted source does not parse: %v", path, err)
+			continue
+		}
+		if again != formatted {
+			t.Errorf("%s: formatting is not idempotent", path)
+		}
+
+		sourceTokens, sourceComments := significantTokens(t, string(source))
+		formattedTokens, formattedComments := significantTokens(t, formatted)
+		if strings.Join(sourceComments, "\n") != strings.Join(formattedComments, "\n") {
+			t.Errorf("%s: the comments changed:\n%v\n%v", path, sourceComments, formattedComments)
+		}
+		for i := range min(len(sourceTokens), len(formattedTokens)) {
+			if sourceTokens[i] != formattedTokens[i] {
+				t.Errorf("%s: the tokens changed at %d: %s, %s", path, i, sourceTokens[i], formattedTokens[i])
+				break
+			}
+		}
+		if len(sourceTokens) != len(formattedTokens) {
+			t.Errorf("%s: the token count changed from %d to %d", path, len(sourceTokens), len(formattedTokens))
+		}
+	}
+}
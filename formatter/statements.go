@@ -0,0 +1,228 @@
+package formatter
+
+import (
+	"hybroid/ast"
+	"hybroid/tokens"
+	"slices"
+)
+
+// Returns false when the node is not a statement
+func (p *printer) statement(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.AssignmentStmt:
+		p.expressions(node.Identifiers)
+		p.write(" " + node.AssignOp.Lexeme + " ")
+		p.expressions(node.Values)
+	case *ast.DestroyStmt:
+		p.token(node.Token)
+		p.genericArgs(node.GenericsArgs)
+		p.write(" ")
+		p.expression(node.Identifier)
+		p.args(node.Args)
+	case *ast.RemoveStmt:
+		p.token(node.Token)
+		p.write(" ")
+		p.expression(node.Index)
+		p.write(" from ")
+		p.expression(node.Container)
+	case *ast.IfStmt:
+		p.ifStatement(node)
+	case *ast.MatchStmt:
+		p.matchStatement(node)
+	case *ast.RepeatStmt:
+		p.repeatStatement(node)
+	case *ast.WhileStmt:
+		p.token(node.Token)
+		p.write(" ")
+		p.expression(node.Condition)
+		p.body(node.Body)
+	case *ast.ForStmt:
+		p.token(node.Token)
+		p.write(" ")
+		p.token(node.First.Name)
+		if node.Second != nil {
+			p.write(", ")
+			p.token(node.Second.Name)
+		}
+		p.write(" in ")
+		if node.IsEntity {
+			p.write("every ")
+		}
+		p.expression(node.Iterator)
+		p.body(node.Body)
+	case *ast.TickStmt:
+		p.token(node.Token)
+		if node.Variable != nil {
+			p.write(" with ")
+			p.token(node.Variable.Name)
+		}
+		p.body(node.Body)
+	case *ast.WaitStmt:
+		p.token(node.Token)
+		if node.Until != nil {
+			p.write(" until ")
+			p.expression(node.Until)
+		} else {
+			p.write(" ")
+			p.expression(node.Ticks)
+		}
+	case *ast.ReturnStmt:
+		p.token(node.Token)
+		if len(node.Args) != 0 {
+			p.write(" ")
+			p.expressions(node.Args)
+		}
+	case *ast.YieldStmt:
+		p.token(node.Token)
+		if len(node.Args) != 0 {
+			p.write(" ")
+			p.expressions(node.Args)
+		}
+	case *ast.BreakStmt:
+		p.token(node.Token)
+	case *ast.ContinueStmt:
+		p.token(node.Token)
+	case *ast.UseStmt:
+		p.token(node.Token)
+		p.write(" ")
+		p.token(node.PathExpr.Path)
+	default:
+		return false
+	}
+	return true
+}
+
+// The clauses of a repeat statement can come in any order, they keep the one of the source
+func (p *printer) repeatStatement(stmt *ast.RepeatStmt) {
+	type clause struct {
+		start int
+		print func()
+	}
+	clauses := make([]clause, 0, 4)
+	add := func(keyword string, node ast.Node) {
+		if node == nil {
+			return
+		}
+		start := p.start(node)
+		if keyword == "" && start > 0 && p.tokens[start-1].Type == tokens.To {
+			keyword = "to"
+		}
+		clauses = append(clauses, clause{start, func() {
+			p.write(" ")
+			if keyword != "" {
+				p.write(keyword + " ")
+			}
+			p.expression(node)
+		}})
+	}
+	add("", stmt.Iterator)
+	add("from", stmt.Start)
+	add("by", stmt.Skip)
+	if stmt.Variable != nil {
+		add("with", stmt.Variable)
+	}
+	slices.SortFunc(clauses, func(a, b clause) int {
+		return a.start - b.start
+	})
+
+	p.token(stmt.Token)
+	for _, clause := range clauses {
+		clause.print()
+	}
+	p.body(stmt.Body)
+}
+
+func (p *printer) ifStatement(stmt *ast.IfStmt) {
+	p.token(stmt.Token)
+	p.write(" ")
+	p.expression(stmt.BoolExpr)
+	p.body(stmt.Body)
+	for _, elseif := range stmt.Elseifs {
+		p.write(" else if ")
+		p.expression(elseif.BoolExpr)
+		p.body(elseif.Body)
+	}
+	if stmt.Else != nil {
+		p.write(" else")
+		p.body(stmt.Else.Body)
+	}
+}
+
+func (p *printer) matchStatement(stmt *ast.MatchStmt) {
+	p.token(stmt.Token)
+	p.write(" ")
+	p.expression(stmt.ExprToMatch)
+	open := p.find(tokens.LeftBrace, p.cursor)
+	close := p.closing(open)
+	p.cursor = open
+	if p.flat {
+		p.write(" {\n}")
+		p.cursor = close
+		return
+	}
+	p.write(" {")
+	p.indent++
+	for i, caseStmt := range stmt.Cases {
+		p.lineBefore(p.start(caseStmt), i != 0)
+		p.expressions(caseStmt.Expressions)
+		p.write(" =>")
+		// the cases of match expressions can yield their values without a body
+		if len(caseStmt.Body) == 1 {
+			if yield, ok := caseStmt.Body[0].(*ast.YieldStmt); ok && yield.Token.Type != tokens.Yield {
+				p.write(" ")
+				p.expressions(yield.Args)
+				continue
+			}
+		}
+		p.body(caseStmt.Body)
+	}
+	p.indent--
+	p.lineBeforeClosing(close)
+	p.write("}")
+	p.cursor = close
+}
+
+// Prints a body after the construct it belongs to. Bodies written as `=> values` or as a single statement
+// without braces are kept that way, all the others are printed as blocks
+func (p *printer) body(body ast.Body) {
+	if len(body) == 1 {
+		if ret, ok := body[0].(*ast.ReturnStmt); ok && ret.Token.Type != tokens.Return {
+			p.write(" => ")
+			p.expressions(ret.Args)
+			return
+		}
+		if open := p.find(tokens.LeftBrace, p.cursor); open > p.start(body[0]) {
+			p.write(" ")
+			p.node(body[0])
+			return
+		}
+	}
+	p.block(body)
+}
+
+func (p *printer) block(body ast.Body) {
+	open := p.find(tokens.LeftBrace, p.cursor)
+	close := p.closing(open)
+	p.cursor = open
+	p.write(" {")
+	if len(body) == 0 && !p.hasComments(open, close) {
+		p.write("}")
+		p.cursor = close
+		return
+	}
+	if p.flat {
+		// blocks are never flat, the newline makes the literals containing them span lines
+		p.write("\n}")
+		p.cursor = close
+		return
+	}
+	p.indent++
+	for i, node := range body {
+		p.lineBefore(p.start(node), i != 0)
+		p.node(node)
+	}
+	p.indent--
+	p.lineBeforeClosing(close)
+	p.write("}")
+	p.cursor = close
+}
@@ -20,7 +20,7 @@ func (gen *Generator) variableDeclaration(declaration ast.VariableDecl) string {
 	src2 := core.StringBuilder{}
 
 	src.Write(gen.tabString())
-	if !declaration.IsPub {
+	if !declaration.IsPub && !declaration.MacroUsed {
 		src.Write("local ")
 	}
 	for i, ident := range declaration.Identifiers {
@@ -50,6 +50,9 @@ func (gen *Generator) classDeclaration(node ast.ClassDecl) string {
 		src.Write(gen.methodDeclaration(nodebody, node))
 		src.Write("\n")
 	}
+	if len(node.Impls) != 0 {
+		src.Write(gen.implTable(gen.WriteVarExtra(node.Name.Lexeme, hyClass), node.Methods), "\n")
+	}
 
 	totalFieldDecls := make([]ast.VariableDecl, 0)
 	for i := range node.Fields {
@@ -94,11 +97,33 @@ func (gen *Generator) entityDeclaration(node ast.EntityDecl) string {
 		src.Write("\n", gen.entityFunctionDeclaration(v, node))
 	}
 	src.Write("\n")
+	if len(node.Impls) != 0 {
+		src.Write(gen.implTable(entityName, node.Methods), "\n")
+		gen.Twrite(&src, "local function check() for k in pairs(", entityName, ") do if not pewpew.entity_get_is_alive(k) then ", entityName, "[k] = nil HybroidImpls[k] = nil end end end\n")
+		gen.Twrite(&src, "pewpew.add_update_callback(check)")
+		return src.String()
+	}
 	gen.Twrite(&src, "local function check() for k in pairs(", entityName, ") do if not pewpew.entity_get_is_alive(k) then ", entityName, "[k] = nil end end end\n")
 	gen.Twrite(&src, "pewpew.add_update_callback(check)")
 	return src.String()
 }
 
+// The table of the methods of a class or an entity, which HybroidCall looks the interface methods up in
+func (gen *Generator) implTable(typeName string, methods []ast.MethodDecl) string {
+	src := core.StringBuilder{}
+
+	src.Write(typeName, "_Impl = {")
+	for i, method := range methods {
+		src.Write(method.Name.Lexeme, " = ", typeName, "_", method.Name.Lexeme)
+		if i != len(methods)-1 {
+			src.Write(", ")
+		}
+	}
+	src.Write("}")
+
+	return src.String()
+}
+
 func (gen *Generator) constructorDeclaration(node ast.ConstructorDecl, class ast.ClassDecl) string {
 	src := core.StringBuilder{}
 
@@ -107,6 +132,9 @@ func (gen *Generator) constructorDeclaration(node ast.ConstructorDecl, class ast
 
 	gen.tabCount++
 	gen.Twrite(&src, "local Self = {}\n")
+	if len(class.Impls) != 0 {
+		gen.Twrite(&src, "Self.Impl = ", gen.WriteVarExtra(class.Name.Lexeme, hyClass), "_Impl\n")
+	}
 	counter := 1
 	for _, fieldDecl := range class.Fields {
 		src.Write(gen.fieldDeclaration(fieldDecl, counter))
@@ -191,6 +219,9 @@ func (gen *Generator) spawnDeclaration(node ast.EntityFunctionDecl, entity ast.E
 	gen.Twrite(&src, "local id = pewpew.new_customizable_entity(", gen.WriteVar(node.Params[0].Name.Lexeme), ", ", gen.WriteVar(node.Params[1].Name.Lexeme), ")\n")
 	tableAccess := entityName + "[id]"
 	gen.Twrite(&src, tableAccess, " = {}\n")
+	if len(entity.Impls) != 0 {
+		gen.Twrite(&src, "HybroidImpls[id] = ", entityName, "_Impl\n")
+	}
 	gen.Twrite(&src, "local Self = ", tableAccess, "\n")
 	counter := 1
 	for _, field := range entity.Fields {
@@ -242,9 +273,21 @@ func (gen *Generator) destroyDeclaration(node ast.EntityFunctionDecl, entity ast
 	return src.String()
 }
 
+// Registers the test block in the HybroidTests table that the test runner defines
+func (gen *Generator) testDeclaration(node ast.TestDecl) string {
+	src := core.StringBuilder{}
+	gen.Twrite(&src, "table.insert(HybroidTests, {name = \"", node.Name.Literal, "\", source = \"", gen.testSource, "\", line = ", fmt.Sprint(node.Token.Location.Line), ", run = function()\n")
+
+	gen.GenerateBody(&src, node.Body)
+
+	gen.Twrite(&src, "end})")
+
+	return src.String()
+}
+
 func (gen *Generator) functionDeclaration(node ast.FunctionDecl) string {
 	src := core.StringBuilder{}
-	if !node.IsPub {
+	if !node.IsPub && !node.MacroUsed {
 		gen.Twrite(&src, "local ")
 	}
 
@@ -37,6 +37,21 @@ func (gen *Generator) entityExpr(node ast.EntityEvaluationExpr) string {
 	return src.String()
 }
 
+func (gen *Generator) nilCoalescingExpr(node ast.NilCoalescingExpr) string {
+	left, right := gen.GenerateExpr(node.Left), gen.GenerateExpr(node.Right)
+	if node.IsBool {
+		// `or` would also replace false
+		return fmt.Sprintf("(function(value) if value == nil then return %s end return value end)(%s)", right, left)
+	}
+	return fmt.Sprintf("(%s or %s)", left, right)
+}
+
+func (gen *Generator) letUnwrapExpr(node ast.LetUnwrapExpr) string {
+	name := gen.WriteVar(node.Name.Lexeme)
+	gen.Twrite(gen.LatestSrc, "local ", name, " = ", gen.GenerateExpr(node.Expr), "\n")
+	return name + " ~= nil"
+}
+
 func (gen *Generator) binaryExpr(node ast.BinaryExpr) string {
 	left, right := gen.GenerateExpr(node.Left), gen.GenerateExpr(node.Right)
 	op := opToString(node.Operator)
@@ -264,6 +279,17 @@ func (gen *Generator) spawnExpr(spawn ast.SpawnExpr, stmt bool) string {
 	return src.String()
 }
 
+// The call runs in a coroutine, which HybroidStart resumes right away and then on the ticks it waits for
+func (gen *Generator) startExpr(start ast.StartExpr, stmt bool) string {
+	src := core.StringBuilder{}
+
+	if stmt {
+		src.Write(gen.tabString())
+	}
+	src.Write("HybroidStart(function() ", gen.GenerateExpr(start.Call), " end)")
+	return src.String()
+}
+
 func (gen *Generator) matchExpr(match ast.MatchExpr) string {
 	src := core.StringBuilder{}
 	varsSrc := core.StringBuilder{}
@@ -318,6 +344,40 @@ func (gen *Generator) matchExpr(match ast.MatchExpr) string {
 	return varsSrc.String()
 }
 
+// Find is generated as a call of an inline function, so that it is evaluated again every time the
+// expression is, like in the condition of a loop or on the right of `and`/`or`
+func (gen *Generator) findExpr(find ast.FindExpr) string {
+	src := core.StringBuilder{}
+	valueVar := GenerateVar(hyVar)
+	containerVar := GenerateVar(hyVar)
+	keyVar := GenerateVar(hyVar)
+	elemVar := GenerateVar(hyVar)
+
+	iterator := "ipairs"
+	if find.InMap {
+		iterator = "pairs"
+	}
+
+	value := gen.GenerateExpr(find.Value)
+	container := gen.GenerateExpr(find.Container)
+	src.Write("(function(", valueVar, ", ", containerVar, ")\n")
+	gen.tabCount++
+	gen.Twrite(&src, "for ", keyVar, ", ", elemVar, " in ", iterator, "(", containerVar, ") do\n")
+	gen.tabCount++
+	gen.Twrite(&src, "if ", elemVar, " == ", valueVar, " then\n")
+	gen.tabCount++
+	gen.Twrite(&src, "return ", keyVar, "\n")
+	gen.tabCount--
+	gen.Twrite(&src, "end\n")
+	gen.tabCount--
+	gen.Twrite(&src, "end\n")
+	gen.Twrite(&src, "return nil\n")
+	gen.tabCount--
+	src.Write(gen.tabString(), "end)(", value, ", ", container, ")")
+
+	return src.String()
+}
+
 func (gen *Generator) envAccessExpr(node ast.EnvAccessExpr) string {
 	envName := node.PathExpr.Path.Lexeme
 	gen.envPrefixName = envMap[envName]
@@ -353,13 +413,15 @@ func (gen *Generator) methodCallExpr(methodCall ast.MethodCallExpr, stmt bool) s
 	if stmt {
 		src.Write(gen.tabString())
 	}
-	var extra string
-	if methodCall.MethodType == ast.ClassMethod {
-		extra = hyClass
-	} else {
-		extra = hyEntity
+	switch methodCall.MethodType {
+	case ast.ClassMethod:
+		src.Write(hyClass, envMap[methodCall.EnvName], methodCall.TypeName, "_", methodCall.MethodName, "(", gen.GenerateExpr(methodCall.Caller))
+	case ast.EntityMethod:
+		src.Write(hyEntity, envMap[methodCall.EnvName], methodCall.TypeName, "_", methodCall.MethodName, "(", gen.GenerateExpr(methodCall.Caller))
+	case ast.InterfaceMethod:
+		// the method is only known at runtime, from the type of the value
+		src.Write("HybroidCall(", gen.GenerateExpr(methodCall.Caller), ", \"", methodCall.MethodName, "\"")
 	}
-	src.Write(extra, envMap[methodCall.EnvName], methodCall.TypeName, "_", methodCall.MethodName, "(", gen.GenerateExpr(methodCall.Caller))
 	for i := range methodCall.Args {
 		src.Write(", ", gen.GenerateExpr(methodCall.Args[i]))
 	}
@@ -376,10 +438,14 @@ func (gen *Generator) methodExpr(method ast.MethodExpr) string {
 	src := core.StringBuilder{}
 
 	var extra string
-	if method.MethodType == ast.ClassMethod {
+	switch method.MethodType {
+	case ast.ClassMethod:
 		extra = hyClass
-	} else {
+	case ast.EntityMethod:
 		extra = hyEntity
+	case ast.InterfaceMethod:
+		src.Write("function(...) return HybroidCall(", gen.GenerateExpr(method.Access), ", \"", method.MethodName, "\", ...) end")
+		return src.String()
 	}
 	src.Write(extra, envMap[method.EnvName], method.TypeName, "_", method.MethodName)
 
@@ -5,6 +5,7 @@ import (
 	"hybroid/ast"
 	"hybroid/core"
 	"hybroid/generator/mapping"
+	"hybroid/tokens"
 	"strings"
 )
 
@@ -70,6 +71,13 @@ type Generator struct {
 	YieldContexts  core.Stack[YieldContext]
 
 	LatestSrc *core.StringBuilder
+
+	// whether the test blocks are generated, which only the test bundles do, and the path of
+	// the source they are registered with
+	tests      bool
+	testSource string
+
+	locations []tokens.Location // the locations of the statements marked in the source
 }
 
 func (gen *Generator) Twrite(src *core.StringBuilder, chunks ...string) {
@@ -126,7 +134,8 @@ func (gen *Generator) WriteVarExtra(name, extra string) string {
 }
 
 func (gen *Generator) GetSrc() string {
-	return gen.src.String()
+	src, _ := gen.resolveMarkers()
+	return src
 }
 
 func (gen *Generator) GenerateUsedLibraries(usedLibraries []ast.Library) {
@@ -136,20 +145,39 @@ func (gen *Generator) GenerateUsedLibraries(usedLibraries []ast.Library) {
 	}
 }
 
+// Makes the generator write the test blocks, which it leaves out otherwise, registering them
+// with the path of their source
+func (gen *Generator) EnableTests(source string) {
+	gen.tests = true
+	gen.testSource = source
+}
+
 func (gen *Generator) Generate(program []ast.Node, builtins []string) {
+	// the generator is returned by value, so the pointer has to be rebound to this copy
+	gen.LatestSrc = &gen.src
 	for i := range builtins {
 		gen.src.Write(mapping.Functions[builtins[i]])
 		gen.src.Write("\n")
 	}
-	for _, node := range program {
-		gen.src.Write(gen.GenerateStmt(node), "\n")
-	}
+	gen.generateProgram(program)
 }
 
-func (gen *Generator) GenerateWithBuiltins(program []ast.Node) {
+func (gen *Generator) GenerateWithBuiltins(program []ast.Node, extraBuiltins ...string) {
+	gen.LatestSrc = &gen.src
 	gen.src.Write(mapping.ParseSoundFunction, "\n", mapping.ToStringFunction, "\n")
+	for i := range extraBuiltins {
+		gen.src.Write(mapping.Functions[extraBuiltins[i]], "\n")
+	}
+	gen.generateProgram(program)
+}
+
+func (gen *Generator) generateProgram(program []ast.Node) {
 	for _, node := range program {
-		gen.src.Write(gen.GenerateStmt(node), "\n")
+		if _, isTest := node.(*ast.TestDecl); isTest && !gen.tests {
+			continue
+		}
+		gen.src.Write(gen.markStart(node))
+		gen.src.Write(gen.GenerateStmt(node), string(markerEnd), "\n")
 	}
 }
 
@@ -158,7 +186,8 @@ func (gen *Generator) GenerateBody(src *core.StringBuilder, body ast.Body) {
 	prevSrc := gen.LatestSrc
 	gen.LatestSrc = src
 	for _, node := range body {
-		src.Write(gen.GenerateStmt(node), "\n")
+		src.Write(gen.markStart(node))
+		src.Write(gen.GenerateStmt(node), string(markerEnd), "\n")
 	}
 	gen.LatestSrc = prevSrc
 	gen.tabCount--
@@ -169,6 +198,8 @@ func (gen *Generator) GenerateStmt(node ast.Node) string {
 	switch newNode := node.(type) {
 	case *ast.EnvironmentDecl:
 		stmt = gen.envStmt(*newNode)
+	case *ast.TestDecl:
+		stmt = gen.testDeclaration(*newNode)
 	case *ast.AssignmentStmt:
 		assignStmts := gen.breakDownAssignStmt(*newNode)
 		src := core.StringBuilder{}
@@ -199,6 +230,8 @@ func (gen *Generator) GenerateStmt(node ast.Node) string {
 		stmt = gen.forStmt(*newNode)
 	case *ast.TickStmt:
 		stmt = gen.tickStmt(*newNode)
+	case *ast.WaitStmt:
+		stmt = gen.waitStmt(*newNode)
 	case *ast.VariableDecl:
 		src := core.StringBuilder{}
 		varDecls := gen.breakDownVariableDeclaration(*newNode)
@@ -215,6 +248,8 @@ func (gen *Generator) GenerateStmt(node ast.Node) string {
 		stmt = gen.methodCallExpr(*newNode, true)
 	case *ast.SpawnExpr:
 		stmt = gen.spawnExpr(*newNode, true)
+	case *ast.StartExpr:
+		stmt = gen.startExpr(*newNode, true)
 	case *ast.NewExpr:
 		stmt = gen.newExpr(*newNode, true)
 	case *ast.FunctionDecl:
@@ -227,6 +262,10 @@ func (gen *Generator) GenerateStmt(node ast.Node) string {
 		stmt = gen.entityDeclaration(*newNode)
 	case *ast.DestroyStmt:
 		stmt = gen.destroyStmt(*newNode)
+	case *ast.RemoveStmt:
+		stmt = gen.removeStmt(*newNode)
+	case *ast.MacroCallExpr:
+		stmt = gen.macroCallStmt(*newNode)
 	default:
 		return ""
 	}
@@ -239,6 +278,10 @@ func (gen *Generator) GenerateExpr(node ast.Node) string {
 		return gen.literalExpr(*newNode)
 	case *ast.EntityEvaluationExpr:
 		return gen.entityExpr(*newNode)
+	case *ast.NilCoalescingExpr:
+		return gen.nilCoalescingExpr(*newNode)
+	case *ast.LetUnwrapExpr:
+		return gen.letUnwrapExpr(*newNode)
 	case *ast.BinaryExpr:
 		return gen.binaryExpr(*newNode)
 	case *ast.IdentifierExpr:
@@ -265,10 +308,14 @@ func (gen *Generator) GenerateExpr(node ast.Node) string {
 		return gen.newExpr(*newNode, false)
 	case *ast.MatchExpr:
 		return gen.matchExpr(*newNode)
+	case *ast.FindExpr:
+		return gen.findExpr(*newNode)
 	case *ast.EnvAccessExpr:
 		return gen.envAccessExpr(*newNode)
 	case *ast.SpawnExpr:
 		return gen.spawnExpr(*newNode, false)
+	case *ast.StartExpr:
+		return gen.startExpr(*newNode, false)
 	case *ast.MethodCallExpr:
 		return gen.methodCallExpr(*newNode, false)
 	case *ast.MethodExpr:
@@ -279,6 +326,8 @@ func (gen *Generator) GenerateExpr(node ast.Node) string {
 		return gen.fieldExpr(*newNode)
 	case *ast.MemberExpr:
 		return gen.memberExpr(*newNode)
+	case *ast.MacroCallExpr:
+		return gen.GenerateExpr(newNode.Expansion)
 	}
 
 	return ""
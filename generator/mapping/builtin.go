@@ -1,8 +1,12 @@
 package mapping
 
 var Functions = map[string]string{
-	"ToString":   ToStringFunction,
-	"ParseSound": ParseSoundFunction,
+	"ToString":     ToStringFunction,
+	"ParseSound":   ParseSoundFunction,
+	"assert":       AssertFunction,
+	"assert_eq":    AssertEqFunction,
+	"HybroidCall":  HybroidCallFunction,
+	"HybroidStart": HybroidStartFunction,
 }
 
 var ToStringFunction = `function ToString(value)
@@ -68,3 +72,102 @@ function ParseSound(link)
   end
   return sound
 end`
+
+var AssertFunction = `function assert(condition, ...)
+	if not condition then
+		local message = ...
+		error(message or "assertion failed!", 2)
+	end
+	return condition, ...
+end`
+
+var AssertEqFunction = `function assert_eq(actual, expected)
+	local function equal(a, b)
+		if type(a) ~= "table" or type(b) ~= "table" then
+			return a == b
+		end
+		for k, v in pairs(a) do
+			if not equal(v, b[k]) then
+				return false
+			end
+		end
+		for k in pairs(b) do
+			if a[k] == nil then
+				return false
+			end
+		end
+		return true
+	end
+	local function show(value)
+		if type(value) == "string" then
+			return string.format("%q", value)
+		elseif type(value) ~= "table" then
+			return tostring(value)
+		end
+		local parts = {}
+		if #value > 0 then
+			for _, v in ipairs(value) do
+				table.insert(parts, show(v))
+			end
+		else
+			for k, v in pairs(value) do
+				table.insert(parts, tostring(k) .. ": " .. show(v))
+			end
+		end
+		return "{" .. table.concat(parts, ", ") .. "}"
+	end
+	if not equal(actual, expected) then
+		error("assertion failed: expected " .. show(expected) .. ", got " .. show(actual), 2)
+	end
+end`
+
+// Class instances carry the methods of their type, entities are looked up by their id
+var HybroidCallFunction = `HybroidImpls = HybroidImpls or {}
+function HybroidCall(value, method, ...)
+	local impl
+	if type(value) == "table" then
+		impl = value.Impl
+	else
+		impl = HybroidImpls[value]
+	end
+	return impl[method](value, ...)
+end`
+
+// Tasks are coroutines yielding the number of ticks they wait, which a single update callback
+// counts down and resumes them after. A task runs until its first wait when it is started
+var HybroidStartFunction = `HybroidTasks = HybroidTasks or {}
+function HybroidResume(task)
+	local ok, ticks = coroutine.resume(task.co)
+	if not ok then
+		error(ticks, 0)
+	end
+	task.wait = ticks or 0
+end
+function HybroidStart(fn)
+	if not HybroidScheduler then
+		HybroidScheduler = true
+		pewpew.add_update_callback(function()
+			local tasks = HybroidTasks
+			HybroidTasks = {}
+			local kept = {}
+			for _, task in ipairs(tasks) do
+				task.wait = task.wait - 1
+				if task.wait <= 0 then
+					HybroidResume(task)
+				end
+				if coroutine.status(task.co) ~= "dead" then
+					table.insert(kept, task)
+				end
+			end
+			for _, task in ipairs(HybroidTasks) do
+				table.insert(kept, task)
+			end
+			HybroidTasks = kept
+		end)
+	end
+	local task = {co = coroutine.create(fn), wait = 0}
+	HybroidResume(task)
+	if coroutine.status(task.co) ~= "dead" then
+		table.insert(HybroidTasks, task)
+	end
+end`
@@ -0,0 +1,502 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Minify strips the comments and whitespace of Lua code and gives its locals the shortest names
+// they can have. Globals, fields and labels keep their names
+func Minify(src string) (string, error) {
+	toks, err := lexLua(src)
+	if err != nil {
+		return "", err
+	}
+
+	// the names the locals are given cannot hide any of the globals
+	collector := newLuaRenamer(toks, nil)
+	collector.run()
+	renamer := newLuaRenamer(toks, collector.unresolved)
+	renamer.run()
+
+	out := strings.Builder{}
+	var prev *luaToken
+	for i := range toks {
+		tok := toks[i]
+		tok.text = renamer.renamed[i]
+		if prev != nil {
+			out.WriteString(luaSeparator(*prev, tok))
+		}
+		out.WriteString(tok.text)
+		prev = &tok
+	}
+	return out.String(), nil
+}
+
+type luaTokenKind int
+
+const (
+	luaName luaTokenKind = iota
+	luaKeyword
+	luaNumber
+	luaString
+	luaSymbol
+)
+
+type luaToken struct {
+	kind luaTokenKind
+	text string
+	// whether a line break comes before the token
+	newline bool
+}
+
+var luaKeywords = []string{
+	"and", "break", "do", "else", "elseif", "end", "false", "for", "function", "goto", "if", "in",
+	"local", "nil", "not", "or", "repeat", "return", "then", "true", "until", "while",
+}
+
+// longest first, so that the first match is the right one
+var luaSymbols = []string{
+	"...", "..", "==", "~=", "<=", ">=", "//", "::", "<<", ">>",
+	"+", "-", "*", "/", "%", "^", "#", "&", "~", "|", "<", ">", "=",
+	"(", ")", "{", "}", "[", "]", ";", ":", ",", ".",
+}
+
+func isLuaNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isLuaDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Returns the level of the long bracket opening at i, as in [==[, or -1 if there is none
+func longBracketLevel(src string, i int) int {
+	if src[i] != '[' {
+		return -1
+	}
+	j := i + 1
+	for j < len(src) && src[j] == '=' {
+		j++
+	}
+	if j < len(src) && src[j] == '[' {
+		return j - i - 1
+	}
+	return -1
+}
+
+// Returns the index right after the long bracket of the level closing after start
+func closeLongBracket(src string, start, level int) (int, error) {
+	closing := "]" + strings.Repeat("=", level) + "]"
+	end := strings.Index(src[start:], closing)
+	if end == -1 {
+		return 0, fmt.Errorf("unfinished long string or comment")
+	}
+	return start + end + len(closing), nil
+}
+
+func lexLua(src string) ([]luaToken, error) {
+	toks := make([]luaToken, 0)
+	newline := false
+	add := func(kind luaTokenKind, text string) {
+		toks = append(toks, luaToken{kind: kind, text: text, newline: newline})
+		newline = false
+	}
+
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\n':
+			newline = true
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case strings.HasPrefix(src[i:], "--"):
+			if i+2 < len(src) {
+				if level := longBracketLevel(src, i+2); level != -1 {
+					end, err := closeLongBracket(src, i+2, level)
+					if err != nil {
+						return nil, err
+					}
+					i = end
+					continue
+				}
+			}
+			end := strings.IndexByte(src[i:], '\n')
+			if end == -1 {
+				end = len(src) - i
+			}
+			i += end
+		case isLuaNameStart(c):
+			j := i + 1
+			for j < len(src) && (isLuaNameStart(src[j]) || isLuaDigit(src[j])) {
+				j++
+			}
+			text := src[i:j]
+			if slices.Contains(luaKeywords, text) {
+				add(luaKeyword, text)
+			} else {
+				add(luaName, text)
+			}
+			i = j
+		case isLuaDigit(c) || c == '.' && i+1 < len(src) && isLuaDigit(src[i+1]):
+			// also takes the fx suffix of the fixedpoint numbers of PewPew Live
+			exponents := "eE"
+			if strings.HasPrefix(src[i:], "0x") || strings.HasPrefix(src[i:], "0X") {
+				exponents = "pP"
+			}
+			j := i + 1
+			for j < len(src) {
+				if strings.ContainsRune("+-", rune(src[j])) && strings.ContainsRune(exponents, rune(src[j-1])) ||
+					src[j] == '.' || isLuaNameStart(src[j]) || isLuaDigit(src[j]) {
+					j++
+					continue
+				}
+				break
+			}
+			add(luaNumber, src[i:j])
+			i = j
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\n' {
+					return nil, fmt.Errorf("unfinished string")
+				}
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unfinished string")
+			}
+			add(luaString, src[i:j+1])
+			i = j + 1
+		case longBracketLevel(src, i) != -1:
+			end, err := closeLongBracket(src, i, longBracketLevel(src, i))
+			if err != nil {
+				return nil, err
+			}
+			add(luaString, src[i:end])
+			i = end
+		default:
+			symbol := ""
+			for _, s := range luaSymbols {
+				if strings.HasPrefix(src[i:], s) {
+					symbol = s
+					break
+				}
+			}
+			if symbol == "" {
+				return nil, fmt.Errorf("unexpected character '%c'", c)
+			}
+			add(luaSymbol, symbol)
+			i += len(symbol)
+		}
+	}
+	return toks, nil
+}
+
+// Whether the token can end an expression
+func endsLuaExpression(tok luaToken) bool {
+	switch tok.kind {
+	case luaName, luaNumber, luaString:
+		return true
+	case luaKeyword:
+		return slices.Contains([]string{"end", "nil", "true", "false"}, tok.text)
+	}
+	return slices.Contains([]string{")", "]", "}", "..."}, tok.text)
+}
+
+// Returns what has to be written between two tokens for them to be read the same way
+func luaSeparator(prev, tok luaToken) string {
+	// without a semicolon the parentheses would call what comes before them
+	if tok.newline && tok.text == "(" && endsLuaExpression(prev) {
+		return ";"
+	}
+	isWord := func(t luaToken) bool {
+		return t.kind == luaName || t.kind == luaKeyword || t.kind == luaNumber
+	}
+	switch {
+	case isWord(prev) && isWord(tok),
+		prev.kind == luaNumber && tok.text[0] == '.',
+		// the sign would be read as the one of an exponent
+		prev.kind == luaNumber && strings.ContainsAny(prev.text[len(prev.text)-1:], "eEpP") && strings.ContainsAny(tok.text[:1], "+-"),
+		strings.HasSuffix(prev.text, ".") && tok.text[0] == '.',
+		prev.text == "-" && tok.text[0] == '-',
+		prev.text == "[" && (tok.text[0] == '[' || tok.text[0] == '='):
+		return " "
+	}
+	return ""
+}
+
+// Locals that are declared but only come into scope once their statement ends
+type pendingLocals struct {
+	names map[string]string
+	// where the statement is, the amount of open scopes and brackets
+	scopes   int
+	brackets int
+	// the variables of a for loop come into scope at its do
+	atDo bool
+	// the scope of a repeat loop ends after the condition of its until
+	closesScope bool
+}
+
+// Gives the locals of Lua code new names. Without globals it only finds the names that resolve to no local
+type luaRenamer struct {
+	toks       []luaToken
+	globals    map[string]bool
+	unresolved map[string]bool
+	// the text of every token, renamed or not
+	renamed  []string
+	scopes   []map[string]string
+	pending  []*pendingLocals
+	brackets []string
+	// the amount of while and for loops whose do opens no scope of its own
+	loopDos        int
+	declaring      *pendingLocals
+	awaitingParams bool
+	inParams       int
+}
+
+func newLuaRenamer(toks []luaToken, globals map[string]bool) *luaRenamer {
+	return &luaRenamer{
+		toks:       toks,
+		globals:    globals,
+		unresolved: make(map[string]bool),
+		renamed:    make([]string, len(toks)),
+		scopes:     []map[string]string{{}},
+	}
+}
+
+func (r *luaRenamer) at(i int) luaToken {
+	if i < 0 || i >= len(r.toks) {
+		return luaToken{}
+	}
+	return r.toks[i]
+}
+
+func (r *luaRenamer) pushScope() {
+	r.scopes = append(r.scopes, map[string]string{})
+}
+
+func (r *luaRenamer) popScope() {
+	if len(r.scopes) > 1 {
+		r.scopes = r.scopes[:len(r.scopes)-1]
+	}
+}
+
+// Returns the shortest name hiding neither a global nor a local that can be seen from here
+func (r *luaRenamer) newName(name string) string {
+	if r.globals == nil || name == "self" {
+		return name
+	}
+	taken := make(map[string]bool)
+	for _, scope := range r.scopes {
+		for _, newName := range scope {
+			taken[newName] = true
+		}
+	}
+	for _, locals := range r.pending {
+		for _, newName := range locals.names {
+			taken[newName] = true
+		}
+	}
+	for n := 0; ; n++ {
+		candidate := shortName(n)
+		if !taken[candidate] && !r.globals[candidate] && !slices.Contains(luaKeywords, candidate) {
+			return candidate
+		}
+	}
+}
+
+const (
+	shortNameStarts = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_"
+	shortNameChars  = shortNameStarts + "0123456789"
+)
+
+// Returns the nth name in the order of their length
+func shortName(n int) string {
+	name := []byte{shortNameStarts[n%len(shortNameStarts)]}
+	n /= len(shortNameStarts)
+	for n > 0 {
+		n--
+		name = append(name, shortNameChars[n%len(shortNameChars)])
+		n /= len(shortNameChars)
+	}
+	return string(name)
+}
+
+func (r *luaRenamer) declare(scope map[string]string, name string) string {
+	newName := r.newName(name)
+	scope[name] = newName
+	return newName
+}
+
+func (r *luaRenamer) resolve(name string) string {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if newName, found := r.scopes[i][name]; found {
+			return newName
+		}
+	}
+	r.unresolved[name] = true
+	return name
+}
+
+// Whether the statement the locals are declared in ended right before the token
+func (r *luaRenamer) endsStatement(locals *pendingLocals, i int) bool {
+	if len(r.scopes) != locals.scopes || len(r.brackets) != locals.brackets {
+		return false
+	}
+	tok := r.at(i)
+	if locals.atDo {
+		return tok.kind == luaKeyword && tok.text == "do"
+	}
+	if i == len(r.toks) || tok.text == ";" {
+		return true
+	}
+	prev := r.at(i - 1)
+	continues := prev.kind == luaSymbol && !endsLuaExpression(prev) ||
+		prev.kind == luaKeyword && slices.Contains([]string{"and", "or", "not", "local", "in", "until"}, prev.text)
+	startsStatement := tok.kind == luaKeyword && slices.Contains([]string{
+		"local", "return", "if", "while", "for", "repeat", "do", "end", "else", "elseif", "until", "goto", "break",
+	}, tok.text)
+	return (tok.newline || startsStatement) && !continues
+}
+
+func (r *luaRenamer) bringIntoScope(i int) {
+	for len(r.pending) > 0 {
+		locals := r.pending[len(r.pending)-1]
+		if !r.endsStatement(locals, i) {
+			return
+		}
+		r.pending = r.pending[:len(r.pending)-1]
+		if locals.closesScope {
+			r.popScope()
+			continue
+		}
+		for name, newName := range locals.names {
+			r.scopes[len(r.scopes)-1][name] = newName
+		}
+	}
+}
+
+func (r *luaRenamer) startLocals(atDo bool) {
+	r.declaring = &pendingLocals{
+		names:    make(map[string]string),
+		scopes:   len(r.scopes),
+		brackets: len(r.brackets),
+		atDo:     atDo,
+	}
+	r.pending = append(r.pending, r.declaring)
+}
+
+func (r *luaRenamer) run() {
+	for i, tok := range r.toks {
+		r.bringIntoScope(i)
+		r.renamed[i] = tok.text
+
+		if r.declaring != nil && !(tok.kind == luaName || tok.text == ",") {
+			r.declaring = nil
+		}
+
+		switch tok.kind {
+		case luaName:
+			r.renamed[i] = r.name(i)
+		case luaKeyword:
+			r.keyword(i)
+		case luaSymbol:
+			r.symbol(tok.text)
+		}
+	}
+	r.bringIntoScope(len(r.toks))
+}
+
+func (r *luaRenamer) name(i int) string {
+	tok, prev, next := r.at(i), r.at(i-1), r.at(i+1)
+	switch {
+	case r.declaring != nil:
+		newName := r.newName(tok.text)
+		r.declaring.names[tok.text] = newName
+		return newName
+	case r.inParams > 0 && len(r.brackets) == r.inParams:
+		return r.declare(r.scopes[len(r.scopes)-1], tok.text)
+	case prev.text == "function" && r.at(i-2).text == "local":
+		// the function is already in scope in its own body
+		return r.declare(r.scopes[len(r.scopes)-2], tok.text)
+	case prev.kind == luaSymbol && (prev.text == "." || prev.text == ":"),
+		prev.kind == luaKeyword && prev.text == "goto":
+		return tok.text
+	case prev.text == "::" && next.text == "::":
+		// a label, the name after its closing '::' is not one
+		return tok.text
+	case len(r.brackets) > 0 && r.brackets[len(r.brackets)-1] == "{" &&
+		(prev.text == "{" || prev.text == "," || prev.text == ";") && next.text == "=":
+		// the key of a table field
+		return tok.text
+	}
+	return r.resolve(tok.text)
+}
+
+func (r *luaRenamer) keyword(i int) {
+	switch r.toks[i].text {
+	case "local":
+		if r.at(i+1).text != "function" {
+			r.startLocals(false)
+		}
+	case "function":
+		r.pushScope()
+		r.awaitingParams = true
+	case "for":
+		r.pushScope()
+		r.loopDos++
+		r.startLocals(true)
+	case "while":
+		r.pushScope()
+		r.loopDos++
+	case "do":
+		if r.loopDos > 0 {
+			r.loopDos--
+		} else {
+			r.pushScope()
+		}
+	case "repeat", "then":
+		r.pushScope()
+	case "else":
+		r.popScope()
+		r.pushScope()
+	case "elseif", "end":
+		r.popScope()
+	case "until":
+		r.pending = append(r.pending, &pendingLocals{
+			scopes:      len(r.scopes),
+			brackets:    len(r.brackets),
+			closesScope: true,
+		})
+	}
+}
+
+func (r *luaRenamer) symbol(text string) {
+	switch text {
+	case "(", "[", "{":
+		r.brackets = append(r.brackets, text)
+		if text == "(" && r.awaitingParams {
+			r.awaitingParams = false
+			r.inParams = len(r.brackets)
+		}
+	case ")", "]", "}":
+		if text == ")" && r.inParams == len(r.brackets) {
+			r.inParams = 0
+		}
+		if len(r.brackets) > 0 {
+			r.brackets = r.brackets[:len(r.brackets)-1]
+		}
+	case ":":
+		// methods get self as their first parameter
+		if r.awaitingParams {
+			r.scopes[len(r.scopes)-1]["self"] = "self"
+		}
+	}
+}
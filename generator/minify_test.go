@@ -0,0 +1,103 @@
+package generator
+
+import (
+	"hybroid/vm"
+	"slices"
+	"testing"
+)
+
+func TestMinify(t *testing.T) {
+	cases := []struct {
+		name, source, expected string
+	}{
+		{
+			name: "whitespace and comments",
+			source: `-- a comment
+pewpew.print("a  b" .. 1 .. [[
+long]]) --[[ a long
+comment ]]
+print(- -1, 2 .. 3, 1e-2 - 1)`,
+			expected: `pewpew.print("a  b"..1 ..[[
+long]])print(- -1,2 ..3,1e-2-1)`,
+		},
+		{
+			name: `locals, fields and globals`,
+			source: `local pewpew = pewpew
+local E_speed = 1fx
+local E_table = {speed = E_speed, ["E_speed"] = E_speed}
+pewpew.print(E_table.speed + a)`,
+			expected: `local b=pewpew local c=1fx local d={speed=c,["E_speed"]=c}b.print(d.speed+a)`,
+		},
+		{
+			name: "scopes",
+			source: `local E_x = 1
+local function E_f(E_y, ...)
+	local E_x = E_x + E_y
+	for E_i = 1, E_x do
+		E_f(E_i)
+	end
+	return E_x
+end
+do
+	local E_z = E_x
+end
+local E_w = E_f(E_x)`,
+			expected: `local a=1 local function b(c,...)local d=a+c for e=1,d do b(e)end return d end do local c=a end local c=b(a)`,
+		},
+		{
+			name: "methods and labels",
+			source: `function Counter:Add(E_n)
+	local E_self = self
+	goto GL_
+	::GL_::
+	return E_self.count + E_n
+end`,
+			expected: `function Counter:Add(a)local b=self goto GL_::GL_::return b.count+a end`,
+		},
+		{
+			name: "calls on their own line",
+			source: `local E_f = function() end
+(function() end)()`,
+			expected: `local a=function()end;(function()end)()`,
+		},
+	}
+
+	for _, c := range cases {
+		minified, err := Minify(c.source)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if minified != c.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", c.name, c.expected, minified)
+		}
+	}
+}
+
+func TestMinifiedLabelsRun(t *testing.T) {
+	source := `local Self = {}
+for E_i = 1, 3 do
+	if E_i == 2 then
+		goto GL1
+	end
+	Self[E_i] = E_i
+	::GL1::Self[4] = E_i
+end
+return Self[1], Self[2], Self[3], Self[4]`
+
+	minified, err := Minify(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := vm.NewState().DoString("minified", minified)
+	if err != nil {
+		t.Fatalf("the minified source failed: %v\n%s", err, minified)
+	}
+	got := make([]string, len(values))
+	for i, value := range values {
+		got[i] = vm.ToString(value)
+	}
+	if expected := []string{"1", "nil", "3", "3"}; !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v from\n%s", expected, got, minified)
+	}
+}
@@ -84,6 +84,7 @@ func (gen *Generator) breakDownVariableDeclaration(declaration ast.VariableDecl)
 			Expressions: []ast.Node{},
 			IsPub:       declaration.IsPub,
 			IsConst:     declaration.IsConst,
+			MacroUsed:   declaration.MacroUsed,
 		}
 	}
 	decls := []ast.VariableDecl{}
@@ -0,0 +1,203 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"hybroid/ast"
+	"hybroid/core"
+	"hybroid/tokens"
+	"strconv"
+	"strings"
+)
+
+// Markers are written around every generated statement, and are stripped from the
+// final source while recording the location of the statement for every Lua line
+const (
+	markerStart    = '\x01'
+	markerStartEnd = '\x02'
+	markerEnd      = '\x03'
+)
+
+func (gen *Generator) markStart(node ast.Node) string {
+	gen.locations = append(gen.locations, node.GetToken().Location)
+	return string(markerStart) + strconv.Itoa(len(gen.locations)-1) + string(markerStartEnd)
+}
+
+// Strips the markers from the generated source, returning it along with the
+// location of the statement each line originates from. Lines that do not belong
+// to any statement have a zero location
+func (gen *Generator) resolveMarkers() (string, []tokens.Location) {
+	raw := gen.src.String()
+	src := core.StringBuilder{}
+	lines := make([]tokens.Location, 0)
+	stack := make([]tokens.Location, 0)
+	current := func() tokens.Location {
+		if len(stack) == 0 {
+			return tokens.Location{}
+		}
+		return stack[len(stack)-1]
+	}
+
+	var lineLocation *tokens.Location
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case markerStart:
+			end := strings.IndexByte(raw[i:], markerStartEnd)
+			index, _ := strconv.Atoi(raw[i+1 : i+end])
+			stack = append(stack, gen.locations[index])
+			i += end
+			continue
+		case markerEnd:
+			stack = stack[:len(stack)-1]
+			continue
+		case '\n':
+			if lineLocation == nil {
+				location := current()
+				lineLocation = &location
+			}
+			lines = append(lines, *lineLocation)
+			lineLocation = nil
+		case ' ', '\t':
+		default:
+			// a line belongs to the innermost statement it has code of
+			if lineLocation == nil {
+				location := current()
+				lineLocation = &location
+			}
+		}
+		src.WriteByte(raw[i])
+	}
+	if lineLocation == nil {
+		lines = append(lines, current())
+	} else {
+		lines = append(lines, *lineLocation)
+	}
+
+	return src.String(), lines
+}
+
+// A version 3 source map, with one segment at the start of every mapped line
+type SourceMap struct {
+	Version  int      `json:"version"`
+	File     string   `json:"file"`
+	Sources  []string `json:"sources"`
+	Names    []string `json:"names"`
+	Mappings string   `json:"mappings"`
+}
+
+// Creates the source map of the generated Lua file, pointing to the single source it was generated from
+func (gen *Generator) GetSourceMap(file, source string) SourceMap {
+	_, lines := gen.resolveMarkers()
+
+	mappings := core.StringBuilder{}
+	prevLine, prevColumn := 0, 0
+	for i, location := range lines {
+		if i != 0 {
+			mappings.WriteByte(';')
+		}
+		if location.Line == 0 {
+			continue
+		}
+		line, column := location.Line-1, max(location.Column.Start-1, 0)
+		mappings.Write(encodeVLQ(0), encodeVLQ(0), encodeVLQ(line-prevLine), encodeVLQ(column-prevColumn))
+		prevLine, prevColumn = line, column
+	}
+
+	return SourceMap{
+		Version:  3,
+		File:     file,
+		Sources:  []string{source},
+		Names:    []string{},
+		Mappings: mappings.String(),
+	}
+}
+
+const base64Chars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+func encodeVLQ(value int) string {
+	vlq := value << 1
+	if value < 0 {
+		vlq = (-value << 1) | 1
+	}
+
+	encoded := core.StringBuilder{}
+	for {
+		digit := vlq & 31
+		vlq >>= 5
+		if vlq > 0 {
+			digit |= 32
+		}
+		encoded.WriteByte(base64Chars[digit])
+		if vlq == 0 {
+			break
+		}
+	}
+	return encoded.String()
+}
+
+func decodeVLQ(segment string) ([]int, error) {
+	values := make([]int, 0, 4)
+	value, shift := 0, 0
+	for i := range segment {
+		digit := strings.IndexByte(base64Chars, segment[i])
+		if digit == -1 {
+			return nil, fmt.Errorf("invalid character '%c' in source map mappings", segment[i])
+		}
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+		if value&1 == 1 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	return values, nil
+}
+
+type OriginalPosition struct {
+	Source string
+	Line   int // 1-based
+	Column int // 1-based
+}
+
+// Returns the original position of every line in the generated file. Unmapped lines are nil
+func (sm *SourceMap) Lines() ([]*OriginalPosition, error) {
+	positions := make([]*OriginalPosition, 0)
+	source, line, column := 0, 0, 0
+	for _, mappedLine := range strings.Split(sm.Mappings, ";") {
+		var position *OriginalPosition
+		for _, segment := range strings.Split(mappedLine, ",") {
+			if segment == "" {
+				continue
+			}
+			values, err := decodeVLQ(segment)
+			if err != nil {
+				return nil, err
+			}
+			if len(values) < 4 {
+				continue
+			}
+			source, line, column = source+values[1], line+values[2], column+values[3]
+			if position == nil && source >= 0 && source < len(sm.Sources) {
+				position = &OriginalPosition{Source: sm.Sources[source], Line: line + 1, Column: column + 1}
+			}
+		}
+		positions = append(positions, position)
+	}
+	return positions, nil
+}
+
+func ParseSourceMap(data []byte) (*SourceMap, error) {
+	sourceMap := &SourceMap{}
+	if err := json.Unmarshal(data, sourceMap); err != nil {
+		return nil, err
+	}
+	if sourceMap.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", sourceMap.Version)
+	}
+	return sourceMap, nil
+}
@@ -24,7 +24,20 @@ func (gen *Generator) ifStmt(node ast.IfStmt) string {
 	gen.Twrite(&src, "if ", expr, " then\n")
 
 	gen.GenerateBody(&src, node.Body)
-	for _, elseif := range node.Elseifs {
+	for i, elseif := range node.Elseifs {
+		if _, ok := elseif.BoolExpr.(*ast.LetUnwrapExpr); ok {
+			// the unwrapped value needs a local before its condition, so the rest of the branches go in the else
+			gen.Twrite(&src, "else\n")
+			gen.tabCount++
+			prevSrc := gen.LatestSrc
+			gen.LatestSrc = &src
+			nested := gen.ifStmt(ast.IfStmt{BoolExpr: elseif.BoolExpr, Body: elseif.Body, Elseifs: node.Elseifs[i+1:], Else: node.Else, Token: elseif.Token})
+			src.Write(nested, "\n")
+			gen.LatestSrc = prevSrc
+			gen.tabCount--
+			gen.Twrite(&src, "end")
+			return src.String()
+		}
 		gen.Twrite(&src, "elseif ", gen.GenerateExpr(elseif.BoolExpr), " then\n")
 		gen.GenerateBody(&src, elseif.Body)
 	}
@@ -242,6 +255,22 @@ func (gen *Generator) tickStmt(node ast.TickStmt) string {
 	return src.String()
 }
 
+// Waiting yields the coroutine of the task with the number of ticks HybroidStart resumes it after
+func (gen *Generator) waitStmt(node ast.WaitStmt) string {
+	src := core.StringBuilder{}
+	if node.Until != nil {
+		gen.Twrite(&src, "while not (", gen.GenerateExpr(node.Until), ") do\n")
+		gen.tabCount++
+		gen.Twrite(&src, "coroutine.yield(1)\n")
+		gen.tabCount--
+		gen.Twrite(&src, "end")
+		return src.String()
+	}
+
+	gen.Twrite(&src, "coroutine.yield(", gen.GenerateExpr(node.Ticks), ")")
+	return src.String()
+}
+
 func (gen *Generator) matchStmt(node ast.MatchStmt) string {
 	src := core.StringBuilder{}
 	label := GenerateVar(hyGotoLabel)
@@ -285,3 +314,27 @@ func (gen *Generator) destroyStmt(node ast.DestroyStmt) string {
 	src.Write(")")
 	return src.String()
 }
+
+func (gen *Generator) macroCallStmt(node ast.MacroCallExpr) string {
+	if node.Macro.MacroType == ast.ExpressionExpansion {
+		return gen.GenerateStmt(node.Expansion)
+	}
+
+	src := core.StringBuilder{}
+	gen.Twrite(&src, "do\n")
+	gen.GenerateBody(&src, node.Body)
+	gen.Twrite(&src, "end")
+	return src.String()
+}
+
+func (gen *Generator) removeStmt(node ast.RemoveStmt) string {
+	src := core.StringBuilder{}
+
+	index, container := gen.GenerateExpr(node.Index), gen.GenerateExpr(node.Container)
+	if node.InMap {
+		gen.Twrite(&src, container, "[", index, "] = nil")
+	} else {
+		gen.Twrite(&src, "table.remove(", container, ", ", index, ")")
+	}
+	return src.String()
+}
@@ -12,8 +12,9 @@ import (
 type Lexer struct {
 	alerts.Collector
 
-	buffer []byte
-	source *bufio.Reader
+	buffer   []byte
+	source   *bufio.Reader
+	comments []tokens.Token
 
 	line   int
 	column int
@@ -23,6 +24,7 @@ func NewLexer(reader io.Reader) Lexer {
 	return Lexer{
 		Collector: alerts.NewCollector(),
 		buffer:    make([]byte, 0),
+		comments:  make([]tokens.Token, 0),
 		source:    bufio.NewReader(reader),
 		line:      1,
 		column:    1,
@@ -33,6 +35,13 @@ func (l *Lexer) Alert(alertType alerts.Alert, args ...any) {
 	l.Alert_(alertType, args...)
 }
 
+// Returns the comments found while tokenizing, in the order they appear in the source.
+// They are not part of the token stream, the formatter uses them as trivia. Doc comments
+// are also given in the stream, as DocComment tokens
+func (l *Lexer) Comments() []tokens.Token {
+	return l.comments
+}
+
 func (l *Lexer) Tokenize() ([]tokens.Token, error) {
 	lexerTokens := make([]tokens.Token, 0)
 
@@ -98,6 +107,14 @@ func (l *Lexer) next() (*tokens.Token, error) {
 		token.Type = tokens.Colon
 	case '#':
 		token.Type = tokens.Hash
+	case '?':
+		if l.match('?') {
+			token.Type = tokens.QuestionQuestion
+		} else {
+			token.Type = tokens.Question
+		}
+	case '@':
+		token.Type = tokens.At
 	case '.':
 		if l.match('.') {
 			if l.match('.') {
@@ -164,11 +181,9 @@ func (l *Lexer) next() (*tokens.Token, error) {
 		token.Type = tokens.Ampersand
 	case '/':
 		if l.match('/') {
-			err := l.handleComment(false)
-			return nil, err
+			return l.handleComment(false)
 		} else if l.match('*') {
-			err := l.handleComment(true)
-			return nil, err
+			return l.handleComment(true)
 		} else {
 			if l.match('=') {
 				token.Type = tokens.SlashEqual
@@ -384,26 +399,73 @@ func (l *Lexer) handleIdentifier() (*tokens.Token, error) {
 	return &token, nil
 }
 
-func (l *Lexer) handleComment(multiline bool) error {
+// Keeps the comment aside, and returns a doc comment token when it is one
+func (l *Lexer) handleComment(multiline bool) (*tokens.Token, error) {
+	comment := tokens.Token{
+		Type:     tokens.Comment,
+		Location: tokens.NewLocation(l.line, l.column-2, l.column),
+	}
+	var err error
 	if !multiline {
-		_, err := l.source.ReadBytes('\n')
-		if err != nil {
-			return err
+		var rest []byte
+		rest, err = l.source.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		comment.Lexeme = strings.TrimRight(l.bufferString()+string(rest), " \t\r\n")
+		comment.Column.End = comment.Column.Start + len(comment.Lexeme)
+		if err == nil {
+			l.line++
+			l.column = 1
 		}
-		l.line++
-		l.column = 1
-		return nil
 	} else {
-		for !l.match('*', '/') && !l.isEOF() {
-			if l.match('/', '*') {
-				l.handleComment(true)
-			} else {
-				_, err := l.advance()
-				if err != nil {
-					return err
-				}
+		err = l.skipMultilineComment()
+		// the lexeme spans every line of the comment, the location is where it starts
+		comment.Lexeme = l.bufferString()
+		comment.Column.End = l.column
+	}
+	l.comments = append(l.comments, comment)
+
+	doc, isDoc := docCommentText(comment.Lexeme)
+	if !isDoc {
+		return nil, err
+	}
+	comment.Type = tokens.DocComment
+	comment.Literal = doc
+	return &comment, err
+}
+
+// Returns the text of a /// or /** */ doc comment, without the comment markers
+func docCommentText(lexeme string) (string, bool) {
+	if strings.HasPrefix(lexeme, "///") && !strings.HasPrefix(lexeme, "////") {
+		return strings.TrimPrefix(lexeme[3:], " "), true
+	}
+	if !strings.HasPrefix(lexeme, "/**") || strings.HasPrefix(lexeme, "/***") || lexeme == "/**/" {
+		return "", false
+	}
+
+	lines := strings.Split(strings.TrimSuffix(lexeme[3:], "*/"), "\n")
+	for i, line := range lines {
+		line = strings.TrimLeft(strings.TrimRight(line, " \t\r"), " \t")
+		// the lines of a block comment often start with a star lining up with the one of /**
+		if line == "*" || strings.HasPrefix(line, "* ") {
+			line = line[1:]
+		}
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n"), true
+}
+
+func (l *Lexer) skipMultilineComment() error {
+	for !l.match('*', '/') && !l.isEOF() {
+		if l.match('/', '*') {
+			l.skipMultilineComment()
+		} else {
+			_, err := l.advance()
+			if err != nil {
+				return err
 			}
 		}
-		return nil
 	}
+	return nil
 }
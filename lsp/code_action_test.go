@@ -0,0 +1,62 @@
+package lsp
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+const codeActionSource = `env TestLevel as Level
+
+use Pewpew
+
+Print(ToString(Sqrt(4f)))
+`
+
+func TestCodeActionAddsUse(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"hybconfig.toml": minimalHybConfig,
+		"level.hyb":      codeActionSource,
+	})
+	h, _ := newTestHandlerWithRoot(t, root)
+	h.preAnalyzeWorkspace()
+	if h.eval == nil {
+		t.Fatal("expected the workspace to be analyzed")
+	}
+
+	uri := toURI(filepath.Join(root, "level.hyb"))
+	params := CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        Range{Start: Position{Line: 4, Character: 15}, End: Position{Line: 4, Character: 15}},
+	}
+	result, err := h.handleTextDocumentCodeAction(context.Background(), nil, newTestRequest("textDocument/codeAction", params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := result.([]CodeAction)
+	if len(actions) != 1 {
+		t.Fatalf("expected one action, got %+v", actions)
+	}
+
+	action := actions[0]
+	if action.Title != "add 'use Fmath'" || action.Kind != QuickFix || len(action.Diagnostics) != 1 {
+		t.Errorf("unexpected action: %+v", action)
+	}
+	edits := action.Edit.Changes[uri]
+	if len(edits) != 1 {
+		t.Fatalf("expected one edit, got %+v", edits)
+	}
+	expected := TextEdit{
+		Range:   Range{Start: Position{Line: 2, Character: 10}, End: Position{Line: 2, Character: 10}},
+		NewText: "\nuse Fmath",
+	}
+	if edits[0] != expected {
+		t.Errorf("unexpected edit %+v, expected %+v", edits[0], expected)
+	}
+
+	params.Context.Only = []CodeActionKind{Refactor}
+	result, _ = h.handleTextDocumentCodeAction(context.Background(), nil, newTestRequest("textDocument/codeAction", params))
+	if actions := result.([]CodeAction); len(actions) != 0 {
+		t.Errorf("expected no refactoring actions, got %+v", actions)
+	}
+}
@@ -0,0 +1,91 @@
+package lsp
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const docCommentSource = `env TestLevel as Level
+
+/// Moves the player by ` + "`amount`" + `.
+///
+/// Returns the new position.
+pub fn Move(fixed amount) -> fixed {
+    return amount
+}
+
+/**
+ * The states of the level.
+ */
+enum State {
+    /// Before the first wave
+    Waiting,
+    Playing
+}
+
+let s = State.Waiting
+let x = Move(1f)
+`
+
+func TestDocComments(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"hybconfig.toml": minimalHybConfig,
+		"level.hyb":      docCommentSource,
+	})
+	h, _ := newTestHandlerWithRoot(t, root)
+	h.preAnalyzeWorkspace()
+	if h.eval == nil {
+		t.Fatal("expected the workspace to be analyzed")
+	}
+	uri := toURI(filepath.Join(root, "level.hyb"))
+	h.files[uri] = &File{LanguageID: "hybroid", Text: docCommentSource}
+
+	hover := func(line, character int) string {
+		t.Helper()
+		result, err := h.handleTextDocumentHover(context.Background(), nil, newTestRequest("textDocument/hover", HoverParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: line, Character: character}},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result == nil {
+			t.Fatalf("expected a hover at %d:%d", line, character)
+		}
+		if h, ok := result.(*Hover); ok {
+			result = *h
+		}
+		return result.(Hover).Contents.(MarkupContent).Value
+	}
+
+	moveDoc := "Moves the player by `amount`.\n\nReturns the new position."
+	if value := hover(19, 9); !strings.Contains(value, moveDoc) {
+		t.Errorf("expected the hover of Move to contain its doc, got %q", value)
+	}
+	if value := hover(18, 9); !strings.Contains(value, "The states of the level.") {
+		t.Errorf("expected the hover of State to contain its doc, got %q", value)
+	}
+	if value := hover(18, 16); !strings.Contains(value, "Before the first wave") {
+		t.Errorf("expected the hover of Waiting to contain its doc, got %q", value)
+	}
+
+	item, err := h.completionResolve(&CompletionItem{Label: "Move", Data: string(uri)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Documentation == nil || item.Documentation.Kind != Markdown || item.Documentation.Value != moveDoc {
+		t.Errorf("unexpected completion documentation %+v", item.Documentation)
+	}
+
+	result, err := h.handleTextDocumentSignatureHelp(context.Background(), nil, newTestRequest("textDocument/signatureHelp", SignatureHelpParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 19, Character: 13}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	help := result.(SignatureHelp)
+	if documentation := help.Signatures[0].Documentation; documentation == nil || documentation.Value != moveDoc {
+		t.Errorf("unexpected signature documentation %+v", documentation)
+	}
+}
@@ -0,0 +1,166 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const symbolsSource = `env Enemies as Shared
+
+use Pewpew
+
+pub alias Point = struct { fixed x, fixed y }
+
+pub enum State {
+	Idle,
+	Chasing
+}
+
+pub const SPEED = 2f
+let spawned, destroyed = 0, 0
+
+pub entity Chaser {
+	fixed x, y
+	State state
+
+	spawn(fixed x, y) {
+		self.x, self.y = x, y
+		spawned += 1
+	}
+
+	destroy() {
+		destroyed += 1
+	}
+
+	Update() {
+		self.x += SPEED
+	}
+
+	fn Stop() {
+		self.state = State.Idle
+	}
+}
+
+class Counter {
+	number count
+
+	new() {
+		self.count = 0
+	}
+
+	fn Increment() {
+		self.count += 1
+	}
+}
+
+pub fn Spawn(fixed x, y) {
+	spawn Chaser(x, y)
+}
+`
+
+// Renders the symbol tree as indented "name kind" lines
+func renderSymbols(symbols []DocumentSymbol, indent string) string {
+	out := strings.Builder{}
+	for _, symbol := range symbols {
+		fmt.Fprintf(&out, "%s%s %d\n", indent, symbol.Name, symbol.Kind)
+		out.WriteString(renderSymbols(symbol.Children, indent+"  "))
+	}
+	return out.String()
+}
+
+func rangeContains(outer, inner Range) bool {
+	return comparePositions(outer.Start, inner.Start) <= 0 && comparePositions(inner.End, outer.End) <= 0
+}
+
+func checkRanges(t *testing.T, symbols []DocumentSymbol) {
+	t.Helper()
+	for _, symbol := range symbols {
+		if !rangeContains(symbol.Range, symbol.SelectionRange) {
+			t.Errorf("the range of %s does not contain its selection range: %v, %v", symbol.Name, symbol.Range, symbol.SelectionRange)
+		}
+		for _, child := range symbol.Children {
+			if !rangeContains(symbol.Range, child.Range) {
+				t.Errorf("the range of %s does not contain the range of %s: %v, %v", symbol.Name, child.Name, symbol.Range, child.Range)
+			}
+		}
+		checkRanges(t, symbol.Children)
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	h, _ := newTestHandler(t)
+	uri := DocumentURI("file:///enemies.hyb")
+	h.files[uri] = &File{LanguageID: "hybroid", Text: symbolsSource}
+
+	result, err := h.handleTextDocumentSymbol(context.Background(), nil, newTestRequest("textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	symbols := result.([]DocumentSymbol)
+
+	expected := `Enemies 2
+  Point 26
+  State 10
+    Idle 22
+    Chasing 22
+  SPEED 14
+  spawned 13
+  destroyed 13
+  Chaser 23
+    x 8
+    y 8
+    state 8
+    spawn 9
+    destroy 6
+    Update 24
+    Stop 6
+  Counter 5
+    count 8
+    new 9
+    Increment 6
+  Spawn 12
+`
+	if got := renderSymbols(symbols, ""); got != expected {
+		t.Errorf("unexpected outline:\n%s\nexpected:\n%s", got, expected)
+	}
+	checkRanges(t, symbols)
+
+	spawn := symbols[0].Children[len(symbols[0].Children)-1]
+	if spawn.Detail != "pub" || spawn.SelectionRange.Start != (Position{Line: 48, Character: 7}) {
+		t.Errorf("unexpected function symbol: %+v", spawn)
+	}
+}
+
+func TestWorkspaceSymbols(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"hybconfig.toml": minimalHybConfig,
+		"level.hyb":      minimalLevelSource,
+		"enemies.hyb":    symbolsSource,
+	})
+	h, _ := newTestHandlerWithRoot(t, root)
+	h.preAnalyzeWorkspace()
+	if h.eval == nil {
+		t.Fatal("expected the workspace to be analyzed")
+	}
+
+	result, err := h.handleWorkspaceSymbol(context.Background(), nil, newTestRequest("workspace/symbol", WorkspaceSymbolParams{Query: "chas"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	symbols := result.([]SymbolInformation)
+
+	names := make([]string, 0)
+	for _, symbol := range symbols {
+		names = append(names, fmt.Sprintf("%s in %s", symbol.Name, *symbol.ContainerName))
+		if !strings.HasSuffix(string(symbol.Location.URI), "/enemies.hyb") {
+			t.Errorf("expected %s to be in enemies.hyb, got %s", symbol.Name, symbol.Location.URI)
+		}
+	}
+	if got := strings.Join(names, ", "); got != "Chasing in State, Chaser in Enemies" {
+		t.Errorf("unexpected symbols: %s", got)
+	}
+}
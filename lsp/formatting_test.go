@@ -0,0 +1,115 @@
+package lsp
+
+import (
+	"context"
+	"hybroid/formatter"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const unformattedSource = `env Test as Level
+
+// the speed
+let speed=2f
+let unchanged = 1
+fn Move(fixed x)->fixed{
+return x+speed
+}
+
+let point = struct{x=1,y=2}
+`
+
+// Applies edits given in the order of the document, the way an editor does
+func applyEdits(t *testing.T, text string, edits []TextEdit) string {
+	t.Helper()
+	lines := splitLines(text)
+	offset := func(position Position) int {
+		result := 0
+		for _, line := range lines[:min(position.Line, len(lines))] {
+			result += len(line)
+		}
+		if position.Line < len(lines) {
+			units := utf16.Encode([]rune(lines[position.Line]))
+			result += len(string(utf16.Decode(units[:position.Character])))
+		}
+		return result
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		start, end := offset(edits[i].Range.Start), offset(edits[i].Range.End)
+		if start > end {
+			t.Fatalf("edit %d has a reversed range: %v", i, edits[i].Range)
+		}
+		text = text[:start] + edits[i].NewText + text[end:]
+	}
+	return text
+}
+
+func TestFormatting(t *testing.T) {
+	h, _ := newTestHandler(t)
+	uri := DocumentURI("file:///test.hyb")
+	h.files[uri] = &File{LanguageID: "hybroid", Text: unformattedSource}
+
+	result, err := h.handleTextDocumentFormatting(context.Background(), nil, newTestRequest("textDocument/formatting", DocumentFormattingParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edits := result.([]TextEdit)
+	expected, _ := formatter.Format(unformattedSource)
+	if got := applyEdits(t, unformattedSource, edits); got != expected {
+		t.Errorf("the edits do not format the document:\n%s\nexpected:\n%s", got, expected)
+	}
+	for _, edit := range edits {
+		if strings.Contains(edit.NewText, "unchanged") || strings.Contains(edit.NewText, "the speed") {
+			t.Errorf("an edit replaces a line that did not change: %+v", edit)
+		}
+	}
+}
+
+func TestRangeFormatting(t *testing.T) {
+	h, _ := newTestHandler(t)
+	uri := DocumentURI("file:///test.hyb")
+	h.files[uri] = &File{LanguageID: "hybroid", Text: unformattedSource}
+
+	// only the function
+	result, err := h.handleTextDocumentRangeFormatting(context.Background(), nil, newTestRequest("textDocument/rangeFormatting", DocumentRangeFormattingParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        Range{Start: Position{Line: 5}, End: Position{Line: 7, Character: 1}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := applyEdits(t, unformattedSource, result.([]TextEdit))
+	if !strings.Contains(got, "fn Move(fixed x) -> fixed {\n    return x + speed\n}") {
+		t.Errorf("the function was not formatted:\n%s", got)
+	}
+	if !strings.Contains(got, "let speed=2f") || !strings.Contains(got, "struct{x=1,y=2}") {
+		t.Errorf("lines outside of the range were formatted:\n%s", got)
+	}
+}
+
+func TestFormattingSkipsSyntaxErrors(t *testing.T) {
+	h, _ := newTestHandler(t)
+	uri := DocumentURI("file:///test.hyb")
+	h.files[uri] = &File{LanguageID: "hybroid", Text: "env Test as Level\nlet a = (1\n"}
+
+	result, err := h.handleTextDocumentFormatting(context.Background(), nil, newTestRequest("textDocument/formatting", DocumentFormattingParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edits := result.([]TextEdit); len(edits) != 0 {
+		t.Errorf("expected no edits, got %+v", edits)
+	}
+}
+
+func TestLineEditsWithoutFinalNewline(t *testing.T) {
+	oldText := "let a = 1\nlet b=2"
+	newText := "let a = 1\nlet b = 2\n"
+	if got := applyEdits(t, oldText, lineEdits(oldText, newText)); got != newText {
+		t.Errorf("unexpected result %q", got)
+	}
+}
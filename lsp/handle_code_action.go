@@ -0,0 +1,89 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"hybroid/alerts"
+	"slices"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *langHandler) handleTextDocumentCodeAction(ctx context.Context, _ notifier, req *jsonrpc2.Request) (result any, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params CodeActionParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if len(params.Context.Only) != 0 && !slices.Contains(params.Context.Only, QuickFix) {
+		return []CodeAction{}, nil
+	}
+
+	if !h.waitReady(ctx) {
+		return nil, nil
+	}
+
+	h.mu.Lock()
+	eval := h.eval
+	h.mu.Unlock()
+
+	if eval == nil {
+		return []CodeAction{}, nil
+	}
+
+	path, err := fromURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+	relPath := getRelPath(h.rootPath, path)
+
+	h.evalMu.Lock()
+	fileAlerts := eval.GetAlerts(relPath)
+	h.evalMu.Unlock()
+
+	actions := make([]CodeAction, 0)
+	for _, alert := range fileAlerts {
+		fixable, ok := alert.(alerts.Fixable)
+		if !ok {
+			continue
+		}
+		fix, ok := fixable.Fix()
+		if !ok {
+			continue
+		}
+		diagnostic := alertsToDiagnostics(params.TextDocument.URI, []alerts.Alert{alert})[0]
+		if !rangesIntersect(diagnostic.Range, params.Range) {
+			continue
+		}
+
+		edits := make([]TextEdit, 0, len(fix.Edits))
+		for _, edit := range fix.Edits {
+			edits = append(edits, TextEdit{
+				Range: Range{
+					Start: Position{Line: edit.Start.Line - 1, Character: edit.Start.Column - 1},
+					End:   Position{Line: edit.End.Line - 1, Character: edit.End.Column - 1},
+				},
+				NewText: edit.NewText,
+			})
+		}
+		actions = append(actions, CodeAction{
+			Title:       fix.Title,
+			Kind:        QuickFix,
+			Diagnostics: []Diagnostic{diagnostic},
+			IsPreferred: true,
+			Edit: &WorkspaceEdit{
+				Changes: map[DocumentURI][]TextEdit{params.TextDocument.URI: edits},
+			},
+		})
+	}
+	return actions, nil
+}
+
+// Whether the ranges share a position, the end of a range included
+func rangesIntersect(a, b Range) bool {
+	return comparePositions(a.Start, b.End) <= 0 && comparePositions(b.Start, a.End) <= 0
+}
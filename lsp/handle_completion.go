@@ -234,9 +234,9 @@ func (h *langHandler) completion(file *File, w *walker.Walker, params *Completio
 	// 5. Standard Keywords
 	keywords := []string{
 		"is", "isnt", "alias", "and", "as", "break", "by", "const", "continue",
-		"else", "entity", "enum", "env", "false", "fn", "to", "for", "if", "in",
-		"let", "match", "new", "or", "pub", "repeat", "return", "self", "spawn",
-		"struct", "class", "tick", "true", "use", "from", "while", "with",
+		"else", "entity", "enum", "env", "false", "find", "fn", "to", "for", "if", "in", "impl", "interface",
+		"let", "match", "new", "nil", "or", "pub", "remove", "repeat", "return", "self", "spawn", "start",
+		"struct", "task", "class", "test", "tick", "true", "until", "use", "wait", "from", "while", "with",
 		"yield", "destroy", "every",
 	}
 	for _, kw := range keywords {
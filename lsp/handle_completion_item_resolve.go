@@ -47,13 +47,20 @@ func (h *langHandler) completionResolve(item *CompletionItem) (CompletionItem, e
 		}
 	}
 
-	detail, documentation := getSymbolMetadata(w, walkers, item.Label)
+	detail, doc := getSymbolMetadata(w, walkers, item.Label)
+	// the variables of the file are left to the scope lookups of hover, their docs are found here
+	if doc == "" && w != nil {
+		if variable, ok := w.Env().Scope.Variables[item.Label]; ok {
+			doc = variable.Doc
+		}
+	}
 
 	if detail == "" {
 		detail = item.Detail
 	}
-	if documentation == "" {
-		documentation = item.Documentation
+	documentation := item.Documentation
+	if doc != "" {
+		documentation = &MarkupContent{Kind: Markdown, Value: doc}
 	}
 
 	return CompletionItem{
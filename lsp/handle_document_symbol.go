@@ -0,0 +1,274 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"hybroid/ast"
+	"hybroid/lexer"
+	"hybroid/parser"
+	"hybroid/tokens"
+	"hybroid/walker"
+	"path/filepath"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// The end tokens of declarations are found with the AST helpers of the walker, which do not depend on a walk
+var declarationWalker = walker.NewWalker("", "")
+
+func (h *langHandler) handleTextDocumentSymbol(_ context.Context, _ notifier, req *jsonrpc2.Request) (result any, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params DocumentSymbolParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	file, fileOk := h.files[params.TextDocument.URI]
+	h.mu.Unlock()
+
+	if !fileOk {
+		return nil, nil
+	}
+
+	// The outline only needs the declarations, so the buffer is parsed on its own without waiting for the project
+	lex := lexer.NewLexer(strings.NewReader(file.Text))
+	toks, err := lex.Tokenize()
+	if err != nil {
+		return []DocumentSymbol{}, nil
+	}
+	p := parser.NewParser(toks)
+	return documentSymbols(p.Parse()), nil
+}
+
+func (h *langHandler) handleWorkspaceSymbol(ctx context.Context, _ notifier, req *jsonrpc2.Request) (result any, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params WorkspaceSymbolParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if !h.waitReady(ctx) {
+		return nil, nil
+	}
+
+	h.mu.Lock()
+	eval := h.eval
+	rootPath := h.rootPath
+	h.mu.Unlock()
+
+	if eval == nil {
+		return []SymbolInformation{}, nil
+	}
+
+	h.evalMu.Lock()
+	defer h.evalMu.Unlock()
+
+	symbols := make([]SymbolInformation, 0)
+	for _, w := range eval.WalkerList() {
+		path := w.Env().HybroidPath()
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(rootPath, path)
+		}
+		symbols = append(symbols, workspaceSymbols(documentSymbols(w.Program()), params.Query, toURI(path), nil)...)
+	}
+	return symbols, nil
+}
+
+// Flattens the document symbols matching the query (case insensitive) into symbol informations
+func workspaceSymbols(docSymbols []DocumentSymbol, query string, uri DocumentURI, container *string) []SymbolInformation {
+	symbols := make([]SymbolInformation, 0)
+	query = strings.ToLower(query)
+	for _, symbol := range docSymbols {
+		if strings.Contains(strings.ToLower(symbol.Name), query) {
+			symbols = append(symbols, SymbolInformation{
+				Name:          symbol.Name,
+				Kind:          symbol.Kind,
+				Location:      Location{URI: uri, Range: symbol.SelectionRange},
+				ContainerName: container,
+			})
+		}
+		name := symbol.Name
+		symbols = append(symbols, workspaceSymbols(symbol.Children, query, uri, &name)...)
+	}
+	return symbols
+}
+
+// Builds the outline of a program from its declarations. The environment contains every other top level declaration
+func documentSymbols(program []ast.Node) []DocumentSymbol {
+	symbols := make([]DocumentSymbol, 0)
+	var env *DocumentSymbol
+	for _, node := range program {
+		if envDecl, ok := node.(*ast.EnvironmentDecl); ok && env == nil && envDecl.Env != nil && envDecl.EnvType != nil {
+			env = &DocumentSymbol{
+				Name:           envDecl.Env.Path.Lexeme,
+				Detail:         envDecl.EnvType.Token.Lexeme,
+				Kind:           ModuleSymbol,
+				SelectionRange: tokenRange(envDecl.Env.Path, envDecl.Env.Path),
+				Children:       make([]DocumentSymbol, 0),
+			}
+			continue
+		}
+
+		for _, symbol := range declarationSymbols(node) {
+			if env != nil {
+				env.Children = append(env.Children, symbol)
+			} else {
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+
+	if env == nil {
+		return symbols
+	}
+	env.Range = env.SelectionRange
+	if len(env.Children) != 0 {
+		env.Range.End = env.Children[len(env.Children)-1].Range.End
+	}
+	return append([]DocumentSymbol{*env}, symbols...)
+}
+
+func declarationSymbols(node ast.Node) []DocumentSymbol {
+	switch decl := node.(type) {
+	case *ast.VariableDecl:
+		return variableSymbols(decl)
+	case *ast.FunctionDecl:
+		return []DocumentSymbol{newDocumentSymbol(decl.Name.Lexeme, visibility(decl.IsPub), FunctionSymbol, decl.Token, decl.Name, endToken(decl))}
+	case *ast.AliasDecl:
+		end := decl.Name
+		if decl.Type != nil {
+			end = endToken(decl.Type)
+		}
+		return []DocumentSymbol{newDocumentSymbol(decl.Name.Lexeme, visibility(decl.IsPub), TypeParameterSymbol, decl.Token, decl.Name, end)}
+	case *ast.EnumDecl:
+		symbol := newDocumentSymbol(decl.Name.Lexeme, visibility(decl.IsPub), EnumSymbol, decl.Token, decl.Name, endToken(decl))
+		for _, field := range decl.Fields {
+			symbol.addChild(newDocumentSymbol(field.Name.Lexeme, "", EnumMemberSymbol, field.Name, field.Name, endToken(field)))
+		}
+		return []DocumentSymbol{symbol}
+	case *ast.EntityDecl:
+		symbol := newDocumentSymbol(decl.Name.Lexeme, visibility(decl.IsPub), StructSymbol, decl.Token, decl.Name, endToken(decl))
+		for i := range decl.Fields {
+			symbol.addFields(&decl.Fields[i])
+		}
+		if decl.Spawner != nil {
+			symbol.addChild(newDocumentSymbol(decl.Spawner.Token.Lexeme, "", ConstructorSymbol, decl.Spawner.Token, decl.Spawner.Token, endToken(decl.Spawner)))
+		}
+		if decl.Destroyer != nil {
+			symbol.addChild(newDocumentSymbol(decl.Destroyer.Token.Lexeme, "", MethodSymbol, decl.Destroyer.Token, decl.Destroyer.Token, endToken(decl.Destroyer)))
+		}
+		for _, callback := range decl.Callbacks {
+			symbol.addChild(newDocumentSymbol(callback.Token.Lexeme, "", EventSymbol, callback.Token, callback.Token, endToken(callback)))
+		}
+		for i := range decl.Methods {
+			symbol.addChild(methodSymbol(&decl.Methods[i]))
+		}
+		return []DocumentSymbol{symbol}
+	case *ast.ClassDecl:
+		symbol := newDocumentSymbol(decl.Name.Lexeme, visibility(decl.IsPub), ClassSymbol, decl.Token, decl.Name, endToken(decl))
+		for i := range decl.Fields {
+			symbol.addFields(&decl.Fields[i])
+		}
+		if decl.Constructor != nil {
+			symbol.addChild(newDocumentSymbol(decl.Constructor.Token.Lexeme, "", ConstructorSymbol, decl.Constructor.Token, decl.Constructor.Token, endToken(decl.Constructor)))
+		}
+		for i := range decl.Methods {
+			symbol.addChild(methodSymbol(&decl.Methods[i]))
+		}
+		return []DocumentSymbol{symbol}
+	case *ast.InterfaceDecl:
+		symbol := newDocumentSymbol(decl.Name.Lexeme, visibility(decl.IsPub), InterfaceSymbol, decl.Token, decl.Name, endToken(decl))
+		for i := range decl.Methods {
+			symbol.addChild(methodSymbol(&decl.Methods[i]))
+		}
+		return []DocumentSymbol{symbol}
+	case *ast.TestDecl:
+		return []DocumentSymbol{newDocumentSymbol(decl.Name.Lexeme, "test", FunctionSymbol, decl.Token, decl.Name, endToken(decl))}
+	}
+	return nil
+}
+
+// Every variable of `let a, b = 1, 2` gets its own symbol, spanning the whole declaration
+func variableSymbols(decl *ast.VariableDecl) []DocumentSymbol {
+	kind := VariableSymbol
+	detail := visibility(decl.IsPub)
+	if decl.IsConst {
+		kind = ConstantSymbol
+		detail += " const"
+	}
+	symbols := make([]DocumentSymbol, 0, len(decl.Identifiers))
+	for _, ident := range decl.Identifiers {
+		symbols = append(symbols, newDocumentSymbol(ident.Name.Lexeme, detail, kind, decl.Token, ident.Name, endToken(decl)))
+	}
+	return symbols
+}
+
+func methodSymbol(decl *ast.MethodDecl) DocumentSymbol {
+	return newDocumentSymbol(decl.Name.Lexeme, "", MethodSymbol, decl.Name, decl.Name, endToken(decl))
+}
+
+func (ds *DocumentSymbol) addFields(decl *ast.VariableDecl) {
+	for _, ident := range decl.Identifiers {
+		ds.addChild(newDocumentSymbol(ident.Name.Lexeme, "", FieldSymbol, ident.Name, ident.Name, endToken(ident)))
+	}
+}
+
+func (ds *DocumentSymbol) addChild(child DocumentSymbol) {
+	ds.Children = append(ds.Children, child)
+	// the range of a symbol has to contain the ranges of its children
+	if comparePositions(child.Range.End, ds.Range.End) > 0 {
+		ds.Range.End = child.Range.End
+	}
+}
+
+func newDocumentSymbol(name, detail string, kind SymbolKind, start, nameToken, end tokens.Token) DocumentSymbol {
+	selection := tokenRange(nameToken, nameToken)
+	full := tokenRange(start, end)
+	if comparePositions(selection.Start, full.Start) < 0 {
+		full.Start = selection.Start
+	}
+	if comparePositions(selection.End, full.End) > 0 {
+		full.End = selection.End
+	}
+	return DocumentSymbol{
+		Name:           name,
+		Detail:         detail,
+		Kind:           kind,
+		Range:          full,
+		SelectionRange: selection,
+		Children:       make([]DocumentSymbol, 0),
+	}
+}
+
+func endToken(node ast.Node) tokens.Token {
+	return declarationWalker.GetNodeEndToken(node)
+}
+
+func visibility(isPub bool) string {
+	if isPub {
+		return "pub"
+	}
+	return "local"
+}
+
+func tokenRange(start, end tokens.Token) Range {
+	return Range{
+		Start: Position{Line: max(start.Line-1, 0), Character: max(start.Column.Start-1, 0)},
+		End:   Position{Line: max(end.Line-1, 0), Character: max(end.Column.End-1, 0)},
+	}
+}
+
+func comparePositions(a, b Position) int {
+	if a.Line != b.Line {
+		return a.Line - b.Line
+	}
+	return a.Character - b.Character
+}
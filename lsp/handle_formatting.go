@@ -0,0 +1,179 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"hybroid/formatter"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// Above this many changed lines the changed region is replaced as a whole instead of being diffed
+const maxDiffLines = 2000
+
+func (h *langHandler) handleTextDocumentFormatting(_ context.Context, _ notifier, req *jsonrpc2.Request) (result any, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params DocumentFormattingParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.formattingEdits(params.TextDocument.URI, nil), nil
+}
+
+func (h *langHandler) handleTextDocumentRangeFormatting(_ context.Context, _ notifier, req *jsonrpc2.Request) (result any, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params DocumentRangeFormattingParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.formattingEdits(params.TextDocument.URI, &params.Range), nil
+}
+
+// Returns the edits turning the buffer into its formatted layout, only the ones touching the lines of the range when
+// one is given. A buffer that does not parse gets no edits, its diagnostics already tell why
+func (h *langHandler) formattingEdits(uri DocumentURI, within *Range) []TextEdit {
+	h.mu.Lock()
+	file, fileOk := h.files[uri]
+	h.mu.Unlock()
+
+	if !fileOk {
+		return nil
+	}
+
+	formatted, err := formatter.Format(file.Text)
+	if err != nil {
+		return []TextEdit{}
+	}
+
+	edits := make([]TextEdit, 0)
+	for _, edit := range lineEdits(file.Text, formatted) {
+		if within != nil {
+			// an edit ending at the start of a line does not touch that line
+			lastLine := edit.Range.End.Line
+			if edit.Range.End.Character == 0 && lastLine > edit.Range.Start.Line {
+				lastLine--
+			}
+			if lastLine < within.Start.Line || edit.Range.Start.Line > within.End.Line {
+				continue
+			}
+		}
+		edits = append(edits, edit)
+	}
+	return edits
+}
+
+// Returns the edits replacing the lines of the old text that differ from the new one. The lines both texts share are
+// left alone, so that the cursor and the folds of the editor stay where they were
+func lineEdits(oldText, newText string) []TextEdit {
+	oldLines, newLines := splitLines(oldText), splitLines(newText)
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	oldMiddle := oldLines[prefix : len(oldLines)-suffix]
+	newMiddle := newLines[prefix : len(newLines)-suffix]
+
+	position := func(line int) Position {
+		if line < len(oldLines) || len(oldLines) == 0 {
+			return Position{Line: line, Character: 0}
+		}
+		// past the last line, which has no line break after it
+		last := oldLines[len(oldLines)-1]
+		if strings.HasSuffix(last, "\n") {
+			return Position{Line: len(oldLines), Character: 0}
+		}
+		return Position{Line: len(oldLines) - 1, Character: len(utf16.Encode([]rune(last)))}
+	}
+
+	edits := make([]TextEdit, 0)
+	for _, hunk := range diffLines(oldMiddle, newMiddle) {
+		edits = append(edits, TextEdit{
+			Range: Range{
+				Start: position(prefix + hunk.oldStart),
+				End:   position(prefix + hunk.oldEnd),
+			},
+			NewText: strings.Join(newMiddle[hunk.newStart:hunk.newEnd], ""),
+		})
+	}
+	return edits
+}
+
+// Splits a text into its lines, each one keeping its line break
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// A range of old lines replaced by a range of new lines
+type hunk struct {
+	oldStart, oldEnd int
+	newStart, newEnd int
+}
+
+// Returns the hunks turning the old lines into the new ones, from their longest common subsequence
+func diffLines(oldLines, newLines []string) []hunk {
+	if len(oldLines) == 0 && len(newLines) == 0 {
+		return nil
+	}
+	if len(oldLines) == 0 || len(newLines) == 0 || len(oldLines)*len(newLines) > maxDiffLines*maxDiffLines {
+		return []hunk{{0, len(oldLines), 0, len(newLines)}}
+	}
+
+	// common[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+	common := make([][]int, len(oldLines)+1)
+	for i := range common {
+		common[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	hunks := make([]hunk, 0)
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		if i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j] {
+			i++
+			j++
+			continue
+		}
+		current := hunk{i, i, j, j}
+		for i < len(oldLines) || j < len(newLines) {
+			if i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j] {
+				break
+			}
+			if j == len(newLines) || i < len(oldLines) && common[i+1][j] >= common[i][j+1] {
+				i++
+			} else {
+				j++
+			}
+		}
+		current.oldEnd, current.newEnd = i, j
+		hunks = append(hunks, current)
+	}
+	return hunks
+}
@@ -120,6 +120,19 @@ func (h *langHandler) handleTextDocumentHover(ctx context.Context, _ notifier, r
 		return res, nil
 	}
 
+	// 2.5. Check for members of enums, entities and classes (e.g. State.Waiting)
+	if access := getMemberAccessAt(fileText, params.Position.Line, params.Position.Character); access != word {
+		if detail, doc := getSymbolMetadata(w, eval.Walkers(), access); detail != "" {
+			return Hover{
+				Contents: MarkupContent{
+					Kind:  Markdown,
+					Value: withDoc(fmt.Sprintf("**%s**: `%s`", access, detail), doc),
+				},
+			}, nil
+		}
+		word = access
+	}
+
 	// 3. Check for variables or members in current scope
 	line := params.Position.Line + 1
 	col := params.Position.Character + 1
@@ -141,7 +154,7 @@ func (h *langHandler) handleTextDocumentHover(ctx context.Context, _ notifier, r
 									return &Hover{
 										Contents: MarkupContent{
 											Kind:  Markdown,
-											Value: fmt.Sprintf("**%s**: `%s`", word, currentVal.GetType().String()),
+											Value: withDoc(fmt.Sprintf("**%s**: `%s`", word, currentVal.GetType().String()), v.Doc),
 										},
 									}, nil
 								}
@@ -155,7 +168,7 @@ func (h *langHandler) handleTextDocumentHover(ctx context.Context, _ notifier, r
 									return &Hover{
 										Contents: MarkupContent{
 											Kind:  Markdown,
-											Value: fmt.Sprintf("**%s**: `%s` (method)", word, currentVal.GetType().String()),
+											Value: withDoc(fmt.Sprintf("**%s**: `%s` (method)", word, currentVal.GetType().String()), v.Doc),
 										},
 									}, nil
 								}
@@ -173,7 +186,7 @@ func (h *langHandler) handleTextDocumentHover(ctx context.Context, _ notifier, r
 			res := Hover{
 				Contents: MarkupContent{
 					Kind:  Markdown,
-					Value: fmt.Sprintf("**%s**: `%s`", word, typStr),
+					Value: withDoc(fmt.Sprintf("**%s**: `%s`", word, typStr), variable.Doc),
 				},
 			}
 			return res, nil
@@ -46,18 +46,10 @@ func (h *langHandler) handleInitialize(_ context.Context, conn notifier, req *js
 
 	var completion *CompletionProvider
 	// var hasCompletionCommand bool
-	var hasCodeActionCommand bool
-	var hasSymbolCommand bool
-	var hasFormatCommand bool
-	var hasRangeFormatCommand bool
 
-	if params.InitializationOptions != nil {
-		//hasCompletionCommand = params.InitializationOptions.Completion
-		hasCodeActionCommand = params.InitializationOptions.CodeAction
-		hasSymbolCommand = params.InitializationOptions.DocumentSymbol
-		hasFormatCommand = params.InitializationOptions.DocumentFormatting
-		hasRangeFormatCommand = params.InitializationOptions.RangeFormatting
-	}
+	// if params.InitializationOptions != nil {
+	//	hasCompletionCommand = params.InitializationOptions.Completion
+	// }
 
 	completion = &CompletionProvider{
 		ResolveProvider:   true,
@@ -65,19 +57,26 @@ func (h *langHandler) handleInitialize(_ context.Context, conn notifier, req *js
 	}
 	return InitializeResult{
 		Capabilities: ServerCapabilities{
-			TextDocumentSync:           TDSKFull,
-			DocumentFormattingProvider: hasFormatCommand,
-			RangeFormattingProvider:    hasRangeFormatCommand,
-			DocumentSymbolProvider:     hasSymbolCommand,
+			TextDocumentSync:           TDSKIncremental,
+			DocumentFormattingProvider: true,
+			RangeFormattingProvider:    true,
+			DocumentSymbolProvider:     true,
+			WorkspaceSymbolProvider:    true,
 			DefinitionProvider:         true,
 			ReferencesProvider:         true,
-			RenameProvider:             true,
+			RenameProvider:             &RenameOptions{PrepareProvider: true},
 			CompletionProvider:         completion,
 			SignatureHelpProvider: &SignatureHelpProvider{
 				TriggerCharacters: []string{"(", ","},
 			},
 			HoverProvider:      true,
-			CodeActionProvider: hasCodeActionCommand,
+			CodeActionProvider: true,
+			SemanticTokensProvider: &SemanticTokensOptions{
+				Legend: SemanticTokensLegend{TokenTypes: semanticTokenTypes, TokenModifiers: semanticTokenModifiers},
+				Range:  true,
+				Full:   true,
+			},
+			InlayHintProvider: true,
 			Workspace: &ServerCapabilitiesWorkspace{
 				WorkspaceFolders: WorkspaceFoldersServerCapabilities{
 					Supported:           true,
@@ -0,0 +1,213 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"hybroid/ast"
+	"hybroid/tokens"
+	"hybroid/walker"
+	"slices"
+	"strings"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *langHandler) handleTextDocumentInlayHint(ctx context.Context, _ notifier, req *jsonrpc2.Request) (result any, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params InlayHintParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if !h.waitReady(ctx) {
+		return nil, nil
+	}
+
+	h.mu.Lock()
+	eval := h.eval
+	file, fileOk := h.files[params.TextDocument.URI]
+	h.mu.Unlock()
+
+	if eval == nil || !fileOk {
+		return nil, nil
+	}
+
+	path, err := fromURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
+	relPath := getRelPath(h.rootPath, path)
+	h.evalMu.Lock()
+	defer h.evalMu.Unlock()
+	w := eval.AnalyzeFile(relPath)
+	if w == nil {
+		return nil, nil
+	}
+
+	return inlayHints(w, eval.Walkers(), file.Text, params.Range), nil
+}
+
+// Returns the hints of the range: the types the walker inferred for the names declared without
+// one, and the names of the parameters the arguments of calls are given to
+func inlayHints(w *walker.Walker, walkers map[string]*walker.Walker, text string, within Range) []InlayHint {
+	lexed, classified := classifyTokens(w, walkers, text)
+	lines := strings.Split(text, "\n")
+
+	hints := make([]InlayHint, 0)
+	for _, token := range classified {
+		if token.modifiers&modifierDeclaration != 0 {
+			if !inferredDeclaration(lexed, token.index) {
+				continue
+			}
+			value, ok := token.target.(walker.Value)
+			if !ok || value == nil {
+				continue
+			}
+			typ := value.GetType()
+			if typ == nil || typ.PVT() == ast.Invalid || typ.GetType() == walker.NA {
+				continue
+			}
+			hints = append(hints, InlayHint{
+				Position: tokenPosition(lines, token.token.Line, token.token.Column.End),
+				Label:    ": " + typ.String(),
+				Kind:     TypeHint,
+			})
+		} else if fn, open := calledFunction(lexed, token); fn != nil {
+			hints = append(hints, parameterHints(lexed, open, fn, lines)...)
+		}
+	}
+
+	return slices.DeleteFunc(hints, func(hint InlayHint) bool {
+		return positionBefore(hint.Position, within.Start) || positionBefore(within.End, hint.Position)
+	})
+}
+
+// Whether the name at the index is declared without a type, by `let`, `const` or `pub`, a for
+// loop, `tick with` or `if let`. The names before it in the same declaration are skipped
+func inferredDeclaration(lexed []tokens.Token, i int) bool {
+	for i >= 2 && lexed[i-1].Type == tokens.Comma && lexed[i-2].Type == tokens.Identifier {
+		i -= 2
+	}
+	if i < 1 {
+		return false
+	}
+	switch lexed[i-1].Type {
+	case tokens.Let, tokens.Const, tokens.Pub, tokens.For, tokens.With:
+		return true
+	}
+	return false
+}
+
+// Returns the function a classified identifier calls and the index of the '(' of the call. Along
+// with functions and methods, `new` calls the constructor of a class and `spawn` the spawner of an entity
+func calledFunction(lexed []tokens.Token, token semanticToken) (*walker.FunctionVal, int) {
+	var fn *walker.FunctionVal
+	switch target := token.target.(type) {
+	case *walker.FunctionVal:
+		fn = target
+	case *walker.ClassVal:
+		if token.index > 0 && lexed[token.index-1].Type == tokens.New {
+			fn = target.New
+		}
+	case *walker.EntityVal:
+		if token.index > 0 && lexed[token.index-1].Type == tokens.Spawn {
+			fn = target.Spawn
+		}
+	}
+	if fn == nil {
+		return nil, 0
+	}
+
+	// the generic arguments between the name and the arguments
+	open := token.index + 1
+	if open < len(lexed) && lexed[open].Type == tokens.Less {
+		depth := 0
+		for ; open < len(lexed); open++ {
+			if lexed[open].Type == tokens.Less {
+				depth++
+			} else if lexed[open].Type == tokens.Greater {
+				depth--
+			}
+			if depth == 0 {
+				break
+			}
+		}
+		open++
+	}
+	if open >= len(lexed) || lexed[open].Type != tokens.LeftParen {
+		return nil, 0
+	}
+	return fn, open
+}
+
+// Returns the hints naming the parameters before the arguments of the call whose '(' is at the
+// index. A variadic parameter is only named before its first argument, and an argument that is
+// just the name of its parameter has no hint
+func parameterHints(lexed []tokens.Token, open int, fn *walker.FunctionVal, lines []string) []InlayHint {
+	hints := make([]InlayHint, 0)
+	arg, depth := 0, 0
+	argStart := true
+
+	for i := open + 1; i < len(lexed); i++ {
+		token := lexed[i]
+		switch token.Type {
+		case tokens.Eof:
+			return hints
+		case tokens.RightParen, tokens.RightBracket, tokens.RightBrace:
+			if depth == 0 {
+				return hints
+			}
+			depth--
+			continue
+		case tokens.Comma:
+			if depth == 0 {
+				arg++
+				argStart = true
+				continue
+			}
+		}
+
+		if argStart {
+			argStart = false
+			if name := parameterName(fn, arg); name != "" && !(token.Type == tokens.Identifier && token.Lexeme == name && argumentEnds(lexed, i+1)) {
+				hints = append(hints, InlayHint{
+					Position:     tokenPosition(lines, token.Line, token.Column.Start),
+					Label:        name + ":",
+					Kind:         ParameterHint,
+					PaddingRight: true,
+				})
+			}
+		}
+		switch token.Type {
+		case tokens.LeftParen, tokens.LeftBracket, tokens.LeftBrace:
+			depth++
+		}
+	}
+	return hints
+}
+
+func parameterName(fn *walker.FunctionVal, arg int) string {
+	if arg >= len(fn.Params) || arg >= len(fn.ParamNames) {
+		return ""
+	}
+	if name := fn.ParamNames[arg]; name != "_" {
+		return name
+	}
+	return ""
+}
+
+func argumentEnds(lexed []tokens.Token, i int) bool {
+	return i < len(lexed) && (lexed[i].Type == tokens.Comma || lexed[i].Type == tokens.RightParen)
+}
+
+// Returns the LSP position of a lexer line and byte column, which both start at 1
+func tokenPosition(lines []string, line, column int) Position {
+	line--
+	if line < 0 || line >= len(lines) {
+		return Position{Line: max(line, 0)}
+	}
+	return Position{Line: line, Character: utf16Column(lines[line], column-1)}
+}
@@ -3,18 +3,23 @@ package lsp
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"hybroid/lexer"
+	"hybroid/tokens"
+	"hybroid/walker"
+	"os"
 	"path/filepath"
-	"strconv"
+	"strings"
 
 	"github.com/sourcegraph/jsonrpc2"
 )
 
-func (h *langHandler) handleTextDocumentRename(ctx context.Context, _ *jsonrpc2.Conn, req *jsonrpc2.Request) (result any, err error) {
+func (h *langHandler) handleTextDocumentPrepareRename(ctx context.Context, _ notifier, req *jsonrpc2.Request) (result any, err error) {
 	if req.Params == nil {
 		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
 	}
 
-	var params RenameParams
+	var params TextDocumentPositionParams
 	if err := json.Unmarshal(*req.Params, &params); err != nil {
 		return nil, err
 	}
@@ -32,59 +37,218 @@ func (h *langHandler) handleTextDocumentRename(ctx context.Context, _ *jsonrpc2.
 		return nil, nil
 	}
 
-	if isInCommentOrString(file.Text, params.Position.Line, params.Position.Character) {
-		return nil, nil
-	}
-
 	path, err := fromURI(params.TextDocument.URI)
 	if err != nil {
 		return nil, nil
 	}
 	relPath := getRelPath(h.rootPath, path)
 	h.evalMu.Lock()
+	defer h.evalMu.Unlock()
 	w := eval.AnalyzeFile(relPath)
 	if w == nil {
-		h.evalMu.Unlock()
 		return nil, nil
 	}
-	defer h.evalMu.Unlock()
 
-	word := getWordAt(file.Text, params.Position.Line, params.Position.Character)
-	if word == "" {
-		return nil, nil
+	target, err := renameTarget(w, eval.Walkers(), file.Text, params.Position)
+	if target == nil || err != nil {
+		return nil, err
 	}
+	return nameRange(strings.Split(file.Text, "\n"), target.token), nil
+}
 
-	newName := params.NewName
-	if newName == "" || newName == word {
+func (h *langHandler) handleTextDocumentRename(ctx context.Context, _ notifier, req *jsonrpc2.Request) (result any, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params RenameParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if !h.waitReady(ctx) {
 		return nil, nil
 	}
 
+	// the renamed names can be in every file of the project, the open ones are renamed in their buffers
+	h.mu.Lock()
+	eval := h.eval
+	file, fileOk := h.files[params.TextDocument.URI]
+	openTexts := make(map[DocumentURI]string, len(h.files))
+	for uri, open := range h.files {
+		openTexts[uri] = open.Text
+	}
 	rootDir := h.rootPath
+	h.mu.Unlock()
+
+	if eval == nil || !fileOk {
+		return nil, nil
+	}
+
+	path, err := fromURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil
+	}
 	if rootDir == "" {
 		rootDir = filepath.Dir(path)
 	}
-	locations := h.findReferences(w, eval.Walkers(), eval.WalkerList(), word, params.Position.Line+1, params.Position.Character+1, true, rootDir)
-	if len(locations) == 0 {
+	relPath := getRelPath(h.rootPath, path)
+	h.evalMu.Lock()
+	defer h.evalMu.Unlock()
+	w := eval.AnalyzeFile(relPath)
+	if w == nil {
 		return nil, nil
 	}
 
-	changes := make(map[DocumentURI][]TextEdit)
-	seenEdits := make(map[string]bool)
+	walkers := eval.Walkers()
+	target, err := renameTarget(w, walkers, file.Text, params.Position)
+	if target == nil || err != nil {
+		return nil, err
+	}
+	newName := params.NewName
+	if newName == target.token.Lexeme {
+		return nil, nil
+	}
+	if !isIdentifier(newName) {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams, Message: fmt.Sprintf("'%s' is not a valid name", newName)}
+	}
 
-	for _, loc := range locations {
-		editKey := string(loc.URI) + ":" + strconv.Itoa(loc.Range.Start.Line) + ":" + strconv.Itoa(loc.Range.Start.Character)
-		if seenEdits[editKey] {
+	renamed := renamedSymbols(target.symbol, eval.WalkerList())
+	member := target.typ == semanticProperty || target.typ == semanticMethod
+	changes := make(map[DocumentURI][]TextEdit)
+	seen := make(map[DocumentURI]bool)
+	for _, wk := range eval.WalkerList() {
+		wkPath := wk.Env().HybroidPath()
+		if !filepath.IsAbs(wkPath) {
+			wkPath = filepath.Join(rootDir, wkPath)
+		}
+		uri := toURI(wkPath)
+		if seen[uri] {
 			continue
 		}
-		seenEdits[editKey] = true
+		seen[uri] = true
+
+		text, ok := openTexts[uri]
+		if !ok {
+			contents, err := os.ReadFile(wkPath)
+			if err != nil {
+				continue
+			}
+			text = string(contents)
+		}
 
-		changes[loc.URI] = append(changes[loc.URI], TextEdit{
-			Range:   loc.Range,
-			NewText: newName,
-		})
+		lines := strings.Split(text, "\n")
+		_, classified := classifyTokens(wk, walkers, text)
+		for _, token := range classified {
+			if member && token.unresolved && token.token.Lexeme == target.token.Lexeme {
+				message := fmt.Sprintf("'%s' is accessed in %s from a value whose type is unknown, so it cannot be renamed everywhere", token.token.Lexeme, filepath.Base(wkPath))
+				return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidRequest, Message: message}
+			}
+			if renamed[token.symbol] {
+				changes[uri] = append(changes[uri], TextEdit{Range: nameRange(lines, token.token), NewText: newName})
+			}
+		}
 	}
 
 	return WorkspaceEdit{
 		Changes: changes,
 	}, nil
 }
+
+// Returns the name at the position with the declaration it resolved to, nil when there is no
+// name there or it did not resolve. The names of the builtins and libraries are not renamed
+func renameTarget(w *walker.Walker, walkers map[string]*walker.Walker, text string, position Position) (*semanticToken, error) {
+	lines := strings.Split(text, "\n")
+	_, classified := classifyTokens(w, walkers, text)
+	for _, token := range classified {
+		r := nameRange(lines, token.token)
+		if comparePositions(r.Start, position) > 0 || comparePositions(position, r.End) > 0 {
+			continue
+		}
+		if token.symbol == (symbol{}) {
+			return nil, nil
+		}
+		if token.symbol.builtin() {
+			message := fmt.Sprintf("'%s' is part of the %s API and cannot be renamed", token.token.Lexeme, token.symbol.env)
+			if token.symbol.env == walker.BuiltinEnv.Name {
+				message = fmt.Sprintf("'%s' is a builtin and cannot be renamed", token.token.Lexeme)
+			}
+			return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidRequest, Message: message}
+		}
+		return &token, nil
+	}
+	return nil, nil
+}
+
+// A class or an entity, for the interfaces it implements
+type implementor struct {
+	typ     *walker.NamedType
+	methods map[string]*walker.VariableVal
+}
+
+// Returns the symbols renamed along with the target. A method of an interface is renamed
+// with the methods implementing it, which are renamed with the methods of the interface
+func renamedSymbols(target symbol, walkerList []*walker.Walker) map[symbol]bool {
+	renamed := map[symbol]bool{target: true}
+
+	interfaces := make(map[string]*walker.InterfaceVal)
+	implementors := make([]implementor, 0)
+	for _, wk := range walkerList {
+		env := wk.Env()
+		for _, iface := range env.Interfaces {
+			interfaces[walker.RefKey(env.Name, iface.Type.Name)] = iface
+		}
+		for _, class := range env.Classes {
+			implementors = append(implementors, implementor{typ: &class.Type, methods: class.Methods})
+		}
+		for _, entity := range env.Entities {
+			implementors = append(implementors, implementor{typ: &entity.Type, methods: entity.Methods})
+		}
+	}
+
+	// the interfaces the target is a method of, or whose method it implements
+	related := make([]*walker.InterfaceVal, 0)
+	for _, iface := range interfaces {
+		if method, ok := iface.Methods[target.name]; ok && iface.Type.EnvName == target.env && method.Token.Location == target.location {
+			related = append(related, iface)
+		}
+	}
+	for _, impl := range implementors {
+		method, ok := impl.methods[target.name]
+		if !ok || impl.typ.EnvName != target.env || method.Token.Location != target.location {
+			continue
+		}
+		for _, implemented := range impl.typ.Impls {
+			if iface, ok := interfaces[walker.RefKey(implemented.EnvName, implemented.Name)]; ok && iface.Methods[target.name] != nil {
+				related = append(related, iface)
+			}
+		}
+	}
+
+	for _, iface := range related {
+		method := iface.Methods[target.name]
+		renamed[symbol{env: iface.Type.EnvName, name: method.Name, location: method.Token.Location}] = true
+		for _, impl := range implementors {
+			if method, ok := impl.methods[target.name]; ok && impl.typ.Implements(&iface.Type) {
+				renamed[symbol{env: impl.typ.EnvName, name: method.Name, location: method.Token.Location}] = true
+			}
+		}
+	}
+	return renamed
+}
+
+// Whether the name is lexed as a single identifier, so that it is not a keyword
+func isIdentifier(name string) bool {
+	lex := lexer.NewLexer(strings.NewReader(name))
+	lexed, err := lex.Tokenize()
+	return err == nil && len(lex.GetAlerts()) == 0 && len(lexed) == 2 &&
+		lexed[0].Type == tokens.Identifier && lexed[0].Lexeme == name
+}
+
+// Returns the range of a name, in the UTF-16 columns of the LSP
+func nameRange(lines []string, token tokens.Token) Range {
+	return Range{
+		Start: tokenPosition(lines, token.Line, token.Column.Start),
+		End:   tokenPosition(lines, token.Line, token.Column.End),
+	}
+}
@@ -0,0 +1,552 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"hybroid/lexer"
+	"hybroid/tokens"
+	"hybroid/walker"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+// The token types and modifiers of the legend, the index of a type and the bit of a modifier
+// are what the encoded tokens refer to. "entity" is not one of the types the LSP predefines,
+// so editors that do not know it can map it to class
+var semanticTokenTypes = []string{
+	"namespace", "type", "class", "entity", "enum", "interface",
+	"enumMember", "function", "method", "property", "variable", "typeParameter",
+}
+
+var semanticTokenModifiers = []string{
+	"declaration", "readonly", "public", "defaultLibrary", "deprecated",
+}
+
+type semanticType int
+
+const (
+	semanticNamespace semanticType = iota
+	semanticAlias
+	semanticClass
+	semanticEntity
+	semanticEnum
+	semanticInterface
+	semanticEnumMember
+	semanticFunction
+	semanticMethod
+	semanticProperty
+	semanticVariable
+	semanticTypeParameter
+)
+
+const (
+	modifierDeclaration uint32 = 1 << iota
+	modifierReadonly
+	modifierPublic
+	modifierDefaultLibrary
+	modifierDeprecated
+)
+
+// An identifier classified from what the walker resolved it to
+type semanticToken struct {
+	token     tokens.Token
+	typ       semanticType
+	modifiers uint32
+	// what the identifier resolved to, for the member accesses after it
+	target any
+	// the index of the identifier in the lexed tokens
+	index int
+	// the declaration it resolved to, for renaming
+	symbol symbol
+	// set for a member whose owner is unknown, which could be any declaration with its name
+	unresolved bool
+}
+
+// The declaration a name resolves to, the same for every name that is renamed along with it: the
+// environment it is in, its name and where it is declared. A generic parameter is declared where the
+// scope of its function or type starts
+type symbol struct {
+	env      string
+	name     string
+	location tokens.Location
+}
+
+// Whether the symbol comes from the builtins or a library, which are not part of the project
+func (s symbol) builtin() bool {
+	return s.env == walker.BuiltinEnv.Name || resolveBuiltinEnvByName(s.env) != nil
+}
+
+func (h *langHandler) handleTextDocumentSemanticTokens(ctx context.Context, _ notifier, req *jsonrpc2.Request) (result any, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params SemanticTokensParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.semanticTokens(ctx, params.TextDocument.URI, nil)
+}
+
+func (h *langHandler) handleTextDocumentSemanticTokensRange(ctx context.Context, _ notifier, req *jsonrpc2.Request) (result any, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params SemanticTokensRangeParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.semanticTokens(ctx, params.TextDocument.URI, &params.Range)
+}
+
+// Returns the semantic tokens of the document, only the ones starting in the range if there is one
+func (h *langHandler) semanticTokens(ctx context.Context, uri DocumentURI, within *Range) (any, error) {
+	if !h.waitReady(ctx) {
+		return nil, nil
+	}
+
+	h.mu.Lock()
+	eval := h.eval
+	file, fileOk := h.files[uri]
+	h.mu.Unlock()
+
+	if eval == nil || !fileOk {
+		return nil, nil
+	}
+
+	path, err := fromURI(uri)
+	if err != nil {
+		return nil, nil
+	}
+	relPath := getRelPath(h.rootPath, path)
+	h.evalMu.Lock()
+	defer h.evalMu.Unlock()
+	w := eval.AnalyzeFile(relPath)
+	if w == nil {
+		return nil, nil
+	}
+
+	_, classified := classifyTokens(w, eval.Walkers(), file.Text)
+	return SemanticTokens{Data: encodeSemanticTokens(classified, file.Text, within)}, nil
+}
+
+// Classifies the identifiers of the text with the scopes, environments and libraries the walker resolved.
+// The lexed tokens are returned as well, without the comments, for what comes around the identifiers
+func classifyTokens(w *walker.Walker, walkers map[string]*walker.Walker, text string) ([]tokens.Token, []semanticToken) {
+	lex := lexer.NewLexer(strings.NewReader(text))
+	lexed, _ := lex.Tokenize()
+	lexed = slices.DeleteFunc(lexed, func(token tokens.Token) bool {
+		return token.Type == tokens.Comment || token.Type == tokens.DocComment
+	})
+
+	classified := make([]semanticToken, 0)
+	// the classified identifiers by their index in lexed, for the accesses after them
+	byIndex := make(map[int]semanticToken)
+	at := func(i int) tokens.TokenType {
+		if i < 0 || i >= len(lexed) {
+			return tokens.Eof
+		}
+		return lexed[i].Type
+	}
+
+	for i, token := range lexed {
+		if token.Type != tokens.Identifier {
+			continue
+		}
+		name := token.Lexeme
+
+		var result *semanticToken
+		switch {
+		case at(i-1) == tokens.Env || at(i-1) == tokens.Use:
+			result = classifyNamespace(token, walkers)
+		case at(i+1) == tokens.Colon && namespaceEnv(name, walkers) != nil:
+			result = classifyNamespace(token, walkers)
+		case at(i-1) == tokens.Colon && at(i-2) == tokens.Identifier && namespaceEnv(lexed[i-2].Lexeme, walkers) != nil:
+			ns := lexed[i-2].Lexeme
+			result = classifyInEnv(token, namespaceEnv(ns, walkers), ns)
+		case at(i-1) == tokens.Dot:
+			// the walker knows what every access is made on, including indexes, calls and interfaces
+			var owner any
+			if accessed, ok := w.MemberOwners[token.Location]; ok {
+				owner = accessed
+			} else if at(i-2) == tokens.Self {
+				owner = enclosingType(w.GetScopeAt(token.Line, token.Column.Start))
+			} else if previous, ok := byIndex[i-2]; ok {
+				owner = previous.target
+			}
+			result = classifyMember(token, owner, at(i+1) == tokens.LeftParen)
+			result.unresolved = owner == nil
+		default:
+			result = classifyIdentifier(w, walkers, token)
+		}
+
+		if result != nil {
+			result.index = i
+			classified = append(classified, *result)
+			byIndex[i] = *result
+		}
+	}
+
+	return lexed, classified
+}
+
+// Returns the environment a namespace names, either a library or another environment
+func namespaceEnv(name string, walkers map[string]*walker.Walker) *walker.Environment {
+	if env := resolveBuiltinEnvByName(name); env != nil {
+		return env
+	}
+	if other, ok := walkers[name]; ok && other.Env().Name == name {
+		return other.Env()
+	}
+	return nil
+}
+
+func classifyNamespace(token tokens.Token, walkers map[string]*walker.Walker) *semanticToken {
+	if env := resolveBuiltinEnvByName(token.Lexeme); env != nil {
+		return &semanticToken{token: token, typ: semanticNamespace, modifiers: modifierDefaultLibrary, target: env, symbol: symbol{env: env.Name, name: env.Name}}
+	}
+	result := &semanticToken{token: token, typ: semanticNamespace}
+	if other, ok := walkers[token.Lexeme]; ok {
+		declaration := other.Env().GetEnvToken()
+		result.target = other.Env()
+		result.symbol = symbol{env: other.Env().Name, name: other.Env().Name, location: declaration.Location}
+		if declaration.Location == token.Location {
+			result.modifiers |= modifierDeclaration
+		}
+	}
+	return result
+}
+
+// Classifies an identifier that is not accessed from something else, from the innermost scope outwards
+func classifyIdentifier(w *walker.Walker, walkers map[string]*walker.Walker, token tokens.Token) *semanticToken {
+	name := token.Lexeme
+	env := w.Env()
+
+	for scope := w.GetScopeAt(token.Line, token.Column.Start); scope != nil; scope = scope.Parent {
+		if variable, ok := scope.Variables[name]; ok {
+			result := classifyVariable(token, variable, scope)
+			if scope.Environment != nil && scope.Environment.Name == "Builtin" {
+				result.modifiers |= modifierDefaultLibrary | modifierReadonly
+			}
+			return result
+		}
+		if alias, ok := scope.AliasTypes[name]; ok {
+			return &semanticToken{token: token, typ: semanticAlias, symbol: symbol{env: scope.Environment.Name, name: name, location: alias.Token.Location}}
+		}
+		if genericParamOf(scope, name) {
+			return &semanticToken{token: token, typ: semanticTypeParameter, symbol: symbol{env: env.Name, name: name, location: scopeStart(w, scope)}}
+		}
+	}
+	if variable, ok := walker.BuiltinEnv.Scope.Variables[name]; ok {
+		result := classifyVariable(token, variable, &walker.BuiltinEnv.Scope)
+		result.modifiers |= modifierDefaultLibrary | modifierReadonly
+		return result
+	}
+	if alias, ok := walker.BuiltinEnv.Scope.AliasTypes[name]; ok {
+		return &semanticToken{token: token, typ: semanticAlias, modifiers: modifierDefaultLibrary, symbol: symbol{env: walker.BuiltinEnv.Name, name: name, location: alias.Token.Location}}
+	}
+	if result := classifyType(token, env, false); result != nil {
+		return result
+	}
+
+	for _, imp := range env.Imports() {
+		if !imp.ThroughUse {
+			continue
+		}
+		if result := classifyInEnv(token, imp.Env(), imp.Env().Name); result != nil {
+			return result
+		}
+	}
+	for _, lib := range env.ImportedLibraries {
+		if libEnv := resolveBuiltinEnv(lib); libEnv != nil {
+			if result := classifyInEnv(token, libEnv, libEnv.Name); result != nil {
+				return result
+			}
+		}
+	}
+
+	// the variants in the declaration of an enum, which are only in the scope of the enum
+	for _, enum := range env.Enums {
+		if field, ok := enum.Fields[name]; ok && field.Token.Location == token.Location {
+			return &semanticToken{
+				token:     token,
+				typ:       semanticEnumMember,
+				modifiers: modifierDeclaration | modifierReadonly,
+				symbol:    symbol{env: env.Name, name: name, location: field.Token.Location},
+			}
+		}
+	}
+	// the methods in the declaration of an interface, which has no scope
+	for _, iface := range env.Interfaces {
+		if method, ok := iface.Methods[name]; ok && method.Token.Location == token.Location {
+			result := memberOf(token, method, semanticMethod, env.Name)
+			result.modifiers |= modifierDeclaration
+			return result
+		}
+	}
+	// environments used as values
+	if namespaceEnv(name, walkers) != nil {
+		return classifyNamespace(token, walkers)
+	}
+	return nil
+}
+
+// Whether the function, class or entity the scope belongs to has the generic parameter
+func genericParamOf(scope *walker.Scope, name string) bool {
+	switch tag := scope.Tag.(type) {
+	case *walker.FuncTag:
+		return slices.ContainsFunc(tag.Generics, func(generic *walker.GenericType) bool { return generic.Name == name })
+	case *walker.ClassTag:
+		return slices.ContainsFunc(tag.Val.Type.Generics, func(generic walker.GenericWithType) bool { return generic.GenericName == name })
+	case *walker.EntityTag:
+		return tag.EntityVal != nil && slices.ContainsFunc(tag.EntityVal.Type.Generics, func(generic walker.GenericWithType) bool { return generic.GenericName == name })
+	}
+	return false
+}
+
+// Returns where the scope starts in the file of the walker
+func scopeStart(w *walker.Walker, scope *walker.Scope) tokens.Location {
+	for _, scopeRange := range w.ScopeMap {
+		if scopeRange.Scope == scope {
+			return tokens.NewLocation(scopeRange.StartLine, scopeRange.StartColumn, scopeRange.StartColumn)
+		}
+	}
+	return tokens.Location{}
+}
+
+// Classifies a symbol of another environment or of a library, which only has its public symbols
+func classifyInEnv(token tokens.Token, env *walker.Environment, ns string) *semanticToken {
+	if env == nil {
+		return nil
+	}
+	library := resolveBuiltinEnvByName(ns) == env
+
+	var result *semanticToken
+	if variable, ok := env.Scope.Variables[token.Lexeme]; ok && (library || variable.IsPub) {
+		result = classifyVariable(token, variable, &env.Scope)
+		if library && isDeprecated(ApiDocs[ns+":"+token.Lexeme]) {
+			result.modifiers |= modifierDeprecated
+		}
+	} else {
+		result = classifyType(token, env, !library)
+	}
+	if result != nil && library {
+		result.modifiers |= modifierDefaultLibrary | modifierReadonly
+	}
+	return result
+}
+
+// Classifies the name of a class, entity, enum or interface of the environment
+func classifyType(token tokens.Token, env *walker.Environment, publicOnly bool) *semanticToken {
+	name := token.Lexeme
+	var result *semanticToken
+	var declaration tokens.Token
+	var isPub bool
+	var doc string
+
+	if class, ok := env.Classes[name]; ok {
+		result = &semanticToken{token: token, typ: semanticClass, target: class}
+		declaration, isPub, doc = class.Token, class.IsPub, class.Doc
+	} else if entity, ok := env.Entities[name]; ok {
+		result = &semanticToken{token: token, typ: semanticEntity, target: entity}
+		declaration, isPub, doc = entity.Token, entity.IsPub, entity.Doc
+	} else if enum, ok := env.Enums[name]; ok {
+		result = &semanticToken{token: token, typ: semanticEnum, target: enum}
+		declaration, isPub, doc = enum.Token, enum.IsPub, enum.Doc
+	} else if iface, ok := env.Interfaces[name]; ok {
+		result = &semanticToken{token: token, typ: semanticInterface, target: iface}
+		declaration, isPub, doc = iface.Token, iface.IsPub, iface.Doc
+	} else {
+		return nil
+	}
+
+	if publicOnly && !isPub {
+		return nil
+	}
+	result.symbol = symbol{env: env.Name, name: name, location: declaration.Location}
+	if isPub {
+		result.modifiers |= modifierPublic
+	}
+	if declaration.Location == token.Location && declaration.Lexeme == token.Lexeme {
+		result.modifiers |= modifierDeclaration
+	}
+	if isDeprecated(doc) {
+		result.modifiers |= modifierDeprecated
+	}
+	return result
+}
+
+// Classifies a variable by its value and the scope it was declared in
+func classifyVariable(token tokens.Token, variable *walker.VariableVal, scope *walker.Scope) *semanticToken {
+	result := &semanticToken{token: token, typ: semanticVariable, target: variable.Value}
+	result.symbol = symbol{env: scope.Environment.Name, name: variable.Name, location: variable.Token.Location}
+
+	member := false
+	if scope.Tag != nil {
+		switch scope.Tag.(type) {
+		case *walker.ClassTag, *walker.EntityTag:
+			member = true
+		}
+	}
+	if fn, ok := variable.Value.(*walker.FunctionVal); ok {
+		result.typ = semanticFunction
+		if member || fn.ProcType == walker.Method {
+			result.typ = semanticMethod
+		}
+	} else if member {
+		result.typ = semanticProperty
+	}
+
+	if variable.IsConst {
+		result.modifiers |= modifierReadonly
+	}
+	if variable.IsPub {
+		result.modifiers |= modifierPublic
+	}
+	if variable.Token.Location == token.Location && variable.Token.Lexeme == token.Lexeme {
+		result.modifiers |= modifierDeclaration
+	}
+	if isDeprecated(variable.Doc) {
+		result.modifiers |= modifierDeprecated
+	}
+	return result
+}
+
+// Classifies the name after a '.', from what the name before it resolved to
+func classifyMember(token tokens.Token, owner any, called bool) *semanticToken {
+	name := token.Lexeme
+
+	switch owner := owner.(type) {
+	case *walker.VariableVal:
+		return classifyMember(token, owner.Value, called)
+	case *walker.EnumVal:
+		if field, ok := owner.Fields[name]; ok {
+			result := &semanticToken{
+				token:     token,
+				typ:       semanticEnumMember,
+				modifiers: modifierReadonly,
+				symbol:    symbol{env: owner.Type.EnvName, name: name, location: field.Token.Location},
+			}
+			if isDeprecated(field.Doc) {
+				result.modifiers |= modifierDeprecated
+			}
+			return result
+		}
+	case *walker.ClassVal:
+		if field, _, ok := owner.ContainsField(name); ok {
+			return memberOf(token, field, semanticProperty, owner.Type.EnvName)
+		}
+		if method, ok := owner.ContainsMethod(name); ok {
+			return memberOf(token, method, semanticMethod, owner.Type.EnvName)
+		}
+	case *walker.EntityVal:
+		if field, _, ok := owner.ContainsField(name); ok {
+			return memberOf(token, field, semanticProperty, owner.Type.EnvName)
+		}
+		if method, ok := owner.ContainsMethod(name); ok {
+			return memberOf(token, method, semanticMethod, owner.Type.EnvName)
+		}
+	case *walker.InterfaceVal:
+		if method, ok := owner.Methods[name]; ok {
+			return memberOf(token, method, semanticMethod, owner.Type.EnvName)
+		}
+	}
+
+	// the fields of structs, maps and the values the walker could not resolve
+	if called {
+		return &semanticToken{token: token, typ: semanticMethod}
+	}
+	return &semanticToken{token: token, typ: semanticProperty}
+}
+
+func memberOf(token tokens.Token, variable *walker.VariableVal, typ semanticType, env string) *semanticToken {
+	result := &semanticToken{token: token, typ: typ, target: variable.Value, symbol: symbol{env: env, name: variable.Name, location: variable.Token.Location}}
+	if variable.IsConst {
+		result.modifiers |= modifierReadonly
+	}
+	if isDeprecated(variable.Doc) {
+		result.modifiers |= modifierDeprecated
+	}
+	return result
+}
+
+// Returns the class or entity whose declaration the scope is in, for the accesses from self
+func enclosingType(scope *walker.Scope) any {
+	for ; scope != nil; scope = scope.Parent {
+		switch tag := scope.Tag.(type) {
+		case *walker.ClassTag:
+			return tag.Val
+		case *walker.EntityTag:
+			return tag.EntityVal
+		}
+	}
+	return nil
+}
+
+// Whether a doc comment marks its declaration as deprecated, with a line starting with
+// "@deprecated" or "Deprecated"
+func isDeprecated(doc string) bool {
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "@deprecated") || strings.HasPrefix(line, "Deprecated") {
+			return true
+		}
+	}
+	return false
+}
+
+// Encodes the tokens the way the LSP wants them: five numbers per token, its line and start
+// relative to the previous token, its length, its type and the bits of its modifiers. The
+// columns of the lexer count bytes, the ones of the LSP count UTF-16 code units
+func encodeSemanticTokens(classified []semanticToken, text string, within *Range) []uint32 {
+	lines := strings.Split(text, "\n")
+	data := make([]uint32, 0, len(classified)*5)
+	previousLine, previousStart := 0, 0
+
+	for _, token := range classified {
+		line := token.token.Line - 1
+		if line < 0 || line >= len(lines) {
+			continue
+		}
+		start := utf16Column(lines[line], token.token.Column.Start-1)
+		length := len(utf16.Encode([]rune(token.token.Lexeme)))
+
+		if within != nil {
+			position := Position{Line: line, Character: start}
+			if positionBefore(position, within.Start) || !positionBefore(position, within.End) {
+				continue
+			}
+		}
+
+		deltaStart := start
+		if line == previousLine {
+			deltaStart = start - previousStart
+		}
+		data = append(data, uint32(line-previousLine), uint32(deltaStart), uint32(length), uint32(token.typ), token.modifiers)
+		previousLine, previousStart = line, start
+	}
+
+	return data
+}
+
+// Returns how many UTF-16 code units the first bytes of the line take
+func utf16Column(line string, bytes int) int {
+	if bytes > len(line) {
+		bytes = len(line)
+	}
+	units := 0
+	for _, r := range line[:bytes] {
+		units += utf16.RuneLen(r)
+	}
+	return units
+}
+
+func positionBefore(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
@@ -56,6 +56,7 @@ func (h *langHandler) handleTextDocumentSignatureHelp(ctx context.Context, _ *js
 	}
 
 	var fnVal *walker.FunctionVal
+	var fnDoc string
 
 	line := params.Position.Line + 1
 	col := params.Position.Character + 1
@@ -66,6 +67,7 @@ func (h *langHandler) handleTextDocumentSignatureHelp(ctx context.Context, _ *js
 			if v, ok := current.Variables[funcName]; ok {
 				if f, ok := v.Value.(*walker.FunctionVal); ok {
 					fnVal = f
+					fnDoc = v.Doc
 					break
 				}
 			}
@@ -95,6 +97,7 @@ func (h *langHandler) handleTextDocumentSignatureHelp(ctx context.Context, _ *js
 		if env != nil {
 			if v, ok := env.Scope.Variables[lookupName]; ok {
 				fnVal, _ = v.Value.(*walker.FunctionVal)
+				fnDoc = v.Doc
 			}
 		} else {
 			// Check builtins
@@ -112,6 +115,7 @@ func (h *langHandler) handleTextDocumentSignatureHelp(ctx context.Context, _ *js
 						if v, ok := imp.Env().Scope.Variables[lookupName]; ok && v.IsPub {
 							if f, ok := v.Value.(*walker.FunctionVal); ok {
 								fnVal = f
+								fnDoc = v.Doc
 								break
 							}
 						}
@@ -125,6 +129,7 @@ func (h *langHandler) handleTextDocumentSignatureHelp(ctx context.Context, _ *js
 						if v, ok := libEnv.Scope.Variables[lookupName]; ok {
 							if f, ok := v.Value.(*walker.FunctionVal); ok {
 								fnVal = f
+								fnDoc = v.Doc
 								break
 							}
 						}
@@ -161,13 +166,16 @@ func (h *langHandler) handleTextDocumentSignatureHelp(ctx context.Context, _ *js
 		activeParam = len(fnVal.Params) - 1
 	}
 
+	signature := SignatureInformation{
+		Label:      signatureLabel,
+		Parameters: paramsInfo,
+	}
+	if fnDoc != "" {
+		signature.Documentation = &MarkupContent{Kind: Markdown, Value: fnDoc}
+	}
+
 	res := SignatureHelp{
-		Signatures: []SignatureInformation{
-			{
-				Label:      signatureLabel,
-				Parameters: paramsInfo,
-			},
-		},
+		Signatures:      []SignatureInformation{signature},
 		ActiveSignature: 0,
 		ActiveParameter: activeParam,
 	}
@@ -7,6 +7,7 @@ import (
 	"hybroid/evaluator"
 	"path/filepath"
 	"strings"
+	"unicode/utf16"
 
 	"github.com/sourcegraph/jsonrpc2"
 )
@@ -103,15 +104,13 @@ func (h *langHandler) handleTextDocumentDidChange(ctx context.Context, conn noti
 	h.mu.Lock()
 	file, ok := h.files[params.TextDocument.URI]
 	if ok {
-		// Since we use TDSKFull in initialize, we assume the last change
-		// contains the full text. Version is updated unconditionally —
-		// a didChange with an empty ContentChanges list (which the LSP
-		// allows) must still advance the version, otherwise downstream
-		// publishDiagnostics carries the old version and editors treat
-		// the diagnostic as stale.
-		if len(params.ContentChanges) > 0 {
-			file.Text = params.ContentChanges[len(params.ContentChanges)-1].Text
-		}
+		// Since we use TDSKIncremental in initialize, the changes are
+		// edits of ranges, applied one after the other. Version is updated
+		// unconditionally — a didChange with an empty ContentChanges list
+		// (which the LSP allows) must still advance the version, otherwise
+		// downstream publishDiagnostics carries the old version and editors
+		// treat the diagnostic as stale.
+		file.Text = applyContentChanges(file.Text, params.ContentChanges)
 		file.Version = params.TextDocument.Version
 		fileText = file.Text
 	}
@@ -124,6 +123,52 @@ func (h *langHandler) handleTextDocumentDidChange(ctx context.Context, conn noti
 	return nil, nil
 }
 
+// Applies the changes of a didChange to the text, in order. A change without a range
+// replaces the whole text, as clients may still send those
+func applyContentChanges(text string, changes []TextDocumentContentChangeEvent) string {
+	for _, change := range changes {
+		if change.Range == nil {
+			text = change.Text
+			continue
+		}
+		start := positionOffset(text, change.Range.Start)
+		end := positionOffset(text, change.Range.End)
+		if end < start {
+			start, end = end, start
+		}
+		text = text[:start] + change.Text + text[end:]
+	}
+	return text
+}
+
+// Returns the byte offset of a position in the text. Lines end at "\n", "\r\n" or "\r" and
+// characters count UTF-16 code units, as the LSP says. Positions past the end of their line,
+// or of the text, are clamped to it
+func positionOffset(text string, position Position) int {
+	offset := 0
+	for range position.Line {
+		next := strings.IndexAny(text[offset:], "\r\n")
+		if next < 0 {
+			return len(text)
+		}
+		offset += next
+		if strings.HasPrefix(text[offset:], "\r\n") {
+			offset += 2
+		} else {
+			offset++
+		}
+	}
+
+	units := 0
+	for i, r := range text[offset:] {
+		if r == '\r' || r == '\n' || units >= position.Character {
+			return offset + i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(text)
+}
+
 func (h *langHandler) analyzeAndPublish(ctx context.Context, conn notifier, uri DocumentURI, text string) {
 	path, err := fromURI(uri)
 	if err != nil {
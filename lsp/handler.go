@@ -111,11 +111,11 @@ func (h *langHandler) handle(ctx context.Context, conn *jsonrpc2.Conn, req *json
 	case "textDocument/didClose":
 		return h.handleTextDocumentDidClose(ctx, conn, req)
 	case "textDocument/formatting":
-		return // h.handleTextDocumentFormatting(ctx, conn, req)
+		return h.handleTextDocumentFormatting(ctx, conn, req)
 	case "textDocument/rangeFormatting":
-		return // h.handleTextDocumentRangeFormatting(ctx, conn, req)
+		return h.handleTextDocumentRangeFormatting(ctx, conn, req)
 	case "textDocument/documentSymbol":
-		return // h.handleTextDocumentSymbol(ctx, conn, req)
+		return h.handleTextDocumentSymbol(ctx, conn, req)
 	case "textDocument/completion":
 		return h.handleTextDocumentCompletion(ctx, conn, req)
 	case "textDocument/signatureHelp":
@@ -130,8 +130,18 @@ func (h *langHandler) handle(ctx context.Context, conn *jsonrpc2.Conn, req *json
 		return h.handleTextDocumentHover(ctx, conn, req)
 	case "textDocument/rename":
 		return h.handleTextDocumentRename(ctx, conn, req)
+	case "textDocument/prepareRename":
+		return h.handleTextDocumentPrepareRename(ctx, conn, req)
 	case "textDocument/codeAction":
-		return // h.handleTextDocumentCodeAction(ctx, conn, req)
+		return h.handleTextDocumentCodeAction(ctx, conn, req)
+	case "textDocument/semanticTokens/full":
+		return h.handleTextDocumentSemanticTokens(ctx, conn, req)
+	case "textDocument/semanticTokens/range":
+		return h.handleTextDocumentSemanticTokensRange(ctx, conn, req)
+	case "textDocument/inlayHint":
+		return h.handleTextDocumentInlayHint(ctx, conn, req)
+	case "workspace/symbol":
+		return h.handleWorkspaceSymbol(ctx, conn, req)
 	case "workspace/executeCommand":
 		return // h.handleWorkspaceExecuteCommand(ctx, conn, req)
 	case "workspace/didChangeConfiguration":
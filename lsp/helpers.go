@@ -115,6 +115,25 @@ func getWordAt(text string, line, character int) string {
 	return string(runes[start:end])
 }
 
+// getMemberAccessAt returns the word under the cursor together with the
+// member accesses before it, e.g. "State.Waiting" when hovering "Waiting".
+func getMemberAccessAt(text string, line, character int) string {
+	word := getWordAt(text, line, character)
+	if word == "" {
+		return ""
+	}
+	runes := []rune(strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")[line])
+	start := character
+	for start > 0 && (IsWordChar(runes[start-1]) || runes[start-1] == '.') {
+		start--
+	}
+	end := character
+	for end < len(runes) && IsWordChar(runes[end]) {
+		end++
+	}
+	return strings.TrimLeft(string(runes[start:end]), ".")
+}
+
 func toLSPLocation(path string, token tokens.Token) Location {
 	return Location{
 		URI: toURI(path),
@@ -0,0 +1,135 @@
+package lsp
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func rangeChange(startLine, startCharacter, endLine, endCharacter int, text string) TextDocumentContentChangeEvent {
+	return TextDocumentContentChangeEvent{
+		Range: &Range{
+			Start: Position{Line: startLine, Character: startCharacter},
+			End:   Position{Line: endLine, Character: endCharacter},
+		},
+		Text: text,
+	}
+}
+
+func TestApplyContentChanges(t *testing.T) {
+	cases := []struct {
+		name, text string
+		changes    []TextDocumentContentChangeEvent
+		expected   string
+	}{
+		{
+			"insertion",
+			"let x = 1\n",
+			[]TextDocumentContentChangeEvent{rangeChange(0, 8, 0, 9, "42")},
+			"let x = 42\n",
+		},
+		{
+			// every change applies to the text the ones before it left
+			"batch",
+			"let a = 1\nlet b = 2\n",
+			[]TextDocumentContentChangeEvent{
+				rangeChange(1, 4, 1, 5, "bee"),
+				rangeChange(0, 0, 0, 0, "// numbers\n"),
+				rangeChange(2, 10, 2, 11, "3"),
+				rangeChange(1, 8, 2, 0, ""),
+			},
+			"// numbers\nlet a = let bee = 3\n",
+		},
+		{
+			"whole text",
+			"let x = 1\n",
+			[]TextDocumentContentChangeEvent{rangeChange(0, 0, 0, 3, "const"), {Text: "let y = 2\n"}, rangeChange(0, 4, 0, 5, "z")},
+			"let z = 2\n",
+		},
+		{
+			"crlf",
+			"let a = 1\r\nlet b = 2\r\nlet c = 3\r\n",
+			[]TextDocumentContentChangeEvent{
+				rangeChange(1, 8, 1, 9, "20"),
+				rangeChange(2, 0, 2, 3, "const"),
+				// past the end of the line stays before its "\r\n"
+				rangeChange(0, 50, 0, 50, " // one"),
+			},
+			"let a = 1 // one\r\nlet b = 20\r\nconst c = 3\r\n",
+		},
+		{
+			"lines joined across crlf",
+			"a\r\nb\r\n",
+			[]TextDocumentContentChangeEvent{rangeChange(0, 1, 1, 0, " ")},
+			"a b\r\n",
+		},
+		{
+			// é and ü take one UTF-16 unit but two bytes, the emoji takes two units and four bytes
+			"non-ascii",
+			"let s = \"héllo 🎉 wörld\"\nlet t = 1\n",
+			[]TextDocumentContentChangeEvent{
+				rangeChange(0, 18, 0, 23, "welt"),
+				rangeChange(0, 15, 0, 17, "🚀"),
+				rangeChange(0, 11, 0, 14, "i!"),
+				rangeChange(1, 4, 1, 5, "ü"),
+			},
+			"let s = \"héi! 🚀 welt\"\nlet ü = 1\n",
+		},
+		{
+			"past the end",
+			"let x = 1",
+			[]TextDocumentContentChangeEvent{rangeChange(5, 0, 6, 0, "\n")},
+			"let x = 1\n",
+		},
+	}
+
+	for _, c := range cases {
+		if got := applyContentChanges(c.text, c.changes); got != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestDidChange_IncrementalEdits(t *testing.T) {
+	dir := t.TempDir()
+	pathHasNoProjectMarker(t, dir)
+	uri := toURI(filepath.Join(dir, "level.hyb"))
+
+	h, _ := newTestHandler(t)
+	openReq := newTestRequest("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{
+			URI:        uri,
+			LanguageID: "hybroid",
+			Version:    0,
+			Text:       "env TestLevel as Level\r\n\r\nlet name = \"café\"\r\n",
+		},
+	})
+	if _, err := h.handleTextDocumentDidOpen(context.Background(), h.conn, openReq); err != nil {
+		t.Fatalf("didOpen: %v", err)
+	}
+
+	changeReq := newTestRequest("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument: VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: TextDocumentIdentifier{URI: uri},
+			Version:                1,
+		},
+		ContentChanges: []TextDocumentContentChangeEvent{
+			rangeChange(2, 16, 2, 16, " ☕"),
+			rangeChange(2, 4, 2, 8, "drink"),
+		},
+	})
+	if _, err := h.handleTextDocumentDidChange(context.Background(), h.conn, changeReq); err != nil {
+		t.Fatalf("didChange: %v", err)
+	}
+
+	h.mu.Lock()
+	file := h.files[uri]
+	h.mu.Unlock()
+	expected := "env TestLevel as Level\r\n\r\nlet drink = \"café ☕\"\r\n"
+	if file.Text != expected {
+		t.Errorf("expected %q, got %q", expected, file.Text)
+	}
+	if file.Version != 1 {
+		t.Errorf("expected version 1, got %d", file.Version)
+	}
+}
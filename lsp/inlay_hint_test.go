@@ -0,0 +1,129 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const inlayHintSource = `env TestLevel as Level
+
+use Pewpew
+
+class Point {
+    fixed x
+
+    new(fixed x) {
+        self.x = x
+    }
+
+    fn Moved(fixed dx) -> Point {
+        return new Point(self.x + dx)
+    }
+}
+
+entity Crate {
+    spawn(fixed x, fixed y) {
+        let px, py = GetEntityPosition(self)
+    }
+
+    destroy() {}
+}
+
+fn Sum(number a, number b) -> number {
+    return a + b
+}
+
+let total = Sum(1, Sum(2, 3))
+let b = 4
+const other = Sum(total, b)
+for i, v in [1, 2] {
+    Print(ToString(v))
+}
+tick with time {
+    Print(ToString(time))
+}
+let p = new Point(1f)
+let moved = p.Moved(2f)
+let e = spawn Crate(0f, 10f)
+if let c = e is Crate {
+}
+number typed = 1
+`
+
+func TestInlayHints(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"hybconfig.toml": minimalHybConfig,
+		"level.hyb":      inlayHintSource,
+	})
+	h, _ := newTestHandlerWithRoot(t, root)
+	h.preAnalyzeWorkspace()
+	if h.eval == nil {
+		t.Fatal("expected the workspace to be analyzed")
+	}
+	uri := toURI(filepath.Join(root, "level.hyb"))
+	h.files[uri] = &File{LanguageID: "hybroid", Text: inlayHintSource}
+
+	hintsIn := func(within Range) []string {
+		result, err := h.handleTextDocumentInlayHint(context.Background(), nil, newTestRequest("textDocument/inlayHint", InlayHintParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Range:        within,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hints := make([]string, 0)
+		for _, hint := range result.([]InlayHint) {
+			hints = append(hints, fmt.Sprintf("%d:%d %s", hint.Position.Line, hint.Position.Character, hint.Label))
+		}
+		return hints
+	}
+
+	hints := hintsIn(Range{End: Position{Line: 50}})
+	expected := []string{
+		// the constructor, the parameters of the spawner are typed
+		"12:25 x:",
+		// both results of a library function, and the parameter of the library function
+		"18:14 : fixed",
+		"18:18 : fixed",
+		"18:39 entity_id:",
+		// nested calls, the argument named like its parameter has no hint
+		"28:9 : number",
+		"28:16 a:",
+		"28:19 b:",
+		"28:23 a:",
+		"28:26 b:",
+		"29:5 : number",
+		"30:11 : number",
+		"30:18 a:",
+		// loop variables and the time of a tick
+		"31:5 : number",
+		"31:8 : number",
+		"32:10 str:",
+		"32:19 obj:",
+		"34:14 : number",
+		"35:10 str:",
+		"35:19 obj:",
+		// constructors, methods, spawners and smart casts
+		"37:5 : Point",
+		"37:18 x:",
+		"38:9 : Point",
+		"38:20 dx:",
+		"39:5 : Crate",
+		"39:20 x:",
+		"39:24 y:",
+		"40:8 : Crate",
+	}
+	if !slices.Equal(hints, expected) {
+		t.Errorf("expected the hints\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(hints, "\n"))
+	}
+
+	ranged := hintsIn(Range{Start: Position{Line: 28, Character: 20}, End: Position{Line: 29, Character: 5}})
+	expectedRange := []string{"28:23 a:", "28:26 b:", "29:5 : number"}
+	if !slices.Equal(ranged, expectedRange) {
+		t.Errorf("expected the hints of the range\n%s\ngot\n%s", strings.Join(expectedRange, "\n"), strings.Join(ranged, "\n"))
+	}
+}
@@ -75,18 +75,74 @@ type ServerCapabilitiesWorkspace struct {
 type ServerCapabilities struct {
 	TextDocumentSync           TextDocumentSyncKind         `json:"textDocumentSync,omitempty"`
 	DocumentSymbolProvider     bool                         `json:"documentSymbolProvider,omitempty"`
+	WorkspaceSymbolProvider    bool                         `json:"workspaceSymbolProvider,omitempty"`
 	CompletionProvider         *CompletionProvider          `json:"completionProvider,omitempty"`
 	SignatureHelpProvider      *SignatureHelpProvider       `json:"signatureHelpProvider,omitempty"`
 	DefinitionProvider         bool                         `json:"definitionProvider,omitempty"`
 	ReferencesProvider         bool                         `json:"referencesProvider,omitempty"`
-	RenameProvider             bool                         `json:"renameProvider,omitempty"`
+	RenameProvider             *RenameOptions               `json:"renameProvider,omitempty"`
 	DocumentFormattingProvider bool                         `json:"documentFormattingProvider,omitempty"`
 	RangeFormattingProvider    bool                         `json:"documentRangeFormattingProvider,omitempty"`
 	HoverProvider              bool                         `json:"hoverProvider,omitempty"`
 	CodeActionProvider         bool                         `json:"codeActionProvider,omitempty"`
+	SemanticTokensProvider     *SemanticTokensOptions       `json:"semanticTokensProvider,omitempty"`
+	InlayHintProvider          bool                         `json:"inlayHintProvider,omitempty"`
 	Workspace                  *ServerCapabilitiesWorkspace `json:"workspace,omitempty"`
 }
 
+// SemanticTokensLegend is
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+// SemanticTokensOptions is
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	Range  bool                 `json:"range,omitempty"`
+	Full   bool                 `json:"full,omitempty"`
+}
+
+// SemanticTokensParams is
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// SemanticTokensRangeParams is
+type SemanticTokensRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// SemanticTokens is
+type SemanticTokens struct {
+	Data []uint32 `json:"data"`
+}
+
+// InlayHintParams is
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// InlayHintKind is
+type InlayHintKind int
+
+// TypeHint is
+const (
+	TypeHint      InlayHintKind = 1
+	ParameterHint InlayHintKind = 2
+)
+
+// InlayHint is
+type InlayHint struct {
+	Position     Position      `json:"position"`
+	Label        string        `json:"label"`
+	Kind         InlayHintKind `json:"kind,omitempty"`
+	PaddingLeft  bool          `json:"paddingLeft,omitempty"`
+	PaddingRight bool          `json:"paddingRight,omitempty"`
+}
+
 // SignatureHelpProvider is
 type SignatureHelpProvider struct {
 	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
@@ -102,7 +158,7 @@ type SignatureHelp struct {
 // SignatureInformation is
 type SignatureInformation struct {
 	Label         string                 `json:"label"`
-	Documentation string                 `json:"documentation,omitempty"`
+	Documentation *MarkupContent         `json:"documentation,omitempty"`
 	Parameters    []ParameterInformation `json:"parameters,omitempty"`
 }
 
@@ -148,8 +204,8 @@ type DidCloseTextDocumentParams struct {
 
 // TextDocumentContentChangeEvent is
 type TextDocumentContentChangeEvent struct {
-	Range       Range  `json:"range"`
-	RangeLength int    `json:"rangeLength"`
+	Range       *Range `json:"range,omitempty"` // nil when Text is the whole document
+	RangeLength int    `json:"rangeLength,omitempty"`
 	Text        string `json:"text"`
 }
 
@@ -188,6 +244,11 @@ type HoverParams struct {
 	TextDocumentPositionParams
 }
 
+// RenameOptions is
+type RenameOptions struct {
+	PrepareProvider bool `json:"prepareProvider,omitempty"`
+}
+
 // RenameParams is
 type RenameParams struct {
 	TextDocumentPositionParams
@@ -264,13 +325,61 @@ type DocumentSymbolParams struct {
 
 // SymbolInformation is
 type SymbolInformation struct {
-	Name          string   `json:"name"`
-	Kind          int64    `json:"kind"`
-	Deprecated    bool     `json:"deprecated"`
-	Location      Location `json:"location"`
-	ContainerName *string  `json:"containerName"`
+	Name          string     `json:"name"`
+	Kind          SymbolKind `json:"kind"`
+	Deprecated    bool       `json:"deprecated"`
+	Location      Location   `json:"location"`
+	ContainerName *string    `json:"containerName"`
 }
 
+// DocumentSymbol is
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           SymbolKind       `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// WorkspaceSymbolParams is
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+// SymbolKind is
+type SymbolKind int64
+
+// FileSymbol is
+const (
+	FileSymbol          SymbolKind = 1
+	ModuleSymbol        SymbolKind = 2
+	NamespaceSymbol     SymbolKind = 3
+	PackageSymbol       SymbolKind = 4
+	ClassSymbol         SymbolKind = 5
+	MethodSymbol        SymbolKind = 6
+	PropertySymbol      SymbolKind = 7
+	FieldSymbol         SymbolKind = 8
+	ConstructorSymbol   SymbolKind = 9
+	EnumSymbol          SymbolKind = 10
+	InterfaceSymbol     SymbolKind = 11
+	FunctionSymbol      SymbolKind = 12
+	VariableSymbol      SymbolKind = 13
+	ConstantSymbol      SymbolKind = 14
+	StringSymbol        SymbolKind = 15
+	NumberSymbol        SymbolKind = 16
+	BooleanSymbol       SymbolKind = 17
+	ArraySymbol         SymbolKind = 18
+	ObjectSymbol        SymbolKind = 19
+	KeySymbol           SymbolKind = 20
+	NullSymbol          SymbolKind = 21
+	EnumMemberSymbol    SymbolKind = 22
+	StructSymbol        SymbolKind = 23
+	EventSymbol         SymbolKind = 24
+	OperatorSymbol      SymbolKind = 25
+	TypeParameterSymbol SymbolKind = 26
+)
+
 // CompletionItemKind is
 type CompletionItemKind int
 
@@ -332,6 +441,7 @@ type WorkspaceEdit struct {
 // CodeAction is
 type CodeAction struct {
 	Title       string         `json:"title"`
+	Kind        CodeActionKind `json:"kind,omitempty"`
 	Diagnostics []Diagnostic   `json:"diagnostics"`
 	IsPreferred bool           `json:"isPreferred"` // TODO
 	Edit        *WorkspaceEdit `json:"edit"`
@@ -344,7 +454,7 @@ type CompletionItem struct {
 	Kind                CompletionItemKind  `json:"kind,omitempty"`
 	Tags                []CompletionItemTag `json:"tags,omitempty"`
 	Detail              string              `json:"detail,omitempty"`
-	Documentation       string              `json:"documentation,omitempty"` // string | MarkupContent
+	Documentation       *MarkupContent      `json:"documentation,omitempty"`
 	Deprecated          bool                `json:"deprecated,omitempty"`
 	Preselect           bool                `json:"preselect,omitempty"`
 	SortText            string              `json:"sortText,omitempty"`
@@ -7,45 +7,55 @@ import (
 
 var keywordDocs = map[string]string{
 	// ... (rest of the map remains the same)
-	"is":       "Checks if a value is of a certain entity type.",
-	"isnt":     "Checks if a value is NOT of a certain entity type.",
-	"alias":    "Creates a new name for an existing type.",
-	"and":      "Logical AND operator.",
-	"as":       "Used in environment declarations or type casting.",
-	"break":    "Exits the innermost loop or match case.",
-	"by":       "Used in range-based for loops to specify the step.",
-	"const":    "Declares a constant value that cannot be reassigned.",
-	"continue": "Skips to the next iteration of the innermost loop.",
-	"else":     "Executes when the 'if' condition is false.",
-	"entity":   "Defines a new game entity type or refers to the generic entity type.",
-	"enum":     "Defines a set of named constants.",
-	"env":      "Declares the environment (Level, Mesh, Sound, Shared) for the current file.",
-	"false":    "Boolean false value.",
-	"fn":       "Defines a function or function type.",
-	"to":       "Specifies the end of a range in a for loop.",
-	"for":      "Starts a loop over a collection or range.",
-	"if":       "Starts a conditional block.",
-	"in":       "Used in for loops to specify the collection.",
-	"let":      "Declares a local variable.",
-	"match":    "Starts a pattern-matching block or expression.",
-	"new":      "Instantiates a new class instance.",
-	"or":       "Logical OR operator.",
-	"pub":      "Declares a global variable.",
-	"repeat":   "Starts a loop that repeats a specific number of times.",
-	"return":   "Exits a function and optionally returns values.",
-	"self":     "Refers to the current class or entity instance.",
-	"spawn":    "Creates a new instance of an entity.",
-	"struct":   "Defines a collection of named fields.",
-	"class":    "Defines a new class with fields and methods.",
-	"tick":     "Starts a block that executes every game tick.",
-	"true":     "Boolean true value.",
-	"use":      "Imports another environment or library.",
-	"from":     "Specifies the start of a range in a for loop.",
-	"while":    "Starts a loop that continues while a condition is true.",
-	"with":     "Used in certain expressions to provide additional context.",
-	"yield":    "Returns a value from a match expression.",
-	"destroy":  "Removes an entity from the game.",
-	"every":    "Specifies a frequency for tick-based logic.",
+	"is":        "Checks if a value is of a certain entity type.",
+	"isnt":      "Checks if a value is NOT of a certain entity type.",
+	"alias":     "Creates a new name for an existing type.",
+	"and":       "Logical AND operator.",
+	"as":        "Used in environment declarations or type casting.",
+	"break":     "Exits the innermost loop or match case.",
+	"by":        "Used in range-based for loops to specify the step.",
+	"const":     "Declares a constant value that cannot be reassigned.",
+	"continue":  "Skips to the next iteration of the innermost loop.",
+	"else":      "Executes when the 'if' condition is false.",
+	"entity":    "Defines a new game entity type or refers to the generic entity type.",
+	"enum":      "Defines a set of named constants.",
+	"env":       "Declares the environment (Level, Mesh, Sound, Shared) for the current file.",
+	"false":     "Boolean false value.",
+	"find":      "Searches a list or map for a value, returning its first index or key.",
+	"fn":        "Defines a function or function type.",
+	"to":        "Specifies the end of a range in a for loop.",
+	"for":       "Starts a loop over a collection or range.",
+	"if":        "Starts a conditional block.",
+	"in":        "Used in for loops to specify the collection.",
+	"impl":      "Lists the interfaces a class or an entity implements, as in `class Square impl Shape`.",
+	"interface": "Defines the method signatures that the classes and entities implementing it must declare.",
+	"let":       "Declares a local variable, or unwraps an optional value in `if let x = maybe`.",
+	"match":     "Starts a pattern-matching block or expression.",
+	"new":       "Instantiates a new class instance.",
+	"nil":       "The absence of a value, which only optional types like `number?` can hold.",
+	"or":        "Logical OR operator.",
+	"pub":       "Declares a global variable.",
+	"remove":    "Removes an element from a list by index, or from a map by key.",
+	"repeat":    "Starts a loop that repeats a specific number of times.",
+	"return":    "Exits a function and optionally returns values.",
+	"self":      "Refers to the current class or entity instance.",
+	"spawn":     "Creates a new instance of an entity.",
+	"start":     "Runs a task alongside the code starting it, as in `start Intro()`.",
+	"struct":    "Defines a collection of named fields.",
+	"task":      "Declares a function that can wait, as in `task fn Intro()`.",
+	"class":     "Defines a new class with fields and methods.",
+	"test":      "Defines a named test block, which only `hybroid test` runs.",
+	"tick":      "Starts a block that executes every game tick.",
+	"true":      "Boolean true value.",
+	"until":     "Waits for the tick a condition is true on, as in `wait until ready`.",
+	"use":       "Imports another environment or library.",
+	"wait":      "Pauses a task for a number of ticks, or until a condition is true.",
+	"from":      "Specifies the start of a range in a for loop.",
+	"while":     "Starts a loop that continues while a condition is true.",
+	"with":      "Used in certain expressions to provide additional context.",
+	"yield":     "Returns a value from a match expression.",
+	"destroy":   "Removes an entity from the game.",
+	"every":     "Specifies a frequency for tick-based logic.",
 }
 
 var typeDocs = map[string]string{
@@ -76,7 +86,7 @@ var environmentDocs = map[string]string{
 
 var builtinDocs = map[string]string{
 	"ToString":   "```hybroid\nToString(value) -> string\n```\nConverts any value to a string.",
-	"ParseSound": "```hybroid\nParseSound(string jfxrUrl) -> Sound\n```\nAllows you to parse a sound from a [JFXR](https://pewpew.live/jfxr/index.html) URL. Only available in sound environments.",
+	"ParseSound": "```hybroid\nParseSound(string jfxrUrl) -> Sound\n```\nAllows you to parse a sound from a [JFXR](https://pewpew.live/jfxr/index.html) URL. Only available in sound environments. Literal URLs are parsed and checked while compiling.",
 }
 
 var aliasDocs = map[string]string{
@@ -129,23 +139,28 @@ func getSymbolMetadata(w *walker.Walker, walkers map[string]*walker.Walker, labe
 			if env == nil && w != nil {
 				if ev, ok := w.Env().Enums[ns]; ok {
 					if field, _, found := ev.ContainsField(sym); found {
-						return field.Value.GetType().String(), ""
+						return field.Value.GetType().String(), field.Doc
 					}
 				}
 				if ev, ok := w.Env().Entities[ns]; ok {
 					if v, _, found := ev.ContainsField(sym); found {
-						return v.Value.GetType().String(), ""
+						return v.Value.GetType().String(), v.Doc
 					}
 					if v, found := ev.ContainsMethod(sym); found {
-						return v.Value.GetType().String(), ""
+						return v.Value.GetType().String(), v.Doc
 					}
 				}
 				if cv, ok := w.Env().Classes[ns]; ok {
 					if v, _, found := cv.ContainsField(sym); found {
-						return v.Value.GetType().String(), ""
+						return v.Value.GetType().String(), v.Doc
 					}
 					if v, found := cv.ContainsMethod(sym); found {
-						return v.Value.GetType().String(), ""
+						return v.Value.GetType().String(), v.Doc
+					}
+				}
+				if iv, ok := w.Env().Interfaces[ns]; ok {
+					if v, found := iv.ContainsMethod(sym); found {
+						return v.Value.GetType().String(), v.Doc
 					}
 				}
 			}
@@ -167,19 +182,19 @@ func getSymbolMetadata(w *walker.Walker, walkers map[string]*walker.Walker, labe
 				// Check variables
 				if v, ok := env.Scope.Variables[sym]; ok {
 					if isBuiltin || v.IsPub {
-						return v.Value.GetType().String(), ""
+						return v.Value.GetType().String(), v.Doc
 					}
 				}
 				// Check enums in this namespace
 				if ev, ok := env.Enums[sym]; ok {
 					if isBuiltin || ev.IsPub {
-						return "enum " + ev.Type.Name, ""
+						return "enum " + ev.Type.Name, ev.Doc
 					}
 				}
 				// Check if ns is an enum
 				if ev, ok := env.Enums[ns]; ok {
 					if field, _, found := ev.ContainsField(sym); found {
-						return field.Value.GetType().String(), ""
+						return field.Value.GetType().String(), field.Doc
 					}
 				}
 			}
@@ -202,13 +217,16 @@ func getSymbolMetadata(w *walker.Walker, walkers map[string]*walker.Walker, labe
 
 		// 1. Current file types
 		if ev, ok := env.Enums[label]; ok {
-			return "enum " + ev.Type.Name, "Enum"
+			return "enum " + ev.Type.Name, docOr(ev.Doc, "Enum")
 		}
 		if ev, ok := env.Entities[label]; ok {
-			return "entity " + ev.Type.Name, "Entity"
+			return "entity " + ev.Type.Name, docOr(ev.Doc, "Entity")
 		}
 		if cv, ok := env.Classes[label]; ok {
-			return "class " + cv.Type.Name, "Class"
+			return "class " + cv.Type.Name, docOr(cv.Doc, "Class")
+		}
+		if iv, ok := env.Interfaces[label]; ok {
+			return "interface " + iv.Type.Name, docOr(iv.Doc, "Interface")
 		}
 		if alias, ok := env.Scope.AliasTypes[label]; ok {
 			if d, ok := aliasDocs[label]; ok {
@@ -225,19 +243,22 @@ func getSymbolMetadata(w *walker.Walker, walkers map[string]*walker.Walker, labe
 					if d, ok := ApiDocs[impEnv.Name+":"+label]; ok {
 						return v.Value.GetType().String(), d
 					}
-					return v.Value.GetType().String(), impEnv.Name
+					return v.Value.GetType().String(), docOr(v.Doc, impEnv.Name)
 				}
 				if ev, ok := impEnv.Enums[label]; ok && ev.IsPub {
 					if d, ok := ApiDocs[impEnv.Name+":"+label]; ok {
 						return "enum " + ev.Type.Name, d
 					}
-					return "enum " + ev.Type.Name, impEnv.Name
+					return "enum " + ev.Type.Name, docOr(ev.Doc, impEnv.Name)
 				}
 				if cv, ok := impEnv.Classes[label]; ok && cv.IsPub {
-					return "class " + label, impEnv.Name
+					return "class " + label, docOr(cv.Doc, impEnv.Name)
 				}
 				if ev, ok := impEnv.Entities[label]; ok && ev.IsPub {
-					return "entity " + label, impEnv.Name
+					return "entity " + label, docOr(ev.Doc, impEnv.Name)
+				}
+				if iv, ok := impEnv.Interfaces[label]; ok && iv.IsPub {
+					return "interface " + label, docOr(iv.Doc, impEnv.Name)
 				}
 			}
 		}
@@ -264,3 +285,19 @@ func getSymbolMetadata(w *walker.Walker, walkers map[string]*walker.Walker, labe
 
 	return "", ""
 }
+
+// Returns the doc comment of a declaration, or the fallback when it has none
+func docOr(doc, fallback string) string {
+	if doc != "" {
+		return doc
+	}
+	return fallback
+}
+
+// Returns the hover or completion text of a symbol followed by its doc comment, if it has one
+func withDoc(text, doc string) string {
+	if doc == "" {
+		return text
+	}
+	return text + "\n\n" + doc
+}
@@ -0,0 +1,260 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+const renameHelpersSource = `env Helpers as Shared
+
+pub fn Double(number n) -> number {
+    return n * 2
+}
+
+pub enum Mode {
+    Easy,
+    Hard
+}
+
+pub alias Score = number
+
+pub fn Pick<T>(T a, T b) -> T {
+    return a
+}
+
+pub class Box {
+    number value
+
+    new(number value) {
+        self.value = value
+    }
+
+    fn Get() -> Score {
+        return value
+    }
+}
+
+pub fn MakeBox() -> Box {
+    return new Box(1)
+}
+
+pub interface Named {
+    fn Name() -> text
+}
+
+pub class Tag impl Named {
+    new() {}
+
+    fn Name() -> text {
+        return "tag"
+    }
+}
+
+pub class Label impl Named {
+    new() {}
+
+    fn Name() -> text {
+        return "label"
+    }
+}
+
+pub class Plain {
+    new() {}
+
+    fn Name() -> text {
+        return "plain"
+    }
+}
+`
+
+const renameLevelSource = `env TestLevel as Level
+
+use Helpers
+use Pewpew
+
+let value = 1
+fn Shadow() {
+    let value = 2
+    Print(ToString(value))
+}
+let doubled = Double(value)
+let again = Helpers:Double(Pick(1, 2))
+let mode = Mode.Easy
+let box = new Box(3)
+Print(ToString(box.Get() + box.value + doubled + again))
+let boxes = [new Box(4)]
+Print(ToString(boxes[1].value + MakeBox().value))
+fn Describe(Named n) -> text {
+    return n.Name() .. new Plain().Name()
+}
+Print(Describe(new Tag()))
+`
+
+func TestRename(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"hybconfig.toml": minimalHybConfig,
+		"level.hyb":      renameLevelSource,
+		"helpers.hyb":    renameHelpersSource,
+	})
+	h, _ := newTestHandlerWithRoot(t, root)
+	h.preAnalyzeWorkspace()
+	if h.eval == nil {
+		t.Fatal("expected the workspace to be analyzed")
+	}
+	levelURI := toURI(filepath.Join(root, "level.hyb"))
+	helpersURI := toURI(filepath.Join(root, "helpers.hyb"))
+	h.files[levelURI] = &File{LanguageID: "hybroid", Text: renameLevelSource}
+
+	// the edits as "file line:character", sorted
+	rename := func(uri DocumentURI, line, character int, newName string) []string {
+		t.Helper()
+		result, err := h.handleTextDocumentRename(context.Background(), nil, newTestRequest("textDocument/rename", RenameParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{URI: uri},
+				Position:     Position{Line: line, Character: character},
+			},
+			NewName: newName,
+		}))
+		if err != nil {
+			t.Fatalf("rename at %d:%d: %v", line, character, err)
+		}
+		edits := make([]string, 0)
+		if result == nil {
+			return edits
+		}
+		for editURI, fileEdits := range result.(WorkspaceEdit).Changes {
+			for _, edit := range fileEdits {
+				if edit.NewText != newName || edit.Range.End.Character-edit.Range.Start.Character == 0 {
+					t.Errorf("unexpected edit %+v", edit)
+				}
+				edits = append(edits, fmt.Sprintf("%s %d:%d", filepath.Base(string(editURI)), edit.Range.Start.Line, edit.Range.Start.Character))
+			}
+		}
+		slices.Sort(edits)
+		return edits
+	}
+
+	cases := []struct {
+		name      string
+		uri       DocumentURI
+		line, col int
+		expected  []string
+	}{
+		{"global shadowed by a local", levelURI, 5, 4, []string{"level.hyb 10:21", "level.hyb 5:4"}},
+		{"local shadowing a global", levelURI, 8, 19, []string{"level.hyb 7:8", "level.hyb 8:19"}},
+		{"field, not the parameter named like it", levelURI, 14, 35, []string{"helpers.hyb 18:11", "helpers.hyb 21:13", "helpers.hyb 25:15", "level.hyb 14:31", "level.hyb 16:24", "level.hyb 16:42"}},
+		{"method called through an interface", levelURI, 18, 13, []string{"helpers.hyb 34:7", "helpers.hyb 40:7", "helpers.hyb 48:7", "level.hyb 18:13"}},
+		{"method implementing an interface", helpersURI, 48, 7, []string{"helpers.hyb 34:7", "helpers.hyb 40:7", "helpers.hyb 48:7", "level.hyb 18:13"}},
+		{"method named like the one of an interface", levelURI, 18, 35, []string{"helpers.hyb 56:7", "level.hyb 18:35"}},
+		{"method", levelURI, 14, 20, []string{"helpers.hyb 24:7", "level.hyb 14:19"}},
+		{"function of another environment", levelURI, 10, 14, []string{"helpers.hyb 2:7", "level.hyb 10:14", "level.hyb 11:20"}},
+		{"enum variant", levelURI, 12, 17, []string{"helpers.hyb 7:4", "level.hyb 12:16"}},
+		{"alias", helpersURI, 11, 10, []string{"helpers.hyb 11:10", "helpers.hyb 24:16"}},
+		{"generic parameter", helpersURI, 13, 12, []string{"helpers.hyb 13:12", "helpers.hyb 13:15", "helpers.hyb 13:20", "helpers.hyb 13:28"}},
+		{"environment", levelURI, 2, 5, []string{"helpers.hyb 0:4", "level.hyb 11:12", "level.hyb 2:4"}},
+	}
+	for _, c := range cases {
+		if c.uri == helpersURI {
+			h.files[helpersURI] = &File{LanguageID: "hybroid", Text: renameHelpersSource}
+		}
+		if edits := rename(c.uri, c.line, c.col, "Renamed"); !slices.Equal(edits, c.expected) {
+			t.Errorf("%s: expected the edits\n%s\ngot\n%s", c.name, strings.Join(c.expected, "\n"), strings.Join(edits, "\n"))
+		}
+	}
+
+	// a keyword is not a name
+	_, err := h.handleTextDocumentRename(context.Background(), nil, newTestRequest("textDocument/rename", RenameParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: levelURI},
+			Position:     Position{Line: 5, Character: 4},
+		},
+		NewName: "fn",
+	}))
+	if err == nil {
+		t.Error("expected renaming to a keyword to fail")
+	}
+}
+
+func TestPrepareRename(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"hybconfig.toml": minimalHybConfig,
+		"level.hyb":      renameLevelSource,
+		"helpers.hyb":    renameHelpersSource,
+	})
+	h, _ := newTestHandlerWithRoot(t, root)
+	h.preAnalyzeWorkspace()
+	if h.eval == nil {
+		t.Fatal("expected the workspace to be analyzed")
+	}
+	uri := toURI(filepath.Join(root, "level.hyb"))
+	h.files[uri] = &File{LanguageID: "hybroid", Text: renameLevelSource}
+
+	prepare := func(line, character int) (any, error) {
+		return h.handleTextDocumentPrepareRename(context.Background(), nil, newTestRequest("textDocument/prepareRename", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: character},
+		}))
+	}
+
+	result, err := prepare(12, 18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Range{Start: Position{Line: 12, Character: 16}, End: Position{Line: 12, Character: 20}}
+	if result != expected {
+		t.Errorf("expected the range %+v, got %+v", expected, result)
+	}
+
+	// Print of the Pewpew library, the library itself and the builtin ToString
+	for _, position := range []Position{{Line: 14, Character: 2}, {Line: 3, Character: 6}, {Line: 14, Character: 8}} {
+		result, err := prepare(position.Line, position.Character)
+		if _, ok := err.(*jsonrpc2.Error); !ok || result != nil {
+			t.Errorf("expected the name at %d:%d to be rejected, got %v", position.Line, position.Character, result)
+		}
+	}
+
+	// not a name
+	if result, err := prepare(5, 12); result != nil || err != nil {
+		t.Errorf("expected nothing to rename, got %v, %v", result, err)
+	}
+}
+
+func TestRenameUnresolvedMember(t *testing.T) {
+	level := `env TestLevel as Level
+
+use Helpers
+
+let box = new Box(3)
+let broken = Missing().value
+`
+	root := writeProject(t, map[string]string{
+		"hybconfig.toml": minimalHybConfig,
+		"level.hyb":      level,
+		"helpers.hyb":    renameHelpersSource,
+	})
+	h, _ := newTestHandlerWithRoot(t, root)
+	h.preAnalyzeWorkspace()
+	if h.eval == nil {
+		t.Fatal("expected the workspace to be analyzed")
+	}
+	uri := toURI(filepath.Join(root, "helpers.hyb"))
+	h.files[uri] = &File{LanguageID: "hybroid", Text: renameHelpersSource}
+
+	// the value accessed from the call that did not resolve could be the field of Box
+	result, err := h.handleTextDocumentRename(context.Background(), nil, newTestRequest("textDocument/rename", RenameParams{
+		TextDocumentPositionParams: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: 18, Character: 11},
+		},
+		NewName: "Renamed",
+	}))
+	if _, ok := err.(*jsonrpc2.Error); !ok || result != nil {
+		t.Errorf("expected the rename to be refused, got %v, %v", result, err)
+	}
+}
@@ -0,0 +1,159 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const semanticTokensSource = `env TestLevel as Level
+
+use Pewpew
+
+/// @deprecated use Speed instead
+pub fn OldSpeed() -> number {
+    return 1
+}
+
+const LIMIT = 10
+pub let score = 0
+
+enum State {
+    Waiting,
+    Playing
+}
+
+class Counter {
+    number count
+
+    new() {
+        self.count = 0
+    }
+
+    fn Add() {
+        self.count += 1
+    }
+}
+
+entity Crate {
+    number hp = 3
+
+    spawn(fixed x, fixed y) {
+        hp = 2
+    }
+
+    destroy() {}
+}
+
+let s = State.Waiting
+let c = new Counter()
+c.Add()
+let msg = "é 🎉"; let tail = LIMIT
+Pewpew:Print(ToString(LIMIT + score + OldSpeed() + c.count))
+Print(msg)
+`
+
+// A decoded semantic token, as "line:character name type modifiers"
+func decodeSemanticTokens(text string, data []uint32) []string {
+	lines := strings.Split(text, "\n")
+	decoded := make([]string, 0)
+	line, character := 0, 0
+	for i := 0; i+4 < len(data); i += 5 {
+		if data[i] != 0 {
+			character = 0
+		}
+		line += int(data[i])
+		character += int(data[i+1])
+
+		units := utf16.Encode([]rune(lines[line]))
+		name := string(utf16.Decode(units[character : character+int(data[i+2])]))
+
+		modifiers := make([]string, 0)
+		for bit, modifier := range semanticTokenModifiers {
+			if data[i+4]&(1<<bit) != 0 {
+				modifiers = append(modifiers, modifier)
+			}
+		}
+		decoded = append(decoded, fmt.Sprintf("%d:%d %s %s %s", line, character, name, semanticTokenTypes[data[i+3]], strings.Join(modifiers, ",")))
+	}
+	return decoded
+}
+
+func TestSemanticTokens(t *testing.T) {
+	root := writeProject(t, map[string]string{
+		"hybconfig.toml": minimalHybConfig,
+		"level.hyb":      semanticTokensSource,
+	})
+	h, _ := newTestHandlerWithRoot(t, root)
+	h.preAnalyzeWorkspace()
+	if h.eval == nil {
+		t.Fatal("expected the workspace to be analyzed")
+	}
+	uri := toURI(filepath.Join(root, "level.hyb"))
+	h.files[uri] = &File{LanguageID: "hybroid", Text: semanticTokensSource}
+
+	result, err := h.handleTextDocumentSemanticTokens(context.Background(), nil, newTestRequest("textDocument/semanticTokens/full", SemanticTokensParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := decodeSemanticTokens(semanticTokensSource, result.(SemanticTokens).Data)
+
+	expected := []string{
+		"0:4 TestLevel namespace declaration",
+		"2:4 Pewpew namespace defaultLibrary",
+		"5:7 OldSpeed function declaration,public,deprecated",
+		"9:6 LIMIT variable declaration,readonly",
+		"10:8 score variable declaration,public",
+		"12:5 State enum declaration",
+		"17:6 Counter class declaration",
+		"21:13 count property ",
+		"24:7 Add method declaration",
+		"29:7 Crate entity declaration",
+		"33:8 hp property ",
+		"39:8 State enum ",
+		"39:14 Waiting enumMember readonly",
+		"40:12 Counter class ",
+		"41:0 c variable ",
+		"41:2 Add method ",
+		"42:29 LIMIT variable readonly",
+		"43:0 Pewpew namespace defaultLibrary",
+		"43:7 Print function readonly,public,defaultLibrary",
+		"43:13 ToString function readonly,public,defaultLibrary",
+		"43:38 OldSpeed function public,deprecated",
+		"43:51 c variable ",
+		"43:53 count property ",
+		"44:0 Print function readonly,public,defaultLibrary",
+		"44:6 msg variable ",
+	}
+	for _, token := range expected {
+		if !slices.Contains(tokens, token) {
+			t.Errorf("expected the token %q in\n%s", token, strings.Join(tokens, "\n"))
+		}
+	}
+
+	// the range only has the tokens that start in it
+	result, err = h.handleTextDocumentSemanticTokensRange(context.Background(), nil, newTestRequest("textDocument/semanticTokens/range", SemanticTokensRangeParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        Range{Start: Position{Line: 41, Character: 1}, End: Position{Line: 43, Character: 7}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranged := decodeSemanticTokens(semanticTokensSource, result.(SemanticTokens).Data)
+	expectedRange := []string{
+		"41:2 Add method ",
+		"42:4 msg variable declaration",
+		"42:22 tail variable declaration",
+		"42:29 LIMIT variable readonly",
+		"43:0 Pewpew namespace defaultLibrary",
+	}
+	if !slices.Equal(ranged, expectedRange) {
+		t.Errorf("expected the tokens of the range\n%s\ngot\n%s", strings.Join(expectedRange, "\n"), strings.Join(ranged, "\n"))
+	}
+}
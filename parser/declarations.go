@@ -100,13 +100,18 @@ func (p *Parser) typedVariableDeclaration() ast.Node {
 
 func (p *Parser) functionDeclaration() ast.Node {
 	functionDecl := ast.FunctionDecl{
-		IsPub: p.context.isPub,
+		IsPub:  p.context.isPub,
+		IsTask: p.peek(-2).Type == tokens.Task,
+	}
+	// the token of the declaration is its first keyword, in `pub task fn`
+	start := -1
+	if functionDecl.IsTask {
+		start--
 	}
 	if functionDecl.IsPub {
-		functionDecl.Token = p.peek(-2)
-	} else {
-		functionDecl.Token = p.peek(-1)
+		start--
 	}
+	functionDecl.Token = p.peek(start)
 
 	name, nameOk := p.consume(p.NewAlert(&alerts.ExpectedIdentifier{}, alerts.NewSingle(p.peek()), "as the name of the function"), tokens.Identifier)
 	if !nameOk {
@@ -139,6 +144,24 @@ func (p *Parser) functionDeclaration() ast.Node {
 	return &functionDecl
 }
 
+func (p *Parser) testDeclaration() ast.Node {
+	testDecl := &ast.TestDecl{
+		Token: p.peek(-1),
+		Name:  p.advance(),
+	}
+	if p.context.isPub {
+		p.Alert(&alerts.UnexpectedKeyword{}, alerts.NewSingle(p.peek(-3)), tokens.Pub, "before test block")
+	}
+
+	body, ok := p.body(false, false)
+	if !ok {
+		return ast.NewImproper(testDecl.Token, ast.TestDeclaration)
+	}
+	testDecl.Body = body
+
+	return testDecl
+}
+
 func (p *Parser) enumDeclaration() ast.Node {
 	enumStmt := &ast.EnumDecl{
 		Token: p.peek(-1),
@@ -160,6 +183,7 @@ func (p *Parser) enumDeclaration() ast.Node {
 	for _, v := range fields {
 		if v.GetType() == ast.Identifier {
 			enumStmt.Fields = append(enumStmt.Fields, v.(*ast.IdentifierExpr))
+			enumStmt.FieldDocs = append(enumStmt.FieldDocs, p.docOf(v.GetToken()))
 		} else {
 			p.AlertSingle(&alerts.InvalidEnumVariantName{}, v.GetToken())
 			p.sync(tokens.RightBrace)
@@ -232,7 +256,13 @@ func (p *Parser) classDeclaration() ast.Node {
 		}
 	}
 
-	_, ok := p.alertSingleConsume(&alerts.ExpectedSymbol{}, tokens.LeftBrace)
+	impls, ok := p.impls()
+	if !ok && !p.sync(tokens.LeftBrace) {
+		return ast.NewImproper(stmt.Token, ast.ClassDeclaration)
+	}
+	stmt.Impls = impls
+
+	_, ok = p.alertSingleConsume(&alerts.ExpectedSymbol{}, tokens.LeftBrace)
 	if !ok {
 		return ast.NewImproper(stmt.Token, ast.ClassDeclaration)
 	}
@@ -259,6 +289,78 @@ func (p *Parser) classDeclaration() ast.Node {
 	return stmt
 }
 
+func (p *Parser) interfaceDeclaration() ast.Node {
+	stmt := &ast.InterfaceDecl{
+		IsPub: p.context.isPub,
+		Token: p.peek(-1),
+	}
+	if p.context.isPub {
+		stmt.Token = p.peek(-2)
+	}
+
+	name, ok := p.consume(p.NewAlert(&alerts.ExpectedIdentifier{}, alerts.NewSingle(p.peek()), "as the name of the interface"), tokens.Identifier)
+	if !ok {
+		return ast.NewImproper(stmt.Token, ast.InterfaceDeclaration)
+	}
+	stmt.Name = name
+
+	start, ok := p.alertSingleConsume(&alerts.ExpectedSymbol{}, tokens.LeftBrace)
+	if !ok {
+		return ast.NewImproper(stmt.Token, ast.InterfaceDeclaration)
+	}
+
+	for p.consumeTill("in interface declaration", start, tokens.RightBrace) {
+		doc := p.docOf(p.peek())
+		if !p.match(tokens.Fn) {
+			p.AlertSingle(&alerts.UnknownStatement{}, p.peek(), "in interface declaration")
+			p.synchronizeDeclBody()
+			continue
+		}
+		method, ok := p.interfaceMethod()
+		if !ok {
+			p.synchronizeDeclBody()
+			continue
+		}
+		method.Doc = doc
+		stmt.Methods = append(stmt.Methods, method)
+	}
+
+	return stmt
+}
+
+// Parses the signature of an interface method, which has no body
+func (p *Parser) interfaceMethod() (ast.MethodDecl, bool) {
+	method := ast.MethodDecl{IsPub: true}
+
+	name, ok := p.consume(p.NewAlert(&alerts.ExpectedIdentifier{}, alerts.NewSingle(p.peek()), "as the name of the method"), tokens.Identifier)
+	if !ok {
+		return method, false
+	}
+	method.Name = name
+	generics, ok := p.genericParams()
+	if !ok {
+		return method, false
+	}
+	method.Generics = generics
+	params, ok := p.functionParams(tokens.LeftParen, tokens.RightParen)
+	if !ok {
+		return method, false
+	}
+	method.Params = params
+	returns, ok := p.functionReturns()
+	if !ok {
+		return method, false
+	}
+	method.Returns = returns
+
+	if p.check(tokens.LeftBrace) || p.check(tokens.FatArrow) {
+		p.AlertSingle(&alerts.InterfaceMethodWithBody{}, p.peek(), name.Lexeme)
+		return method, false
+	}
+
+	return method, true
+}
+
 func (p *Parser) entityDeclaration() ast.Node {
 	stmt := &ast.EntityDecl{
 		IsPub:         p.context.isPub,
@@ -281,6 +383,12 @@ func (p *Parser) entityDeclaration() ast.Node {
 		}
 	}
 
+	impls, ok := p.impls()
+	if !ok && !p.sync(tokens.LeftBrace) {
+		return ast.NewImproper(stmt.Token, ast.EntityDeclaration)
+	}
+	stmt.Impls = impls
+
 	if !p.match(tokens.LeftBrace) {
 		p.disadvance(2)
 		return ast.NewImproper(stmt.Token, ast.NA)
@@ -7,111 +7,155 @@ import (
 )
 
 func (p *Parser) expression() ast.Node {
-	return p.multiComparison()
+	return p.nilCoalescing()
 }
 
-func (p *Parser) multiComparison() ast.Node {
-	expr := p.comparison()
+// The binary operators bind the way the ones of Lua do, as they are generated without parentheses:
+// or < and < comparisons < | < ~ < & < shifts < .. < + - < * / % \ < unary operators < ^
+// All of them are left associative, except for '..' and '^'. '??' has no Lua counterpart, it binds
+// the loosest, is right associative and is generated in parentheses
+
+func (p *Parser) nilCoalescing() ast.Node {
+	expr := p.multiComparison()
 	if ast.IsImproper(expr, ast.NA) {
 		return expr
 	}
 
-	if p.isMultiComparison() {
+	if p.match(tokens.QuestionQuestion) {
 		operator := p.peek(-1)
-		right := p.multiComparison()
+		right := p.nilCoalescing()
 		if ast.IsImproper(right, ast.NA) {
-			p.AlertSingle(&alerts.ExpectedExpression{}, right.GetToken(), "as right value in binary expression")
+			p.AlertSingle(&alerts.ExpectedExpression{}, right.GetToken(), "as fallback value in '??' expression")
 		}
-		expr = &ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+		return &ast.NilCoalescingExpr{Left: expr, Operator: operator, Right: right}
 	}
 
 	return expr
 }
 
+func (p *Parser) multiComparison() ast.Node {
+	return p.binary(p.and, func() (tokens.Token, bool) {
+		return p.peek(), p.match(tokens.Or)
+	})
+}
+
+func (p *Parser) and() ast.Node {
+	return p.binary(p.comparison, func() (tokens.Token, bool) {
+		return p.peek(), p.match(tokens.And)
+	})
+}
+
 func (p *Parser) comparison() ast.Node {
+	return p.binary(p.bitwiseOr, p.isComparison)
+}
+
+func (p *Parser) bitwiseOr() ast.Node {
+	return p.binary(p.bitwiseXor, func() (tokens.Token, bool) {
+		return p.peek(), p.match(tokens.Pipe)
+	})
+}
+
+func (p *Parser) bitwiseXor() ast.Node {
+	return p.binary(p.bitwiseAnd, func() (tokens.Token, bool) {
+		return p.peek(), p.match(tokens.Tilde)
+	})
+}
+
+func (p *Parser) bitwiseAnd() ast.Node {
+	return p.binary(p.shift, func() (tokens.Token, bool) {
+		return p.peek(), p.match(tokens.Ampersand)
+	})
+}
+
+func (p *Parser) shift() ast.Node {
+	return p.binary(p.concat, func() (tokens.Token, bool) {
+		isLeftShift := p.peek().Type == tokens.Less && p.peek(1).Type == tokens.Less && p.peek(2).Type != tokens.Equal
+		isRightShift := p.peek().Type == tokens.Greater && p.peek(1).Type == tokens.Greater && p.peek(2).Type != tokens.Equal
+		if isLeftShift {
+			return p.combineTokens(tokens.LeftShift, 2)
+		} else if isRightShift {
+			return p.combineTokens(tokens.RightShift, 2)
+		}
+		return tokens.Token{}, false
+	})
+}
+
+func (p *Parser) concat() ast.Node {
 	expr := p.term()
 	if ast.IsImproper(expr, ast.NA) {
 		return expr
 	}
 
-	if op, ok := p.isComparison(); ok {
-		right := p.term()
+	if p.match(tokens.Concat) {
+		operator := p.peek(-1)
+		right := p.concat()
 		if ast.IsImproper(right, ast.NA) {
-			p.AlertSingle(&alerts.ExpectedExpression{}, right.GetToken(), "as right value in binary expression")
+			p.AlertSingle(&alerts.ExpectedExpression{}, right.GetToken(), "as right value in concat expression")
 		}
-		expr = &ast.BinaryExpr{Left: expr, Operator: op, Right: right}
+		return &ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
 	}
 
 	return expr
 }
 
 func (p *Parser) term() ast.Node {
-	expr := p.factor()
+	return p.binary(p.factor, func() (tokens.Token, bool) {
+		return p.peek(), p.match(tokens.Plus, tokens.Minus)
+	})
+}
+
+func (p *Parser) factor() ast.Node {
+	return p.binary(p.unary, func() (tokens.Token, bool) {
+		return p.peek(), p.match(tokens.Star, tokens.Slash, tokens.Modulo, tokens.BackSlash)
+	})
+}
+
+// Parses the operands with the given function for as long as they are separated by an operator the
+// other function matches, grouping them from the left
+func (p *Parser) binary(operand func() ast.Node, operator func() (tokens.Token, bool)) ast.Node {
+	expr := operand()
 	if ast.IsImproper(expr, ast.NA) {
 		return expr
 	}
 
-	isLeftShift := p.peek().Type == tokens.Less && p.peek(1).Type == tokens.Less && p.peek(2).Type != tokens.Equal
-	isRightShift := p.peek().Type == tokens.Greater && p.peek(1).Type == tokens.Greater && p.peek(2).Type != tokens.Equal
-	isNormalOp := p.check(tokens.Plus, tokens.Minus, tokens.Ampersand, tokens.Pipe, tokens.Tilde)
-
-	var op tokens.Token
-	if isNormalOp {
-		op = p.advance()
-	} else if isLeftShift {
-		newToken, success := p.combineTokens(tokens.LeftShift, 2)
-		if !success {
-			return expr
-		}
-		op = newToken
-	} else if isRightShift {
-		newToken, success := p.combineTokens(tokens.RightShift, 2)
-		if !success {
+	for {
+		op, ok := operator()
+		if !ok {
 			return expr
 		}
-		op = newToken
-	}
-
-	if isNormalOp || isLeftShift || isRightShift {
-		right := p.term()
+		right := operand()
 		if ast.IsImproper(right, ast.NA) {
 			p.AlertSingle(&alerts.ExpectedExpression{}, right.GetToken(), "as right value in binary expression")
+			return &ast.BinaryExpr{Left: expr, Operator: op, Right: right}
 		}
 		expr = &ast.BinaryExpr{Left: expr, Operator: op, Right: right}
 	}
-
-	return expr
 }
 
-func (p *Parser) factor() ast.Node {
-	expr := p.concat()
-	if ast.IsImproper(expr, ast.NA) {
-		return expr
-	}
-
-	if p.match(tokens.Star, tokens.Slash, tokens.Caret, tokens.Modulo, tokens.BackSlash) {
+func (p *Parser) unary() ast.Node {
+	if p.match(tokens.Bang, tokens.Minus, tokens.Hash) {
 		operator := p.peek(-1)
-		right := p.factor()
+		right := p.unary()
 		if ast.IsImproper(right, ast.NA) {
-			p.AlertSingle(&alerts.ExpectedExpression{}, right.GetToken(), "as right value in binary expression")
+			p.AlertSingle(&alerts.ExpectedExpression{}, right.GetToken(), "in unary expression")
 		}
-		expr = &ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
+		return &ast.UnaryExpr{Operator: operator, Value: right}
 	}
-
-	return expr
+	return p.power()
 }
 
-func (p *Parser) concat() ast.Node {
-	expr := p.unary()
+func (p *Parser) power() ast.Node {
+	expr := p.find()
 	if ast.IsImproper(expr, ast.NA) {
 		return expr
 	}
 
-	if p.match(tokens.Concat) {
+	if p.match(tokens.Caret) {
 		operator := p.peek(-1)
-		right := p.concat()
+		// the exponent can have unary operators, and is another power when followed by a '^'
+		right := p.unary()
 		if ast.IsImproper(right, ast.NA) {
-			p.AlertSingle(&alerts.ExpectedExpression{}, right.GetToken(), "as right value in concat expression")
+			p.AlertSingle(&alerts.ExpectedExpression{}, right.GetToken(), "as right value in binary expression")
 		}
 		return &ast.BinaryExpr{Left: expr, Operator: operator, Right: right}
 	}
@@ -119,16 +163,29 @@ func (p *Parser) concat() ast.Node {
 	return expr
 }
 
-func (p *Parser) unary() ast.Node {
-	if p.match(tokens.Bang, tokens.Minus, tokens.Hash) {
-		operator := p.peek(-1)
-		right := p.unary()
-		if ast.IsImproper(right, ast.NA) {
-			p.AlertSingle(&alerts.ExpectedExpression{}, right.GetToken(), "in unary expression")
-		}
-		return &ast.UnaryExpr{Operator: operator, Value: right}
+func (p *Parser) find() ast.Node {
+	if !p.match(tokens.Find) {
+		return p.entity()
+	}
+
+	findExpr := &ast.FindExpr{
+		Token: p.peek(-1),
+	}
+	findExpr.Value = p.expression()
+	if ast.IsImproper(findExpr.Value, ast.NA) {
+		p.AlertSingle(&alerts.ExpectedExpression{}, findExpr.Value.GetToken(), "in find expression")
+		return ast.NewImproper(findExpr.Token, ast.FindExpression)
+	}
+	if _, ok := p.consume(p.NewAlert(&alerts.ExpectedKeyword{}, alerts.NewSingle(p.peek()), tokens.In, "in find expression"), tokens.In); !ok {
+		return ast.NewImproper(findExpr.Token, ast.FindExpression)
 	}
-	return p.entity()
+	findExpr.Container = p.AccessorExpr()
+	if ast.IsImproper(findExpr.Container, ast.NA) {
+		p.AlertSingle(&alerts.ExpectedExpression{}, findExpr.Container.GetToken(), "in find expression")
+		return ast.NewImproper(findExpr.Token, ast.FindExpression)
+	}
+
+	return findExpr
 }
 
 func (p *Parser) entity() ast.Node {
@@ -147,7 +204,12 @@ func (p *Parser) entity() ast.Node {
 			tkn := variable.GetToken()
 			conv = &tkn
 
-			expr = p.AccessorExpr()
+			// find gives an optional, so it can be unwrapped too
+			if p.check(tokens.Find) {
+				expr = p.find()
+			} else {
+				expr = p.AccessorExpr()
+			}
 		} else {
 			p.AlertSingle(&alerts.ExpectedSymbol{}, p.peek(), tokens.Equal, "in entity expression")
 		}
@@ -174,8 +236,14 @@ func (p *Parser) entity() ast.Node {
 	}
 
 	if letMatched {
-		p.AlertSingle(&alerts.ExpectedKeyword{}, p.peek(), "is/isnt")
-		return ast.NewImproper(token, ast.EntityEvaluationExpression)
+		// without is/isnt, `let name = maybe` unwraps an optional
+		if conv == nil {
+			return ast.NewImproper(token, ast.LetUnwrapExpression)
+		}
+		if variable.GetType() != ast.Identifier {
+			p.AlertSingle(&alerts.ExpectedIdentifier{}, variable.GetToken())
+		}
+		return &ast.LetUnwrapExpr{Name: *conv, Expr: expr, Token: token}
 	}
 
 	return variable
@@ -262,23 +330,7 @@ func (p *Parser) matchExpr() ast.Node {
 		return &ast.MatchExpr{MatchStmt: *node.(*ast.MatchStmt)}
 	}
 
-	return p.macroCall() // We do not parse macros right now
-}
-
-func (p *Parser) macroCall() ast.Node {
-	// if p.match(tokens.At) {
-	// 	macroCall := &ast.MacroCallExpr{}
-	// 	caller := p.primary()
-	// 	callerType := caller.GetType()
-	// 	if callerType != ast.CallExpression {
-	// 		p.AlertSingle(&alerts.ExpectedCallAfterMacroSymbol{}, caller.GetToken()))
-	// 		return ast.NewImproper(p.peek(), ast.MacroCallExpression)
-	// 	}
-	// 	macroCall.Caller = caller.(*ast.CallExpr)
-	// 	return macroCall
-	// }
-
-	return p.new()
+	return p.macroCall()
 }
 
 func (p *Parser) new() ast.Node {
@@ -338,6 +390,27 @@ func (p *Parser) spawn() ast.Node {
 		return &expr
 	}
 
+	return p.start()
+}
+
+func (p *Parser) start() ast.Node {
+	if p.match(tokens.Start) {
+		expr := ast.StartExpr{
+			Token: p.peek(-1),
+		}
+
+		// start Task(...)
+		expr.Call = p.AccessorExpr()
+		if expr.Call.GetType() != ast.CallExpression {
+			if !ast.IsImproper(expr.Call, ast.NA) {
+				p.AlertSingle(&alerts.ExpectedCallAfterStart{}, expr.Call.GetToken())
+			}
+			return ast.NewImproper(expr.Token, ast.StartExpression)
+		}
+
+		return &expr
+	}
+
 	return p.self()
 }
 
@@ -392,6 +465,9 @@ func (p *Parser) primary() ast.Node {
 	if p.match(tokens.True) {
 		return &ast.LiteralExpr{Value: "true", Token: p.peek(-1)}
 	}
+	if p.match(tokens.Nil) {
+		return &ast.LiteralExpr{Value: "nil", Token: p.peek(-1)}
+	}
 
 	if p.match(tokens.Number, tokens.Fixed, tokens.FixedPoint, tokens.Degree, tokens.Radian, tokens.String) {
 		literal := p.peek(-1)
@@ -408,6 +484,7 @@ func (p *Parser) primary() ast.Node {
 
 	if p.match(tokens.Identifier) {
 		token := p.peek(-1)
+		qualified := p.qualifiers[p.current-1]
 		if !p.match(tokens.Colon) {
 			return &ast.IdentifierExpr{Name: token}
 		}
@@ -426,7 +503,8 @@ func (p *Parser) primary() ast.Node {
 			next = p.advance()
 		}
 		envExpr := &ast.EnvAccessExpr{
-			PathExpr: envPath,
+			PathExpr:  envPath,
+			FromMacro: qualified,
 		}
 		envExpr.Accessed = &ast.IdentifierExpr{
 			Name: next,
@@ -634,11 +712,25 @@ func (p *Parser) typeExpr(typeContext string) *ast.TypeExpr {
 	typeExpr := ast.TypeExpr{}
 	if expr.GetType() == ast.EnvironmentAccessExpression {
 		typeExpr = ast.TypeExpr{Name: expr}
+		typeExpr.IsOptional = p.match(tokens.Question)
 		typeExpr.IsVariadic = p.match(tokens.Ellipsis)
 		return &typeExpr
 	}
 	exprToken := expr.GetToken()
 
+	// task fn(...)
+	if exprToken.Type == tokens.Task {
+		fn, ok := p.alertSingleConsume(&alerts.ExpectedKeyword{}, tokens.Fn, "after 'task' in type expression")
+		if !ok {
+			return improperType
+		}
+		typeExpr.IsTask = true
+		exprToken = fn
+		expr = &ast.IdentifierExpr{
+			Name: fn,
+		}
+	}
+
 	switch exprToken.Type {
 	case tokens.Identifier:
 		if p.match(tokens.Less) {
@@ -660,7 +752,7 @@ func (p *Parser) typeExpr(typeContext string) *ast.TypeExpr {
 			}
 		}
 		typeExpr.Name = expr
-	case tokens.Fn: // fn
+	case tokens.Fn: // fn, task fn
 		_, ok := p.alertSingleConsume(&alerts.ExpectedSymbol{}, tokens.LeftParen, "after 'fn' in type expression")
 		if !ok {
 			return improperType
@@ -705,6 +797,7 @@ func (p *Parser) typeExpr(typeContext string) *ast.TypeExpr {
 		p.AlertSingle(&alerts.ExpectedType{}, expr.GetToken(), typeContext)
 		typeExpr.Name = ast.NewImproper(expr.GetToken(), ast.NA)
 	}
+	typeExpr.IsOptional = p.match(tokens.Question)
 	typeExpr.IsVariadic = p.match(tokens.Ellipsis)
 
 	return &typeExpr
@@ -2,10 +2,67 @@ package parser
 
 import (
 	"hybroid/alerts"
+	"hybroid/ast"
 	"hybroid/tokens"
 	"slices"
+	"strings"
 )
 
+type docPosition struct {
+	line, column int
+}
+
+// Takes the doc comments out of the stream. The ones right after each other are joined into one doc,
+// kept by the position of the token that follows them
+func splitDocComments(stream []tokens.Token) ([]tokens.Token, map[docPosition]string) {
+	docs := make(map[docPosition]string)
+	if !slices.ContainsFunc(stream, func(t tokens.Token) bool { return t.Type == tokens.DocComment }) {
+		return stream, docs
+	}
+
+	rest := make([]tokens.Token, 0, len(stream))
+	lines := make([]string, 0)
+	for _, token := range stream {
+		if token.Type == tokens.DocComment {
+			lines = append(lines, token.Literal)
+			continue
+		}
+		if len(lines) != 0 {
+			docs[docPosition{token.Line, token.Column.Start}] = strings.Join(lines, "\n")
+			lines = lines[:0]
+		}
+		rest = append(rest, token)
+	}
+	return rest, docs
+}
+
+// Returns the doc comment written right before the token
+func (p *Parser) docOf(token tokens.Token) string {
+	return p.docs[docPosition{token.Line, token.Column.Start}]
+}
+
+func attachDoc(node ast.Node, doc string) {
+	if doc == "" {
+		return
+	}
+	switch node := node.(type) {
+	case *ast.FunctionDecl:
+		node.Doc = doc
+	case *ast.MethodDecl:
+		node.Doc = doc
+	case *ast.VariableDecl:
+		node.Doc = doc
+	case *ast.ClassDecl:
+		node.Doc = doc
+	case *ast.EntityDecl:
+		node.Doc = doc
+	case *ast.EnumDecl:
+		node.Doc = doc
+	case *ast.InterfaceDecl:
+		node.Doc = doc
+	}
+}
+
 func (p *Parser) isMultiComparison() bool {
 	return p.match(tokens.And, tokens.Or)
 }
@@ -96,6 +153,19 @@ func (p *Parser) peek(offset ...int) tokens.Token {
 	}
 }
 
+// Checks if the current token is on the same line as the previous one.
+// Tokens substituted into a macro expansion are laid out on the line of the parameter they replace
+func (p *Parser) onSameLine() bool {
+	line := func(index int) int {
+		if p.lines != nil && index >= 0 && index < len(p.lines) {
+			return p.lines[index]
+		}
+		return p.peek(index - p.current).Line
+	}
+
+	return line(p.current-1) == line(p.current)
+}
+
 // Checks if the current type is the specified token type. Returns false if it's the End Of File
 func (p *Parser) check(tokens ...tokens.TokenType) bool {
 	if p.isAtEnd() {
@@ -0,0 +1,419 @@
+package parser
+
+import (
+	"hybroid/alerts"
+	"hybroid/ast"
+	"hybroid/tokens"
+)
+
+// Gives the macros declared in the files of an environment, nil when there is no such environment
+type MacroLookup func(env string) map[string]*ast.MacroDecl
+
+// Holds the macros declared in a file, shared with the parsers of macro expansions
+type macroRegistry struct {
+	macros map[string]*ast.MacroDecl
+	// the environments the file uses, whose public macros it can call
+	uses   []string
+	lookup MacroLookup
+	// the environment declaring the macros, only set for the ones of a used environment
+	env string
+}
+
+func newMacroRegistry() *macroRegistry {
+	return &macroRegistry{
+		macros: make(map[string]*ast.MacroDecl),
+	}
+}
+
+// Returns the macro with the name along with the registry its expansion is parsed with. The macros
+// of the file come first, then the public ones of the used environments, in the order of their use
+func (r *macroRegistry) find(name string) (*ast.MacroDecl, *macroRegistry) {
+	if macro, found := r.macros[name]; found {
+		return macro, r
+	}
+	if r.lookup == nil {
+		return nil, nil
+	}
+	for _, env := range r.uses {
+		macros := r.lookup(env)
+		if macro, found := macros[name]; found && macro.IsPub {
+			// the macros the expansion calls are the ones of its own environment
+			return macro, &macroRegistry{macros: macros, lookup: r.lookup, env: env}
+		}
+	}
+	return nil, nil
+}
+
+// Sets where the public macros of the used environments are looked up
+func (p *Parser) SetMacroLookup(lookup MacroLookup) {
+	p.macros.lookup = lookup
+}
+
+// Returns the macros declared in the parsed file
+func (p *Parser) Macros() map[string]*ast.MacroDecl {
+	return p.macros.macros
+}
+
+func (p *Parser) macroDeclaration() ast.Node {
+	token := p.peek(-1)
+
+	name, ok := p.consume(p.NewAlert(&alerts.ExpectedIdentifier{}, alerts.NewSingle(p.peek()), "as the name of the macro"), tokens.Identifier)
+	if !ok {
+		return ast.NewImproper(token, ast.MacroDeclaration)
+	}
+	macroDecl := &ast.MacroDecl{
+		Name:   name,
+		Params: make([]*ast.IdentifierExpr, 0),
+		IsPub:  p.context.isPub,
+	}
+
+	if _, ok := p.alertSingleConsume(&alerts.ExpectedSymbol{}, tokens.LeftParen, "in macro declaration"); !ok {
+		return ast.NewImproper(token, ast.MacroDeclaration)
+	}
+	if !p.match(tokens.RightParen) {
+		params, ok := p.identifiers("as a macro parameter", true)
+		if !ok {
+			return ast.NewImproper(token, ast.MacroDeclaration)
+		}
+		macroDecl.Params = params
+		if _, ok := p.alertSingleConsume(&alerts.ExpectedSymbol{}, tokens.RightParen, "in macro declaration"); !ok {
+			return ast.NewImproper(token, ast.MacroDeclaration)
+		}
+	}
+
+	// The body is parsed once here so that syntax errors are reported at the declaration,
+	// type checking happens only on the expansions
+	if p.match(tokens.FatArrow) {
+		macroDecl.MacroType = ast.ExpressionExpansion
+		if !p.onSameLine() {
+			p.AlertSingle(&alerts.ExpectedExpression{}, p.peek(-1), "in macro declaration")
+			return ast.NewImproper(token, ast.MacroDeclaration)
+		}
+		start := p.current
+		expr := p.expression()
+		if ast.IsImproper(expr, ast.NA) {
+			p.AlertSingle(&alerts.ExpectedExpression{}, expr.GetToken(), "in macro declaration")
+		}
+		if expr.GetType() == ast.NA {
+			return ast.NewImproper(token, ast.MacroDeclaration)
+		}
+		macroDecl.Tokens = p.hygienicTokens(macroDecl, p.tokens[start:p.current])
+	} else if p.check(tokens.LeftBrace) {
+		macroDecl.MacroType = ast.ProgramExpansion
+		start := p.current + 1
+		if _, ok := p.body(false, false); !ok || p.peek(-1).Type != tokens.RightBrace {
+			return ast.NewImproper(token, ast.MacroDeclaration)
+		}
+		macroDecl.Tokens = p.hygienicTokens(macroDecl, p.tokens[start:p.current-1])
+	} else {
+		p.AlertSingle(&alerts.ExpectedExpressionOrBody{}, p.peek())
+		return ast.NewImproper(token, ast.MacroDeclaration)
+	}
+
+	p.macros.macros[name.Lexeme] = macroDecl
+
+	return macroDecl
+}
+
+// Returns a copy of the macro body where every identifier bound inside of it is renamed,
+// so that the expansion can never capture a variable given through the arguments
+func (p *Parser) hygienicTokens(macro *ast.MacroDecl, body []tokens.Token) []tokens.Token {
+	keys := fieldNames(body)
+	bound := make(map[string]bool)
+	bind := func(i int) {
+		if i < len(body) && body[i].Type == tokens.Identifier && !keys[i] && !isMacroParam(macro, body[i].Lexeme) && body[i].Lexeme != "_" {
+			bound[body[i].Lexeme] = true
+		}
+	}
+	for i, token := range body {
+		switch token.Type {
+		case tokens.Let, tokens.Const, tokens.For:
+			// let a, b = ... / for k, v in ...
+			for j := i + 1; j < len(body) && body[j].Type == tokens.Identifier; j += 2 {
+				bind(j)
+				if j+1 >= len(body) || body[j+1].Type != tokens.Comma {
+					break
+				}
+			}
+		case tokens.With, tokens.Fn:
+			bind(i + 1)
+		case tokens.Identifier:
+			// typed declarations and parameters: number a
+			if i > 0 && body[i-1].Type == tokens.Identifier {
+				bind(i)
+			}
+		}
+	}
+
+	renamed := make([]tokens.Token, len(body))
+	copy(renamed, body)
+	for i, token := range renamed {
+		if token.Type != tokens.Identifier || !bound[token.Lexeme] || keys[i] {
+			continue
+		}
+		if i > 0 && (renamed[i-1].Type == tokens.Dot || renamed[i-1].Type == tokens.Colon) {
+			continue
+		}
+		renamed[i].Lexeme = "__" + macro.Name.Lexeme + "_" + token.Lexeme
+	}
+
+	return renamed
+}
+
+// Marks the tokens of the macro bodies naming the global variables and functions of the file, which the
+// expansions in other environments qualify with the environment of the macro. Those declarations are
+// then kept reachable from the files expanding the macros, even when they are not public
+func (p *Parser) markMacroGlobals() {
+	declared := make(map[string]ast.Node)
+	for _, node := range p.program {
+		switch decl := node.(type) {
+		case *ast.VariableDecl:
+			for _, ident := range decl.Identifiers {
+				declared[ident.Name.Lexeme] = decl
+			}
+		case *ast.FunctionDecl:
+			declared[decl.Name.Lexeme] = decl
+		}
+	}
+
+	for _, macro := range p.macros.macros {
+		macro.Globals = make([]bool, len(macro.Tokens))
+		keys := fieldNames(macro.Tokens)
+		for i, token := range macro.Tokens {
+			if token.Type != tokens.Identifier || keys[i] || isMacroParam(macro, token.Lexeme) {
+				continue
+			}
+			// names after `.` and `:` are fields and members, the ones after `@` macros
+			if i > 0 && (macro.Tokens[i-1].Type == tokens.Dot || macro.Tokens[i-1].Type == tokens.Colon || macro.Tokens[i-1].Type == tokens.At) {
+				continue
+			}
+			if i+1 < len(macro.Tokens) && macro.Tokens[i+1].Type == tokens.Colon {
+				continue
+			}
+			switch decl := declared[token.Lexeme].(type) {
+			case *ast.VariableDecl:
+				decl.MacroUsed = true
+			case *ast.FunctionDecl:
+				decl.MacroUsed = true
+			default:
+				continue
+			}
+			macro.Globals[i] = true
+		}
+	}
+}
+
+func isMacroParam(macro *ast.MacroDecl, lexeme string) bool {
+	for _, param := range macro.Params {
+		if param.Name.Lexeme == lexeme {
+			return true
+		}
+	}
+	return false
+}
+
+// Marks the tokens of the body that name fields rather than variables, which are the keys
+// of struct literals and the fields of struct types
+func fieldNames(body []tokens.Token) []bool {
+	const (
+		block = iota
+		group
+		literal
+		structType
+	)
+	next := func(i int) tokens.TokenType {
+		if i+1 < len(body) {
+			return body[i+1].Type
+		}
+		return tokens.Eof
+	}
+
+	keys := make([]bool, len(body))
+	frames := make([]int, 0)
+	for i, token := range body {
+		frame := block
+		if len(frames) > 0 {
+			frame = frames[len(frames)-1]
+		}
+		switch token.Type {
+		case tokens.LeftParen, tokens.LeftBracket:
+			frames = append(frames, group)
+		case tokens.LeftBrace:
+			kind := block
+			if i > 0 && body[i-1].Type == tokens.Struct {
+				// struct{ number x } is a type, struct{ x = 1 } a literal
+				kind = structType
+				if next(i) == tokens.RightBrace || next(i) == tokens.Identifier && next(i+1) == tokens.Equal {
+					kind = literal
+				}
+			}
+			frames = append(frames, kind)
+		case tokens.RightParen, tokens.RightBracket, tokens.RightBrace:
+			if len(frames) > 0 {
+				frames = frames[:len(frames)-1]
+			}
+		case tokens.Identifier:
+			if i == 0 {
+				continue
+			}
+			previous := body[i-1].Type
+			if frame == literal && next(i) == tokens.Equal && (previous == tokens.LeftBrace || previous == tokens.Comma) {
+				keys[i] = true
+			}
+			if frame == structType && (next(i) == tokens.Comma || next(i) == tokens.RightBrace) {
+				keys[i] = true
+			}
+		}
+	}
+	return keys
+}
+
+// Substitutes the arguments into the macro body. Arguments spanning multiple tokens
+// are grouped, so the precedence of the argument is kept in the expansion. The globals of a
+// macro from another environment are qualified with it, so they never resolve at the call site.
+// Also returns the layout line of every token, which is the line of the parameter for substituted ones,
+// and the tokens naming the environment of the qualified globals
+func (p *Parser) substituteMacroArgs(macro *ast.MacroDecl, args [][]tokens.Token, end tokens.Token, env string) ([]tokens.Token, []int, map[int]bool) {
+	expanded := make([]tokens.Token, 0, len(macro.Tokens)+1)
+	lines := make([]int, 0, len(macro.Tokens)+1)
+	qualifiers := make(map[int]bool)
+	keys := fieldNames(macro.Tokens)
+	for i, token := range macro.Tokens {
+		if env != "" && macro.Globals != nil && macro.Globals[i] {
+			qualifiers[len(expanded)] = true
+			expanded = append(expanded, tokens.NewToken(tokens.Identifier, env, "", token.Location))
+			expanded = append(expanded, tokens.NewToken(tokens.Colon, ":", "", token.Location))
+			expanded = append(expanded, token)
+			lines = append(lines, token.Line, token.Line, token.Line)
+			continue
+		}
+		param := -1
+		if token.Type == tokens.Identifier && !keys[i] && (i == 0 || (macro.Tokens[i-1].Type != tokens.Dot && macro.Tokens[i-1].Type != tokens.Colon)) {
+			for j := range macro.Params {
+				if macro.Params[j].Name.Lexeme == token.Lexeme {
+					param = j
+					break
+				}
+			}
+		}
+		if param == -1 {
+			expanded = append(expanded, token)
+			lines = append(lines, token.Line)
+			continue
+		}
+
+		arg := args[param]
+		if len(arg) == 1 {
+			expanded = append(expanded, arg[0])
+			lines = append(lines, token.Line)
+			continue
+		}
+		expanded = append(expanded, tokens.NewToken(tokens.LeftParen, "(", "", arg[0].Location))
+		expanded = append(expanded, arg...)
+		expanded = append(expanded, tokens.NewToken(tokens.RightParen, ")", "", arg[len(arg)-1].Location))
+		for range len(arg) + 2 {
+			lines = append(lines, token.Line)
+		}
+	}
+
+	expanded = append(expanded, tokens.NewToken(tokens.Eof, "eof", "", end.Location))
+	lines = append(lines, end.Line)
+	if len(macro.Tokens) != 0 {
+		lines[len(lines)-1] = lines[len(lines)-2] + 1
+	}
+
+	return expanded, lines, qualifiers
+}
+
+func (p *Parser) macroCall() ast.Node {
+	if !p.match(tokens.At) {
+		return p.new()
+	}
+
+	at := p.peek(-1)
+	isStatement := p.current-1 == p.context.statementStart
+	name, ok := p.consume(p.NewAlert(&alerts.ExpectedCallAfterMacroSymbol{}, alerts.NewSingle(p.peek())), tokens.Identifier)
+	if !ok {
+		return ast.NewImproper(at, ast.MacroCallExpression)
+	}
+	if !p.check(tokens.LeftParen) {
+		p.AlertSingle(&alerts.ExpectedCallAfterMacroSymbol{}, name)
+		return ast.NewImproper(at, ast.MacroCallExpression)
+	}
+
+	alertCount := len(p.GetAlerts())
+	p.advance()
+	args := make([]ast.Node, 0)
+	argTokens := make([][]tokens.Token, 0)
+	if !p.check(tokens.RightParen) {
+		for {
+			start := p.current
+			arg := p.expression()
+			if ast.IsImproper(arg, ast.NA) {
+				p.AlertSingle(&alerts.ExpectedExpression{}, arg.GetToken(), "in macro arguments")
+			}
+			if arg.GetType() == ast.NA {
+				return ast.NewImproper(at, ast.MacroCallExpression)
+			}
+			args = append(args, arg)
+			argTokens = append(argTokens, p.tokens[start:p.current])
+			if !p.match(tokens.Comma) {
+				break
+			}
+		}
+	}
+	end, ok := p.alertSingleConsume(&alerts.ExpectedSymbol{}, tokens.RightParen, "in macro arguments")
+	if !ok {
+		return ast.NewImproper(at, ast.MacroCallExpression)
+	}
+
+	macro, registry := p.macros.find(name.Lexeme)
+	if macro == nil {
+		p.AlertSingle(&alerts.UnknownMacro{}, name, name.Lexeme)
+		return ast.NewImproper(at, ast.MacroCallExpression)
+	}
+	if len(args) != len(macro.Params) {
+		p.AlertMulti(&alerts.MacroArgumentCountMismatch{}, at, end, name.Lexeme, len(macro.Params), len(args))
+		return ast.NewImproper(at, ast.MacroCallExpression)
+	}
+	if macro.MacroType == ast.ProgramExpansion && !isStatement {
+		p.AlertMulti(&alerts.InvalidExpression{}, at, end, "block macro call", "in an expression")
+		return ast.NewImproper(at, ast.MacroCallExpression)
+	}
+	if len(p.GetAlerts()) != alertCount {
+		return ast.NewImproper(at, ast.MacroCallExpression)
+	}
+
+	macroCall := &ast.MacroCallExpr{
+		Token: at,
+		Caller: &ast.CallExpr{
+			Caller: &ast.IdentifierExpr{Name: name},
+			Args:   args,
+		},
+		Macro: macro,
+	}
+
+	expanded, lines, qualifiers := p.substituteMacroArgs(macro, argTokens, end, registry.env)
+	expansion := NewParser(expanded)
+	expansion.lines = lines
+	expansion.qualifiers = qualifiers
+	expansion.macros = registry
+	if macro.MacroType == ast.ExpressionExpansion {
+		macroCall.Expansion = expansion.expression()
+		if !expansion.isAtEnd() {
+			expansion.AlertSingle(&alerts.UnknownStatement{}, expansion.peek(), "in macro expansion")
+		}
+	} else {
+		macroCall.Body = expansion.Parse()
+	}
+
+	if expansionAlerts := expansion.GetAlerts(); len(expansionAlerts) != 0 {
+		for _, alert := range expansionAlerts {
+			p.AlertI(alert)
+		}
+		p.AlertMulti(&alerts.MacroExpansionFailed{}, at, end, name.Lexeme, macro.Name.Line)
+		return ast.NewImproper(at, ast.MacroCallExpression)
+	}
+
+	return macroCall
+}
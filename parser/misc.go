@@ -241,6 +241,29 @@ func (p *Parser) functionReturns() ([]*ast.TypeExpr, bool) {
 	return returns, returns[0].GetType() != ast.NA
 }
 
+// Parses the optional `impl A, B` clause of a class or entity declaration
+func (p *Parser) impls() ([]*ast.TypeExpr, bool) {
+	var impls []*ast.TypeExpr
+	if !p.match(tokens.Impl) {
+		return impls, true
+	}
+
+	typ := p.typeExpr("in impl clause")
+	if typ.Name.GetType() == ast.NA {
+		return impls, false
+	}
+	impls = append(impls, typ)
+	for p.match(tokens.Comma) {
+		typ := p.typeExpr("in impl clause")
+		if typ.Name.GetType() == ast.NA {
+			return impls, false
+		}
+		impls = append(impls, typ)
+	}
+
+	return impls, true
+}
+
 func (p *Parser) identifier(typeContext string) *ast.IdentifierExpr {
 	if p.peek().Type != tokens.Identifier {
 		expr := p.expression()
@@ -312,8 +335,8 @@ func (p *Parser) expressions(typeContext string, allowTrailing bool) ([]ast.Node
 }
 
 func (p *Parser) identExprPairs(typeContext string, optional bool) ([]*ast.IdentifierExpr, []ast.Node, bool) {
-	if previous := p.peek(-1); previous.Line != p.peek().Line {
-		p.AlertSingle(&alerts.ExpectedIdentifier{}, previous, typeContext)
+	if !p.onSameLine() {
+		p.AlertSingle(&alerts.ExpectedIdentifier{}, p.peek(-1), typeContext)
 		return nil, nil, false
 	}
 	idents, ok := p.identifiers(typeContext, false)
@@ -330,7 +353,7 @@ func (p *Parser) identExprPairs(typeContext string, optional bool) ([]*ast.Ident
 		return nil, nil, false
 	}
 	equal := p.peek(-1)
-	if p.peek().Line != equal.Line {
+	if !p.onSameLine() {
 		p.AlertSingle(&alerts.ExpectedExpression{}, equal, typeContext)
 		return idents, []ast.Node{}, false
 	}
@@ -455,7 +478,9 @@ func (p *Parser) isCall(nodeType ast.NodeType) bool {
 	return nodeType == ast.CallExpression ||
 		nodeType == ast.MethodCallExpression ||
 		nodeType == ast.NewExpession ||
-		nodeType == ast.SpawnExpression
+		nodeType == ast.SpawnExpression ||
+		nodeType == ast.StartExpression ||
+		nodeType == ast.MacroCallExpression
 }
 
 // this is used only for maps, lists and structs
@@ -508,13 +533,13 @@ func (p *Parser) synchronizeBody() {
 
 			expectedBlockCount--
 		case tokens.Entity:
-			if p.peek(1).Type == tokens.Identifier && p.peek(2).Type == tokens.LeftBrace {
+			if p.peek(1).Type == tokens.Identifier && (p.peek(2).Type == tokens.LeftBrace || p.peek(2).Type == tokens.Impl) {
 				if p.context.syncedToken == p.peek() {
 					break
 				}
 				return
 			}
-		case tokens.Let, tokens.Pub, tokens.Const, tokens.Class, tokens.Alias, tokens.Repeat, tokens.For, tokens.Destroy, tokens.Spawn, tokens.New:
+		case tokens.Let, tokens.Pub, tokens.Const, tokens.Class, tokens.Interface, tokens.Alias, tokens.Repeat, tokens.For, tokens.Destroy, tokens.Remove, tokens.Spawn, tokens.New, tokens.Macro, tokens.Task, tokens.Wait:
 			return
 		case tokens.If:
 			if p.peek(-1).Type != tokens.Else {
@@ -554,7 +579,7 @@ func (p *Parser) synchronizeDeclBody() {
 			}
 
 			expectedBlockCount--
-		case tokens.Let, tokens.Pub, tokens.Const, tokens.Class, tokens.Alias, tokens.Repeat, tokens.For, tokens.Destroy, tokens.Spawn, tokens.New:
+		case tokens.Let, tokens.Pub, tokens.Const, tokens.Class, tokens.Alias, tokens.Repeat, tokens.For, tokens.Destroy, tokens.Spawn, tokens.New, tokens.Task:
 			return
 		case tokens.If:
 			if p.peek(-1).Type != tokens.Else {
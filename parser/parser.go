@@ -13,23 +13,34 @@ type Parser struct {
 	program []ast.Node
 	current int
 	tokens  []tokens.Token
+	lines   []int // The layout lines of the tokens, only set when parsing a macro expansion
 	context parserContext
+	macros  *macroRegistry
+	// The tokens qualifying the variables of a macro with its environment, only set when parsing a macro expansion
+	qualifiers map[int]bool
+	// The doc comments of the stream, by the position of the token they are before
+	docs map[docPosition]string
 }
 
 type parserContext struct {
-	isPub        bool
-	ignoreAlerts core.Stack[bool]
-	syncedToken  tokens.Token
+	isPub          bool
+	ignoreAlerts   core.Stack[bool]
+	syncedToken    tokens.Token
+	statementStart int // The index of the token that started the current expression statement
 }
 
 func NewParser(tokens []tokens.Token) Parser {
+	tokens, docs := splitDocComments(tokens)
 	parser := Parser{
 		program: make([]ast.Node, 0),
 		current: 0,
 		tokens:  tokens,
 		context: parserContext{
-			ignoreAlerts: core.NewStack[bool]("IgnoreAlerts"),
+			ignoreAlerts:   core.NewStack[bool]("IgnoreAlerts"),
+			statementStart: -1,
 		},
+		macros:    newMacroRegistry(),
+		docs:      docs,
 		Collector: alerts.NewCollector(),
 	}
 
@@ -83,6 +94,9 @@ func (p *Parser) Parse() []ast.Node {
 			continue
 		}
 	}
+	if p.lines == nil {
+		p.markMacroGlobals()
+	}
 
 	return p.program
 }
@@ -90,8 +104,10 @@ func (p *Parser) Parse() []ast.Node {
 func (p *Parser) parseNode(syncFunc func()) (returnNode ast.Node) {
 	returnNode = ast.NewImproper(p.peek(), ast.NA)
 	p.context.isPub = false
+	doc := p.docOf(p.peek())
 
 	defer func() {
+		attachDoc(returnNode, doc)
 		p.context.isPub = false
 		if returnNode.GetType() == ast.NA {
 			syncFunc()
@@ -107,19 +123,34 @@ func (p *Parser) parseNode(syncFunc func()) (returnNode ast.Node) {
 		p.context.isPub = true
 	}
 
-	if p.peek().Type == tokens.Entity && p.peek(1).Type == tokens.Identifier && (p.peek(2).Type == tokens.Less || p.peek(2).Type == tokens.LeftBrace) {
+	// test is not a keyword, only a name followed by a string starts a test block
+	if p.peek().Type == tokens.Identifier && p.peek().Lexeme == "test" && p.peek(1).Type == tokens.String && p.peek(2).Type == tokens.LeftBrace {
+		p.advance()
+		returnNode = p.testDeclaration()
+		return
+	}
+
+	if p.peek().Type == tokens.Entity && p.peek(1).Type == tokens.Identifier && (p.peek(2).Type == tokens.Less || p.peek(2).Type == tokens.LeftBrace || p.peek(2).Type == tokens.Impl) {
 		p.advance()
 		returnNode = p.entityDeclaration()
 		return
 	}
 
 	switch {
+	case p.peek().Type == tokens.Task && p.peek(1).Type == tokens.Fn && p.peek(2).Type == tokens.Identifier:
+		p.advance()
+		p.advance()
+		returnNode = p.functionDeclaration()
 	case p.match(tokens.Enum):
 		returnNode = p.enumDeclaration()
 	case p.match(tokens.Class):
 		returnNode = p.classDeclaration()
+	case p.match(tokens.Interface):
+		returnNode = p.interfaceDeclaration()
 	case p.match(tokens.Alias):
 		returnNode = p.aliasDeclaration()
+	case p.match(tokens.Macro):
+		returnNode = p.macroDeclaration()
 	case p.match(tokens.Let) || p.match(tokens.Const):
 		returnNode = p.simpleVariableDeclaration()
 	default:
@@ -155,7 +186,15 @@ func (p *Parser) parseNode(syncFunc func()) (returnNode ast.Node) {
 	return
 }
 
-func (p *Parser) auxiliaryNode() ast.Node {
+func (p *Parser) auxiliaryNode() (returnNode ast.Node) {
+	doc := p.docOf(p.peek())
+	defer func() {
+		attachDoc(returnNode, doc)
+	}()
+
+	if p.peek().Type == tokens.Task && p.peek(1).Type == tokens.Fn {
+		p.advance()
+	}
 	if p.match(tokens.Fn) {
 		fnDec := p.functionDeclaration()
 
@@ -171,6 +210,7 @@ func (p *Parser) auxiliaryNode() ast.Node {
 			Returns:  fnDecl.Returns,
 			Params:   fnDecl.Params,
 			Generics: fnDecl.Generics,
+			IsTask:   fnDecl.IsTask,
 			Body:     fnDecl.Body,
 		}
 	} else if p.match(tokens.New) {
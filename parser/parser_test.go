@@ -3,6 +3,7 @@ package parser_test
 import (
 	"fmt"
 	"hybroid/alerts"
+	"hybroid/ast"
 	"hybroid/core"
 	"hybroid/lexer"
 	"hybroid/parser"
@@ -10,6 +11,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -139,3 +141,95 @@ func TestExpressions(t *testing.T) {
 	}
 	performTest(t, "expressions", expectedAlerts)
 }
+
+func TestMacros(t *testing.T) {
+	expectedAlerts := []reflect.Type{
+		reflect.TypeFor[alerts.ExpectedIdentifier](),
+		reflect.TypeFor[alerts.ExpectedExpression](),
+		reflect.TypeFor[alerts.MacroArgumentCountMismatch](),
+		reflect.TypeFor[alerts.InvalidExpression](),
+		reflect.TypeFor[alerts.UnknownMacro](),
+		reflect.TypeFor[alerts.ExpectedCallAfterMacroSymbol](),
+	}
+	performTest(t, "macros", expectedAlerts)
+}
+
+// Writes the expression with each binary and unary expression in parentheses
+func parenthesize(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.BinaryExpr:
+		return "(" + parenthesize(n.Left) + " " + n.Operator.Lexeme + " " + parenthesize(n.Right) + ")"
+	case *ast.NilCoalescingExpr:
+		return "(" + parenthesize(n.Left) + " ?? " + parenthesize(n.Right) + ")"
+	case *ast.UnaryExpr:
+		return "(" + n.Operator.Lexeme + parenthesize(n.Value) + ")"
+	case *ast.GroupExpr:
+		return parenthesize(n.Expr)
+	case *ast.IdentifierExpr:
+		return n.Name.Lexeme
+	case *ast.LiteralExpr:
+		return n.Value
+	}
+	return fmt.Sprintf("<%s>", node.GetType())
+}
+
+func TestOperatorPrecedence(t *testing.T) {
+	cases := []struct {
+		source, expected string
+	}{
+		// from the loosest to the tightest
+		{"a ?? b or c", "(a ?? (b or c))"},
+		{"a or b and c", "(a or (b and c))"},
+		{"a and b == c", "(a and (b == c))"},
+		{"a != b | c", "(a != (b | c))"},
+		{"a | b ~ c", "(a | (b ~ c))"},
+		{"a ~ b & c", "(a ~ (b & c))"},
+		{"a & b << c", "(a & (b << c))"},
+		{"a >> b .. c", "(a >> (b .. c))"},
+		{"a .. b + c", "(a .. (b + c))"},
+		{"a - b * c", "(a - (b * c))"},
+		{"a \\ -b", "(a \\ (-b))"},
+		{"-a ^ b", "(-(a ^ b))"},
+		{"!a and b", "((!a) and b)"},
+		{"#a + 1", "((#a) + 1)"},
+		{"(a or b) and c", "((a or b) and c)"},
+
+		// associativity
+		{"a ?? b ?? c", "(a ?? (b ?? c))"},
+		{"a or b or c", "((a or b) or c)"},
+		{"a and b and c", "((a and b) and c)"},
+		{"a <= b == c", "((a <= b) == c)"},
+		{"a | b | c", "((a | b) | c)"},
+		{"a ~ b ~ c", "((a ~ b) ~ c)"},
+		{"a & b & c", "((a & b) & c)"},
+		{"a << b >> c", "((a << b) >> c)"},
+		{"a .. b .. c", "(a .. (b .. c))"},
+		{"a - b + c", "((a - b) + c)"},
+		{"a / b % c * d", "(((a / b) % c) * d)"},
+		{"a ^ b ^ c", "(a ^ (b ^ c))"},
+		{"a ^ -b ^ c", "(a ^ (-(b ^ c)))"},
+	}
+
+	for _, c := range cases {
+		lex := lexer.NewLexer(strings.NewReader("let _ = " + c.source))
+		tokens, err := lex.Tokenize()
+		if err != nil {
+			t.Fatalf("%s: %v", c.source, err)
+		}
+		p := parser.NewParser(tokens)
+		program := p.Parse()
+		if len(p.GetAlerts()) != 0 {
+			t.Errorf("%s: unexpected alerts", c.source)
+			alerts.PrintAlerts(t, "Unexpected", p.GetAlerts()...)
+			continue
+		}
+		declaration, ok := program[0].(*ast.VariableDecl)
+		if !ok || len(declaration.Expressions) != 1 {
+			t.Errorf("%s: expected a variable declaration, got %v", c.source, program)
+			continue
+		}
+		if got := parenthesize(declaration.Expressions[0]); got != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.source, c.expected, got)
+		}
+	}
+}
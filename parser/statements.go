@@ -18,6 +18,8 @@ func (p *Parser) statement() (returnNode ast.Node) {
 		returnNode = &ast.BreakStmt{Token: p.peek(-1)}
 	case tokens.Destroy:
 		returnNode = p.destroyStatement()
+	case tokens.Remove:
+		returnNode = p.removeStatement()
 	case tokens.Continue:
 		returnNode = &ast.ContinueStmt{Token: p.peek(-1)}
 	case tokens.If:
@@ -28,6 +30,8 @@ func (p *Parser) statement() (returnNode ast.Node) {
 		returnNode = p.forStatement()
 	case tokens.Tick:
 		returnNode = p.tickStatement()
+	case tokens.Wait:
+		returnNode = p.waitStatement()
 	case tokens.Use:
 		returnNode = p.useStatement()
 	case tokens.While:
@@ -44,9 +48,24 @@ func (p *Parser) statement() (returnNode ast.Node) {
 }
 
 func (p *Parser) expressionStatement() ast.Node {
+	p.context.statementStart = p.current
 	expr := p.expression()
 	exprType := expr.GetType()
 
+	if macroCall, ok := expr.(*ast.MacroCallExpr); ok {
+		for macroCall.Expansion != nil {
+			if !p.isCall(macroCall.Expansion.GetType()) {
+				return ast.NewImproper(expr.GetToken(), ast.NA)
+			}
+			next, ok := macroCall.Expansion.(*ast.MacroCallExpr)
+			if !ok {
+				break
+			}
+			macroCall = next
+		}
+		return expr
+	}
+
 	if exprType == ast.Identifier || exprType == ast.EnvironmentAccessExpression ||
 		exprType == ast.MemberExpression || exprType == ast.FieldExpression {
 		return p.assignmentStatement(expr)
@@ -94,6 +113,28 @@ func (p *Parser) destroyStatement() ast.Node {
 	return &destroyStmt
 }
 
+func (p *Parser) removeStatement() ast.Node {
+	removeStmt := ast.RemoveStmt{
+		Token: p.peek(-1),
+	}
+
+	removeStmt.Index = p.expression()
+	if ast.IsImproper(removeStmt.Index, ast.NA) {
+		p.AlertSingle(&alerts.ExpectedExpression{}, removeStmt.Index.GetToken(), "in remove statement")
+		return ast.NewImproper(removeStmt.Token, ast.RemoveStatement)
+	}
+	if _, ok := p.consume(p.NewAlert(&alerts.ExpectedKeyword{}, alerts.NewSingle(p.peek()), tokens.From, "in remove statement"), tokens.From); !ok {
+		return ast.NewImproper(removeStmt.Token, ast.RemoveStatement)
+	}
+	removeStmt.Container = p.AccessorExpr()
+	if ast.IsImproper(removeStmt.Container, ast.NA) {
+		p.AlertSingle(&alerts.ExpectedExpression{}, removeStmt.Container.GetToken(), "in remove statement")
+		return ast.NewImproper(removeStmt.Token, ast.RemoveStatement)
+	}
+
+	return &removeStmt
+}
+
 func (p *Parser) ifStatement(else_exists bool, is_else bool, is_elseif bool) ast.Node {
 	ifStmt := ast.IfStmt{
 		Token: p.peek(-1),
@@ -101,7 +142,7 @@ func (p *Parser) ifStatement(else_exists bool, is_else bool, is_elseif bool) ast
 
 	var expr ast.Node = nil
 	if !is_else {
-		expr = p.multiComparison()
+		expr = p.expression()
 		if ast.IsImproper(expr, ast.NA) {
 			return ast.NewImproper(ifStmt.Token, ast.IfStatement)
 		}
@@ -155,7 +196,7 @@ func (p *Parser) assignmentStatement(expr ast.Node) ast.Node {
 	}
 	if p.match(tokens.Equal) {
 		equal := p.peek(-1)
-		if p.peek().Line != equal.Line {
+		if !p.onSameLine() {
 			p.AlertSingle(&alerts.ExpectedExpression{}, equal, "in assignment declaration")
 			return ast.NewImproper(p.peek(-1), ast.AssignmentStatement)
 		}
@@ -202,7 +243,7 @@ func (p *Parser) returnStatement() ast.Node {
 		Args:  []ast.Node{},
 	}
 
-	if p.peek().Line != returnStmt.Token.Line {
+	if !p.onSameLine() {
 		return returnStmt
 	}
 	returnStmt.Args, _ = p.expressions("in return arguments", false)
@@ -215,7 +256,7 @@ func (p *Parser) yieldStatement() ast.Node {
 		Token: p.peek(-1),
 	}
 
-	if p.peek().Line != yieldStmt.Token.Line {
+	if !p.onSameLine() {
 		return yieldStmt
 	}
 	yieldStmt.Args, _ = p.expressions("in yield statement", false)
@@ -300,7 +341,7 @@ func (p *Parser) whileStatement() ast.Node {
 		Token: p.peek(-1),
 	}
 
-	condition := p.multiComparison()
+	condition := p.expression()
 
 	if ast.IsImproper(condition, ast.NA) {
 		p.Alert(&alerts.ExpectedExpression{}, alerts.NewSingle(condition.GetToken()))
@@ -387,6 +428,26 @@ func (p *Parser) tickStatement() ast.Node {
 	return &tickStmt
 }
 
+func (p *Parser) waitStatement() ast.Node {
+	waitStmt := ast.WaitStmt{
+		Token: p.peek(-1),
+	}
+
+	if p.match(tokens.Until) {
+		waitStmt.Until = p.expression()
+		if ast.IsImproper(waitStmt.Until, ast.NA) {
+			return ast.NewImproper(waitStmt.Token, ast.WaitStatement)
+		}
+	} else {
+		waitStmt.Ticks = p.expression()
+		if ast.IsImproper(waitStmt.Ticks, ast.NA) {
+			return ast.NewImproper(waitStmt.Token, ast.WaitStatement)
+		}
+	}
+
+	return &waitStmt
+}
+
 func (p *Parser) useStatement() ast.Node {
 	useStmt := &ast.UseStmt{
 		Token: p.peek(-1),
@@ -398,6 +459,7 @@ func (p *Parser) useStatement() ast.Node {
 		return ast.NewImproper(p.peek(), ast.UseStatement)
 	}
 	useStmt.PathExpr = filepath.(*ast.EnvPathExpr)
+	p.macros.uses = append(p.macros.uses, useStmt.PathExpr.Path.Lexeme)
 
 	return useStmt
 }
@@ -439,6 +501,7 @@ func (p *Parser) matchStatement(isExpr bool) ast.Node {
 		}
 		matchStmt.Cases = append(matchStmt.Cases, caseStmt)
 	}
+	matchStmt.ClosingBrace = p.peek(-1)
 
 	return &matchStmt
 }
@@ -0,0 +1,104 @@
+package simulator
+
+import (
+	"hybroid/generator/mapping"
+	"hybroid/vm"
+	"hybroid/walker"
+	"math"
+	"slices"
+)
+
+// The functions of fmath, by their Lua names. Their arguments are checked against the types of
+// the walker before they are called
+var fmathHandlers = map[string]native{
+	"max_fixedpoint": func(s *vm.State, args []vm.Value) []vm.Value {
+		return []vm.Value{vm.Fixed(math.MaxInt64)}
+	},
+	"random_fixedpoint": func(s *vm.State, args []vm.Value) []vm.Value {
+		low, high := args[0].(vm.Fixed), args[1].(vm.Fixed)
+		if low > high {
+			s.Errorf("bad argument #2 to 'random_fixedpoint' (interval is empty)")
+		}
+		return []vm.Value{vm.Fixed(s.Random(int64(low), int64(high)))}
+	},
+	"random_int": func(s *vm.State, args []vm.Value) []vm.Value {
+		low, high := s.CheckInteger(args, 0, "random_int"), s.CheckInteger(args, 1, "random_int")
+		if low > high {
+			s.Errorf("bad argument #2 to 'random_int' (interval is empty)")
+		}
+		return []vm.Value{s.Random(low, high)}
+	},
+	"sqrt": func(s *vm.State, args []vm.Value) []vm.Value {
+		x := args[0].(vm.Fixed)
+		if x < 0 {
+			return []vm.Value{vm.Fixed(0)}
+		}
+		return []vm.Value{vm.FixedFromFloat(math.Sqrt(x.Float()))}
+	},
+	"from_fraction": func(s *vm.State, args []vm.Value) []vm.Value {
+		numerator := s.CheckInteger(args, 0, "from_fraction")
+		denominator := s.CheckInteger(args, 1, "from_fraction")
+		if denominator == 0 {
+			s.Errorf("bad argument #2 to 'from_fraction' (division by zero)")
+		}
+		return []vm.Value{vm.Fixed(numerator * vm.FixedOne / denominator)}
+	},
+	"to_int": func(s *vm.State, args []vm.Value) []vm.Value {
+		x := args[0].(vm.Fixed)
+		return []vm.Value{int64(x / vm.FixedOne)}
+	},
+	"abs_fixedpoint": func(s *vm.State, args []vm.Value) []vm.Value {
+		x := args[0].(vm.Fixed)
+		return []vm.Value{max(x, -x)}
+	},
+	"to_fixedpoint": func(s *vm.State, args []vm.Value) []vm.Value {
+		return []vm.Value{vm.Fixed(s.CheckInteger(args, 0, "to_fixedpoint") * vm.FixedOne)}
+	},
+	"sincos": func(s *vm.State, args []vm.Value) []vm.Value {
+		sin, cos := math.Sincos(args[0].(vm.Fixed).Float())
+		return []vm.Value{vm.FixedFromFloat(sin), vm.FixedFromFloat(cos)}
+	},
+	"atan2": func(s *vm.State, args []vm.Value) []vm.Value {
+		y, x := args[0].(vm.Fixed), args[1].(vm.Fixed)
+		return []vm.Value{vm.FixedFromFloat(math.Atan2(y.Float(), x.Float()))}
+	},
+	"tau": func(s *vm.State, args []vm.Value) []vm.Value {
+		return []vm.Value{vm.FixedFromFloat(2 * math.Pi)}
+	},
+	"exp": func(s *vm.State, args []vm.Value) []vm.Value {
+		return []vm.Value{vm.FixedFromFloat(math.Exp(args[0].(vm.Fixed).Float()))}
+	},
+	"ln": func(s *vm.State, args []vm.Value) []vm.Value {
+		x := args[0].(vm.Fixed)
+		if x <= 0 {
+			s.Errorf("bad argument #1 to 'ln' (the logarithm of %s is not defined)", x)
+		}
+		return []vm.Value{vm.FixedFromFloat(math.Log(x.Float()))}
+	},
+}
+
+// Builds the fmath table, whose math is done on the fixedpoint numbers like in PewPew
+func fmathLibrary() *vm.Table {
+	lib := vm.NewTable()
+	names := make([]string, 0, len(walker.FmathAPI.Scope.Variables))
+	for name := range walker.FmathAPI.Scope.Variables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fn, ok := walker.FmathAPI.Scope.Variables[name].Value.(*walker.FunctionVal)
+		if !ok {
+			continue
+		}
+		luaName, ok := mapping.FmathVariables[name]
+		if !ok {
+			continue
+		}
+		handler, ok := fmathHandlers[luaName]
+		if !ok {
+			handler = zeroHandler(fn.Returns)
+		}
+		lib.Set(luaName, vm.NewFunction(luaName, checked(luaName, fn, handler)))
+	}
+	return lib
+}
@@ -0,0 +1,617 @@
+package simulator
+
+import (
+	"fmt"
+	"hybroid/ast"
+	"hybroid/generator/mapping"
+	"hybroid/vm"
+	"hybroid/walker"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+type native = func(s *vm.State, args []vm.Value) []vm.Value
+
+// The radius of the entities whose behavior is not simulated
+const defaultRadius = vm.Fixed(20 * vm.FixedOne)
+
+const shipRadius = vm.Fixed(18 * vm.FixedOne)
+
+// How far a ship moves each tick with its move joystick fully pushed
+const shipSpeed = vm.Fixed(10 * vm.FixedOne)
+
+type entity struct {
+	id     int64
+	kind   string
+	x, y   vm.Fixed
+	radius vm.Fixed
+	alive  bool
+	// the ticks left before an exploding entity is destroyed, 0 when it does not explode
+	explodeIn int64
+	// the index of the player of a ship, -1 for the other entities
+	player int64
+	tag    int64
+
+	update, playerCollision *vm.Function
+}
+
+type player struct {
+	ship    int64
+	score   int64
+	shield  int64
+	hasLost bool
+}
+
+// The state of the level that the stand-in of the PewPew API keeps
+type world struct {
+	sim *simulation
+	// the entities in the order they were created
+	entities  []*entity
+	byID      map[int64]*entity
+	nextID    int64
+	callbacks []*vm.Function
+	players   []*player
+	nextWall  int64
+	width     vm.Fixed
+	height    vm.Fixed
+	// the number of meshes or sounds of the files the level used, by their path
+	assets map[string]int64
+	// the values of pewpew.EntityType, by the name of the type
+	entityTypes map[string]int64
+}
+
+func newWorld(sim *simulation) *world {
+	w := &world{
+		sim:         sim,
+		byID:        make(map[int64]*entity),
+		nextID:      1,
+		nextWall:    1,
+		width:       1000 * vm.FixedOne,
+		height:      1000 * vm.FixedOne,
+		assets:      make(map[string]int64),
+		entityTypes: enumValues("EntityType"),
+	}
+	for range sim.options.Players {
+		w.players = append(w.players, &player{shield: 3})
+	}
+	return w
+}
+
+// Runs one tick of the level
+func (w *world) step() {
+	s := w.sim.state
+	for _, callback := range slices.Clone(w.callbacks) {
+		s.Call(callback)
+	}
+	for _, e := range slices.Clone(w.entities) {
+		if e.alive && e.update != nil {
+			s.Call(e.update, e.id)
+		}
+	}
+	w.moveShips()
+	for _, e := range w.entities {
+		if e.explodeIn > 0 {
+			e.explodeIn--
+			if e.explodeIn == 0 {
+				e.alive = false
+			}
+		}
+	}
+	w.entities = slices.DeleteFunc(w.entities, func(e *entity) bool {
+		if !e.alive {
+			delete(w.byID, e.id)
+		}
+		return !e.alive
+	})
+	if !slices.ContainsFunc(w.players, func(p *player) bool { return !p.hasLost }) {
+		w.sim.stopped = true
+	}
+}
+
+// Moves the ships with the fake inputs of their players, colliding them with the entities
+func (w *world) moveShips() {
+	s := w.sim.state
+	for index, p := range w.players {
+		ship := w.byID[p.ship]
+		if ship == nil || !ship.alive {
+			continue
+		}
+		angle, distance, _, _ := w.inputs()
+		sin, cos := math.Sincos(angle.Float())
+		step := distance.Float() * shipSpeed.Float()
+		ship.x = clamp(ship.x+vm.FixedFromFloat(cos*step), 0, w.width)
+		ship.y = clamp(ship.y+vm.FixedFromFloat(sin*step), 0, w.height)
+		for _, e := range slices.Clone(w.entities) {
+			if e.alive && e.explodeIn == 0 && e.playerCollision != nil && w.collides(e, ship.x, ship.y, ship.radius) {
+				s.Call(e.playerCollision, e.id, int64(index), ship.id)
+			}
+		}
+	}
+}
+
+// The inputs of the players, which make the ships circle and shoot every other second
+func (w *world) inputs() (moveAngle, moveDistance, shootAngle, shootDistance vm.Fixed) {
+	turn := float64(w.sim.tick%(4*TicksPerSecond)) / (4 * TicksPerSecond)
+	moveAngle = vm.FixedFromFloat(turn * 2 * math.Pi)
+	shootAngle = vm.FixedFromFloat(math.Mod(turn*2*math.Pi+math.Pi, 2*math.Pi))
+	if (w.sim.tick/TicksPerSecond)%2 == 0 {
+		shootDistance = vm.FixedOne
+	}
+	return moveAngle, vm.FixedOne, shootAngle, shootDistance
+}
+
+func (w *world) collides(e *entity, x, y, radius vm.Fixed) bool {
+	dx, dy := (e.x - x).Float(), (e.y - y).Float()
+	reach := (e.radius + radius).Float()
+	return dx*dx+dy*dy < reach*reach
+}
+
+func clamp(v, low, high vm.Fixed) vm.Fixed {
+	return min(max(v, low), high)
+}
+
+func (w *world) spawn(kind string, x, y vm.Fixed) *entity {
+	e := &entity{id: w.nextID, kind: kind, x: x, y: y, radius: defaultRadius, alive: true, player: -1}
+	if kind == "CUSTOMIZABLE_ENTITY" {
+		e.radius = 0
+	}
+	w.nextID++
+	w.entities = append(w.entities, e)
+	w.byID[e.id] = e
+	w.sim.report.Spawned[kind]++
+	return e
+}
+
+// Returns the entity whose id is the argument, nil when it does not exist anymore like PewPew
+// ignores the entities that were destroyed
+func (w *world) entity(args []vm.Value, i int) *entity {
+	id, _ := vm.Arg(args, i).(int64)
+	e := w.byID[id]
+	if e == nil || !e.alive {
+		return nil
+	}
+	return e
+}
+
+func (w *world) player(args []vm.Value, i int) *player {
+	index, _ := vm.Arg(args, i).(int64)
+	if index < 0 || index >= int64(len(w.players)) {
+		return nil
+	}
+	return w.players[index]
+}
+
+// Checks that the mesh or sound file exists, and that it has the index when the file defines
+// the global listing its meshes or sounds
+func (w *world) checkAsset(s *vm.State, args []vm.Value, pathArg int, indexArgs []int, global, name string) {
+	path := args[pathArg].(string)
+	count, ok := w.assets[path]
+	if !ok {
+		count = w.loadAsset(s, path, global, name)
+		w.assets[path] = count
+	}
+	if count < 0 {
+		return
+	}
+	for _, i := range indexArgs {
+		index, _ := vm.Arg(args, i).(int64)
+		if index < 0 || index >= count {
+			s.Errorf("bad argument #%d to '%s' (index %d is out of the %d %s of '%s')", i+1, name, index, count, global, path)
+		}
+	}
+}
+
+// Returns the number of meshes or sounds the file defines, -1 when it is not known
+func (w *world) loadAsset(s *vm.State, path, global, name string) int64 {
+	file, err := w.sim.resolve(path)
+	if err != nil {
+		s.Errorf("bad argument #1 to '%s' (%s: %v)", name, path, err)
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		s.Errorf("bad argument #1 to '%s' (the file '%s' does not exist)", name, path)
+	}
+	if !strings.HasSuffix(file, ".lua") {
+		return -1
+	}
+	// the files of meshes and sounds run on their own, with the same files to require
+	assetState := vm.NewState()
+	assetState.Loader = w.sim.load
+	assetState.SetGlobal("fmath", fmathLibrary())
+	if _, err := assetState.DoString(path, string(src)); err != nil {
+		s.Errorf("failed loading '%s': %v", path, err)
+	}
+	list, ok := assetState.GetGlobal(global).(*vm.Table)
+	if !ok {
+		return -1
+	}
+	return list.Len()
+}
+
+// Builds the pewpew table: every function of the API checks its arguments against the types
+// of the walker, and the ones the simulation does not need return the zero values of their types
+func (sim *simulation) pewpewLibrary() *vm.Table {
+	w := sim.world
+	handlers := w.handlers()
+
+	lib := vm.NewTable()
+	names := make([]string, 0, len(walker.PewpewAPI.Scope.Variables))
+	for name := range walker.PewpewAPI.Scope.Variables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fn, ok := walker.PewpewAPI.Scope.Variables[name].Value.(*walker.FunctionVal)
+		if !ok {
+			continue
+		}
+		luaName, ok := mapping.PewpewVariables[name]
+		if !ok {
+			continue
+		}
+		handler, ok := handlers[luaName]
+		if !ok {
+			handler = zeroHandler(fn.Returns)
+			if kind, isSpawner := spawnedKind(name); isSpawner {
+				handler = w.spawner(kind, fn)
+			}
+		}
+		lib.Set(luaName, vm.NewFunction(luaName, checked(luaName, fn, handler)))
+	}
+
+	for enum := range mapping.PewpewEnums {
+		values := vm.NewTable()
+		for variant, value := range enumValues(enum) {
+			values.Set(variant, value)
+		}
+		lib.Set(mapping.PewpewVariables[enum], values)
+	}
+	return lib
+}
+
+// Returns the values of the variants of a PewPew enum, by their Lua names
+func enumValues(enum string) map[string]int64 {
+	variants := make([]string, 0, len(mapping.PewpewEnums[enum]))
+	for _, variant := range mapping.PewpewEnums[enum] {
+		variants = append(variants, variant)
+	}
+	slices.Sort(variants)
+	values := make(map[string]int64, len(variants))
+	for i, variant := range variants {
+		values[variant] = int64(i)
+	}
+	return values
+}
+
+// Returns the Lua name of the type of the entities a New function of the API creates
+func spawnedKind(name string) (string, bool) {
+	variant, ok := strings.CutPrefix(name, "New")
+	if !ok {
+		return "", false
+	}
+	switch variant {
+	case "Entity":
+		variant = "CustomizableEntity"
+	case "AsteroidWithSize":
+		variant = "Asteroid"
+	}
+	kind, ok := mapping.PewpewEnums["EntityType"][variant]
+	if !ok {
+		kind = strings.ToUpper(variant)
+	}
+	return kind, true
+}
+
+func (w *world) spawner(kind string, fn *walker.FunctionVal) native {
+	positioned := len(fn.Params) >= 2 && fn.Params[0].PVT() == ast.Fixed && fn.Params[1].PVT() == ast.Fixed
+	return func(s *vm.State, args []vm.Value) []vm.Value {
+		var x, y vm.Fixed
+		if positioned {
+			x, y = args[0].(vm.Fixed), args[1].(vm.Fixed)
+		}
+		return []vm.Value{w.spawn(kind, x, y).id}
+	}
+}
+
+// Checks the arguments of a function of the API before calling its handler
+func checked(name string, fn *walker.FunctionVal, handler native) native {
+	return func(s *vm.State, args []vm.Value) []vm.Value {
+		for i, param := range fn.Params {
+			if expected, ok := checkArg(vm.Arg(args, i), param); !ok {
+				got := "no value"
+				if i < len(args) {
+					got = vm.TypeName(args[i])
+				}
+				s.Errorf("bad argument #%d to '%s' (%s expected, got %s)", i+1, name, expected, got)
+			}
+		}
+		return handler(s, args)
+	}
+}
+
+// Returns whether the value has the Lua type of the API type, and the name of that Lua type
+func checkArg(value vm.Value, typ walker.Type) (string, bool) {
+	switch typ.PVT() {
+	case ast.Number:
+		switch value.(type) {
+		case int64, float64:
+			return "number", true
+		}
+		return "number", false
+	case ast.Entity, ast.Enum:
+		_, ok := value.(int64)
+		return "integer", ok
+	case ast.Fixed:
+		_, ok := value.(vm.Fixed)
+		return "fixedpoint", ok
+	case ast.Text, ast.Path:
+		_, ok := value.(string)
+		return "string", ok
+	case ast.Bool:
+		_, ok := value.(bool)
+		return "boolean", ok
+	case ast.Func:
+		_, ok := value.(*vm.Function)
+		return "function", ok
+	case ast.List, ast.Map, ast.Struct:
+		_, ok := value.(*vm.Table)
+		return "table", ok
+	}
+	return "", true
+}
+
+func zeroHandler(returns []walker.Type) native {
+	return func(s *vm.State, args []vm.Value) []vm.Value {
+		values := make([]vm.Value, len(returns))
+		for i, typ := range returns {
+			values[i] = zeroValue(typ)
+		}
+		return values
+	}
+}
+
+func zeroValue(typ walker.Type) vm.Value {
+	switch typ.PVT() {
+	case ast.Number, ast.Entity, ast.Enum:
+		return int64(0)
+	case ast.Fixed:
+		return vm.Fixed(0)
+	case ast.Bool:
+		return false
+	case ast.Text, ast.Path:
+		return ""
+	case ast.List, ast.Map:
+		return vm.NewTable()
+	case ast.Struct:
+		t := vm.NewTable()
+		if st, ok := typ.(*walker.StructType); ok {
+			for name, field := range st.Fields {
+				t.Set(name, zeroValue(field.Var.GetType()))
+			}
+		}
+		return t
+	}
+	return nil
+}
+
+// The functions of the API that the simulation implements, by their Lua names. Their arguments
+// are checked before they are called
+func (w *world) handlers() map[string]native {
+	sim := w.sim
+	return map[string]native{
+		"print": func(s *vm.State, args []vm.Value) []vm.Value {
+			s.Print(args[0].(string))
+			return nil
+		},
+		"print_debug_info": func(s *vm.State, args []vm.Value) []vm.Value {
+			s.Print(fmt.Sprintf("entities: %d", len(w.entities)))
+			return nil
+		},
+		"add_update_callback": func(s *vm.State, args []vm.Value) []vm.Value {
+			w.callbacks = append(w.callbacks, args[0].(*vm.Function))
+			return nil
+		},
+		"stop_game": func(s *vm.State, args []vm.Value) []vm.Value {
+			sim.stopped = true
+			return nil
+		},
+		"set_level_size": func(s *vm.State, args []vm.Value) []vm.Value {
+			w.width, w.height = args[0].(vm.Fixed), args[1].(vm.Fixed)
+			return nil
+		},
+		"add_wall": func(s *vm.State, args []vm.Value) []vm.Value {
+			w.nextWall++
+			return []vm.Value{w.nextWall - 1}
+		},
+
+		"get_number_of_players": func(s *vm.State, args []vm.Value) []vm.Value {
+			return []vm.Value{int64(len(w.players))}
+		},
+		"get_player_inputs": func(s *vm.State, args []vm.Value) []vm.Value {
+			if p := w.player(args, 0); p == nil || p.hasLost {
+				return []vm.Value{vm.Fixed(0), vm.Fixed(0), vm.Fixed(0), vm.Fixed(0)}
+			}
+			moveAngle, moveDistance, shootAngle, shootDistance := w.inputs()
+			return []vm.Value{moveAngle, moveDistance, shootAngle, shootDistance}
+		},
+		"configure_player": func(s *vm.State, args []vm.Value) []vm.Value {
+			p := w.player(args, 0)
+			if p == nil {
+				return nil
+			}
+			config := args[1].(*vm.Table)
+			if hasLost, ok := config.GetString("has_lost").(bool); ok {
+				p.hasLost = hasLost
+			}
+			if shield, ok := config.GetString("shield").(int64); ok {
+				p.shield = shield
+			}
+			return nil
+		},
+		"get_player_configuration": func(s *vm.State, args []vm.Value) []vm.Value {
+			config := vm.NewTable()
+			if p := w.player(args, 0); p != nil {
+				config.Set("shield", p.shield)
+				config.Set("has_lost", p.hasLost)
+			}
+			return []vm.Value{config}
+		},
+		"increase_score_of_player": func(s *vm.State, args []vm.Value) []vm.Value {
+			if p := w.player(args, 0); p != nil {
+				delta, _ := args[1].(int64)
+				p.score += delta
+			}
+			return nil
+		},
+		"get_score_of_player": func(s *vm.State, args []vm.Value) []vm.Value {
+			if p := w.player(args, 0); p != nil {
+				return []vm.Value{p.score}
+			}
+			return []vm.Value{int64(0)}
+		},
+		"new_player_ship": func(s *vm.State, args []vm.Value) []vm.Value {
+			ship := w.spawn("SHIP", args[0].(vm.Fixed), args[1].(vm.Fixed))
+			ship.radius = shipRadius
+			if p := w.player(args, 2); p != nil {
+				ship.player = args[2].(int64)
+				p.ship = ship.id
+			}
+			return []vm.Value{ship.id}
+		},
+		"add_damage_to_player_ship": func(s *vm.State, args []vm.Value) []vm.Value {
+			ship := w.entity(args, 0)
+			if ship == nil || ship.player < 0 {
+				return nil
+			}
+			p := w.players[ship.player]
+			damage, _ := args[1].(int64)
+			p.shield -= damage
+			if p.shield < 0 {
+				ship.alive = false
+			}
+			return nil
+		},
+
+		"get_all_entities": func(s *vm.State, args []vm.Value) []vm.Value {
+			ids := vm.NewTable()
+			for _, e := range w.entities {
+				if e.alive {
+					ids.Insert(ids.Len()+1, e.id)
+				}
+			}
+			return []vm.Value{ids}
+		},
+		"get_entities_colliding_with_disk": func(s *vm.State, args []vm.Value) []vm.Value {
+			x, y, radius := args[0].(vm.Fixed), args[1].(vm.Fixed), args[2].(vm.Fixed)
+			ids := vm.NewTable()
+			for _, e := range w.entities {
+				if e.alive && w.collides(e, x, y, radius) {
+					ids.Insert(ids.Len()+1, e.id)
+				}
+			}
+			return []vm.Value{ids}
+		},
+		"get_entity_count": func(s *vm.State, args []vm.Value) []vm.Value {
+			count := int64(0)
+			for _, e := range w.entities {
+				if e.alive && w.entityTypes[e.kind] == args[0].(int64) {
+					count++
+				}
+			}
+			return []vm.Value{count}
+		},
+		"get_entity_type": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil {
+				return []vm.Value{w.entityTypes[e.kind]}
+			}
+			return []vm.Value{int64(0)}
+		},
+		"entity_get_position": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil {
+				return []vm.Value{e.x, e.y}
+			}
+			return []vm.Value{vm.Fixed(0), vm.Fixed(0)}
+		},
+		"entity_set_position": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil {
+				e.x, e.y = args[1].(vm.Fixed), args[2].(vm.Fixed)
+			}
+			return nil
+		},
+		"entity_move": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil {
+				e.x += args[1].(vm.Fixed)
+				e.y += args[2].(vm.Fixed)
+			}
+			return nil
+		},
+		"entity_set_radius": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil {
+				e.radius = args[1].(vm.Fixed)
+			}
+			return nil
+		},
+		"entity_get_is_alive": func(s *vm.State, args []vm.Value) []vm.Value {
+			return []vm.Value{w.entity(args, 0) != nil}
+		},
+		"entity_get_is_started_to_be_destroyed": func(s *vm.State, args []vm.Value) []vm.Value {
+			e := w.entity(args, 0)
+			return []vm.Value{e != nil && e.explodeIn > 0}
+		},
+		"entity_destroy": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil {
+				e.alive = false
+			}
+			return nil
+		},
+		"entity_set_update_callback": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil {
+				e.update = args[1].(*vm.Function)
+			}
+			return nil
+		},
+		"customizable_entity_set_player_collision_callback": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil {
+				e.playerCollision = args[1].(*vm.Function)
+			}
+			return nil
+		},
+		"customizable_entity_start_exploding": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil && e.explodeIn == 0 {
+				e.explodeIn = max(args[1].(int64), 1)
+			}
+			return nil
+		},
+		"customizable_entity_set_tag": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil {
+				e.tag = args[1].(int64)
+			}
+			return nil
+		},
+		"customizable_entity_get_tag": func(s *vm.State, args []vm.Value) []vm.Value {
+			if e := w.entity(args, 0); e != nil {
+				return []vm.Value{e.tag}
+			}
+			return []vm.Value{int64(0)}
+		},
+
+		"customizable_entity_set_mesh": func(s *vm.State, args []vm.Value) []vm.Value {
+			w.checkAsset(s, args, 1, []int{2}, "meshes", "customizable_entity_set_mesh")
+			return nil
+		},
+		"customizable_entity_set_flipping_meshes": func(s *vm.State, args []vm.Value) []vm.Value {
+			w.checkAsset(s, args, 1, []int{2, 3}, "meshes", "customizable_entity_set_flipping_meshes")
+			return nil
+		},
+		"play_sound": func(s *vm.State, args []vm.Value) []vm.Value {
+			w.checkAsset(s, args, 0, []int{1}, "sounds", "play_sound")
+			return nil
+		},
+		"play_ambient_sound": func(s *vm.State, args []vm.Value) []vm.Value {
+			w.checkAsset(s, args, 0, []int{1}, "sounds", "play_ambient_sound")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,183 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"hybroid/vm"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PewPew Live runs levels at 30 ticks per second
+const TicksPerSecond = 30
+
+// The most statements one tick of the level can run before it is considered stuck in a loop
+const stepsPerTick = 10_000_000
+
+type Options struct {
+	// the number of ticks to simulate after the level is loaded
+	Ticks int
+	// seeds the random numbers of fmath and math
+	Seed    uint64
+	Players int
+}
+
+// What happened while a level was simulated
+type Report struct {
+	// the number of ticks that were simulated
+	Ticks int
+	// whether the level stopped the game, or all the players lost
+	Stopped bool
+	Prints  []Print
+	// the error that stopped the simulation, if any
+	Error *RuntimeError
+	// the entities alive at the end, by their type
+	EntityCounts map[string]int
+	// the entities created during the simulation, by their type
+	Spawned map[string]int
+	Scores  []int64
+}
+
+type Print struct {
+	Tick int
+	Text string
+}
+
+type RuntimeError struct {
+	Tick      int
+	Message   string
+	Traceback string
+}
+
+// The path the levels refer to their files with, which is the output directory
+const dynamicPrefix = "/dynamic/"
+
+// Runs the level built into the output directory with a stand-in of the PewPew API, starting
+// from its level.lua. The error is only for a level that cannot be loaded, the errors of the
+// Lua code are in the report
+func Run(outputDir string, options Options) (*Report, error) {
+	entry := filepath.Join(outputDir, "level.lua")
+	src, err := os.ReadFile(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading the level entry point, make sure the project was built: %v", err)
+	}
+	if options.Players < 1 {
+		options.Players = 1
+	}
+
+	sim := newSimulation(outputDir, options)
+	main, err := sim.state.Load(dynamicPrefix+"level.lua", string(src))
+	if err != nil {
+		return nil, err
+	}
+	sim.run(main)
+	return sim.report, nil
+}
+
+type simulation struct {
+	state     *vm.State
+	outputDir string
+	options   Options
+	report    *Report
+	tick      int
+	world     *world
+	stopped   bool
+}
+
+func newSimulation(outputDir string, options Options) *simulation {
+	sim := &simulation{
+		state:     vm.NewState(),
+		outputDir: outputDir,
+		options:   options,
+		report: &Report{
+			EntityCounts: make(map[string]int),
+			Spawned:      make(map[string]int),
+		},
+	}
+	sim.state.Seed(options.Seed)
+	sim.state.StepLimit = stepsPerTick
+	sim.state.Print = func(text string) {
+		sim.report.Prints = append(sim.report.Prints, Print{Tick: sim.tick, Text: text})
+	}
+	sim.state.Loader = sim.load
+	sim.world = newWorld(sim)
+	sim.state.SetGlobal("pewpew", sim.pewpewLibrary())
+	sim.state.SetGlobal("fmath", fmathLibrary())
+	return sim
+}
+
+// Loads the files the level requires, which it refers to from /dynamic/
+func (sim *simulation) load(name string) (string, string, error) {
+	path, err := sim.resolve(name)
+	if err != nil {
+		return "", "", err
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	return name, string(src), nil
+}
+
+func (sim *simulation) resolve(name string) (string, error) {
+	rel, ok := strings.CutPrefix(name, dynamicPrefix)
+	if !ok {
+		return "", fmt.Errorf("the path does not start with %s", dynamicPrefix)
+	}
+	if !filepath.IsLocal(rel) {
+		return "", errors.New("the path leaves the level directory")
+	}
+	return filepath.Join(sim.outputDir, filepath.FromSlash(rel)), nil
+}
+
+// Runs the level until the ticks are over, the game stops or an error is raised
+func (sim *simulation) run(main *vm.Function) {
+	if !sim.protect(func() { sim.state.Call(main) }) {
+		sim.finish()
+		return
+	}
+	for sim.tick < sim.options.Ticks && !sim.stopped {
+		sim.tick++
+		sim.report.Ticks = sim.tick
+		if !sim.protect(sim.world.step) {
+			break
+		}
+	}
+	sim.finish()
+}
+
+// Runs a part of the simulation, recording the error it raises
+func (sim *simulation) protect(fn func()) bool {
+	sim.state.ResetSteps()
+	err := sim.state.Protect(fn)
+	if err == nil {
+		return true
+	}
+	luaErr := err.(*vm.Error)
+	sim.report.Error = &RuntimeError{Tick: sim.tick, Message: luaErr.Error(), Traceback: luaErr.Traceback}
+	return false
+}
+
+func (sim *simulation) finish() {
+	sim.report.Stopped = sim.stopped
+	for _, e := range sim.world.entities {
+		if e.alive {
+			sim.report.EntityCounts[e.kind]++
+		}
+	}
+	for _, player := range sim.world.players {
+		sim.report.Scores = append(sim.report.Scores, player.score)
+	}
+}
+
+// Returns the names of the entity types of the counts, sorted
+func SortedKinds(counts map[string]int) []string {
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
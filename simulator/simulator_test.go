@@ -0,0 +1,156 @@
+package simulator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes the files of a built level into a temporary output directory
+func writeLevel(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeLevel(t, map[string]string{
+		"level.lua": `local pewpew = pewpew
+local fmath = fmath
+local Helpers = require("/dynamic/helpers.lua")
+pewpew.set_level_size(500fx, 500fx)
+local ship = pewpew.new_player_ship(250fx, 250fx, 0)
+local ticks = 0
+pewpew.add_update_callback(function()
+  ticks = ticks + 1
+  if ticks % 10 == 0 then
+    local id = pewpew.new_customizable_entity(fmath.random_fixedpoint(0fx, 500fx), 100fx)
+    pewpew.entity_set_update_callback(id, function(entity)
+      pewpew.entity_move(entity, 0fx, 1fx)
+    end)
+    pewpew.customizable_entity_set_tag(id, ticks)
+  end
+  if ticks == 30 then
+    pewpew.print(Helpers.describe(ticks))
+    pewpew.new_asteroid(10fx, 10fx)
+    pewpew.increase_score_of_player(0, 5)
+  end
+  if ticks == 45 then
+    pewpew.stop_game()
+  end
+end)
+print("loaded " .. fmath.to_int(fmath.sqrt(16fx)))
+`,
+		"helpers.lua": `local Helpers = {}
+function Helpers.describe(ticks)
+  return "tick " .. ticks .. ", " .. pewpew.get_entity_count(pewpew.EntityType.CUSTOMIZABLE_ENTITY) .. " entities"
+end
+return Helpers
+`,
+	})
+
+	report, err := Run(dir, Options{Ticks: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Error != nil {
+		t.Fatalf("unexpected error: %s\n%s", report.Error.Message, report.Error.Traceback)
+	}
+	if report.Ticks != 45 || !report.Stopped {
+		t.Errorf("expected the game to stop at tick 45, got %d ticks (stopped: %v)", report.Ticks, report.Stopped)
+	}
+
+	prints := []Print{{0, "loaded 4"}, {30, "tick 30, 3 entities"}}
+	if len(report.Prints) != len(prints) {
+		t.Fatalf("expected the prints %v, got %v", prints, report.Prints)
+	}
+	for i, print := range prints {
+		if report.Prints[i] != print {
+			t.Errorf("expected the print %v, got %v", print, report.Prints[i])
+		}
+	}
+
+	counts := map[string]int{"CUSTOMIZABLE_ENTITY": 4, "ASTEROID": 1, "SHIP": 1}
+	for kind, count := range counts {
+		if report.EntityCounts[kind] != count {
+			t.Errorf("expected %d %s, got %d", count, kind, report.EntityCounts[kind])
+		}
+	}
+	if len(report.Scores) != 1 || report.Scores[0] != 5 {
+		t.Errorf("expected the score of the player to be 5, got %v", report.Scores)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		tick int
+		want string
+	}{
+		{
+			name: "runtime error",
+			src: `local n = 0
+pewpew.add_update_callback(function()
+  n = n + 1
+  if n == 12 then
+    local missing = nil
+    missing.field = 1
+  end
+end)`,
+			tick: 12,
+			want: "/dynamic/level.lua:6: attempt to index a nil value (local 'missing')",
+		},
+		{
+			name: "bad api argument",
+			src:  `pewpew.new_customizable_entity(1, 2fx)`,
+			tick: 0,
+			want: "/dynamic/level.lua:1: bad argument #1 to 'new_customizable_entity' (fixedpoint expected, got number)",
+		},
+		{
+			name: "missing mesh",
+			src: `local id = pewpew.new_customizable_entity(0fx, 0fx)
+pewpew.customizable_entity_set_mesh(id, "/dynamic/missing.lua", 0)`,
+			tick: 0,
+			want: "the file '/dynamic/missing.lua' does not exist",
+		},
+		{
+			name: "mesh index",
+			src: `local id = pewpew.new_customizable_entity(0fx, 0fx)
+pewpew.customizable_entity_set_mesh(id, "/dynamic/mesh.lua", 1)`,
+			tick: 0,
+			want: "index 1 is out of the 1 meshes of '/dynamic/mesh.lua'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeLevel(t, map[string]string{
+				"level.lua": test.src,
+				"mesh.lua":  `meshes = {{vertexes = {{0, 0}}, segments = {}}}`,
+			})
+			report, err := Run(dir, Options{Ticks: 30})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Error == nil {
+				t.Fatal("expected an error")
+			}
+			if report.Error.Tick != test.tick {
+				t.Errorf("expected the error at tick %d, got %d", test.tick, report.Error.Tick)
+			}
+			if !strings.Contains(report.Error.Message, test.want) {
+				t.Errorf("expected the error to contain %q, got %q", test.want, report.Error.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package simulator
+
+import (
+	"hybroid/vm"
+	"strings"
+)
+
+// A file of a test bundle that has test blocks
+type TestFile struct {
+	Source string
+	// the path the Lua of the file is required with, like /dynamic/helpers/math.lua
+	Module string
+}
+
+type TestResult struct {
+	Source string
+	Name   string
+	// the line of the test block in the source
+	Line int
+	// what the test printed
+	Prints []string
+	// the error the test raised, nil when it passed
+	Error *RuntimeError
+}
+
+// Runs the test blocks of a test bundle, which register themselves in the HybroidTests table
+// with their source when their file is required. The files run with the stand-in of the PewPew
+// API, before the first tick. Only the tests whose name contains the filter run
+func RunTests(outputDir string, files []TestFile, filter string) []TestResult {
+	sim := newSimulation(outputDir, Options{Players: 1})
+	registry := vm.NewTable()
+	sim.state.SetGlobal("HybroidTests", registry)
+	require := sim.state.GetGlobal("require")
+
+	results := make([]TestResult, 0)
+	for _, file := range files {
+		if !sim.protect(func() { sim.state.Call(require, file.Module) }) {
+			// the tests of the file are not registered, the error is reported once for the file
+			results = append(results, TestResult{Source: file.Source, Name: "(loading the file)", Error: sim.report.Error})
+		}
+	}
+
+	for i := int64(1); i <= registry.Len(); i++ {
+		test, ok := registry.Get(i).(*vm.Table)
+		if !ok {
+			continue
+		}
+		result := TestResult{}
+		result.Name, _ = test.GetString("name").(string)
+		if !strings.Contains(result.Name, filter) {
+			continue
+		}
+		result.Source, _ = test.GetString("source").(string)
+		line, _ := test.GetString("line").(int64)
+		result.Line = int(line)
+
+		printed := len(sim.report.Prints)
+		if !sim.protect(func() { sim.state.Call(test.GetString("run")) }) {
+			result.Error = sim.report.Error
+		}
+		for _, print := range sim.report.Prints[printed:] {
+			result.Prints = append(result.Prints, print.Text)
+		}
+		results = append(results, result)
+	}
+	return results
+}
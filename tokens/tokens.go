@@ -9,59 +9,59 @@ type TokenType int
 
 const (
 	// Unused tokens
-	// At @
 	// SemiColon ;
-	// Find
-	// Macro
 
 	// Tokens
 
-	Hash            TokenType = iota // #
-	LeftParen                        // (
-	RightParen                       // )
-	LeftBrace                        // {
-	RightBrace                       // }
-	LeftBracket                      // [
-	RightBracket                     // ]
-	Comma                            // ,
-	Colon                            // :
-	Dot                              // .
-	Concat                           // ..
-	Ellipsis                         // ...
-	Minus                            // -
-	MinusEqual                       // -=
-	Plus                             // +
-	PlusEqual                        // +=
-	Slash                            // /
-	SlashEqual                       // /=
-	BackSlash                        // \
-	BackSlashEqual                   // \=
-	Star                             // *
-	StarEqual                        // *=
-	Caret                            // ^
-	CaretEqual                       // ^=
-	Bang                             // !
-	BangEqual                        // !=
-	Equal                            // =
-	EqualEqual                       // ==
-	FatArrow                         // =>
-	ThinArrow                        // ->
-	Greater                          // >
-	GreaterEqual                     // >=
-	Less                             // <
-	LessEqual                        // <=
-	Modulo                           // %
-	ModuloEqual                      // %=
-	LeftShift                        // <<
-	LeftShiftEqual                   // <<=
-	RightShift                       // >>
-	RightShiftEqual                  // >>=
-	Pipe                             // |
-	PipeEqual                        // |=
-	Ampersand                        // &
-	AmpersandEqual                   // &=
-	Tilde                            // ~
-	TildeEqual                       // ~=
+	Hash             TokenType = iota // #
+	At                                // @
+	LeftParen                         // (
+	RightParen                        // )
+	LeftBrace                         // {
+	RightBrace                        // }
+	LeftBracket                       // [
+	RightBracket                      // ]
+	Comma                             // ,
+	Colon                             // :
+	Dot                               // .
+	Concat                            // ..
+	Ellipsis                          // ...
+	Minus                             // -
+	MinusEqual                        // -=
+	Plus                              // +
+	PlusEqual                         // +=
+	Slash                             // /
+	SlashEqual                        // /=
+	BackSlash                         // \
+	BackSlashEqual                    // \=
+	Star                              // *
+	StarEqual                         // *=
+	Caret                             // ^
+	CaretEqual                        // ^=
+	Bang                              // !
+	BangEqual                         // !=
+	Equal                             // =
+	EqualEqual                        // ==
+	FatArrow                          // =>
+	ThinArrow                         // ->
+	Greater                           // >
+	GreaterEqual                      // >=
+	Less                              // <
+	LessEqual                         // <=
+	Modulo                            // %
+	ModuloEqual                       // %=
+	LeftShift                         // <<
+	LeftShiftEqual                    // <<=
+	RightShift                        // >>
+	RightShiftEqual                   // >>=
+	Pipe                              // |
+	PipeEqual                         // |=
+	Ampersand                         // &
+	AmpersandEqual                    // &=
+	Tilde                             // ~
+	TildeEqual                        // ~=
+	Question                          // ?
+	QuestionQuestion                  // ??
 
 	// Literals
 
@@ -75,89 +75,117 @@ const (
 
 	// Keywords
 
-	Is       // is
-	Isnt     // isnt
-	Alias    // alias
-	And      // and
-	As       // as
-	Break    // break
-	By       // by
-	Const    // const
-	Continue // continue
-	Every    // every
-	Else     // else
-	Entity   // entity
-	Enum     // enum
-	Env      // env
-	False    // false
-	Fn       // fn
-	For      // for
-	If       // if
-	In       // in
-	From     // from
-	To       // to
-	Let      // let
-	Match    // match
-	New      // new
-	Or       // or
-	Pub      // pub
-	Repeat   // repeat
-	Return   // return
-	Self     // self
-	Spawn    // spawn
-	Struct   // struct
-	Class    // class
-	Tick     // tick
-	True     // true
-	Use      // use
-	While    // while
-	With     // with
-	Yield    // yield
-	Destroy  // destroy
+	Is        // is
+	Isnt      // isnt
+	Alias     // alias
+	And       // and
+	As        // as
+	Break     // break
+	By        // by
+	Const     // const
+	Continue  // continue
+	Every     // every
+	Else      // else
+	Entity    // entity
+	Enum      // enum
+	Env       // env
+	False     // false
+	Fn        // fn
+	Find      // find
+	For       // for
+	If        // if
+	In        // in
+	Impl      // impl
+	Interface // interface
+	From      // from
+	To        // to
+	Let       // let
+	Match     // match
+	Macro     // macro
+	New       // new
+	Nil       // nil
+	Or        // or
+	Pub       // pub
+	Remove    // remove
+	Repeat    // repeat
+	Return    // return
+	Self      // self
+	Spawn     // spawn
+	Start     // start
+	Struct    // struct
+	Task      // task
+	Class     // class
+	Tick      // tick
+	True      // true
+	Until     // until
+	Use       // use
+	Wait      // wait
+	While     // while
+	With      // with
+	Yield     // yield
+	Destroy   // destroy
+
+	// Given to the parser, which attaches it to the declaration after it
+
+	DocComment // doc comment
+
+	// Trivia, kept aside by the lexer instead of being given to the parser
+
+	Comment // comment
 
 	Eof // EOF (End of File)
 )
 
 var keywords = map[string]TokenType{
-	"is":       Is,
-	"isnt":     Isnt,
-	"alias":    Alias,
-	"and":      And,
-	"as":       As,
-	"break":    Break,
-	"by":       By,
-	"const":    Const,
-	"continue": Continue,
-	"else":     Else,
-	"entity":   Entity,
-	"enum":     Enum,
-	"env":      Env,
-	"false":    False,
-	"fn":       Fn,
-	"to":       To,
-	"for":      For,
-	"if":       If,
-	"in":       In,
-	"let":      Let,
-	"match":    Match,
-	"new":      New,
-	"or":       Or,
-	"pub":      Pub,
-	"repeat":   Repeat,
-	"return":   Return,
-	"self":     Self,
-	"spawn":    Spawn,
-	"struct":   Struct,
-	"class":    Class,
-	"tick":     Tick,
-	"true":     True,
-	"use":      Use,
-	"from":     From,
-	"while":    While,
-	"with":     With,
-	"yield":    Yield,
-	"destroy":  Destroy,
-	"every":    Every,
+	"is":        Is,
+	"isnt":      Isnt,
+	"alias":     Alias,
+	"and":       And,
+	"as":        As,
+	"break":     Break,
+	"by":        By,
+	"const":     Const,
+	"continue":  Continue,
+	"else":      Else,
+	"entity":    Entity,
+	"enum":      Enum,
+	"env":       Env,
+	"false":     False,
+	"fn":        Fn,
+	"find":      Find,
+	"to":        To,
+	"for":       For,
+	"if":        If,
+	"in":        In,
+	"impl":      Impl,
+	"interface": Interface,
+	"let":       Let,
+	"match":     Match,
+	"macro":     Macro,
+	"new":       New,
+	"nil":       Nil,
+	"or":        Or,
+	"pub":       Pub,
+	"remove":    Remove,
+	"repeat":    Repeat,
+	"return":    Return,
+	"self":      Self,
+	"spawn":     Spawn,
+	"start":     Start,
+	"struct":    Struct,
+	"task":      Task,
+	"class":     Class,
+	"tick":      Tick,
+	"true":      True,
+	"until":     Until,
+	"use":       Use,
+	"wait":      Wait,
+	"from":      From,
+	"while":     While,
+	"with":      With,
+	"yield":     Yield,
+	"destroy":   Destroy,
+	"every":     Every,
 }
 
 func KeywordToToken(keyword string) (TokenType, bool) {
@@ -9,103 +9,118 @@ func _() {
 	// Re-run the stringer command to generate them again.
 	var x [1]struct{}
 	_ = x[Hash-0]
-	_ = x[LeftParen-1]
-	_ = x[RightParen-2]
-	_ = x[LeftBrace-3]
-	_ = x[RightBrace-4]
-	_ = x[LeftBracket-5]
-	_ = x[RightBracket-6]
-	_ = x[Comma-7]
-	_ = x[Colon-8]
-	_ = x[Dot-9]
-	_ = x[Concat-10]
-	_ = x[Ellipsis-11]
-	_ = x[Minus-12]
-	_ = x[MinusEqual-13]
-	_ = x[Plus-14]
-	_ = x[PlusEqual-15]
-	_ = x[Slash-16]
-	_ = x[SlashEqual-17]
-	_ = x[BackSlash-18]
-	_ = x[BackSlashEqual-19]
-	_ = x[Star-20]
-	_ = x[StarEqual-21]
-	_ = x[Caret-22]
-	_ = x[CaretEqual-23]
-	_ = x[Bang-24]
-	_ = x[BangEqual-25]
-	_ = x[Equal-26]
-	_ = x[EqualEqual-27]
-	_ = x[FatArrow-28]
-	_ = x[ThinArrow-29]
-	_ = x[Greater-30]
-	_ = x[GreaterEqual-31]
-	_ = x[Less-32]
-	_ = x[LessEqual-33]
-	_ = x[Modulo-34]
-	_ = x[ModuloEqual-35]
-	_ = x[LeftShift-36]
-	_ = x[LeftShiftEqual-37]
-	_ = x[RightShift-38]
-	_ = x[RightShiftEqual-39]
-	_ = x[Pipe-40]
-	_ = x[PipeEqual-41]
-	_ = x[Ampersand-42]
-	_ = x[AmpersandEqual-43]
-	_ = x[Tilde-44]
-	_ = x[TildeEqual-45]
-	_ = x[Degree-46]
-	_ = x[Fixed-47]
-	_ = x[FixedPoint-48]
-	_ = x[Identifier-49]
-	_ = x[Number-50]
-	_ = x[Radian-51]
-	_ = x[String-52]
-	_ = x[Is-53]
-	_ = x[Isnt-54]
-	_ = x[Alias-55]
-	_ = x[And-56]
-	_ = x[As-57]
-	_ = x[Break-58]
-	_ = x[By-59]
-	_ = x[Const-60]
-	_ = x[Continue-61]
-	_ = x[Every-62]
-	_ = x[Else-63]
-	_ = x[Entity-64]
-	_ = x[Enum-65]
-	_ = x[Env-66]
-	_ = x[False-67]
-	_ = x[Fn-68]
-	_ = x[For-69]
-	_ = x[If-70]
-	_ = x[In-71]
-	_ = x[From-72]
-	_ = x[To-73]
-	_ = x[Let-74]
-	_ = x[Match-75]
-	_ = x[New-76]
-	_ = x[Or-77]
-	_ = x[Pub-78]
-	_ = x[Repeat-79]
-	_ = x[Return-80]
-	_ = x[Self-81]
-	_ = x[Spawn-82]
-	_ = x[Struct-83]
-	_ = x[Class-84]
-	_ = x[Tick-85]
-	_ = x[True-86]
-	_ = x[Use-87]
-	_ = x[While-88]
-	_ = x[With-89]
-	_ = x[Yield-90]
-	_ = x[Destroy-91]
-	_ = x[Eof-92]
+	_ = x[At-1]
+	_ = x[LeftParen-2]
+	_ = x[RightParen-3]
+	_ = x[LeftBrace-4]
+	_ = x[RightBrace-5]
+	_ = x[LeftBracket-6]
+	_ = x[RightBracket-7]
+	_ = x[Comma-8]
+	_ = x[Colon-9]
+	_ = x[Dot-10]
+	_ = x[Concat-11]
+	_ = x[Ellipsis-12]
+	_ = x[Minus-13]
+	_ = x[MinusEqual-14]
+	_ = x[Plus-15]
+	_ = x[PlusEqual-16]
+	_ = x[Slash-17]
+	_ = x[SlashEqual-18]
+	_ = x[BackSlash-19]
+	_ = x[BackSlashEqual-20]
+	_ = x[Star-21]
+	_ = x[StarEqual-22]
+	_ = x[Caret-23]
+	_ = x[CaretEqual-24]
+	_ = x[Bang-25]
+	_ = x[BangEqual-26]
+	_ = x[Equal-27]
+	_ = x[EqualEqual-28]
+	_ = x[FatArrow-29]
+	_ = x[ThinArrow-30]
+	_ = x[Greater-31]
+	_ = x[GreaterEqual-32]
+	_ = x[Less-33]
+	_ = x[LessEqual-34]
+	_ = x[Modulo-35]
+	_ = x[ModuloEqual-36]
+	_ = x[LeftShift-37]
+	_ = x[LeftShiftEqual-38]
+	_ = x[RightShift-39]
+	_ = x[RightShiftEqual-40]
+	_ = x[Pipe-41]
+	_ = x[PipeEqual-42]
+	_ = x[Ampersand-43]
+	_ = x[AmpersandEqual-44]
+	_ = x[Tilde-45]
+	_ = x[TildeEqual-46]
+	_ = x[Question-47]
+	_ = x[QuestionQuestion-48]
+	_ = x[Degree-49]
+	_ = x[Fixed-50]
+	_ = x[FixedPoint-51]
+	_ = x[Identifier-52]
+	_ = x[Number-53]
+	_ = x[Radian-54]
+	_ = x[String-55]
+	_ = x[Is-56]
+	_ = x[Isnt-57]
+	_ = x[Alias-58]
+	_ = x[And-59]
+	_ = x[As-60]
+	_ = x[Break-61]
+	_ = x[By-62]
+	_ = x[Const-63]
+	_ = x[Continue-64]
+	_ = x[Every-65]
+	_ = x[Else-66]
+	_ = x[Entity-67]
+	_ = x[Enum-68]
+	_ = x[Env-69]
+	_ = x[False-70]
+	_ = x[Fn-71]
+	_ = x[Find-72]
+	_ = x[For-73]
+	_ = x[If-74]
+	_ = x[In-75]
+	_ = x[Impl-76]
+	_ = x[Interface-77]
+	_ = x[From-78]
+	_ = x[To-79]
+	_ = x[Let-80]
+	_ = x[Match-81]
+	_ = x[Macro-82]
+	_ = x[New-83]
+	_ = x[Nil-84]
+	_ = x[Or-85]
+	_ = x[Pub-86]
+	_ = x[Remove-87]
+	_ = x[Repeat-88]
+	_ = x[Return-89]
+	_ = x[Self-90]
+	_ = x[Spawn-91]
+	_ = x[Start-92]
+	_ = x[Struct-93]
+	_ = x[Task-94]
+	_ = x[Class-95]
+	_ = x[Tick-96]
+	_ = x[True-97]
+	_ = x[Until-98]
+	_ = x[Use-99]
+	_ = x[Wait-100]
+	_ = x[While-101]
+	_ = x[With-102]
+	_ = x[Yield-103]
+	_ = x[Destroy-104]
+	_ = x[DocComment-105]
+	_ = x[Comment-106]
+	_ = x[Eof-107]
 }
 
-const _TokenType_name = "#(){}[],:......--=++=//=\\\\=**=^^=!!=====>->>>=<<=%%=<<<<=>>>>=||=&&=~~=degreefixedfixedPointidentifiernumberradianstringisisntaliasandasbreakbyconstcontinueeveryelseentityenumenvfalsefnforifinfromtoletmatchneworpubrepeatreturnselfspawnstructclassticktrueusewhilewithyielddestroyEOF (End of File)"
+const _TokenType_name = "#@(){}[],:......--=++=//=\\\\=**=^^=!!=====>->>>=<<=%%=<<<<=>>>>=||=&&=~~=???degreefixedfixedPointidentifiernumberradianstringisisntaliasandasbreakbyconstcontinueeveryelseentityenumenvfalsefnfindforifinimplinterfacefromtoletmatchmacronewnilorpubremoverepeatreturnselfspawnstartstructtaskclassticktrueuntilusewaitwhilewithyielddestroydoc commentcommentEOF (End of File)"
 
-var _TokenType_index = [...]uint16{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 12, 15, 16, 18, 19, 21, 22, 24, 25, 27, 28, 30, 31, 33, 34, 36, 37, 39, 41, 43, 44, 46, 47, 49, 50, 52, 54, 57, 59, 62, 63, 65, 66, 68, 69, 71, 77, 82, 92, 102, 108, 114, 120, 122, 126, 131, 134, 136, 141, 143, 148, 156, 161, 165, 171, 175, 178, 183, 185, 188, 190, 192, 196, 198, 201, 206, 209, 211, 214, 220, 226, 230, 235, 241, 246, 250, 254, 257, 262, 266, 271, 278, 295}
+var _TokenType_index = [...]uint16{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 16, 17, 19, 20, 22, 23, 25, 26, 28, 29, 31, 32, 34, 35, 37, 38, 40, 42, 44, 45, 47, 48, 50, 51, 53, 55, 58, 60, 63, 64, 66, 67, 69, 70, 72, 73, 75, 81, 86, 96, 106, 112, 118, 124, 126, 130, 135, 138, 140, 145, 147, 152, 160, 165, 169, 175, 179, 182, 187, 189, 193, 196, 198, 200, 204, 213, 217, 219, 222, 227, 232, 235, 238, 240, 243, 249, 255, 261, 265, 270, 275, 281, 285, 290, 294, 298, 303, 306, 310, 315, 319, 324, 331, 342, 349, 366}
 
 func (i TokenType) String() string {
 	if i < 0 || i >= TokenType(len(_TokenType_index)-1) {
@@ -37,6 +37,7 @@ class Alert:
     message_format: list[helpers.Format]
     note: str
     note_format: list[helpers.Format]
+    fix: dict | None
     id: int
 
     def __init__(self, raw: dict, stage: str, id: int):
@@ -69,6 +70,16 @@ class Alert:
 
         self.note_format = raw.get("note_format", [])
 
+        self.fix = raw.get("fix")  # None means that the alert cannot be fixed
+        if self.fix is not None:
+            for key in ("title", "where"):
+                if key not in self.fix:
+                    raise ValueError(f"Fix must have a {key}, Raw info: {raw}")
+            if self.fix["where"] not in helpers.FIX_LOCATIONS:
+                raise ValueError(
+                    f"Fix location must be one of {helpers.FIX_LOCATIONS}, Raw info: {raw}"
+                )
+
         self.id = id
 
     def generate(self) -> str:
@@ -102,6 +113,8 @@ class Alert:
             ),
             Function(name="AlertType", returns="Type", code=f"return {self.type}"),
         ]
+        if self.fix is not None:
+            functions.append(self.generate_fix())
 
         return ALERT_TEMPLATE.format_map(
             {
@@ -114,3 +127,24 @@ class Alert:
                 ),
             }
         )
+
+    def generate_fix(self) -> Function:
+        # The fix is only given when the field it requires was set by the one raising the alert
+        code = ""
+        requires = self.fix.get("requires")
+        if requires is not None:
+            code += f"if isZero({self.receiver}.{requires}) {{\n    return Fix{{}}, false\n  }}\n  "
+
+        title = helpers.format_string(
+            self.fix["title"], self.fix.get("title_format", []), self.receiver
+        )
+        insert = helpers.format_string(
+            self.fix.get("insert", ""), self.fix.get("insert_format", []), self.receiver
+        )
+        at = self.fix.get("at", "Specifier")
+        edit = helpers.FIX_LOCATIONS[self.fix["where"]]
+
+        code += f"start, end := spanOf({self.receiver}.{at})\n  "
+        code += f"return Fix{{Title: {title}, Edits: []TextEdit{{{edit}(start, end, {insert})}}}}, true"
+
+        return Function(name="Fix", returns="(Fix, bool)", code=code)
@@ -20,6 +20,15 @@ def to_receiver(original: str) -> str:
 
 type Format = dict[str, str] | str
 
+# Where the text of a fix goes, relative to the tokens it is given, and the Go helper making the edit
+FIX_LOCATIONS = {
+    "before": "insertBefore",
+    "after": "insertAfter",
+    "replace": "replace",
+    "line_before": "insertLineBefore",
+    "lines": "replaceLines",
+}
+
 
 def format_string(string: str, string_format: list[Format], receiver: str) -> str:
     if len(string_format) == 0:
@@ -1,11 +1,11 @@
-_POSSIBLE_PACKAGES = ["fmt", "strings", "hybroid/ast"]
+_POSSIBLE_PACKAGES = ["fmt", "strings", "hybroid/ast", "hybroid/tokens"]
 _imports = set()
 
 
 def update_imports(string: str):
     for package in _POSSIBLE_PACKAGES:
-        package = package[8:] if "hybroid/" in package else package
-        if package in string:
+        name = package[8:] if "hybroid/" in package else package
+        if string == name or f"{name}." in string:
             _imports.add(f'"{package}"')
 
 
@@ -0,0 +1,442 @@
+package vm
+
+import (
+	"math"
+	"math/big"
+	"strings"
+)
+
+var arithmeticEvents = map[string]string{
+	"+": "__add", "-": "__sub", "*": "__mul", "/": "__div", "%": "__mod", "^": "__pow", "//": "__idiv",
+	"&": "__band", "|": "__bor", "~": "__bxor", "<<": "__shl", ">>": "__shr", "..": "__concat",
+}
+
+// the operand of an arithmetic error, for its description
+func operandOf(e expr, left bool) expr {
+	if binary, ok := e.(*binaryExpr); ok {
+		if left {
+			return binary.left
+		}
+		return binary.right
+	}
+	if unary, ok := e.(*unaryExpr); ok {
+		return unary.operand
+	}
+	return nil
+}
+
+func (s *State) binary(op string, a, b Value, e expr) Value {
+	switch op {
+	case "==":
+		return s.equals(a, b)
+	case "~=":
+		return !s.equals(a, b)
+	case "<":
+		return s.lessThan(a, b)
+	case "<=":
+		return s.lessEqual(a, b)
+	case ">":
+		return s.lessThan(b, a)
+	case ">=":
+		return s.lessEqual(b, a)
+	case "..":
+		return s.concat(a, b, e)
+	}
+	return s.Arith(op, a, b, e)
+}
+
+// Performs an arithmetic or bitwise operation, with the metamethods of the operands
+func (s *State) Arith(op string, a, b Value, e expr) Value {
+	if result, ok := arith(op, a, b); ok {
+		return result
+	}
+	if handler := s.metamethod(a, arithmeticEvents[op]); handler != nil {
+		return first(s.call(handler, []Value{a, b}, ""))
+	}
+	if handler := s.metamethod(b, arithmeticEvents[op]); handler != nil {
+		return first(s.call(handler, []Value{a, b}, ""))
+	}
+
+	_, aFixed := a.(Fixed)
+	_, bFixed := b.(Fixed)
+	na, aNumber := toNumber(a)
+	nb, bNumber := toNumber(b)
+	switch {
+	case aFixed != bFixed && aNumber && bNumber:
+		s.Errorf("attempt to perform arithmetic between a fixedpoint and a number (%s %s %s)", TypeName(na), op, TypeName(nb))
+	case (op == "//" || op == "%") && isZero(nb) && aNumber:
+		if aFixed {
+			s.Errorf("attempt to perform 'n%s0' on fixedpoints", op)
+		}
+		s.Errorf("attempt to perform 'n%s0'", op)
+	case op == "/" && aFixed && isZero(nb):
+		s.Errorf("attempt to divide a fixedpoint by zero")
+	case strings.Contains("&|~<<>>", op) && aNumber && bNumber:
+		s.Errorf("number has no integer representation")
+	case !aNumber:
+		s.Errorf("attempt to perform arithmetic on a %s value%s", TypeName(a), describe(operandOf(e, true)))
+	}
+	s.Errorf("attempt to perform arithmetic on a %s value%s", TypeName(b), describe(operandOf(e, false)))
+	return nil
+}
+
+func isZero(v Value) bool {
+	switch v := v.(type) {
+	case int64:
+		return v == 0
+	case Fixed:
+		return v == 0
+	}
+	return false
+}
+
+func arith(op string, a, b Value) (Value, bool) {
+	// the most common case comes first
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok {
+			return arithInteger(op, x, y)
+		}
+	}
+	if x, ok := a.(Fixed); ok {
+		if y, ok := b.(Fixed); ok {
+			return arithFixed(op, x, y)
+		}
+		return nil, false
+	}
+	if _, ok := b.(Fixed); ok {
+		return nil, false
+	}
+
+	na, ok1 := toNumber(a)
+	nb, ok2 := toNumber(b)
+	if !ok1 || !ok2 {
+		return nil, false
+	}
+	if x, ok := na.(int64); ok {
+		if y, ok := nb.(int64); ok {
+			return arithInteger(op, x, y)
+		}
+	}
+	switch op {
+	case "&", "|", "~", "<<", ">>":
+		x, ok1 := toInteger(na)
+		y, ok2 := toInteger(nb)
+		if !ok1 || !ok2 {
+			return nil, false
+		}
+		return arithInteger(op, x, y)
+	}
+	x, _ := toFloat(na)
+	y, _ := toFloat(nb)
+	return arithFloat(op, x, y), true
+}
+
+func arithInteger(op string, a, b int64) (Value, bool) {
+	switch op {
+	case "+":
+		return a + b, true
+	case "-":
+		return a - b, true
+	case "*":
+		return a * b, true
+	case "/":
+		return float64(a) / float64(b), true
+	case "%":
+		if b == 0 {
+			return nil, false
+		}
+		if b == -1 {
+			return int64(0), true
+		}
+		r := a % b
+		if r != 0 && (r^b) < 0 {
+			r += b
+		}
+		return r, true
+	case "//":
+		if b == 0 {
+			return nil, false
+		}
+		if b == -1 {
+			return -a, true
+		}
+		q := a / b
+		if (a%b != 0) && ((a < 0) != (b < 0)) {
+			q--
+		}
+		return q, true
+	case "^":
+		return math.Pow(float64(a), float64(b)), true
+	case "&":
+		return a & b, true
+	case "|":
+		return a | b, true
+	case "~":
+		return a ^ b, true
+	case "<<":
+		return shiftLeft(a, b), true
+	case ">>":
+		return shiftLeft(a, -b), true
+	}
+	return nil, false
+}
+
+func shiftLeft(a, b int64) int64 {
+	switch {
+	case b <= -64 || b >= 64:
+		return 0
+	case b >= 0:
+		return int64(uint64(a) << b)
+	}
+	return int64(uint64(a) >> -b)
+}
+
+func arithFloat(op string, a, b float64) Value {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		return a / b
+	case "%":
+		if math.IsInf(b, 0) && !math.IsInf(a, 0) {
+			if a >= 0 == (b > 0) {
+				return a
+			}
+			return b
+		}
+		r := math.Mod(a, b)
+		if r != 0 && (r < 0) != (b < 0) {
+			r += b
+		}
+		return r
+	case "//":
+		return math.Floor(a / b)
+	case "^":
+		return math.Pow(a, b)
+	}
+	return nil
+}
+
+var (
+	minFixed = big.NewInt(math.MinInt64)
+	maxFixed = big.NewInt(math.MaxInt64)
+)
+
+// Fixedpoints are 64-bit integers counting 1/4096ths: a product is shifted back by 12 bits
+// and a quotient is truncated towards zero, like the ones of PewPew Live
+func arithFixed(op string, a, b Fixed) (Value, bool) {
+	switch op {
+	case "+":
+		return a + b, true
+	case "-":
+		return a - b, true
+	case "*":
+		if a > -(1<<31) && a < 1<<31 && b > -(1<<31) && b < 1<<31 {
+			return (a * b) >> 12, true
+		}
+		result := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(b)))
+		return clampFixed(result.Rsh(result, 12)), true
+	case "/":
+		if b == 0 {
+			return nil, false
+		}
+		if a > -(1<<50) && a < 1<<50 {
+			return (a << 12) / b, true
+		}
+		result := new(big.Int).Lsh(big.NewInt(int64(a)), 12)
+		return clampFixed(result.Quo(result, big.NewInt(int64(b)))), true
+	case "%":
+		if b == 0 {
+			return nil, false
+		}
+		r := a % b
+		if r != 0 && (r < 0) != (b < 0) {
+			r += b
+		}
+		return r, true
+	case "//":
+		if b == 0 {
+			return nil, false
+		}
+		q := a / b
+		if (a%b != 0) && ((a < 0) != (b < 0)) {
+			q--
+		}
+		return q * FixedOne, true
+	case "^":
+		return FixedFromFloat(math.Pow(a.Float(), b.Float())), true
+	}
+	return nil, false
+}
+
+// Wraps the result around like a 64-bit integer would
+func clampFixed(result *big.Int) Fixed {
+	if result.Cmp(minFixed) >= 0 && result.Cmp(maxFixed) <= 0 {
+		return Fixed(result.Int64())
+	}
+	return Fixed(int64(result.Uint64()))
+}
+
+func (s *State) unary(op string, v Value, e expr) Value {
+	switch op {
+	case "not":
+		return !Truthy(v)
+	case "-":
+		switch n := v.(type) {
+		case int64:
+			return -n
+		case float64:
+			return -n
+		case Fixed:
+			return -n
+		}
+		if n, ok := toNumber(v); ok {
+			return s.unary(op, n, e)
+		}
+		if handler := s.metamethod(v, "__unm"); handler != nil {
+			return first(s.call(handler, []Value{v, v}, ""))
+		}
+		s.Errorf("attempt to perform arithmetic on a %s value%s", TypeName(v), describe(operandOf(e, true)))
+	case "#":
+		if text, ok := v.(string); ok {
+			return int64(len(text))
+		}
+		if handler := s.metamethod(v, "__len"); handler != nil {
+			return first(s.call(handler, []Value{v}, ""))
+		}
+		if t, ok := v.(*Table); ok {
+			return t.Len()
+		}
+		s.Errorf("attempt to get length of a %s value%s", TypeName(v), describe(operandOf(e, true)))
+	case "~":
+		if n, ok := toInteger(v); ok {
+			return ^n
+		}
+		if handler := s.metamethod(v, "__bnot"); handler != nil {
+			return first(s.call(handler, []Value{v, v}, ""))
+		}
+		s.Errorf("attempt to perform bitwise operation on a %s value%s", TypeName(v), describe(operandOf(e, true)))
+	}
+	return nil
+}
+
+func (s *State) concat(a, b Value, e expr) Value {
+	x, ok1 := concatOperand(a)
+	y, ok2 := concatOperand(b)
+	if ok1 && ok2 {
+		return x + y
+	}
+	if handler := s.metamethod(a, "__concat"); handler != nil {
+		return first(s.call(handler, []Value{a, b}, ""))
+	}
+	if handler := s.metamethod(b, "__concat"); handler != nil {
+		return first(s.call(handler, []Value{a, b}, ""))
+	}
+	if !ok1 {
+		s.Errorf("attempt to concatenate a %s value%s", TypeName(a), describe(operandOf(e, true)))
+	}
+	s.Errorf("attempt to concatenate a %s value%s", TypeName(b), describe(operandOf(e, false)))
+	return nil
+}
+
+func concatOperand(v Value) (string, bool) {
+	switch v.(type) {
+	case string, int64, float64, Fixed:
+		return ToString(v), true
+	}
+	return "", false
+}
+
+// Compares two values like ==, with the __eq metamethod of tables
+func (s *State) equals(a, b Value) bool {
+	if RawEquals(a, b) {
+		return true
+	}
+	ta, ok1 := a.(*Table)
+	tb, ok2 := b.(*Table)
+	if !ok1 || !ok2 {
+		return false
+	}
+	handler := s.metamethod(ta, "__eq")
+	if handler == nil {
+		handler = s.metamethod(tb, "__eq")
+	}
+	if handler == nil {
+		return false
+	}
+	return Truthy(first(s.call(handler, []Value{a, b}, "")))
+}
+
+func RawEquals(a, b Value) bool {
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(float64); ok {
+			return float64(x) == y
+		}
+	case float64:
+		if y, ok := b.(int64); ok {
+			return x == float64(y)
+		}
+	}
+	return a == b
+}
+
+func (s *State) lessThan(a, b Value) bool {
+	if result, ok := compare(a, b, false); ok {
+		return result
+	}
+	return s.compareMeta("__lt", a, b)
+}
+
+func (s *State) lessEqual(a, b Value) bool {
+	if result, ok := compare(a, b, true); ok {
+		return result
+	}
+	return s.compareMeta("__le", a, b)
+}
+
+func (s *State) compareMeta(event string, a, b Value) bool {
+	handler := s.metamethod(a, event)
+	if handler == nil {
+		handler = s.metamethod(b, event)
+	}
+	if handler == nil {
+		if TypeName(a) == TypeName(b) {
+			s.Errorf("attempt to compare two %s values", TypeName(a))
+		}
+		s.Errorf("attempt to compare %s with %s", TypeName(a), TypeName(b))
+	}
+	return Truthy(first(s.call(handler, []Value{a, b}, "")))
+}
+
+func compare(a, b Value, orEqual bool) (bool, bool) {
+	switch x := a.(type) {
+	case int64:
+		switch y := b.(type) {
+		case int64:
+			return x < y || orEqual && x == y, true
+		case float64:
+			return float64(x) < y || orEqual && float64(x) == y, true
+		}
+	case float64:
+		switch y := b.(type) {
+		case int64:
+			return x < float64(y) || orEqual && x == float64(y), true
+		case float64:
+			return x < y || orEqual && x == y, true
+		}
+	case Fixed:
+		if y, ok := b.(Fixed); ok {
+			return x < y || orEqual && x == y, true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return x < y || orEqual && x == y, true
+		}
+	}
+	return false, false
+}
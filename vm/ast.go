@@ -0,0 +1,139 @@
+package vm
+
+// The expressions and statements of a parsed chunk. Names are resolved while parsing: locals
+// become slots of the frame of their function and upvalues indices into the cells of the closure
+
+type expr interface{}
+
+type (
+	constantExpr struct{ value Value }
+	varargExpr   struct{}
+	localExpr    struct {
+		slot int
+		name string
+	}
+	upvalueExpr struct {
+		index int
+		name  string
+	}
+	globalExpr struct{ name string }
+	indexExpr  struct {
+		object, key expr
+		line        int
+	}
+	callExpr struct {
+		function expr
+		args     []expr
+		line     int
+	}
+	methodCallExpr struct {
+		object expr
+		name   string
+		args   []expr
+		line   int
+	}
+	functionExpr struct{ proto *funcProto }
+	binaryExpr   struct {
+		op          string
+		left, right expr
+		line        int
+	}
+	logicalExpr struct {
+		and         bool
+		left, right expr
+	}
+	unaryExpr struct {
+		op      string
+		operand expr
+		line    int
+	}
+	tableExpr struct {
+		fields []tableField
+		line   int
+	}
+	// parentheses, which truncate the values of calls and varargs to one
+	parenExpr struct{ inner expr }
+)
+
+type tableField struct {
+	// nil for positional fields
+	key   expr
+	value expr
+}
+
+type stmt interface{}
+
+type (
+	localStmt struct {
+		slots []int
+		exprs []expr
+	}
+	assignStmt struct {
+		targets []expr
+		exprs   []expr
+		line    int
+	}
+	callStmt  struct{ call expr }
+	doStmt    struct{ body *block }
+	whileStmt struct {
+		cond expr
+		body *block
+		line int
+	}
+	repeatStmt struct {
+		body *block
+		cond expr
+	}
+	ifStmt struct {
+		conds  []expr
+		blocks []*block
+		// nil without an else branch
+		elseBlock *block
+	}
+	numericForStmt struct {
+		slot               int
+		start, limit, step expr
+		body               *block
+		line               int
+	}
+	genericForStmt struct {
+		slots []int
+		exprs []expr
+		body  *block
+		line  int
+	}
+	localFunctionStmt struct {
+		slot  int
+		proto *funcProto
+	}
+	returnStmt struct{ exprs []expr }
+	breakStmt  struct{}
+	gotoStmt   struct {
+		label string
+		line  int
+	}
+)
+
+type block struct {
+	stmts []stmt
+	// the index of the statement following each label
+	labels map[string]int
+}
+
+type funcProto struct {
+	name     string
+	chunk    string
+	line     int
+	params   []int
+	isVararg bool
+	slots    int
+	upvals   []upvalueDesc
+	body     *block
+}
+
+// Where a closure finds an upvalue when it is created: a local of the enclosing function, or
+// one of its upvalues
+type upvalueDesc struct {
+	fromLocal bool
+	index     int
+}
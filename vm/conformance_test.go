@@ -0,0 +1,355 @@
+package vm
+
+import "testing"
+
+// Checks the chunks give the results of the reference Lua 5.4, each failing check naming itself.
+// PewPew runs Lua 5.4 with fixedpoint numbers, which is what the generated code relies on
+const conformancePrelude = `
+local function eq(got, want, what)
+	if got ~= want or math.type(got) ~= math.type(want) then
+		error(what .. ": got " .. tostring(got) .. " (" .. type(got) .. "), want " .. tostring(want), 2)
+	end
+end
+local function fails(f, what)
+	if pcall(f) then
+		error(what .. ": expected an error", 2)
+	end
+end
+`
+
+func TestConformance(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"integer arithmetic", `
+			eq(1 + 2, 3, "add")
+			eq(5 - 8, -3, "sub")
+			eq(6 * 7, 42, "mul")
+			eq(7 // 2, 3, "floor division")
+			eq(-7 // 2, -4, "negative floor division")
+			eq(7 % 3, 1, "mod")
+			eq(-7 % 3, 2, "negative mod")
+			eq(7 % -3, -2, "mod by a negative")
+			eq(-(-3), 3, "double negation")
+			eq(math.maxinteger + 1, math.mininteger, "overflow wraps")
+			eq(2 ^ 2, 4.0, "power is a float")
+			eq(7 / 7, 1.0, "division is a float")
+			fails(function() return 1 // 0 end, "integer division by zero")
+			fails(function() return 1 % 0 end, "integer mod by zero")
+		`},
+		{"float arithmetic", `
+			eq(1.5 + 1, 2.5, "mixed add")
+			eq(7.0 // 2, 3.0, "float floor division")
+			eq(-7.5 % 2, 0.5, "float mod")
+			eq(5.5 % -2, -0.5, "float mod by a negative")
+			eq(1 / 0, math.huge, "division by zero")
+			eq(-1 / 0, -math.huge, "negative division by zero")
+			local nan = 0 / 0
+			eq(nan ~= nan, true, "nan is not itself")
+			eq(3 == 3.0, true, "integer equals float")
+			eq(math.type(3), "integer", "integer type")
+			eq(math.type(3.0), "float", "float type")
+			eq(math.type("3"), nil, "string type")
+			eq(0x10, 16, "hexadecimal")
+			eq(1e2, 100.0, "exponent")
+		`},
+		{"comparisons", `
+			eq(1 < 2, true, "lt")
+			eq(2 <= 2, true, "le")
+			eq(1 < 1.5, true, "integer below a float")
+			eq("a" < "b", true, "string lt")
+			eq("Z" < "a", true, "byte order")
+			eq("ab" < "abc", true, "prefix")
+			eq(1 == "1", false, "no coercion in equality")
+			fails(function() return 1 < "2" end, "number compared to a string")
+			fails(function() return {} < {} end, "tables compared without __lt")
+		`},
+		{"logic", `
+			eq(nil or 1, 1, "or")
+			eq(false and 1, false, "and")
+			eq(nil and 1, nil, "and with nil")
+			eq(0 and "zero", "zero", "zero is true")
+			eq("" and "empty", "empty", "the empty string is true")
+			eq(not nil, true, "not nil")
+			eq(1 and nil or 2, 2, "ternary with a falsy value")
+		`},
+		{"coercions", `
+			eq("10" + 1, 11, "string to integer")
+			eq("1.5" * 2, 3.0, "string to float")
+			eq(10 .. "", "10", "integer to string")
+			eq(1.5 .. "", "1.5", "float to string")
+			eq(2.0 .. "", "2.0", "integral float to string")
+			eq(tonumber("0x1F"), 31, "hexadecimal string")
+			eq(tonumber("  12  "), 12, "spaces around")
+			eq(tonumber("1e1"), 10.0, "exponent string")
+			eq(tonumber("z", 36), 35, "base")
+			eq(tonumber("12a"), nil, "not a number")
+			eq(tostring(-0.0), "-0.0", "negative zero")
+			eq(tostring(1e100), "1e+100", "large float")
+			eq(math.tointeger(3.0), 3, "integral float to integer")
+			eq(math.tointeger(3.5), nil, "fraction to integer")
+		`},
+		{"math", `
+			eq(math.floor(-1.5), -2, "floor")
+			eq(math.ceil(1.2), 2, "ceil")
+			eq(math.abs(-4), 4, "abs")
+			eq(math.max(3, 9, 1), 9, "max")
+			eq(math.min(3, 9, 1), 1, "min")
+			eq(math.fmod(-7, 3), -1, "fmod")
+			eq(math.sqrt(16), 4.0, "sqrt")
+			local i, f = math.modf(3.25)
+			eq(i, 3, "modf integral part")
+			eq(f, 0.25, "modf fraction")
+			eq(math.ult(1, -1), true, "unsigned lt")
+			for _ = 1, 20 do
+				local r = math.random(3, 5)
+				eq(r >= 3 and r <= 5, true, "random in its range")
+			end
+		`},
+		{"fixedpoint", `
+			-- the decimals of a literal count 4096ths, so 1.2048fx is one and a half
+			eq(1.2048fx + 1fx, 2.2048fx, "add")
+			eq(2fx * 3fx, 6fx, "mul")
+			eq(1fx / 4fx, 0.1024fx, "div")
+			eq(-3fx // 2fx, -2fx, "floor division")
+			eq(5fx % 3fx, 2fx, "mod")
+			eq(1fx < 2fx, true, "lt")
+			eq(tostring(0.2048fx), "0.5", "tostring")
+			fails(function() return 1fx + 1 end, "fixedpoint added to a number")
+		`},
+		{"strings", `
+			eq(#"hello", 5, "length")
+			eq(("hello"):sub(2, 3), "el", "sub")
+			eq(("hello"):sub(-3), "llo", "negative sub")
+			eq(("hello"):sub(4, 100), "lo", "sub past the end")
+			eq(("hello"):sub(0), "hello", "sub from zero")
+			eq(("Hello"):upper(), "HELLO", "upper")
+			eq(("Hello"):lower(), "hello", "lower")
+			eq(("ab"):rep(3), "ababab", "rep")
+			eq(("ab"):rep(0), "", "rep zero times")
+			eq(("abc"):reverse(), "cba", "reverse")
+			eq(("A"):byte(), 65, "byte")
+			local a, b = ("hi"):byte(1, 2)
+			eq(b, 105, "bytes")
+			eq(string.char(72, 105), "Hi", "char")
+			eq(string.len("\0a"), 2, "embedded zero")
+			eq("a\tb\\n", "a" .. "\t" .. "b\\" .. "n", "escapes")
+			eq("\65\x42\u{43}", "ABC", "numeric escapes")
+			eq([[
+long]], "long", "long string skips the first newline")
+		`},
+		{"string formatting", `
+			eq(string.format("%d", 42), "42", "integer")
+			eq(string.format("%5d|%-5d|", 1, 2), "    1|2    |", "widths")
+			eq(string.format("%05.1f", 3.14159), "003.1", "float")
+			eq(string.format("%x %X", 255, 255), "ff FF", "hexadecimal")
+			eq(string.format("%s %s", true, nil), "true nil", "tostring of the arguments")
+			eq(string.format("%q", 'a"b'), '"a\\"b"', "quoted")
+			eq(string.format("%%"), "%", "percent")
+			eq(string.format("%g", 1e20), "1e+20", "general")
+			eq(string.format("%c", 65), "A", "char")
+			fails(function() return string.format("%d", 1.5) end, "fraction as an integer")
+		`},
+		{"patterns", `
+			eq(("hello world"):find("o w"), 5, "find")
+			local s, e = ("hello"):find("l+")
+			eq(e, 4, "find end")
+			eq(("a.b"):find(".", 1, true), 2, "plain find")
+			eq(("abc"):find("x"), nil, "not found")
+			eq(("abc"):find("", 10), nil, "init past the end")
+			eq(("key = value"):match("(%w+)%s*=%s*(%w+)"), "key", "captures")
+			local k, v = ("key = value"):match("(%w+)%s*=%s*(%w+)")
+			eq(v, "value", "second capture")
+			eq(("hello"):match("()ll()"), 3, "position capture")
+			eq(("  trim  "):match("^%s*(.-)%s*$"), "trim", "lazy")
+			eq(("[x]"):match("%[(.)%]"), "x", "escaped")
+			eq(("f(a(b)c)"):match("%b()"), "(a(b)c)", "balanced")
+			eq(("THE (quick) fox"):find("%f[%a]%a+%f[%A]", 5), 6, "frontier")
+			eq(("abc123"):match("[%a]+"), "abc", "class set")
+			eq(("abc123"):match("[^%a]+"), "123", "negated set")
+			eq(("x-y"):match("[a-z]%-[a-z]"), "x-y", "range")
+			eq(("aaa"):match("a-b"), nil, "lazy without a match")
+			eq(("hello hello"):match("(h%a+) %1"), "hello", "back reference")
+		`},
+		{"gsub", `
+			local r, n = ("hello world"):gsub("o", "0")
+			eq(r, "hell0 w0rld", "replace")
+			eq(n, 2, "count")
+			eq((("abc"):gsub("%w", "%0%0")), "aabbcc", "whole match")
+			eq((("hello world"):gsub("(%w+) (%w+)", "%2 %1")), "world hello", "swap")
+			eq((("abc"):gsub("", "-")), "-a-b-c-", "empty pattern")
+			eq((("$name is $age"):gsub("%$(%w+)", {name = "Bob", age = 3})), "Bob is 3", "table")
+			eq((("1 2 3"):gsub("%d", function(d) return d * 2 end)), "2 4 6", "function")
+			eq((("keep"):gsub("%w+", function() return nil end)), "keep", "nil keeps the match")
+			eq((("aaa"):gsub("a", "b", 2)), "bba", "limit")
+			local words = {}
+			for k, v in ("a=1, b=2"):gmatch("(%w+)=(%w+)") do
+				words[#words + 1] = k .. v
+			end
+			eq(table.concat(words, " "), "a1 b2", "gmatch captures")
+		`},
+		{"tables", `
+			local t = {10, 20, 30, nil}
+			eq(#t, 3, "length")
+			t[#t + 1] = 40
+			eq(t[4], 40, "append")
+			local m = {x = 1, ["y z"] = 2, [1.0] = "one", [true] = "yes"}
+			eq(m.x, 1, "field")
+			eq(m["y z"], 2, "string key")
+			eq(m[1], "one", "integral float key is an integer")
+			eq(m[true], "yes", "boolean key")
+			fails(function() m[nil] = 1 end, "nil key")
+			fails(function() m[0 / 0] = 1 end, "nan key")
+			local keys = 0
+			for k in pairs(m) do keys = keys + 1 end
+			eq(keys, 4, "pairs")
+			m.x = nil
+			eq(next({}), nil, "next of an empty table")
+			local ordered = {}
+			for i, v in ipairs({1, 2, nil, 4}) do ordered[#ordered + 1] = v end
+			eq(#ordered, 2, "ipairs stops at nil")
+			local nested = {a = {b = {c = "deep"}}}
+			eq(nested.a.b.c, "deep", "nested")
+			local same = {}
+			eq(({[same] = 1})[same], 1, "table key")
+			eq(rawlen({1, 2}), 2, "rawlen")
+			eq(rawequal(same, same), true, "rawequal")
+			eq(select(-1, 1, 2, 3), 3, "negative select")
+		`},
+		{"table library", `
+			local t = {1, 2, 3}
+			table.insert(t, 4)
+			table.insert(t, 1, 0)
+			eq(table.concat(t, ","), "0,1,2,3,4", "insert")
+			eq(table.remove(t), 4, "remove the last")
+			eq(table.remove(t, 1), 0, "remove the first")
+			eq(table.concat(t, ","), "1,2,3", "after removing")
+			eq(table.concat({}, ","), "", "concat of nothing")
+			eq(table.concat({1, 2, 3}, "-", 2, 3), "2-3", "concat of a range")
+			fails(function() table.concat({{}}) end, "concat of a table")
+			fails(function() table.insert(t, 10, 1) end, "insert out of bounds")
+			local a, b, c = table.unpack({1, 2, 3})
+			eq(c, 3, "unpack")
+			eq(select("#", table.unpack({1, nil, 3}, 1, 3)), 3, "unpack a range")
+			local packed = table.pack(1, nil, 3)
+			eq(packed.n, 3, "pack")
+			local s = {"b", "c", "a"}
+			table.sort(s)
+			eq(table.concat(s), "abc", "sort")
+			local n = {3, 1, 2, 5, 4, 9, 8, 7, 6, 10}
+			table.sort(n, function(x, y) return x > y end)
+			eq(table.concat(n, " "), "10 9 8 7 6 5 4 3 2 1", "sort with a comparison")
+			fails(function() table.sort({1, "a"}) end, "sort of mixed values")
+		`},
+		{"metatables", `
+			local V = {}
+			V.__index = V
+			V.__add = function(a, b) return setmetatable({x = a.x + b.x}, V) end
+			V.__eq = function(a, b) return a.x == b.x end
+			V.__lt = function(a, b) return a.x < b.x end
+			V.__le = function(a, b) return a.x <= b.x end
+			V.__len = function(a) return a.x end
+			V.__concat = function(a, b) return "v" .. (type(a) == "table" and a.x or a) .. (type(b) == "table" and b.x or b) end
+			V.__call = function(self, y) return self.x + y end
+			V.__tostring = function(self) return "V(" .. self.x .. ")" end
+			V.__unm = function(a) return setmetatable({x = -a.x}, V) end
+			function V:double() return self.x * 2 end
+			local function new(x) return setmetatable({x = x}, V) end
+			local one, two = new(1), new(2)
+			eq((one + two).x, 3, "__add")
+			eq(new(1) == new(1), true, "__eq")
+			eq(one < two, true, "__lt")
+			eq(two <= one, false, "__le")
+			eq(#two, 2, "__len")
+			eq(one .. "!", "v1!", "__concat")
+			eq(one(5), 6, "__call")
+			eq(tostring(two), "V(2)", "__tostring")
+			eq((-two).x, -2, "__unm")
+			eq(two:double(), 4, "method from __index")
+			eq(getmetatable(one), V, "getmetatable")
+			local log = {}
+			local proxy = setmetatable({}, {__newindex = function(t, k, v) log[#log + 1] = k rawset(t, k, v) end})
+			proxy.a = 1
+			proxy.a = 2
+			eq(#log, 1, "__newindex only for new keys")
+			eq(rawget(setmetatable({}, {__index = function() return 1 end}), "k"), nil, "rawget skips __index")
+			local base = {greet = "hi"}
+			local child = setmetatable({}, {__index = setmetatable({}, {__index = base})})
+			eq(child.greet, "hi", "__index chain")
+			eq(getmetatable(setmetatable({}, {__metatable = "locked"})), "locked", "__metatable")
+		`},
+		{"functions", `
+			local function fib(n) if n < 2 then return n end return fib(n - 1) + fib(n - 2) end
+			eq(fib(20), 6765, "recursion")
+			local function counter()
+				local count = 0
+				return function() count = count + 1 return count end
+			end
+			local c1, c2 = counter(), counter()
+			c1() c1()
+			eq(c1(), 3, "upvalue")
+			eq(c2(), 1, "upvalues are separate")
+			local function many() return 1, 2, 3 end
+			eq(select("#", many()), 3, "multiple returns")
+			eq(select("#", (many())), 1, "parentheses truncate")
+			eq(select("#", many(), 10), 2, "only the last expands")
+			local t = {many(), many()}
+			eq(#t, 4, "in a table constructor")
+			local function sum(...)
+				local total = 0
+				for _, v in ipairs({...}) do total = total + v end
+				return total
+			end
+			eq(sum(1, 2, 3), 6, "varargs")
+			local function deep(n) if n == 0 then return "done" end return deep(n - 1) end
+			eq(deep(150), "done", "nested calls")
+			fails(function() local function forever() return 1 + forever() end forever() end, "stack overflow")
+			eq(select(2, pcall(error, "plain", 0)), "plain", "error without a position")
+			local ok, err = pcall(error, {code = 7})
+			eq(err.code, 7, "error with a table")
+			eq(select(2, xpcall(function() error("x", 0) end, function(m) return "handled " .. m end)), "handled x", "xpcall")
+		`},
+		{"control flow", `
+			local s = 0
+			for i = 10, 1, -2 do s = s + i end
+			eq(s, 30, "numeric for with a step")
+			local steps = 0
+			for x = 1, 2, 0.5 do steps = steps + 1 end
+			eq(steps, 3, "float step")
+			for i = 1, 0 do error("runs an empty range") end
+			local i = 0
+			while true do
+				i = i + 1
+				if i == 5 then break end
+			end
+			eq(i, 5, "break")
+			local n = 0
+			repeat local stop = n >= 2 n = n + 1 until stop
+			eq(n, 3, "until sees the locals of the body")
+			local x = 1
+			do local x = 2 end
+			eq(x, 1, "do blocks scope their locals")
+			local grade = 75
+			local letter
+			if grade >= 90 then letter = "A" elseif grade >= 70 then letter = "C" else letter = "F" end
+			eq(letter, "C", "elseif")
+			for j = 1, 3 do
+				for k = 1, 3 do
+					if k == 2 then goto next end
+				end
+				::next::
+			end
+			local a, b = 1, 2
+			a, b = b, a
+			eq(a * 10 + b, 21, "swap")
+		`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewState().DoString(test.name, conformancePrelude+test.src); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package vm
+
+// A Lua coroutine. Its function runs on a goroutine of its own, but only one goroutine of a
+// State runs at a time: resuming hands over to the coroutine and waits until it yields or ends
+type Coroutine struct {
+	fn      *Function
+	status  string
+	started bool
+	// the stack of the coroutine, swapped with the one of the State while it runs
+	frames []*frame
+	resume chan []Value
+	yield  chan transfer
+}
+
+// What a coroutine gives back to the code resuming it
+type transfer struct {
+	values []Value
+	err    error
+	done   bool
+}
+
+func newCoroutine(fn *Function) *Coroutine {
+	return &Coroutine{
+		fn:     fn,
+		status: "suspended",
+		resume: make(chan []Value),
+		yield:  make(chan transfer),
+	}
+}
+
+func (s *State) resumeCoroutine(co *Coroutine, args []Value) (bool, []Value) {
+	if co.status == "dead" {
+		return false, []Value{"cannot resume dead coroutine"}
+	}
+	if co.status != "suspended" {
+		return false, []Value{"cannot resume non-suspended coroutine"}
+	}
+
+	previous := s.running
+	if previous != nil {
+		previous.status = "normal"
+	}
+	frames := s.frames
+	s.frames = co.frames
+	s.running = co
+	co.status = "running"
+
+	if !co.started {
+		co.started = true
+		go func() {
+			rets, err := s.PCall(co.fn, args...)
+			co.yield <- transfer{values: rets, err: err, done: true}
+		}()
+	} else {
+		co.resume <- args
+	}
+	result := <-co.yield
+
+	co.frames = s.frames
+	s.frames = frames
+	s.running = previous
+	if previous != nil {
+		previous.status = "running"
+	}
+	if result.done {
+		co.status = "dead"
+		co.frames = nil
+	} else {
+		co.status = "suspended"
+	}
+	if result.err != nil {
+		return false, []Value{result.err.(*Error).Value}
+	}
+	return true, result.values
+}
+
+func (s *State) yieldCoroutine(values []Value) []Value {
+	co := s.running
+	if co == nil {
+		s.Errorf("attempt to yield from outside a coroutine")
+	}
+	co.yield <- transfer{values: values}
+	return <-co.resume
+}
+
+func openCoroutine(s *State) {
+	c := NewTable()
+	s.SetGlobal("coroutine", c)
+	register(c, map[string]func(s *State, args []Value) []Value{
+		"create": func(s *State, args []Value) []Value {
+			return []Value{newCoroutine(s.CheckFunction(args, 0, "create"))}
+		},
+		"resume": func(s *State, args []Value) []Value {
+			co := s.checkCoroutine(args, 0, "resume")
+			ok, values := s.resumeCoroutine(co, args[1:])
+			return append([]Value{ok}, values...)
+		},
+		"yield": func(s *State, args []Value) []Value {
+			return s.yieldCoroutine(args)
+		},
+		"status": func(s *State, args []Value) []Value {
+			return []Value{s.checkCoroutine(args, 0, "status").status}
+		},
+		"running": func(s *State, args []Value) []Value {
+			if s.running == nil {
+				return []Value{nil, true}
+			}
+			return []Value{s.running, false}
+		},
+		"isyieldable": func(s *State, args []Value) []Value {
+			return []Value{s.running != nil}
+		},
+		"wrap": func(s *State, args []Value) []Value {
+			co := newCoroutine(s.CheckFunction(args, 0, "wrap"))
+			return []Value{NewFunction("wrap", func(s *State, args []Value) []Value {
+				ok, values := s.resumeCoroutine(co, args)
+				if !ok {
+					s.raise(first(values))
+				}
+				return values
+			})}
+		},
+	})
+}
+
+func (s *State) checkCoroutine(args []Value, i int, name string) *Coroutine {
+	co, ok := Arg(args, i).(*Coroutine)
+	if !ok {
+		s.typeError(args, i, name, "coroutine")
+	}
+	return co
+}
@@ -0,0 +1,475 @@
+package vm
+
+import "fmt"
+
+type flow int
+
+const (
+	flowNormal flow = iota
+	flowBreak
+	flowReturn
+	flowGoto
+)
+
+func (s *State) execBlock(f *frame, b *block) flow {
+	// the block itself counts, so that the empty bodies of loops are limited too
+	s.steps += int64(len(b.stmts)) + 1
+	if s.StepLimit > 0 && s.steps > s.StepLimit {
+		s.Errorf("the script ran for more than %d steps, it likely loops forever", s.StepLimit)
+	}
+	for i := 0; i < len(b.stmts); i++ {
+		result := s.exec(f, b.stmts[i])
+		if result == flowNormal {
+			continue
+		}
+		if result == flowGoto {
+			if index, ok := b.labels[f.gotoLabel]; ok {
+				i = index - 1
+				continue
+			}
+		}
+		return result
+	}
+	return flowNormal
+}
+
+// Runs the body of a loop, telling whether the loop goes on and the flow to leave it with otherwise
+func (s *State) loopBody(f *frame, b *block) (bool, flow) {
+	switch result := s.execBlock(f, b); result {
+	case flowNormal:
+		return true, flowNormal
+	case flowBreak:
+		return false, flowNormal
+	default:
+		return false, result
+	}
+}
+
+func (s *State) exec(f *frame, statement stmt) flow {
+	switch st := statement.(type) {
+	case *localStmt:
+		if len(st.slots) == 1 && len(st.exprs) == 1 {
+			f.locals[st.slots[0]] = &cell{s.eval(f, st.exprs[0])}
+			return flowNormal
+		}
+		values := s.evalList(f, st.exprs)
+		for i, slot := range st.slots {
+			var value Value
+			if i < len(values) {
+				value = values[i]
+			}
+			f.locals[slot] = &cell{value}
+		}
+	case *assignStmt:
+		f.line = st.line
+		if len(st.targets) == 1 && len(st.exprs) == 1 {
+			s.assign(f, st.targets[0], s.eval(f, st.exprs[0]))
+			return flowNormal
+		}
+		values := s.evalList(f, st.exprs)
+		for i, target := range st.targets {
+			var value Value
+			if i < len(values) {
+				value = values[i]
+			}
+			s.assign(f, target, value)
+		}
+	case *callStmt:
+		s.evalMulti(f, st.call)
+	case *doStmt:
+		return s.execBlock(f, st.body)
+	case *whileStmt:
+		for Truthy(s.eval(f, st.cond)) {
+			f.line = st.line
+			if goOn, result := s.loopBody(f, st.body); !goOn {
+				return result
+			}
+		}
+	case *repeatStmt:
+		for {
+			if goOn, result := s.loopBody(f, st.body); !goOn {
+				return result
+			}
+			if Truthy(s.eval(f, st.cond)) {
+				break
+			}
+		}
+	case *ifStmt:
+		for i, cond := range st.conds {
+			if Truthy(s.eval(f, cond)) {
+				return s.execBlock(f, st.blocks[i])
+			}
+		}
+		if st.elseBlock != nil {
+			return s.execBlock(f, st.elseBlock)
+		}
+	case *numericForStmt:
+		return s.numericFor(f, st)
+	case *genericForStmt:
+		return s.genericFor(f, st)
+	case *localFunctionStmt:
+		// the function sees its own local
+		f.locals[st.slot] = &cell{}
+		f.locals[st.slot].value = s.closure(f, st.proto)
+	case *returnStmt:
+		if len(st.exprs) == 1 {
+			if call, ok := st.exprs[0].(*callExpr); ok {
+				f.ret = s.evalCall(f, call)
+				return flowReturn
+			}
+		}
+		f.ret = s.evalList(f, st.exprs)
+		return flowReturn
+	case *breakStmt:
+		return flowBreak
+	case *gotoStmt:
+		f.line = st.line
+		f.gotoLabel = st.label
+		return flowGoto
+	default:
+		panic(fmt.Sprintf("unknown statement %T", statement))
+	}
+	return flowNormal
+}
+
+func (s *State) numericFor(f *frame, st *numericForStmt) flow {
+	f.line = st.line
+	start, limit := s.eval(f, st.start), s.eval(f, st.limit)
+	var step Value = int64(1)
+	if st.step != nil {
+		step = s.eval(f, st.step)
+	}
+
+	switch start := start.(type) {
+	case int64:
+		step, ok := step.(int64)
+		if !ok {
+			break
+		}
+		var end int64
+		switch limit := limit.(type) {
+		case int64:
+			end = limit
+		case float64:
+			if step > 0 {
+				end = int64(min(limit, 1<<62))
+			} else {
+				end = int64(max(limit, -(1 << 62)))
+			}
+		default:
+			s.Errorf("'for' limit must be a number")
+		}
+		if step == 0 {
+			s.Errorf("'for' step is zero")
+		}
+		for i := start; step > 0 && i <= end || step < 0 && i >= end; i += step {
+			f.locals[st.slot] = &cell{i}
+			if goOn, result := s.loopBody(f, st.body); !goOn {
+				return result
+			}
+			// stops before the counter overflows
+			if step > 0 && i > end-step || step < 0 && i < end-step {
+				break
+			}
+		}
+		return flowNormal
+	case Fixed:
+		step, stepOk := step.(Fixed)
+		end, limitOk := limit.(Fixed)
+		if !stepOk || !limitOk {
+			s.Errorf("'for' limit and step must be fixedpoints like the initial value")
+		}
+		if step == 0 {
+			s.Errorf("'for' step is zero")
+		}
+		for i := start; step > 0 && i <= end || step < 0 && i >= end; i += step {
+			f.locals[st.slot] = &cell{i}
+			if goOn, result := s.loopBody(f, st.body); !goOn {
+				return result
+			}
+		}
+		return flowNormal
+	}
+
+	from, ok1 := toFloat(start)
+	to, ok2 := toFloat(limit)
+	by, ok3 := toFloat(step)
+	switch {
+	case !ok1:
+		s.Errorf("'for' initial value must be a number")
+	case !ok2:
+		s.Errorf("'for' limit must be a number")
+	case !ok3:
+		s.Errorf("'for' step must be a number")
+	case by == 0:
+		s.Errorf("'for' step is zero")
+	}
+	for i := from; by > 0 && i <= to || by < 0 && i >= to; i += by {
+		f.locals[st.slot] = &cell{i}
+		if goOn, result := s.loopBody(f, st.body); !goOn {
+			return result
+		}
+	}
+	return flowNormal
+}
+
+func (s *State) genericFor(f *frame, st *genericForStmt) flow {
+	f.line = st.line
+	values := s.evalList(f, st.exprs)
+	values = append(values, nil, nil, nil)
+	iterator, state, control := values[0], values[1], values[2]
+
+	// pairs and ipairs are run without calls, as most loops go over them
+	if next, ok := iterator.(*Function); ok && next.native != nil && (next.Name == "next" || next.Name == "ipairs_iterator") {
+		if t, ok := state.(*Table); ok && (next.Name == "ipairs_iterator" || t.Metatable == nil) {
+			return s.tableFor(f, st, t, next.Name == "ipairs_iterator", control)
+		}
+	}
+
+	for {
+		results := s.call(iterator, []Value{state, control}, " (for iterator)")
+		if len(results) == 0 || results[0] == nil {
+			return flowNormal
+		}
+		control = results[0]
+		for i, slot := range st.slots {
+			var value Value
+			if i < len(results) {
+				value = results[i]
+			}
+			f.locals[slot] = &cell{value}
+		}
+		if goOn, result := s.loopBody(f, st.body); !goOn {
+			return result
+		}
+	}
+}
+
+func (s *State) tableFor(f *frame, st *genericForStmt, t *Table, sequence bool, control Value) flow {
+	set := func(key, value Value) {
+		f.locals[st.slots[0]] = &cell{key}
+		if len(st.slots) > 1 {
+			f.locals[st.slots[1]] = &cell{value}
+			for _, slot := range st.slots[2:] {
+				f.locals[slot] = &cell{}
+			}
+		}
+	}
+	if sequence {
+		i, _ := control.(int64)
+		for {
+			i++
+			value := s.index(t, i, "")
+			if value == nil {
+				return flowNormal
+			}
+			set(i, value)
+			if goOn, result := s.loopBody(f, st.body); !goOn {
+				return result
+			}
+		}
+	}
+	key := control
+	for {
+		next, value, ok := t.Next(key)
+		if !ok {
+			s.Errorf("invalid key to 'next'")
+		}
+		if next == nil {
+			return flowNormal
+		}
+		key = next
+		set(key, value)
+		if goOn, result := s.loopBody(f, st.body); !goOn {
+			return result
+		}
+	}
+}
+
+func (s *State) assign(f *frame, target expr, value Value) {
+	switch t := target.(type) {
+	case *localExpr:
+		if c := f.locals[t.slot]; c != nil {
+			c.value = value
+		} else {
+			f.locals[t.slot] = &cell{value}
+		}
+	case *upvalueExpr:
+		f.fn.upvals[t.index].value = value
+	case *globalExpr:
+		s.Globals.Set(t.name, value)
+	case *indexExpr:
+		object := s.eval(f, t.object)
+		key := s.eval(f, t.key)
+		f.line = t.line
+		s.setIndex(object, key, value, describe(t.object))
+	}
+}
+
+func (s *State) closure(f *frame, proto *funcProto) *Function {
+	fn := &Function{Name: proto.name, proto: proto, upvals: make([]*cell, len(proto.upvals))}
+	for i, desc := range proto.upvals {
+		if desc.fromLocal {
+			if f.locals[desc.index] == nil {
+				f.locals[desc.index] = &cell{}
+			}
+			fn.upvals[i] = f.locals[desc.index]
+		} else {
+			fn.upvals[i] = f.fn.upvals[desc.index]
+		}
+	}
+	return fn
+}
+
+// Describes the expression a value comes from for errors, as in " (local 'x')"
+func describe(e expr) string {
+	switch e := e.(type) {
+	case *localExpr:
+		return fmt.Sprintf(" (local '%s')", e.name)
+	case *upvalueExpr:
+		return fmt.Sprintf(" (upvalue '%s')", e.name)
+	case *globalExpr:
+		return fmt.Sprintf(" (global '%s')", e.name)
+	case *indexExpr:
+		if key, ok := e.key.(*constantExpr); ok {
+			if name, ok := key.value.(string); ok {
+				return fmt.Sprintf(" (field '%s')", name)
+			}
+		}
+	case *methodCallExpr:
+		return fmt.Sprintf(" (method '%s')", e.name)
+	case *constantExpr:
+		if text, ok := e.value.(string); ok {
+			return fmt.Sprintf(" (constant '%s')", text)
+		}
+	}
+	return ""
+}
+
+func (s *State) eval(f *frame, e expr) Value {
+	switch e := e.(type) {
+	case *constantExpr:
+		return e.value
+	case *localExpr:
+		if c := f.locals[e.slot]; c != nil {
+			return c.value
+		}
+		return nil
+	case *upvalueExpr:
+		return f.fn.upvals[e.index].value
+	case *globalExpr:
+		return s.Globals.GetString(e.name)
+	case *indexExpr:
+		object := s.eval(f, e.object)
+		if t, ok := object.(*Table); ok && t.Metatable == nil {
+			if key, ok := e.key.(*constantExpr); ok {
+				return t.Get(key.value)
+			}
+		}
+		key := s.eval(f, e.key)
+		f.line = e.line
+		return s.index(object, key, describe(e.object))
+	case *callExpr:
+		return first(s.evalCall(f, e))
+	case *methodCallExpr:
+		return first(s.evalMethodCall(f, e))
+	case *varargExpr:
+		if len(f.varargs) == 0 {
+			return nil
+		}
+		return f.varargs[0]
+	case *parenExpr:
+		return s.eval(f, e.inner)
+	case *functionExpr:
+		return s.closure(f, e.proto)
+	case *logicalExpr:
+		left := s.eval(f, e.left)
+		if Truthy(left) != e.and {
+			return left
+		}
+		return s.eval(f, e.right)
+	case *binaryExpr:
+		left := s.eval(f, e.left)
+		right := s.eval(f, e.right)
+		f.line = e.line
+		return s.binary(e.op, left, right, e)
+	case *unaryExpr:
+		operand := s.eval(f, e.operand)
+		f.line = e.line
+		return s.unary(e.op, operand, e)
+	case *tableExpr:
+		return s.evalTable(f, e)
+	}
+	panic(fmt.Sprintf("unknown expression %T", e))
+}
+
+// Evaluates an expression to all of its values, which only calls and varargs have several of
+func (s *State) evalMulti(f *frame, e expr) []Value {
+	switch e := e.(type) {
+	case *callExpr:
+		return s.evalCall(f, e)
+	case *methodCallExpr:
+		return s.evalMethodCall(f, e)
+	case *varargExpr:
+		return append([]Value(nil), f.varargs...)
+	}
+	return []Value{s.eval(f, e)}
+}
+
+// Evaluates a list of expressions, expanding the values of the last one
+func (s *State) evalList(f *frame, exprs []expr) []Value {
+	if len(exprs) == 0 {
+		return nil
+	}
+	values := make([]Value, 0, len(exprs))
+	for _, e := range exprs[:len(exprs)-1] {
+		values = append(values, s.eval(f, e))
+	}
+	return append(values, s.evalMulti(f, exprs[len(exprs)-1])...)
+}
+
+func (s *State) evalCall(f *frame, e *callExpr) []Value {
+	fn := s.eval(f, e.function)
+	args := s.evalList(f, e.args)
+	f.line = e.line
+	return s.call(fn, args, describe(e.function))
+}
+
+func (s *State) evalMethodCall(f *frame, e *methodCallExpr) []Value {
+	object := s.eval(f, e.object)
+	f.line = e.line
+	method := s.index(object, e.name, describe(e.object))
+	args := append([]Value{object}, s.evalList(f, e.args)...)
+	f.line = e.line
+	return s.call(method, args, fmt.Sprintf(" (method '%s')", e.name))
+}
+
+func (s *State) evalTable(f *frame, e *tableExpr) Value {
+	t := NewTable()
+	position := int64(1)
+	for i, field := range e.fields {
+		if field.key != nil {
+			key := s.eval(f, field.key)
+			value := s.eval(f, field.value)
+			f.line = e.line
+			switch {
+			case key == nil:
+				s.Errorf("index is nil")
+			case isNaN(key):
+				s.Errorf("index is NaN")
+			}
+			t.Set(key, value)
+			continue
+		}
+		if i == len(e.fields)-1 {
+			for _, value := range s.evalMulti(f, field.value) {
+				t.Set(position, value)
+				position++
+			}
+			continue
+		}
+		t.Set(position, s.eval(f, field.value))
+		position++
+	}
+	return t
+}
@@ -0,0 +1,353 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	nameToken tokenKind = iota
+	keywordToken
+	numberToken
+	stringToken
+	symbolToken
+	eofToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// the value of number and string tokens
+	value Value
+	line  int
+}
+
+var keywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true, "end": true, "false": true,
+	"for": true, "function": true, "goto": true, "if": true, "in": true, "local": true, "nil": true,
+	"not": true, "or": true, "repeat": true, "return": true, "then": true, "true": true, "until": true,
+	"while": true,
+}
+
+// longest first, so that the first match is the right one
+var symbols = []string{
+	"...", "..", "==", "~=", "<=", ">=", "//", "::", "<<", ">>",
+	"+", "-", "*", "/", "%", "^", "#", "&", "~", "|", "<", ">", "=",
+	"(", ")", "{", "}", "[", "]", ";", ":", ",", ".",
+}
+
+type lexer struct {
+	chunk string
+	src   string
+	pos   int
+	line  int
+}
+
+func (l *lexer) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", l.chunk, l.line, fmt.Sprintf(format, args...))
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func tokenize(chunk, src string) ([]token, error) {
+	l := lexer{chunk: chunk, src: src, line: 1}
+	if strings.HasPrefix(src, "#") {
+		// skips the shebang line
+		l.pos = strings.IndexByte(src, '\n')
+		if l.pos == -1 {
+			l.pos = len(src)
+		}
+	}
+
+	toks := make([]token, 0, len(src)/4)
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		toks = append(toks, tok)
+		if tok.kind == eofToken {
+			return toks, nil
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "--"):
+			l.pos += 2
+			if level := l.longBracketLevel(); level != -1 {
+				if _, err := l.longString(level); err != nil {
+					return token{}, err
+				}
+				continue
+			}
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return l.token()
+		}
+	}
+	return token{kind: eofToken, text: "<eof>", line: l.line}, nil
+}
+
+func (l *lexer) token() (token, error) {
+	c := l.src[l.pos]
+	start := l.pos
+	switch {
+	case isNameStart(c):
+		for l.pos < len(l.src) && (isNameStart(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		text := l.src[start:l.pos]
+		if keywords[text] {
+			return token{kind: keywordToken, text: text, line: l.line}, nil
+		}
+		return token{kind: nameToken, text: text, line: l.line}, nil
+	case isDigit(c) || c == '.' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1]):
+		return l.number()
+	case c == '"' || c == '\'':
+		return l.quotedString(c)
+	case l.longBracketLevel() != -1:
+		line := l.line
+		text, err := l.longString(l.longBracketLevel())
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: stringToken, text: text, value: text, line: line}, nil
+	}
+	for _, symbol := range symbols {
+		if strings.HasPrefix(l.src[l.pos:], symbol) {
+			l.pos += len(symbol)
+			return token{kind: symbolToken, text: symbol, line: l.line}, nil
+		}
+	}
+	return token{}, l.errorf("unexpected symbol near '%c'", c)
+}
+
+// Returns the level of the long bracket opening at the current position, as in [==[, or -1 if there is none
+func (l *lexer) longBracketLevel() int {
+	if l.pos >= len(l.src) || l.src[l.pos] != '[' {
+		return -1
+	}
+	j := l.pos + 1
+	for j < len(l.src) && l.src[j] == '=' {
+		j++
+	}
+	if j < len(l.src) && l.src[j] == '[' {
+		return j - l.pos - 1
+	}
+	return -1
+}
+
+func (l *lexer) longString(level int) (string, error) {
+	l.pos += level + 2
+	// a line break right after the opening bracket is not part of the string
+	if strings.HasPrefix(l.src[l.pos:], "\r\n") {
+		l.pos += 2
+		l.line++
+	} else if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+		l.pos++
+		l.line++
+	}
+	closing := "]" + strings.Repeat("=", level) + "]"
+	end := strings.Index(l.src[l.pos:], closing)
+	if end == -1 {
+		return "", l.errorf("unfinished long string or comment")
+	}
+	text := l.src[l.pos : l.pos+end]
+	l.line += strings.Count(text, "\n")
+	l.pos += end + len(closing)
+	return text, nil
+}
+
+func (l *lexer) quotedString(quote byte) (token, error) {
+	l.pos++
+	text := strings.Builder{}
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return token{}, l.errorf("unfinished string")
+		}
+		c := l.src[l.pos]
+		if c == quote {
+			l.pos++
+			break
+		}
+		if c != '\\' {
+			text.WriteByte(c)
+			l.pos++
+			continue
+		}
+		l.pos++
+		if l.pos >= len(l.src) {
+			return token{}, l.errorf("unfinished string")
+		}
+		escape := l.src[l.pos]
+		l.pos++
+		switch escape {
+		case 'n':
+			text.WriteByte('\n')
+		case 't':
+			text.WriteByte('\t')
+		case 'r':
+			text.WriteByte('\r')
+		case 'a':
+			text.WriteByte('\a')
+		case 'b':
+			text.WriteByte('\b')
+		case 'f':
+			text.WriteByte('\f')
+		case 'v':
+			text.WriteByte('\v')
+		case '\\', '"', '\'':
+			text.WriteByte(escape)
+		case '\n':
+			text.WriteByte('\n')
+			l.line++
+		case 'x':
+			if l.pos+2 > len(l.src) {
+				return token{}, l.errorf("hexadecimal digit expected")
+			}
+			b, err := strconv.ParseUint(l.src[l.pos:l.pos+2], 16, 8)
+			if err != nil {
+				return token{}, l.errorf("hexadecimal digit expected")
+			}
+			text.WriteByte(byte(b))
+			l.pos += 2
+		case 'z':
+			for l.pos < len(l.src) && strings.IndexByte(" \t\r\n\f\v", l.src[l.pos]) != -1 {
+				if l.src[l.pos] == '\n' {
+					l.line++
+				}
+				l.pos++
+			}
+		case 'u':
+			end := strings.IndexByte(l.src[l.pos:], '}')
+			if !strings.HasPrefix(l.src[l.pos:], "{") || end == -1 {
+				return token{}, l.errorf("missing '{' or '}' in \\u{xxxx}")
+			}
+			r, err := strconv.ParseUint(l.src[l.pos+1:l.pos+end], 16, 32)
+			if err != nil {
+				return token{}, l.errorf("hexadecimal digit expected")
+			}
+			text.WriteRune(rune(r))
+			l.pos += end + 1
+		default:
+			if !isDigit(escape) {
+				return token{}, l.errorf("invalid escape sequence '\\%c'", escape)
+			}
+			end := l.pos - 1
+			for end < len(l.src) && end < l.pos+2 && isDigit(l.src[end]) {
+				end++
+			}
+			b, err := strconv.ParseUint(l.src[l.pos-1:end], 10, 8)
+			if err != nil {
+				return token{}, l.errorf("decimal escape too large")
+			}
+			text.WriteByte(byte(b))
+			l.pos = end
+		}
+	}
+	return token{kind: stringToken, text: text.String(), value: text.String(), line: l.line}, nil
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	exponents := "eE"
+	if strings.HasPrefix(l.src[l.pos:], "0x") || strings.HasPrefix(l.src[l.pos:], "0X") {
+		exponents = "pP"
+		l.pos += 2
+	}
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if (c == '+' || c == '-') && strings.IndexByte(exponents, l.src[l.pos-1]) != -1 ||
+			c == '.' || isNameStart(c) || isDigit(c) {
+			l.pos++
+			continue
+		}
+		break
+	}
+	text := l.src[start:l.pos]
+	value, ok := parseNumber(text)
+	if !ok {
+		return token{}, l.errorf("malformed number near '%s'", text)
+	}
+	return token{kind: numberToken, text: text, value: value, line: l.line}, nil
+}
+
+// Parses the numbers of Lua, along with the fixedpoints of PewPew Live: `12.2048fx` is
+// 12 plus 2048/4096ths
+func parseNumber(text string) (Value, bool) {
+	text = strings.TrimSpace(text)
+	if fx, ok := strings.CutSuffix(text, "fx"); ok {
+		negative := strings.HasPrefix(fx, "-")
+		fx = strings.TrimPrefix(fx, "-")
+		integer, fraction, _ := strings.Cut(fx, ".")
+		whole, err := strconv.ParseInt(integer, 10, 64)
+		if err != nil {
+			return nil, false
+		}
+		var frac int64
+		if fraction != "" {
+			frac, err = strconv.ParseInt(fraction, 10, 64)
+			if err != nil || frac >= FixedOne {
+				return nil, false
+			}
+		}
+		value := whole*FixedOne + frac
+		if negative {
+			value = -value
+		}
+		return Fixed(value), true
+	}
+
+	hex := strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X")
+	if !strings.Contains(text, ".") && (hex && !strings.ContainsAny(text, "pP") || !hex && !strings.ContainsAny(text, "eE")) {
+		var value uint64
+		var err error
+		if hex {
+			// hexadecimal integers wrap around
+			for _, c := range text[2:] {
+				digit, perr := strconv.ParseUint(string(c), 16, 8)
+				if perr != nil {
+					return nil, false
+				}
+				value = value<<4 | digit
+			}
+			return int64(value), len(text) > 2
+		}
+		value, err = strconv.ParseUint(text, 10, 64)
+		if err == nil && value <= math.MaxInt64 {
+			return int64(value), true
+		}
+	}
+	if strings.ContainsAny(text, "iInN") && !hex {
+		// rejects inf and nan, which ParseFloat takes
+		return nil, false
+	}
+	float, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); !ok || numErr.Err != strconv.ErrRange {
+			return nil, false
+		}
+	}
+	return float, true
+}
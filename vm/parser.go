@@ -0,0 +1,589 @@
+package vm
+
+import "fmt"
+
+type parseError struct {
+	message string
+}
+
+type funcState struct {
+	parent   *funcState
+	proto    *funcProto
+	scopes   []map[string]int
+	upvalues map[string]int
+}
+
+type parser struct {
+	chunk string
+	toks  []token
+	pos   int
+	fs    *funcState
+}
+
+// Parses a chunk into the prototype of the function running it
+func parse(chunk, src string) (proto *funcProto, err error) {
+	toks, err := tokenize(chunk, src)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{chunk: chunk, toks: toks}
+	defer func() {
+		if r := recover(); r != nil {
+			parseErr, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			proto, err = nil, fmt.Errorf("%s", parseErr.message)
+		}
+	}()
+
+	proto = &funcProto{name: "main chunk", chunk: chunk, line: 0, isVararg: true}
+	p.openFunction(proto)
+	proto.body = p.block()
+	p.closeFunction()
+	if p.peek().kind != eofToken {
+		p.errorf("'<eof>' expected near '%s'", p.peek().text)
+	}
+	return proto, nil
+}
+
+func (p *parser) errorf(format string, args ...any) {
+	panic(parseError{fmt.Sprintf("%s:%d: %s", p.chunk, p.peek().line, fmt.Sprintf(format, args...))})
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) advance() token {
+	tok := p.toks[p.pos]
+	if tok.kind != eofToken {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) check(text string) bool {
+	tok := p.peek()
+	return (tok.kind == symbolToken || tok.kind == keywordToken) && tok.text == text
+}
+
+func (p *parser) match(text string) bool {
+	if p.check(text) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) token {
+	if !p.check(text) {
+		p.errorf("'%s' expected near '%s'", text, p.peek().text)
+	}
+	return p.advance()
+}
+
+// Expects the closing of a construct opened at the line, naming the opening when it is far away
+func (p *parser) expectClosing(text, opening string, line int) {
+	if p.check(text) {
+		p.advance()
+		return
+	}
+	if line == p.peek().line {
+		p.errorf("'%s' expected near '%s'", text, p.peek().text)
+	}
+	p.errorf("'%s' expected (to close '%s' at line %d) near '%s'", text, opening, line, p.peek().text)
+}
+
+func (p *parser) name() string {
+	tok := p.peek()
+	if tok.kind != nameToken {
+		p.errorf("<name> expected near '%s'", tok.text)
+	}
+	p.advance()
+	return tok.text
+}
+
+func (p *parser) openFunction(proto *funcProto) {
+	p.fs = &funcState{parent: p.fs, proto: proto, upvalues: make(map[string]int)}
+	p.openScope()
+}
+
+func (p *parser) closeFunction() {
+	p.fs = p.fs.parent
+}
+
+func (p *parser) openScope() {
+	p.fs.scopes = append(p.fs.scopes, make(map[string]int))
+}
+
+func (p *parser) closeScope() {
+	p.fs.scopes = p.fs.scopes[:len(p.fs.scopes)-1]
+}
+
+// Declares a local in the innermost scope, giving it a new slot of the frame
+func (p *parser) declare(name string) int {
+	slot := p.fs.proto.slots
+	p.fs.proto.slots++
+	p.fs.scopes[len(p.fs.scopes)-1][name] = slot
+	return slot
+}
+
+func (p *parser) resolve(name string) expr {
+	kind, index := resolveIn(p.fs, name)
+	switch kind {
+	case 0:
+		return &localExpr{slot: index, name: name}
+	case 1:
+		return &upvalueExpr{index: index, name: name}
+	}
+	return &globalExpr{name: name}
+}
+
+// Returns 0 and the slot for a local, 1 and the index for an upvalue, and 2 for a global
+func resolveIn(fs *funcState, name string) (int, int) {
+	for i := len(fs.scopes) - 1; i >= 0; i-- {
+		if slot, ok := fs.scopes[i][name]; ok {
+			return 0, slot
+		}
+	}
+	if index, ok := fs.upvalues[name]; ok {
+		return 1, index
+	}
+	if fs.parent == nil {
+		return 2, 0
+	}
+	kind, index := resolveIn(fs.parent, name)
+	if kind == 2 {
+		return 2, 0
+	}
+	fs.proto.upvals = append(fs.proto.upvals, upvalueDesc{fromLocal: kind == 0, index: index})
+	fs.upvalues[name] = len(fs.proto.upvals) - 1
+	return 1, len(fs.proto.upvals) - 1
+}
+
+func (p *parser) blockEnds() bool {
+	tok := p.peek()
+	if tok.kind == eofToken {
+		return true
+	}
+	if tok.kind != keywordToken {
+		return false
+	}
+	switch tok.text {
+	case "end", "else", "elseif", "until":
+		return true
+	}
+	return false
+}
+
+// Parses the statements of a block in the current scope
+func (p *parser) block() *block {
+	b := &block{}
+	for !p.blockEnds() {
+		if p.check("return") {
+			b.stmts = append(b.stmts, p.returnStatement())
+			break
+		}
+		if p.match("::") {
+			label := p.name()
+			p.expect("::")
+			if _, ok := b.labels[label]; ok {
+				p.errorf("label '%s' already defined", label)
+			}
+			if b.labels == nil {
+				b.labels = make(map[string]int)
+			}
+			b.labels[label] = len(b.stmts)
+			continue
+		}
+		if statement := p.statement(); statement != nil {
+			b.stmts = append(b.stmts, statement)
+		}
+	}
+	return b
+}
+
+func (p *parser) scopedBlock() *block {
+	p.openScope()
+	b := p.block()
+	p.closeScope()
+	return b
+}
+
+func (p *parser) returnStatement() stmt {
+	p.expect("return")
+	ret := &returnStmt{}
+	if !p.blockEnds() && !p.check(";") {
+		ret.exprs = p.exprList()
+	}
+	p.match(";")
+	if !p.blockEnds() {
+		p.errorf("'<eof>' expected near '%s'", p.peek().text)
+	}
+	return ret
+}
+
+func (p *parser) statement() stmt {
+	tok := p.peek()
+	line := tok.line
+	switch {
+	case p.match(";"):
+		return nil
+	case p.match("break"):
+		return &breakStmt{}
+	case p.match("goto"):
+		return &gotoStmt{label: p.name(), line: line}
+	case p.match("do"):
+		body := p.scopedBlock()
+		p.expectClosing("end", "do", line)
+		return &doStmt{body}
+	case p.match("while"):
+		cond := p.expr()
+		p.expect("do")
+		body := p.scopedBlock()
+		p.expectClosing("end", "while", line)
+		return &whileStmt{cond, body, line}
+	case p.match("repeat"):
+		// the condition sees the locals of the body
+		p.openScope()
+		body := p.block()
+		p.expectClosing("until", "repeat", line)
+		cond := p.expr()
+		p.closeScope()
+		return &repeatStmt{body, cond}
+	case p.match("if"):
+		return p.ifStatement(line)
+	case p.match("for"):
+		return p.forStatement(line)
+	case p.match("function"):
+		return p.functionStatement(line)
+	case p.match("local"):
+		if p.match("function") {
+			name := p.name()
+			slot := p.declare(name)
+			return &localFunctionStmt{slot: slot, proto: p.functionBody(name, false, line)}
+		}
+		names := []string{p.name()}
+		p.attribute()
+		for p.match(",") {
+			names = append(names, p.name())
+			p.attribute()
+		}
+		var exprs []expr
+		if p.match("=") {
+			exprs = p.exprList()
+		}
+		// the locals are only visible after their declaration
+		slots := make([]int, len(names))
+		for i, name := range names {
+			slots[i] = p.declare(name)
+		}
+		return &localStmt{slots, exprs}
+	}
+	return p.expressionStatement()
+}
+
+// Skips the <const> and <close> attributes of locals
+func (p *parser) attribute() {
+	if p.match("<") {
+		p.name()
+		p.expect(">")
+	}
+}
+
+func (p *parser) ifStatement(line int) stmt {
+	statement := &ifStmt{}
+	statement.conds = append(statement.conds, p.expr())
+	p.expect("then")
+	statement.blocks = append(statement.blocks, p.scopedBlock())
+	for p.match("elseif") {
+		statement.conds = append(statement.conds, p.expr())
+		p.expect("then")
+		statement.blocks = append(statement.blocks, p.scopedBlock())
+	}
+	if p.match("else") {
+		statement.elseBlock = p.scopedBlock()
+	}
+	p.expectClosing("end", "if", line)
+	return statement
+}
+
+func (p *parser) forStatement(line int) stmt {
+	first := p.name()
+	if p.match("=") {
+		start := p.expr()
+		p.expect(",")
+		limit := p.expr()
+		var step expr
+		if p.match(",") {
+			step = p.expr()
+		}
+		p.expect("do")
+		p.openScope()
+		slot := p.declare(first)
+		body := p.block()
+		p.closeScope()
+		p.expectClosing("end", "for", line)
+		return &numericForStmt{slot: slot, start: start, limit: limit, step: step, body: body, line: line}
+	}
+
+	names := []string{first}
+	for p.match(",") {
+		names = append(names, p.name())
+	}
+	p.expect("in")
+	exprs := p.exprList()
+	p.expect("do")
+	p.openScope()
+	slots := make([]int, len(names))
+	for i, name := range names {
+		slots[i] = p.declare(name)
+	}
+	body := p.block()
+	p.closeScope()
+	p.expectClosing("end", "for", line)
+	return &genericForStmt{slots: slots, exprs: exprs, body: body, line: line}
+}
+
+func (p *parser) functionStatement(line int) stmt {
+	name := p.name()
+	target := p.resolve(name)
+	fullName := name
+	isMethod := false
+	for p.check(".") || p.check(":") {
+		isMethod = p.advance().text == ":"
+		key := p.name()
+		fullName += map[bool]string{true: ":", false: "."}[isMethod] + key
+		target = &indexExpr{object: target, key: &constantExpr{key}, line: line}
+		if isMethod {
+			break
+		}
+	}
+	proto := p.functionBody(fullName, isMethod, line)
+	return &assignStmt{targets: []expr{target}, exprs: []expr{&functionExpr{proto}}, line: line}
+}
+
+func (p *parser) functionBody(name string, isMethod bool, line int) *funcProto {
+	proto := &funcProto{name: name, chunk: p.chunk, line: line}
+	p.openFunction(proto)
+	if isMethod {
+		proto.params = append(proto.params, p.declare("self"))
+	}
+	p.expect("(")
+	if !p.check(")") {
+		for {
+			if p.match("...") {
+				proto.isVararg = true
+				break
+			}
+			proto.params = append(proto.params, p.declare(p.name()))
+			if !p.match(",") {
+				break
+			}
+		}
+	}
+	p.expect(")")
+	proto.body = p.block()
+	p.expectClosing("end", "function", line)
+	p.closeFunction()
+	return proto
+}
+
+func (p *parser) expressionStatement() stmt {
+	line := p.peek().line
+	first := p.suffixedExpr()
+	if p.check("=") || p.check(",") {
+		targets := []expr{first}
+		for p.match(",") {
+			targets = append(targets, p.suffixedExpr())
+		}
+		p.expect("=")
+		for _, target := range targets {
+			switch target.(type) {
+			case *localExpr, *upvalueExpr, *globalExpr, *indexExpr:
+			default:
+				p.errorf("syntax error near '='")
+			}
+		}
+		return &assignStmt{targets: targets, exprs: p.exprList(), line: line}
+	}
+	switch first.(type) {
+	case *callExpr, *methodCallExpr:
+		return &callStmt{first}
+	}
+	p.errorf("syntax error near '%s'", p.peek().text)
+	return nil
+}
+
+func (p *parser) exprList() []expr {
+	exprs := []expr{p.expr()}
+	for p.match(",") {
+		exprs = append(exprs, p.expr())
+	}
+	return exprs
+}
+
+func (p *parser) primaryExpr() expr {
+	tok := p.peek()
+	switch {
+	case tok.kind == nameToken:
+		p.advance()
+		return p.resolve(tok.text)
+	case p.match("("):
+		inner := p.expr()
+		p.expectClosing(")", "(", tok.line)
+		return &parenExpr{inner}
+	}
+	p.errorf("unexpected symbol near '%s'", tok.text)
+	return nil
+}
+
+func (p *parser) suffixedExpr() expr {
+	e := p.primaryExpr()
+	for {
+		tok := p.peek()
+		switch {
+		case p.match("."):
+			e = &indexExpr{object: e, key: &constantExpr{p.name()}, line: tok.line}
+		case p.match("["):
+			key := p.expr()
+			p.expect("]")
+			e = &indexExpr{object: e, key: key, line: tok.line}
+		case p.match(":"):
+			name := p.name()
+			e = &methodCallExpr{object: e, name: name, args: p.callArgs(), line: tok.line}
+		case p.check("(") || p.check("{") || tok.kind == stringToken:
+			e = &callExpr{function: e, args: p.callArgs(), line: tok.line}
+		default:
+			return e
+		}
+	}
+}
+
+func (p *parser) callArgs() []expr {
+	tok := p.peek()
+	switch {
+	case tok.kind == stringToken:
+		p.advance()
+		return []expr{&constantExpr{tok.value}}
+	case p.check("{"):
+		return []expr{p.tableConstructor()}
+	case p.match("("):
+		if p.match(")") {
+			return nil
+		}
+		args := p.exprList()
+		p.expectClosing(")", "(", tok.line)
+		return args
+	}
+	p.errorf("function arguments expected near '%s'", tok.text)
+	return nil
+}
+
+func (p *parser) tableConstructor() expr {
+	line := p.expect("{").line
+	table := &tableExpr{line: line}
+	for !p.check("}") {
+		switch {
+		case p.match("["):
+			key := p.expr()
+			p.expect("]")
+			p.expect("=")
+			table.fields = append(table.fields, tableField{key, p.expr()})
+		case p.peek().kind == nameToken && p.toks[p.pos+1].kind == symbolToken && p.toks[p.pos+1].text == "=":
+			key := p.name()
+			p.advance()
+			table.fields = append(table.fields, tableField{&constantExpr{key}, p.expr()})
+		default:
+			table.fields = append(table.fields, tableField{nil, p.expr()})
+		}
+		if !p.match(",") && !p.match(";") {
+			break
+		}
+	}
+	p.expectClosing("}", "{", line)
+	return table
+}
+
+func (p *parser) simpleExpr() expr {
+	tok := p.peek()
+	switch tok.kind {
+	case numberToken, stringToken:
+		p.advance()
+		return &constantExpr{tok.value}
+	case keywordToken:
+		switch tok.text {
+		case "nil":
+			p.advance()
+			return &constantExpr{nil}
+		case "true":
+			p.advance()
+			return &constantExpr{true}
+		case "false":
+			p.advance()
+			return &constantExpr{false}
+		case "function":
+			p.advance()
+			return &functionExpr{p.functionBody("anonymous", false, tok.line)}
+		}
+	case symbolToken:
+		switch tok.text {
+		case "...":
+			if !p.fs.proto.isVararg {
+				p.errorf("cannot use '...' outside a vararg function near '...'")
+			}
+			p.advance()
+			return &varargExpr{}
+		case "{":
+			return p.tableConstructor()
+		}
+	}
+	return p.suffixedExpr()
+}
+
+var binaryPriority = map[string][2]int{
+	"or": {1, 1}, "and": {2, 2},
+	"<": {3, 3}, ">": {3, 3}, "<=": {3, 3}, ">=": {3, 3}, "~=": {3, 3}, "==": {3, 3},
+	"|": {4, 4}, "~": {5, 5}, "&": {6, 6}, "<<": {7, 7}, ">>": {7, 7},
+	"..": {9, 8}, "+": {10, 10}, "-": {10, 10},
+	"*": {11, 11}, "/": {11, 11}, "//": {11, 11}, "%": {11, 11},
+	"^": {14, 13},
+}
+
+const unaryPriority = 12
+
+func (p *parser) expr() expr {
+	return p.subExpr(0)
+}
+
+// Parses an expression whose binary operators bind tighter than the limit
+func (p *parser) subExpr(limit int) expr {
+	var e expr
+	tok := p.peek()
+	if tok.kind == keywordToken && tok.text == "not" || tok.kind == symbolToken && (tok.text == "-" || tok.text == "#" || tok.text == "~") {
+		p.advance()
+		e = &unaryExpr{op: tok.text, operand: p.subExpr(unaryPriority), line: tok.line}
+	} else {
+		e = p.simpleExpr()
+	}
+
+	for {
+		tok := p.peek()
+		if tok.kind != symbolToken && tok.kind != keywordToken {
+			return e
+		}
+		priority, ok := binaryPriority[tok.text]
+		if !ok || priority[0] <= limit {
+			return e
+		}
+		p.advance()
+		right := p.subExpr(priority[1])
+		switch tok.text {
+		case "and", "or":
+			e = &logicalExpr{and: tok.text == "and", left: e, right: right}
+		default:
+			e = &binaryExpr{op: tok.text, left: e, right: right, line: tok.line}
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The most calls that can be nested before a stack overflow is raised
+const maxCallDepth = 200
+
+// A Lua interpreter running the Lua 5.4 subset that the generator emits, with the
+// fixedpoint numbers of PewPew Live
+type State struct {
+	Globals *Table
+	// the metatable of strings, whose __index is the string library
+	stringMeta *Table
+	frames     []*frame
+	// counts the statements run, which are limited by StepLimit when it is not 0
+	steps     int64
+	StepLimit int64
+	// loads the chunk of a required module, like the searchers of package.path
+	Loader  func(name string) (chunk string, src string, err error)
+	modules map[string]Value
+	// prints the values given to print
+	Print  func(text string)
+	random *random
+	// the coroutine that runs, nil for the main one
+	running *Coroutine
+}
+
+// A Lua error, raised by error() or by an invalid operation
+type Error struct {
+	Value     Value
+	Traceback string
+}
+
+func (e *Error) Error() string {
+	if text, ok := e.Value.(string); ok {
+		return text
+	}
+	if e.Value == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("(error object is a %s value)", TypeName(e.Value))
+}
+
+type frame struct {
+	fn      *Function
+	locals  []*cell
+	varargs []Value
+	// the line that runs, for the positions of errors
+	line      int
+	ret       []Value
+	gotoLabel string
+}
+
+func NewState() *State {
+	s := &State{Globals: NewTable(), modules: make(map[string]Value), random: newRandom(0)}
+	s.Print = func(text string) { fmt.Println(text) }
+	openBase(s)
+	openMath(s)
+	openString(s)
+	openTable(s)
+	openCoroutine(s)
+	return s
+}
+
+// Resets the count of steps, which the StepLimit applies to
+func (s *State) ResetSteps() {
+	s.steps = 0
+}
+
+// Seeds the generator of math.random and of the hosts using Random
+func (s *State) Seed(seed uint64) {
+	s.random = newRandom(seed)
+}
+
+// Returns a deterministic random integer between min and max
+func (s *State) Random(min, max int64) int64 {
+	return s.random.between(min, max)
+}
+
+func (s *State) SetGlobal(name string, value Value) {
+	s.Globals.Set(name, value)
+}
+
+func (s *State) GetGlobal(name string) Value {
+	return s.Globals.GetString(name)
+}
+
+// Raises a Lua error with the position of the Lua code that is running
+func (s *State) Errorf(format string, args ...any) {
+	s.raise(s.where(1) + fmt.Sprintf(format, args...))
+}
+
+func (s *State) raise(value Value) {
+	panic(&Error{Value: value, Traceback: s.traceback()})
+}
+
+// Returns the position of the Lua function at the level of the stack, as in "chunk:line: "
+func (s *State) where(level int) string {
+	for i := len(s.frames) - 1; i >= 0; i-- {
+		f := s.frames[i]
+		if f.fn.native != nil {
+			continue
+		}
+		level--
+		if level <= 0 {
+			return fmt.Sprintf("%s:%d: ", f.fn.proto.chunk, f.line)
+		}
+	}
+	return ""
+}
+
+func (s *State) traceback() string {
+	lines := []string{"stack traceback:"}
+	for i := len(s.frames) - 1; i >= 0; i-- {
+		f := s.frames[i]
+		switch {
+		case f.fn.native != nil:
+			lines = append(lines, fmt.Sprintf("\t[builtin]: in function '%s'", f.fn.Name))
+		case f.fn.proto.name == "main chunk":
+			lines = append(lines, fmt.Sprintf("\t%s:%d: in main chunk", f.fn.proto.chunk, f.line))
+		default:
+			lines = append(lines, fmt.Sprintf("\t%s:%d: in function '%s'", f.fn.proto.chunk, f.line, f.fn.proto.name))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Parses and runs a chunk, returning the values it returns
+func (s *State) DoString(chunk, src string) ([]Value, error) {
+	fn, err := s.Load(chunk, src)
+	if err != nil {
+		return nil, err
+	}
+	return s.PCall(fn)
+}
+
+// Parses a chunk into the function running it
+func (s *State) Load(chunk, src string) (*Function, error) {
+	proto, err := parse(chunk, src)
+	if err != nil {
+		return nil, err
+	}
+	return &Function{Name: chunk, proto: proto}, nil
+}
+
+// Calls the function, raising the errors it raises
+func (s *State) Call(fn Value, args ...Value) []Value {
+	return s.call(fn, args, "")
+}
+
+// Calls the function, returning the error it raises as an *Error
+func (s *State) PCall(fn Value, args ...Value) (rets []Value, err error) {
+	depth := len(s.frames)
+	defer func() {
+		if r := recover(); r != nil {
+			luaErr, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			s.frames = s.frames[:depth]
+			rets, err = nil, luaErr
+		}
+	}()
+	return s.call(fn, args, ""), nil
+}
+
+// Runs Go code calling into Lua, returning the error it raises as an *Error
+func (s *State) Protect(fn func()) (err error) {
+	depth := len(s.frames)
+	defer func() {
+		if r := recover(); r != nil {
+			luaErr, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			s.frames = s.frames[:depth]
+			err = luaErr
+		}
+	}()
+	fn()
+	return nil
+}
+
+// Calls a function value, using the description of the expression it came from in errors
+func (s *State) call(fnValue Value, args []Value, description string) []Value {
+	fn, ok := fnValue.(*Function)
+	if !ok {
+		if handler := s.metamethod(fnValue, "__call"); handler != nil {
+			return s.call(handler, append([]Value{fnValue}, args...), description)
+		}
+		s.Errorf("attempt to call a %s value%s", TypeName(fnValue), description)
+	}
+	if len(s.frames) >= maxCallDepth {
+		s.Errorf("stack overflow")
+	}
+
+	f := &frame{fn: fn}
+	s.frames = append(s.frames, f)
+	if fn.native != nil {
+		rets := fn.native(s, args)
+		s.frames = s.frames[:len(s.frames)-1]
+		return rets
+	}
+
+	proto := fn.proto
+	f.line = proto.line
+	f.locals = make([]*cell, proto.slots)
+	for i, slot := range proto.params {
+		var arg Value
+		if i < len(args) {
+			arg = args[i]
+		}
+		f.locals[slot] = &cell{arg}
+	}
+	if proto.isVararg && len(args) > len(proto.params) {
+		f.varargs = args[len(proto.params):]
+	}
+	if s.execBlock(f, proto.body) == flowGoto {
+		s.Errorf("no visible label '%s' for goto", f.gotoLabel)
+	}
+	s.frames = s.frames[:len(s.frames)-1]
+	return f.ret
+}
+
+func (s *State) metamethod(v Value, event string) Value {
+	var meta *Table
+	switch v := v.(type) {
+	case *Table:
+		meta = v.Metatable
+	case string:
+		meta = s.stringMeta
+	}
+	if meta == nil {
+		return nil
+	}
+	return meta.GetString(event)
+}
+
+// Indexes the value like `v[key]`, with the __index metamethod
+func (s *State) Index(v Value, key Value) Value {
+	return s.index(v, key, "")
+}
+
+func (s *State) index(v Value, key Value, description string) Value {
+	for range 100 {
+		if t, ok := v.(*Table); ok {
+			value := t.Get(key)
+			if value != nil || t.Metatable == nil {
+				return value
+			}
+			handler := t.Metatable.GetString("__index")
+			if handler == nil {
+				return nil
+			}
+			if fn, ok := handler.(*Function); ok {
+				return first(s.call(fn, []Value{v, key}, ""))
+			}
+			v, description = handler, ""
+			continue
+		}
+		handler := s.metamethod(v, "__index")
+		if handler == nil {
+			if keyName, ok := key.(string); ok && description == "" {
+				description = fmt.Sprintf(" (field '%s')", keyName)
+			}
+			s.Errorf("attempt to index a %s value%s", TypeName(v), description)
+		}
+		if fn, ok := handler.(*Function); ok {
+			return first(s.call(fn, []Value{v, key}, ""))
+		}
+		v, description = handler, ""
+	}
+	s.Errorf("'__index' chain too long; possible loop")
+	return nil
+}
+
+// Sets `v[key] = value`, with the __newindex metamethod
+func (s *State) SetIndex(v Value, key Value, value Value) {
+	s.setIndex(v, key, value, "")
+}
+
+func (s *State) setIndex(v Value, key Value, value Value, description string) {
+	for range 100 {
+		t, ok := v.(*Table)
+		if !ok {
+			handler := s.metamethod(v, "__newindex")
+			if handler == nil {
+				s.Errorf("attempt to index a %s value%s", TypeName(v), description)
+			}
+			if fn, ok := handler.(*Function); ok {
+				s.call(fn, []Value{v, key, value}, "")
+				return
+			}
+			v, description = handler, ""
+			continue
+		}
+		if t.Metatable != nil && t.Get(key) == nil {
+			if handler := t.Metatable.GetString("__newindex"); handler != nil {
+				if fn, ok := handler.(*Function); ok {
+					s.call(fn, []Value{v, key, value}, "")
+					return
+				}
+				v, description = handler, ""
+				continue
+			}
+		}
+		switch {
+		case key == nil:
+			s.Errorf("index is nil")
+		case isNaN(key):
+			s.Errorf("index is NaN")
+		}
+		t.Set(key, value)
+		return
+	}
+	s.Errorf("'__newindex' chain too long; possible loop")
+}
+
+// Converts the value to a string like tostring does
+func (s *State) ToString(v Value) string {
+	if handler := s.metamethod(v, "__tostring"); handler != nil {
+		result := first(s.call(handler, []Value{v}, ""))
+		text, ok := result.(string)
+		if !ok {
+			s.Errorf("'__tostring' must return a string")
+		}
+		return text
+	}
+	if t, ok := v.(*Table); ok && t.Metatable != nil {
+		if name, ok := t.Metatable.GetString("__name").(string); ok {
+			return fmt.Sprintf("%s: %p", name, t)
+		}
+	}
+	return ToString(v)
+}
+
+func first(values []Value) Value {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
@@ -0,0 +1,605 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+type random struct {
+	rng *rand.Rand
+}
+
+func newRandom(seed uint64) *random {
+	return &random{rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+}
+
+func (r *random) between(min, max int64) int64 {
+	if max <= min {
+		return min
+	}
+	return min + int64(r.rng.Uint64N(uint64(max-min)+1))
+}
+
+func (r *random) float() float64 {
+	return r.rng.Float64()
+}
+
+// Helpers for the arguments of Go functions, raising the errors of the Lua standard library
+
+func Arg(args []Value, i int) Value {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+func (s *State) argError(i int, name, message string) {
+	s.Errorf("bad argument #%d to '%s' (%s)", i+1, name, message)
+}
+
+func (s *State) typeError(args []Value, i int, name, expected string) {
+	got := "no value"
+	if i < len(args) {
+		got = TypeName(args[i])
+	}
+	s.argError(i, name, fmt.Sprintf("%s expected, got %s", expected, got))
+}
+
+func (s *State) CheckTable(args []Value, i int, name string) *Table {
+	t, ok := Arg(args, i).(*Table)
+	if !ok {
+		s.typeError(args, i, name, "table")
+	}
+	return t
+}
+
+func (s *State) CheckInteger(args []Value, i int, name string) int64 {
+	v := Arg(args, i)
+	n, ok := toInteger(v)
+	if !ok {
+		if _, isNumber := toNumber(v); isNumber && v != nil {
+			if _, fixed := v.(Fixed); !fixed {
+				s.argError(i, name, "number has no integer representation")
+			}
+		}
+		s.typeError(args, i, name, "number")
+	}
+	return n
+}
+
+func (s *State) OptInteger(args []Value, i int, name string, def int64) int64 {
+	if Arg(args, i) == nil {
+		return def
+	}
+	return s.CheckInteger(args, i, name)
+}
+
+func (s *State) CheckNumber(args []Value, i int, name string) float64 {
+	n, ok := toFloat(Arg(args, i))
+	if !ok {
+		s.typeError(args, i, name, "number")
+	}
+	return n
+}
+
+func (s *State) CheckFixed(args []Value, i int, name string) Fixed {
+	n, ok := Arg(args, i).(Fixed)
+	if !ok {
+		s.typeError(args, i, name, "fixedpoint")
+	}
+	return n
+}
+
+func (s *State) CheckString(args []Value, i int, name string) string {
+	switch v := Arg(args, i).(type) {
+	case string:
+		return v
+	case int64, float64:
+		return ToString(v)
+	}
+	s.typeError(args, i, name, "string")
+	return ""
+}
+
+func (s *State) CheckFunction(args []Value, i int, name string) *Function {
+	fn, ok := Arg(args, i).(*Function)
+	if !ok {
+		s.typeError(args, i, name, "function")
+	}
+	return fn
+}
+
+func (s *State) checkAny(args []Value, i int, name string) Value {
+	if i >= len(args) {
+		s.argError(i, name, "value expected")
+	}
+	return args[i]
+}
+
+func register(t *Table, functions map[string]func(s *State, args []Value) []Value) {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		t.Set(name, NewFunction(name, functions[name]))
+	}
+}
+
+func openBase(s *State) {
+	s.SetGlobal("_G", s.Globals)
+	s.SetGlobal("_VERSION", "Lua 5.4")
+	next := NewFunction("next", func(s *State, args []Value) []Value {
+		t := s.CheckTable(args, 0, "next")
+		key, value, ok := t.Next(Arg(args, 1))
+		if !ok {
+			s.Errorf("invalid key to 'next'")
+		}
+		if key == nil {
+			return []Value{nil}
+		}
+		return []Value{key, value}
+	})
+	s.SetGlobal("next", next)
+	ipairsIterator := NewFunction("ipairs_iterator", func(s *State, args []Value) []Value {
+		i, _ := Arg(args, 1).(int64)
+		value := s.Index(args[0], i+1)
+		if value == nil {
+			return []Value{nil}
+		}
+		return []Value{i + 1, value}
+	})
+
+	register(s.Globals, map[string]func(s *State, args []Value) []Value{
+		"print": func(s *State, args []Value) []Value {
+			texts := make([]string, len(args))
+			for i, arg := range args {
+				texts[i] = s.ToString(arg)
+			}
+			s.Print(strings.Join(texts, "\t"))
+			return nil
+		},
+		"tostring": func(s *State, args []Value) []Value {
+			return []Value{s.ToString(s.checkAny(args, 0, "tostring"))}
+		},
+		"tonumber": func(s *State, args []Value) []Value {
+			if base := Arg(args, 1); base != nil {
+				b := s.CheckInteger(args, 1, "tonumber")
+				text := strings.ToLower(strings.TrimSpace(s.CheckString(args, 0, "tonumber")))
+				negative := strings.HasPrefix(text, "-")
+				text = strings.TrimPrefix(text, "-")
+				var n int64
+				for _, c := range text {
+					digit := int64(strings.IndexRune("0123456789abcdefghijklmnopqrstuvwxyz", c))
+					if digit < 0 || digit >= b {
+						return []Value{nil}
+					}
+					n = n*b + digit
+				}
+				if text == "" {
+					return []Value{nil}
+				}
+				if negative {
+					n = -n
+				}
+				return []Value{n}
+			}
+			switch v := s.checkAny(args, 0, "tonumber").(type) {
+			case int64, float64, Fixed:
+				return []Value{v}
+			case string:
+				if n, ok := toNumber(v); ok {
+					if _, fixed := n.(Fixed); !fixed {
+						return []Value{n}
+					}
+				}
+			}
+			return []Value{nil}
+		},
+		"type": func(s *State, args []Value) []Value {
+			return []Value{TypeName(s.checkAny(args, 0, "type"))}
+		},
+		"pairs": func(s *State, args []Value) []Value {
+			v := s.checkAny(args, 0, "pairs")
+			if handler := s.metamethod(v, "__pairs"); handler != nil {
+				results := append(s.Call(handler, v), nil, nil, nil)
+				return results[:3]
+			}
+			s.CheckTable(args, 0, "pairs")
+			return []Value{next, v, nil}
+		},
+		"ipairs": func(s *State, args []Value) []Value {
+			return []Value{ipairsIterator, s.checkAny(args, 0, "ipairs"), int64(0)}
+		},
+		"select": func(s *State, args []Value) []Value {
+			if Arg(args, 0) == "#" {
+				return []Value{int64(len(args) - 1)}
+			}
+			n := s.CheckInteger(args, 0, "select")
+			switch {
+			case n < 0:
+				n += int64(len(args))
+				if n < 1 {
+					s.argError(0, "select", "index out of range")
+				}
+			case n == 0:
+				s.argError(0, "select", "index out of range")
+			case n >= int64(len(args)):
+				return nil
+			}
+			return args[n:]
+		},
+		"error": func(s *State, args []Value) []Value {
+			value := Arg(args, 0)
+			level := s.OptInteger(args, 1, "error", 1)
+			if text, ok := value.(string); ok && level > 0 {
+				value = s.where(int(level)) + text
+			}
+			s.raise(value)
+			return nil
+		},
+		"assert": func(s *State, args []Value) []Value {
+			if Truthy(s.checkAny(args, 0, "assert")) {
+				return args
+			}
+			if len(args) > 1 {
+				s.raise(args[1])
+			}
+			s.Errorf("assertion failed!")
+			return nil
+		},
+		"pcall": func(s *State, args []Value) []Value {
+			fn := s.checkAny(args, 0, "pcall")
+			results, err := s.PCall(fn, args[1:]...)
+			if err != nil {
+				return []Value{false, err.(*Error).Value}
+			}
+			return append([]Value{true}, results...)
+		},
+		"xpcall": func(s *State, args []Value) []Value {
+			handler := Arg(args, 1)
+			results, err := s.PCall(s.checkAny(args, 0, "xpcall"), args[min(2, len(args)):]...)
+			if err != nil {
+				return append([]Value{false}, s.Call(handler, err.(*Error).Value)...)
+			}
+			return append([]Value{true}, results...)
+		},
+		"setmetatable": func(s *State, args []Value) []Value {
+			t := s.CheckTable(args, 0, "setmetatable")
+			switch meta := Arg(args, 1).(type) {
+			case nil:
+				t.Metatable = nil
+			case *Table:
+				t.Metatable = meta
+			default:
+				s.typeError(args, 1, "setmetatable", "nil or table")
+			}
+			return []Value{t}
+		},
+		"getmetatable": func(s *State, args []Value) []Value {
+			var meta *Table
+			switch v := Arg(args, 0).(type) {
+			case *Table:
+				meta = v.Metatable
+			case string:
+				meta = s.stringMeta
+			}
+			if meta == nil {
+				return []Value{nil}
+			}
+			if protected := meta.GetString("__metatable"); protected != nil {
+				return []Value{protected}
+			}
+			return []Value{meta}
+		},
+		"rawget": func(s *State, args []Value) []Value {
+			return []Value{s.CheckTable(args, 0, "rawget").Get(Arg(args, 1))}
+		},
+		"rawset": func(s *State, args []Value) []Value {
+			t := s.CheckTable(args, 0, "rawset")
+			if Arg(args, 1) == nil {
+				s.Errorf("index is nil")
+			}
+			t.Set(args[1], Arg(args, 2))
+			return []Value{t}
+		},
+		"rawequal": func(s *State, args []Value) []Value {
+			return []Value{RawEquals(Arg(args, 0), Arg(args, 1))}
+		},
+		"rawlen": func(s *State, args []Value) []Value {
+			switch v := Arg(args, 0).(type) {
+			case *Table:
+				return []Value{v.Len()}
+			case string:
+				return []Value{int64(len(v))}
+			}
+			s.argError(0, "rawlen", "table or string expected")
+			return nil
+		},
+		"require": func(s *State, args []Value) []Value {
+			name := s.CheckString(args, 0, "require")
+			if module, ok := s.modules[name]; ok {
+				return []Value{module}
+			}
+			if s.Loader == nil {
+				s.Errorf("module '%s' not found", name)
+			}
+			chunk, src, err := s.Loader(name)
+			if err != nil {
+				s.Errorf("module '%s' not found: %v", name, err)
+			}
+			fn, err := s.Load(chunk, src)
+			if err != nil {
+				s.raise(err.Error())
+			}
+			// a module requiring itself gets true, as Lua does once it was loaded
+			s.modules[name] = true
+			module := first(s.Call(fn, name))
+			if module == nil {
+				module = true
+			}
+			s.modules[name] = module
+			return []Value{module}
+		},
+	})
+}
+
+func openMath(s *State) {
+	m := NewTable()
+	s.SetGlobal("math", m)
+	m.Set("pi", math.Pi)
+	m.Set("huge", math.Inf(1))
+	m.Set("maxinteger", int64(math.MaxInt64))
+	m.Set("mininteger", int64(math.MinInt64))
+
+	float := func(name string, fn func(float64) float64) func(s *State, args []Value) []Value {
+		return func(s *State, args []Value) []Value {
+			return []Value{fn(s.CheckNumber(args, 0, name))}
+		}
+	}
+	register(m, map[string]func(s *State, args []Value) []Value{
+		"abs": func(s *State, args []Value) []Value {
+			if n, ok := Arg(args, 0).(int64); ok {
+				if n < 0 {
+					n = -n
+				}
+				return []Value{n}
+			}
+			return []Value{math.Abs(s.CheckNumber(args, 0, "abs"))}
+		},
+		"ceil": func(s *State, args []Value) []Value {
+			if n, ok := Arg(args, 0).(int64); ok {
+				return []Value{n}
+			}
+			return []Value{floatToInteger(math.Ceil(s.CheckNumber(args, 0, "ceil")))}
+		},
+		"floor": func(s *State, args []Value) []Value {
+			if n, ok := Arg(args, 0).(int64); ok {
+				return []Value{n}
+			}
+			return []Value{floatToInteger(math.Floor(s.CheckNumber(args, 0, "floor")))}
+		},
+		"sqrt": float("sqrt", math.Sqrt),
+		"sin":  float("sin", math.Sin),
+		"cos":  float("cos", math.Cos),
+		"tan":  float("tan", math.Tan),
+		"asin": float("asin", math.Asin),
+		"acos": float("acos", math.Acos),
+		"exp":  float("exp", math.Exp),
+		"deg":  float("deg", func(x float64) float64 { return x * 180 / math.Pi }),
+		"rad":  float("rad", func(x float64) float64 { return x * math.Pi / 180 }),
+		"sincos": func(s *State, args []Value) []Value {
+			sin, cos := math.Sincos(s.CheckNumber(args, 0, "sincos"))
+			return []Value{sin, cos}
+		},
+		"atan": func(s *State, args []Value) []Value {
+			y := s.CheckNumber(args, 0, "atan")
+			x := 1.0
+			if Arg(args, 1) != nil {
+				x = s.CheckNumber(args, 1, "atan")
+			}
+			return []Value{math.Atan2(y, x)}
+		},
+		"log": func(s *State, args []Value) []Value {
+			x := s.CheckNumber(args, 0, "log")
+			if Arg(args, 1) == nil {
+				return []Value{math.Log(x)}
+			}
+			base := s.CheckNumber(args, 1, "log")
+			switch base {
+			case 2:
+				return []Value{math.Log2(x)}
+			case 10:
+				return []Value{math.Log10(x)}
+			}
+			return []Value{math.Log(x) / math.Log(base)}
+		},
+		"fmod": func(s *State, args []Value) []Value {
+			a, aInt := Arg(args, 0).(int64)
+			b, bInt := Arg(args, 1).(int64)
+			if aInt && bInt {
+				if b == 0 {
+					s.argError(1, "fmod", "zero")
+				}
+				if b == -1 {
+					return []Value{int64(0)}
+				}
+				return []Value{a % b}
+			}
+			return []Value{math.Mod(s.CheckNumber(args, 0, "fmod"), s.CheckNumber(args, 1, "fmod"))}
+		},
+		"modf": func(s *State, args []Value) []Value {
+			x := s.CheckNumber(args, 0, "modf")
+			if math.IsInf(x, 0) {
+				return []Value{x, 0.0}
+			}
+			integer, fraction := math.Modf(x)
+			return []Value{floatToInteger(integer), fraction}
+		},
+		"tointeger": func(s *State, args []Value) []Value {
+			switch v := Arg(args, 0).(type) {
+			case int64:
+				return []Value{v}
+			case float64:
+				if n, ok := toInteger(v); ok {
+					return []Value{n}
+				}
+			}
+			return []Value{nil}
+		},
+		"type": func(s *State, args []Value) []Value {
+			switch s.checkAny(args, 0, "type").(type) {
+			case int64:
+				return []Value{"integer"}
+			case float64:
+				return []Value{"float"}
+			}
+			return []Value{nil}
+		},
+		"ult": func(s *State, args []Value) []Value {
+			return []Value{uint64(s.CheckInteger(args, 0, "ult")) < uint64(s.CheckInteger(args, 1, "ult"))}
+		},
+		"max": func(s *State, args []Value) []Value {
+			return []Value{s.extremum(args, "max", false)}
+		},
+		"min": func(s *State, args []Value) []Value {
+			return []Value{s.extremum(args, "min", true)}
+		},
+		"random": func(s *State, args []Value) []Value {
+			switch len(args) {
+			case 0:
+				return []Value{s.random.float()}
+			case 1:
+				high := s.CheckInteger(args, 0, "random")
+				if high < 1 {
+					s.argError(0, "random", "interval is empty")
+				}
+				return []Value{s.random.between(1, high)}
+			}
+			low, high := s.CheckInteger(args, 0, "random"), s.CheckInteger(args, 1, "random")
+			if low > high {
+				s.argError(1, "random", "interval is empty")
+			}
+			return []Value{s.random.between(low, high)}
+		},
+		"randomseed": func(s *State, args []Value) []Value {
+			s.Seed(uint64(s.OptInteger(args, 0, "randomseed", 0)))
+			return nil
+		},
+	})
+}
+
+func floatToInteger(f float64) Value {
+	if n, ok := toInteger(f); ok {
+		return n
+	}
+	return f
+}
+
+func (s *State) extremum(args []Value, name string, lowest bool) Value {
+	s.CheckNumber(args, 0, name)
+	result := args[0]
+	for i := 1; i < len(args); i++ {
+		s.CheckNumber(args, i, name)
+		if s.lessThan(args[i], result) == lowest && !RawEquals(args[i], result) {
+			result = args[i]
+		}
+	}
+	return result
+}
+
+func openTable(s *State) {
+	t := NewTable()
+	s.SetGlobal("table", t)
+	register(t, map[string]func(s *State, args []Value) []Value{
+		"insert": func(s *State, args []Value) []Value {
+			t := s.CheckTable(args, 0, "insert")
+			n := t.Len()
+			switch len(args) {
+			case 2:
+				t.Set(n+1, args[1])
+			case 3:
+				pos := s.CheckInteger(args, 1, "insert")
+				if pos < 1 || pos > n+1 {
+					s.argError(1, "insert", "position out of bounds")
+				}
+				t.Insert(pos, args[2])
+			default:
+				s.Errorf("wrong number of arguments to 'insert'")
+			}
+			return nil
+		},
+		"remove": func(s *State, args []Value) []Value {
+			t := s.CheckTable(args, 0, "remove")
+			n := t.Len()
+			pos := s.OptInteger(args, 1, "remove", n)
+			if pos != n && (pos < 1 || pos > n+1) {
+				s.argError(1, "remove", "position out of bounds")
+			}
+			return []Value{t.Remove(pos)}
+		},
+		"concat": func(s *State, args []Value) []Value {
+			t := s.CheckTable(args, 0, "concat")
+			separator := ""
+			if Arg(args, 1) != nil {
+				separator = s.CheckString(args, 1, "concat")
+			}
+			from := s.OptInteger(args, 2, "concat", 1)
+			to := s.OptInteger(args, 3, "concat", t.Len())
+			parts := make([]string, 0)
+			for i := from; i <= to; i++ {
+				text, ok := concatOperand(t.Get(i))
+				if !ok {
+					s.Errorf("invalid value (at index %d) in table for 'concat'", i)
+				}
+				parts = append(parts, text)
+			}
+			return []Value{strings.Join(parts, separator)}
+		},
+		"unpack": func(s *State, args []Value) []Value {
+			t := s.CheckTable(args, 0, "unpack")
+			from := s.OptInteger(args, 1, "unpack", 1)
+			to := s.OptInteger(args, 2, "unpack", t.Len())
+			values := make([]Value, 0)
+			for i := from; i <= to; i++ {
+				values = append(values, t.Get(i))
+			}
+			return values
+		},
+		"pack": func(s *State, args []Value) []Value {
+			t := NewList(slices.Clone(args)...)
+			t.Set("n", int64(len(args)))
+			return []Value{t}
+		},
+		"sort": func(s *State, args []Value) []Value {
+			t := s.CheckTable(args, 0, "sort")
+			n := t.Len()
+			values := make([]Value, n)
+			for i := range values {
+				values[i] = t.Get(int64(i + 1))
+			}
+			less := func(a, b Value) bool { return s.lessThan(a, b) }
+			if comparator := Arg(args, 1); comparator != nil {
+				s.CheckFunction(args, 1, "sort")
+				less = func(a, b Value) bool { return Truthy(first(s.Call(comparator, a, b))) }
+			}
+			slices.SortStableFunc(values, func(a, b Value) int {
+				if less(a, b) {
+					return -1
+				}
+				if less(b, a) {
+					return 1
+				}
+				return 0
+			})
+			for i, value := range values {
+				t.Set(int64(i+1), value)
+			}
+			return nil
+		},
+	})
+}
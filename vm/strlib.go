@@ -0,0 +1,693 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func openString(s *State) {
+	str := NewTable()
+	s.SetGlobal("string", str)
+	s.stringMeta = NewTable()
+	s.stringMeta.Set("__index", str)
+
+	register(str, map[string]func(s *State, args []Value) []Value{
+		"len": func(s *State, args []Value) []Value {
+			return []Value{int64(len(s.CheckString(args, 0, "len")))}
+		},
+		"sub": func(s *State, args []Value) []Value {
+			text := s.CheckString(args, 0, "sub")
+			start, end := stringRange(int64(len(text)), s.OptInteger(args, 1, "sub", 1), s.OptInteger(args, 2, "sub", -1))
+			if start > end {
+				return []Value{""}
+			}
+			return []Value{text[start-1 : end]}
+		},
+		"upper": func(s *State, args []Value) []Value {
+			return []Value{strings.ToUpper(s.CheckString(args, 0, "upper"))}
+		},
+		"lower": func(s *State, args []Value) []Value {
+			return []Value{strings.ToLower(s.CheckString(args, 0, "lower"))}
+		},
+		"rep": func(s *State, args []Value) []Value {
+			text := s.CheckString(args, 0, "rep")
+			n := s.CheckInteger(args, 1, "rep")
+			separator := ""
+			if Arg(args, 2) != nil {
+				separator = s.CheckString(args, 2, "rep")
+			}
+			if n <= 0 {
+				return []Value{""}
+			}
+			if int64(len(text)+len(separator))*n > 1<<28 {
+				s.Errorf("resulting string too large")
+			}
+			parts := make([]string, n)
+			for i := range parts {
+				parts[i] = text
+			}
+			return []Value{strings.Join(parts, separator)}
+		},
+		"reverse": func(s *State, args []Value) []Value {
+			text := []byte(s.CheckString(args, 0, "reverse"))
+			for i, j := 0, len(text)-1; i < j; i, j = i+1, j-1 {
+				text[i], text[j] = text[j], text[i]
+			}
+			return []Value{string(text)}
+		},
+		"byte": func(s *State, args []Value) []Value {
+			text := s.CheckString(args, 0, "byte")
+			from := s.OptInteger(args, 1, "byte", 1)
+			start, end := stringRange(int64(len(text)), from, s.OptInteger(args, 2, "byte", from))
+			values := make([]Value, 0)
+			for i := start; i <= end; i++ {
+				values = append(values, int64(text[i-1]))
+			}
+			return values
+		},
+		"char": func(s *State, args []Value) []Value {
+			text := make([]byte, len(args))
+			for i := range args {
+				c := s.CheckInteger(args, i, "char")
+				if c < 0 || c > 255 {
+					s.argError(i, "char", "value out of range")
+				}
+				text[i] = byte(c)
+			}
+			return []Value{string(text)}
+		},
+		"format": func(s *State, args []Value) []Value {
+			return []Value{s.format(args)}
+		},
+		"find": func(s *State, args []Value) []Value {
+			return s.find(args, "find", true)
+		},
+		"match": func(s *State, args []Value) []Value {
+			return s.find(args, "match", false)
+		},
+		"gmatch": func(s *State, args []Value) []Value {
+			text := s.CheckString(args, 0, "gmatch")
+			pattern := s.CheckString(args, 1, "gmatch")
+			position := 0
+			lastEnd := -1
+			return []Value{NewFunction("gmatch_iterator", func(s *State, _ []Value) []Value {
+				for ; position <= len(text); position++ {
+					m := newMatcher(s, text, pattern)
+					if end := m.match(position, 0); end != -1 && end != lastEnd {
+						start := position
+						position, lastEnd = end, end
+						return m.captures(start, end, true)
+					}
+				}
+				return []Value{nil}
+			})}
+		},
+		"gsub": func(s *State, args []Value) []Value {
+			return s.gsub(args)
+		},
+	})
+}
+
+// Converts the start and end of a range of a string, which count from its end when
+// negative, into positions between 1 and its length
+func stringRange(length, start, end int64) (int64, int64) {
+	if start < 0 {
+		start = max(length+start+1, 1)
+	} else if start == 0 {
+		start = 1
+	}
+	if end < 0 {
+		end = length + end + 1
+	} else if end > length {
+		end = length
+	}
+	return start, end
+}
+
+func (s *State) find(args []Value, name string, find bool) []Value {
+	text := s.CheckString(args, 0, name)
+	pattern := s.CheckString(args, 1, name)
+	init, _ := stringRange(int64(len(text)), s.OptInteger(args, 2, name, 1), -1)
+	if init > int64(len(text))+1 {
+		return []Value{nil}
+	}
+	start := int(init - 1)
+
+	plain := Truthy(Arg(args, 3)) || !strings.ContainsAny(pattern, "^$*+?.([%-")
+	if find && plain {
+		index := strings.Index(text[start:], pattern)
+		if index == -1 {
+			return []Value{nil}
+		}
+		return []Value{int64(start + index + 1), int64(start + index + len(pattern))}
+	}
+
+	m := newMatcher(s, text, pattern)
+	anchor := strings.HasPrefix(pattern, "^")
+	patternStart := 0
+	if anchor {
+		patternStart = 1
+	}
+	for position := start; position <= len(text); position++ {
+		m.level = 0
+		if end := m.match(position, patternStart); end != -1 {
+			if find {
+				return append([]Value{int64(position + 1), int64(end)}, m.captures(position, end, false)...)
+			}
+			return m.captures(position, end, true)
+		}
+		if anchor {
+			break
+		}
+	}
+	return []Value{nil}
+}
+
+func (s *State) gsub(args []Value) []Value {
+	text := s.CheckString(args, 0, "gsub")
+	pattern := s.CheckString(args, 1, "gsub")
+	replacement := Arg(args, 2)
+	switch replacement.(type) {
+	case string, int64, float64, *Table, *Function:
+	default:
+		s.typeError(args, 2, "gsub", "string/function/table")
+	}
+	maxReplacements := s.OptInteger(args, 3, "gsub", int64(len(text)+1))
+
+	anchor := strings.HasPrefix(pattern, "^")
+	patternStart := 0
+	if anchor {
+		patternStart = 1
+	}
+	result := strings.Builder{}
+	position := 0
+	lastEnd := -1
+	count := int64(0)
+	for count < maxReplacements {
+		m := newMatcher(s, text, pattern)
+		if end := m.match(position, patternStart); end != -1 && end != lastEnd {
+			count++
+			result.WriteString(s.replacement(m, position, end, replacement))
+			position, lastEnd = end, end
+		} else if position < len(text) {
+			result.WriteByte(text[position])
+			position++
+		} else {
+			break
+		}
+		if anchor {
+			break
+		}
+	}
+	result.WriteString(text[position:])
+	return []Value{result.String(), count}
+}
+
+func (s *State) replacement(m *matcher, start, end int, replacement Value) string {
+	whole := m.text[start:end]
+	var value Value
+	switch r := replacement.(type) {
+	case string, int64, float64:
+		template := ToString(r)
+		out := strings.Builder{}
+		for i := 0; i < len(template); i++ {
+			if template[i] != '%' {
+				out.WriteByte(template[i])
+				continue
+			}
+			i++
+			if i >= len(template) {
+				s.Errorf("invalid use of '%%' in replacement string")
+			}
+			c := template[i]
+			switch {
+			case c == '%':
+				out.WriteByte('%')
+			case c == '0':
+				out.WriteString(whole)
+			case c >= '1' && c <= '9':
+				capture := m.capture(int(c-'1'), start, end)
+				out.WriteString(ToString(capture))
+			default:
+				s.Errorf("invalid use of '%%' in replacement string")
+			}
+		}
+		return out.String()
+	case *Table:
+		value = s.Index(r, m.capture(0, start, end))
+	case *Function:
+		value = first(s.Call(r, m.captures(start, end, true)...))
+	}
+	switch v := value.(type) {
+	case nil:
+		return whole
+	case bool:
+		if !v {
+			return whole
+		}
+	case string, int64, float64:
+		return ToString(v)
+	}
+	s.Errorf("invalid replacement value (a %s)", TypeName(value))
+	return ""
+}
+
+// A port of the pattern matching of Lua
+
+const (
+	maxCaptures     = 32
+	capturePosition = -2
+	captureUnclosed = -1
+)
+
+type matcher struct {
+	s       *State
+	text    string
+	pattern string
+	level   int
+	ranges  [maxCaptures]struct{ start, length int }
+	depth   int
+}
+
+func newMatcher(s *State, text, pattern string) *matcher {
+	return &matcher{s: s, text: text, pattern: pattern}
+}
+
+// Returns the value of the capture, or of the whole match when the pattern has no captures
+func (m *matcher) capture(i, start, end int) Value {
+	if i >= m.level {
+		if i == 0 {
+			return m.text[start:end]
+		}
+		m.s.Errorf("invalid capture index %%%d", i+1)
+	}
+	c := m.ranges[i]
+	switch c.length {
+	case captureUnclosed:
+		m.s.Errorf("unfinished capture")
+	case capturePosition:
+		return int64(c.start + 1)
+	}
+	return m.text[c.start : c.start+c.length]
+}
+
+func (m *matcher) captures(start, end int, wholeIfNone bool) []Value {
+	n := m.level
+	if n == 0 && wholeIfNone {
+		n = 1
+	}
+	values := make([]Value, n)
+	for i := range values {
+		values[i] = m.capture(i, start, end)
+	}
+	return values
+}
+
+func (m *matcher) classEnd(p int) int {
+	if p >= len(m.pattern) {
+		m.s.Errorf("malformed pattern (ends with '%%')")
+	}
+	c := m.pattern[p]
+	p++
+	if c == '%' {
+		if p >= len(m.pattern) {
+			m.s.Errorf("malformed pattern (ends with '%%')")
+		}
+		return p + 1
+	}
+	if c == '[' {
+		if p < len(m.pattern) && m.pattern[p] == '^' {
+			p++
+		}
+		// looks for the closing ']', the first character being part of the set even when it is one
+		for {
+			if p >= len(m.pattern) {
+				m.s.Errorf("malformed pattern (missing ']')")
+			}
+			c := m.pattern[p]
+			p++
+			if c == '%' && p < len(m.pattern) {
+				p++
+			}
+			if p >= len(m.pattern) {
+				m.s.Errorf("malformed pattern (missing ']')")
+			}
+			if m.pattern[p] == ']' {
+				return p + 1
+			}
+		}
+	}
+	return p
+}
+
+func matchClass(c byte, class byte) bool {
+	var result bool
+	lower := class | 0x20
+	switch lower {
+	case 'a':
+		result = c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	case 'c':
+		result = c < 32 || c == 127
+	case 'd':
+		result = c >= '0' && c <= '9'
+	case 'g':
+		result = c > 32 && c < 127
+	case 'l':
+		result = c >= 'a' && c <= 'z'
+	case 'p':
+		result = c > 32 && c < 127 && !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9')
+	case 's':
+		result = c == ' ' || c >= '\t' && c <= '\r'
+	case 'u':
+		result = c >= 'A' && c <= 'Z'
+	case 'w':
+		result = c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	case 'x':
+		result = c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+	default:
+		return class == c
+	}
+	if class >= 'A' && class <= 'Z' {
+		return !result
+	}
+	return result
+}
+
+// Tells whether the character is in the set between p (at its '[') and end (at its ']')
+func (m *matcher) matchSet(c byte, p, end int) bool {
+	negate := false
+	p++
+	if m.pattern[p] == '^' {
+		negate = true
+		p++
+	}
+	for ; p < end; p++ {
+		switch {
+		case m.pattern[p] == '%':
+			p++
+			if matchClass(c, m.pattern[p]) {
+				return !negate
+			}
+		case p+2 < end && m.pattern[p+1] == '-':
+			if m.pattern[p] <= c && c <= m.pattern[p+2] {
+				return !negate
+			}
+			p += 2
+		case m.pattern[p] == c:
+			return !negate
+		}
+	}
+	return negate
+}
+
+func (m *matcher) singleMatch(position, p, ep int) bool {
+	if position >= len(m.text) {
+		return false
+	}
+	c := m.text[position]
+	switch m.pattern[p] {
+	case '.':
+		return true
+	case '%':
+		return matchClass(c, m.pattern[p+1])
+	case '[':
+		return m.matchSet(c, p, ep-1)
+	}
+	return m.pattern[p] == c
+}
+
+// Matches the pattern from p against the text from position, returning the end of the match or -1
+func (m *matcher) match(position, p int) int {
+	m.depth++
+	if m.depth > 200 {
+		m.s.Errorf("pattern too complex")
+	}
+	defer func() { m.depth-- }()
+
+	for {
+		if p >= len(m.pattern) {
+			return position
+		}
+		switch m.pattern[p] {
+		case '(':
+			if p+1 < len(m.pattern) && m.pattern[p+1] == ')' {
+				return m.startCapture(position, p+2, capturePosition)
+			}
+			return m.startCapture(position, p+1, captureUnclosed)
+		case ')':
+			return m.endCapture(position, p+1)
+		case '$':
+			if p+1 == len(m.pattern) {
+				if position == len(m.text) {
+					return position
+				}
+				return -1
+			}
+		case '%':
+			if p+1 < len(m.pattern) {
+				switch next := m.pattern[p+1]; {
+				case next == 'b':
+					position = m.matchBalance(position, p+2)
+					if position == -1 {
+						return -1
+					}
+					p += 4
+					continue
+				case next == 'f':
+					p += 2
+					if p >= len(m.pattern) || m.pattern[p] != '[' {
+						m.s.Errorf("missing '[' after '%%f' in pattern")
+					}
+					ep := m.classEnd(p)
+					var previous, current byte
+					if position > 0 {
+						previous = m.text[position-1]
+					}
+					if position < len(m.text) {
+						current = m.text[position]
+					}
+					if !m.matchSet(previous, p, ep-1) && m.matchSet(current, p, ep-1) {
+						p = ep
+						continue
+					}
+					return -1
+				case next >= '0' && next <= '9':
+					position = m.matchCapture(position, int(next-'1'))
+					if position == -1 {
+						return -1
+					}
+					p += 2
+					continue
+				}
+			}
+		}
+
+		ep := m.classEnd(p)
+		var epc byte
+		if ep < len(m.pattern) {
+			epc = m.pattern[ep]
+		}
+		if !m.singleMatch(position, p, ep) {
+			if epc == '*' || epc == '?' || epc == '-' {
+				// accepts an empty match
+				p = ep + 1
+				continue
+			}
+			return -1
+		}
+		switch epc {
+		case '?':
+			if result := m.match(position+1, ep+1); result != -1 {
+				return result
+			}
+			p = ep + 1
+			continue
+		case '+':
+			return m.maxExpand(position+1, p, ep)
+		case '*':
+			return m.maxExpand(position, p, ep)
+		case '-':
+			return m.minExpand(position, p, ep)
+		}
+		position++
+		p = ep
+	}
+}
+
+func (m *matcher) maxExpand(position, p, ep int) int {
+	i := 0
+	for m.singleMatch(position+i, p, ep) {
+		i++
+	}
+	for ; i >= 0; i-- {
+		if result := m.match(position+i, ep+1); result != -1 {
+			return result
+		}
+	}
+	return -1
+}
+
+func (m *matcher) minExpand(position, p, ep int) int {
+	for {
+		if result := m.match(position, ep+1); result != -1 {
+			return result
+		}
+		if !m.singleMatch(position, p, ep) {
+			return -1
+		}
+		position++
+	}
+}
+
+func (m *matcher) startCapture(position, p, what int) int {
+	if m.level >= maxCaptures {
+		m.s.Errorf("too many captures")
+	}
+	m.ranges[m.level].start = position
+	m.ranges[m.level].length = what
+	m.level++
+	result := m.match(position, p)
+	if result == -1 {
+		m.level--
+	}
+	return result
+}
+
+func (m *matcher) endCapture(position, p int) int {
+	open := -1
+	for i := m.level - 1; i >= 0; i-- {
+		if m.ranges[i].length == captureUnclosed {
+			open = i
+			break
+		}
+	}
+	if open == -1 {
+		m.s.Errorf("invalid pattern capture")
+	}
+	m.ranges[open].length = position - m.ranges[open].start
+	result := m.match(position, p)
+	if result == -1 {
+		m.ranges[open].length = captureUnclosed
+	}
+	return result
+}
+
+func (m *matcher) matchBalance(position, p int) int {
+	if p+1 >= len(m.pattern) {
+		m.s.Errorf("malformed pattern (missing arguments to '%%b')")
+	}
+	if position >= len(m.text) || m.text[position] != m.pattern[p] {
+		return -1
+	}
+	open, close := m.pattern[p], m.pattern[p+1]
+	depth := 1
+	for i := position + 1; i < len(m.text); i++ {
+		switch m.text[i] {
+		case close:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case open:
+			depth++
+		}
+	}
+	return -1
+}
+
+func (m *matcher) matchCapture(position, i int) int {
+	if i < 0 || i >= m.level || m.ranges[i].length == captureUnclosed {
+		m.s.Errorf("invalid capture index %%%d", i+1)
+	}
+	c := m.ranges[i]
+	captured := m.text[c.start : c.start+c.length]
+	if strings.HasPrefix(m.text[position:], captured) {
+		return position + len(captured)
+	}
+	return -1
+}
+
+func (s *State) format(args []Value) string {
+	template := s.CheckString(args, 0, "format")
+	out := strings.Builder{}
+	arg := 0
+	for i := 0; i < len(template); i++ {
+		if template[i] != '%' {
+			out.WriteByte(template[i])
+			continue
+		}
+		i++
+		if i < len(template) && template[i] == '%' {
+			out.WriteByte('%')
+			continue
+		}
+		start := i
+		for i < len(template) && strings.IndexByte("-+ #0123456789.", template[i]) != -1 {
+			i++
+		}
+		if i >= len(template) {
+			s.Errorf("invalid conversion '%%%s' to 'format'", template[start:])
+		}
+		spec := template[start:i]
+		verb := template[i]
+		arg++
+		switch verb {
+		case 'd', 'i':
+			n := s.CheckInteger(args, arg, "format")
+			out.WriteString(fmt.Sprintf("%"+spec+"d", n))
+		case 'u':
+			out.WriteString(fmt.Sprintf("%"+spec+"d", uint64(s.CheckInteger(args, arg, "format"))))
+		case 'c':
+			out.WriteByte(byte(s.CheckInteger(args, arg, "format")))
+		case 'x', 'X', 'o':
+			out.WriteString(fmt.Sprintf("%"+spec+string(verb), uint64(s.CheckInteger(args, arg, "format"))))
+		case 'f', 'F', 'e', 'E', 'g', 'G':
+			var n float64
+			if fixed, ok := Arg(args, arg).(Fixed); ok {
+				n = fixed.Float()
+			} else {
+				n = s.CheckNumber(args, arg, "format")
+			}
+			out.WriteString(fmt.Sprintf("%"+spec+string(verb), n))
+		case 'a', 'A':
+			out.WriteString(strconv.FormatFloat(s.CheckNumber(args, arg, "format"), 'x', -1, 64))
+		case 's':
+			out.WriteString(fmt.Sprintf("%"+spec+"s", s.ToString(s.checkAny(args, arg, "format"))))
+		case 'q':
+			out.WriteString(quote(Arg(args, arg)))
+		default:
+			s.Errorf("invalid conversion '%%%s' to 'format'", template[start:i+1])
+		}
+	}
+	return out.String()
+}
+
+func quote(v Value) string {
+	text, ok := v.(string)
+	if !ok {
+		return ToString(v)
+	}
+	out := strings.Builder{}
+	out.WriteByte('"')
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '"' || c == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case c == '\n':
+			out.WriteString("\\n")
+		case c == '\r':
+			out.WriteString("\\r")
+		case c == 0:
+			out.WriteString("\\0")
+		case c < 32 || c == 127:
+			out.WriteString(fmt.Sprintf("\\%d", c))
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
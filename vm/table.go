@@ -0,0 +1,228 @@
+package vm
+
+import "math"
+
+// A Lua table. The keys of its hash part keep the order they were added in, so that
+// traversing a table with next or pairs gives the same order on every run
+type Table struct {
+	array   []Value
+	entries []tableEntry
+	hash    map[Value]int
+	// the number of entries holding nil
+	removed   int
+	Metatable *Table
+}
+
+type tableEntry struct {
+	key, value Value
+}
+
+func NewTable() *Table {
+	return &Table{}
+}
+
+// Creates a table holding the values as a sequence
+func NewList(values ...Value) *Table {
+	t := &Table{array: values}
+	t.trim()
+	return t
+}
+
+// Normalizes float keys with an integer value to integers, as Lua does
+func normalizeKey(key Value) Value {
+	if f, ok := key.(float64); ok {
+		if i, ok := toInteger(f); ok {
+			return i
+		}
+	}
+	return key
+}
+
+func (t *Table) Get(key Value) Value {
+	if i, ok := key.(int64); ok && i >= 1 && i <= int64(len(t.array)) {
+		return t.array[i-1]
+	}
+	if t.hash == nil {
+		return nil
+	}
+	if index, ok := t.hash[normalizeKey(key)]; ok {
+		return t.entries[index].value
+	}
+	return nil
+}
+
+func (t *Table) GetString(key string) Value {
+	if t.hash == nil {
+		return nil
+	}
+	if index, ok := t.hash[key]; ok {
+		return t.entries[index].value
+	}
+	return nil
+}
+
+// Sets the key without checking it, the caller makes sure it is neither nil nor NaN
+func (t *Table) Set(key, value Value) {
+	key = normalizeKey(key)
+	if i, ok := key.(int64); ok {
+		switch {
+		case i >= 1 && i <= int64(len(t.array)):
+			t.array[i-1] = value
+			if value == nil && i == int64(len(t.array)) {
+				t.trim()
+			}
+			return
+		case i == int64(len(t.array))+1 && value != nil:
+			t.array = append(t.array, value)
+			t.setHash(key, nil)
+			t.migrate()
+			return
+		}
+	}
+	t.setHash(key, value)
+}
+
+func (t *Table) setHash(key, value Value) {
+	if index, ok := t.hash[key]; ok {
+		if t.entries[index].value != nil && value == nil {
+			t.removed++
+		} else if t.entries[index].value == nil && value != nil {
+			t.removed--
+		}
+		t.entries[index].value = value
+		return
+	}
+	if value == nil {
+		return
+	}
+	// the removed entries are only dropped when a key is added, as next has to find the key
+	// of the previous step while the table is traversed and its fields are cleared
+	if t.removed > 8 && t.removed > len(t.entries)/2 {
+		t.compact()
+	}
+	if t.hash == nil {
+		t.hash = make(map[Value]int)
+	}
+	t.hash[key] = len(t.entries)
+	t.entries = append(t.entries, tableEntry{key, value})
+}
+
+func (t *Table) compact() {
+	entries := make([]tableEntry, 0, len(t.entries)-t.removed)
+	for _, entry := range t.entries {
+		if entry.value != nil {
+			t.hash[entry.key] = len(entries)
+			entries = append(entries, entry)
+		} else {
+			delete(t.hash, entry.key)
+		}
+	}
+	t.entries = entries
+	t.removed = 0
+}
+
+// Moves the keys that follow the array part from the hash part into it
+func (t *Table) migrate() {
+	for t.hash != nil {
+		next := int64(len(t.array)) + 1
+		index, ok := t.hash[next]
+		if !ok || t.entries[index].value == nil {
+			return
+		}
+		t.array = append(t.array, t.entries[index].value)
+		t.setHash(next, nil)
+	}
+}
+
+func (t *Table) trim() {
+	for len(t.array) > 0 && t.array[len(t.array)-1] == nil {
+		t.array = t.array[:len(t.array)-1]
+	}
+}
+
+// The length of the table, a border of its sequence
+func (t *Table) Len() int64 {
+	if len(t.array) > 0 || t.hash == nil {
+		return int64(len(t.array))
+	}
+	// the sequence is only found in the hash part when it was built from the back
+	n := int64(0)
+	for t.Get(n+1) != nil {
+		n++
+	}
+	return n
+}
+
+// Returns the key and value after the given key in the traversal order, or nil when the
+// traversal is over. The second result is false for a key that is not in the table
+func (t *Table) Next(key Value) (Value, Value, bool) {
+	i := 0
+	if key != nil {
+		key = normalizeKey(key)
+		if n, ok := key.(int64); ok && n >= 1 && n <= int64(len(t.array)) {
+			i = int(n)
+		} else {
+			index, ok := t.hash[key]
+			if !ok {
+				return nil, nil, false
+			}
+			i = len(t.array) + index + 1
+		}
+	}
+	for ; i < len(t.array); i++ {
+		if t.array[i] != nil {
+			return int64(i + 1), t.array[i], true
+		}
+	}
+	for index := i - len(t.array); index < len(t.entries); index++ {
+		if entry := t.entries[index]; entry.value != nil {
+			return entry.key, entry.value, true
+		}
+	}
+	return nil, nil, true
+}
+
+// Inserts the value at the position of the sequence, moving the values after it up
+func (t *Table) Insert(pos int64, value Value) {
+	n := t.Len()
+	if pos == n+1 {
+		t.Set(pos, value)
+		return
+	}
+	if int64(len(t.array)) == n && value != nil {
+		t.array = append(t.array, nil)
+		copy(t.array[pos:], t.array[pos-1:])
+		t.array[pos-1] = value
+		t.migrate()
+		return
+	}
+	for i := n; i >= pos; i-- {
+		t.Set(i+1, t.Get(i))
+	}
+	t.Set(pos, value)
+}
+
+// Removes the value at the position of the sequence, moving the values after it down
+func (t *Table) Remove(pos int64) Value {
+	n := t.Len()
+	value := t.Get(pos)
+	if int64(len(t.array)) == n && pos >= 1 && pos <= n {
+		copy(t.array[pos-1:], t.array[pos:])
+		t.array[n-1] = nil
+		t.array = t.array[:n-1]
+		t.trim()
+		return value
+	}
+	for i := pos; i < n; i++ {
+		t.Set(i, t.Get(i+1))
+	}
+	if pos <= n {
+		t.Set(n, nil)
+	}
+	return value
+}
+
+func isNaN(v Value) bool {
+	f, ok := v.(float64)
+	return ok && math.IsNaN(f)
+}
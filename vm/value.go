@@ -0,0 +1,177 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Lua value: nil, bool, int64 (integer), float64 (float), Fixed, string, *Table, *Function or *Coroutine
+type Value any
+
+// The fixedpoint numbers of PewPew Live, counting 1/4096ths
+type Fixed int64
+
+const FixedOne = 4096
+
+func FixedFromFloat(f float64) Fixed {
+	return Fixed(math.Round(f * FixedOne))
+}
+
+func (f Fixed) Float() float64 {
+	return float64(f) / FixedOne
+}
+
+// Formats the fixedpoint the way PewPew prints it, with up to 4 decimals
+func (f Fixed) String() string {
+	text := strconv.FormatFloat(f.Float(), 'f', 4, 64)
+	text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	if text == "-0" {
+		return "0"
+	}
+	return text
+}
+
+// A function written in Lua, or provided by Go
+type Function struct {
+	Name   string
+	proto  *funcProto
+	upvals []*cell
+	native func(s *State, args []Value) []Value
+}
+
+// Creates a function provided by Go. It raises errors with State.Errorf
+func NewFunction(name string, fn func(s *State, args []Value) []Value) *Function {
+	return &Function{Name: name, native: fn}
+}
+
+type cell struct {
+	value Value
+}
+
+func TypeName(v Value) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case int64, float64:
+		return "number"
+	case Fixed:
+		return "fixedpoint"
+	case string:
+		return "string"
+	case *Table:
+		return "table"
+	case *Function:
+		return "function"
+	case *Coroutine:
+		return "thread"
+	}
+	return "userdata"
+}
+
+func Truthy(v Value) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	}
+	return true
+}
+
+// Converts the value to a string like tostring does, without the __tostring metamethod
+func ToString(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatFloat(v)
+	case Fixed:
+		return v.String()
+	case string:
+		return v
+	case *Table:
+		return fmt.Sprintf("table: %p", v)
+	case *Function:
+		if v.native != nil {
+			return fmt.Sprintf("builtin: %p", v)
+		}
+		return fmt.Sprintf("function: %p", v)
+	case *Coroutine:
+		return fmt.Sprintf("thread: %p", v)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		if math.Signbit(f) {
+			return "-nan"
+		}
+		return "nan"
+	}
+	text := strconv.FormatFloat(f, 'g', 14, 64)
+	if !strings.ContainsAny(text, ".e") {
+		text += ".0"
+	}
+	return text
+}
+
+// Converts strings to numbers the way the arithmetic of Lua does
+func toNumber(v Value) (Value, bool) {
+	switch v := v.(type) {
+	case int64, float64, Fixed:
+		return v, true
+	case string:
+		n, ok := parseNumber(v)
+		if !ok {
+			if i, err := strconv.ParseInt(strings.TrimSpace(v), 0, 64); err == nil {
+				return i, true
+			}
+		}
+		return n, ok
+	}
+	return nil, false
+}
+
+func toInteger(v Value) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && n >= -(1<<63) && n < 1<<63 {
+			return int64(n), true
+		}
+	case string:
+		if converted, ok := toNumber(n); ok {
+			return toInteger(converted)
+		}
+	}
+	return 0, false
+}
+
+func toFloat(v Value) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		if converted, ok := toNumber(n); ok {
+			return toFloat(converted)
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,86 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunks(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"arithmetic", `return 7 // 2, 7 / 2, 2^10, -7 % 3, 10 - 2 * 3`, "3 3.5 1024.0 2 4"},
+		{"fixedpoint", `return 3.2048fx * 1.2048fx, -7fx // 2fx, 1fx / 4fx, 2fx == 2fx`, "5.25 -4 0.25 true"},
+		{"strings", `return ("a,b,,c"):find(",", 1, true), #"hello", ("x"):rep(3, "-"), string.format("%d %s %5.2f", 4, "y", 1.5)`, "2 5 x-x-x 4 y  1.50"},
+		{"patterns", `local t = {} for w in ("one two  three"):gmatch("%a+") do t[#t+1] = w end return table.concat(t, "|"), ("hello world"):gsub("o", "0")`, "one|two|three hell0 w0rld 2"},
+		{"closures", `local fns = {} for i = 1, 3 do fns[i] = function() return i end end return fns[1](), fns[3]()`, "1 3"},
+		{"goto continue", `local s = 0 for i = 1, 5 do if i % 2 == 0 then goto continue end s = s + i ::continue:: end return s`, "9"},
+		{"metatables", `local v = setmetatable({}, {__index = function(t, k) return k .. "!" end, __add = function(a, b) return 42 end}) return v.hi, v + 1`, "hi! 42"},
+		{"varargs", `local function f(...) return select("#", ...), ... end return f(1, nil, 3)`, "3 1 nil 3"},
+		{"methods", `local C = {} C.__index = C function C.new(x) return setmetatable({x = x}, C) end function C:get() return self.x end return C.new(5):get()`, "5"},
+		{"sort and remove", `local t = {5, 2, 8, 1} table.sort(t, function(a, b) return a > b end) table.remove(t, 1) return table.concat(t, ",")`, "5,2,1"},
+		{"pcall", `return pcall(function() error({code = 1}) end)`, "false table"},
+		{"repeat", `local i = 0 repeat i = i + 1 until i >= 3 return i`, "3"},
+		{"coroutines", `local co = coroutine.create(function(a) local b = coroutine.yield(a + 1) return b * 2 end) local _, x = coroutine.resume(co, 1) local _, y = coroutine.resume(co, 5) return x, y, coroutine.status(co), coroutine.resume(co)`, "2 10 dead false cannot resume dead coroutine"},
+		{"coroutine errors", `local co = coroutine.create(function() coroutine.yield() error("late") end) coroutine.resume(co) return coroutine.resume(co)`, "false test:1: late"},
+		{"wrap", `local gen = coroutine.wrap(function() for i = 1, 3 do coroutine.yield(i) end end) return gen(), gen(), gen(), coroutine.isyieldable()`, "1 2 3 false"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewState()
+			values, err := s.DoString("test", test.src)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := make([]string, len(values))
+			for i, value := range values {
+				if table, ok := value.(*Table); ok && table != nil {
+					got[i] = "table"
+					continue
+				}
+				got[i] = ToString(value)
+			}
+			if strings.Join(got, " ") != test.want {
+				t.Errorf("got %q, want %q", strings.Join(got, " "), test.want)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"mixed fixedpoint", "local a = 1fx\nreturn a + 1", "test:2: attempt to perform arithmetic between a fixedpoint and a number (fixedpoint + number)"},
+		{"nil call", "local t = {}\nt.missing()", "test:2: attempt to call a nil value (field 'missing')"},
+		{"nil index", "local x\nreturn x.y", "test:2: attempt to index a nil value (local 'x')"},
+		{"error", `error("boom")`, "test:1: boom"},
+		{"bad argument", `return ("x"):rep()`, "test:1: bad argument #2 to 'rep' (number expected, got no value)"},
+		{"infinite loop", "while true do end", "test:1: the script ran for more than 1000 steps, it likely loops forever"},
+		{"yield outside a coroutine", "coroutine.yield()", "test:1: attempt to yield from outside a coroutine"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewState()
+			s.StepLimit = 1000
+			_, err := s.DoString("test", test.src)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if err.Error() != test.want {
+				t.Errorf("got %q, want %q", err.Error(), test.want)
+			}
+		})
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := NewState().Load("test", "local x = = 1")
+	if err == nil || !strings.HasPrefix(err.Error(), "test:1:") {
+		t.Errorf("expected a syntax error at test:1, got %v", err)
+	}
+}
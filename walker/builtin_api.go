@@ -76,6 +76,20 @@ var BuiltinEnv = &Environment{
 				IsConst: true,
 				IsPub:   true,
 			},
+			"assert": {
+				Name:    "assert",
+				Value:   NewFunction([]string{"condition", "message"}, NewBasicType(ast.Bool), NewVariadicType(NewBasicType(ast.Text))),
+				IsConst: true,
+				IsPub:   true,
+				Doc:     "Raises an error when the condition is false, with the message if there is one",
+			},
+			"assert_eq": {
+				Name:    "assert_eq",
+				Value:   NewFunction([]string{"actual", "expected"}, NewGeneric("T"), NewGeneric("T")).WithGenerics(NewGeneric("T")),
+				IsConst: true,
+				IsPub:   true,
+				Doc:     "Raises an error showing both values when they are not equal, comparing lists, maps and structs by their contents",
+			},
 		},
 		Tag: &UntaggedTag{},
 		AliasTypes: map[string]*AliasType{
@@ -0,0 +1,431 @@
+package walker
+
+import (
+	"hybroid/alerts"
+	"hybroid/ast"
+	"hybroid/tokens"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Constant folding: unary, binary and grouping expressions whose operands are literals are replaced by the
+// literal of their result, so that the generator emits the result instead of the arithmetic. The operands
+// are folded before the expression using them, which folds whole expressions from their leaves up, and the
+// identifiers of constants are replaced by the expression of their value before that.
+//
+// Numbers follow the semantics of Lua 5.3: integers wrap around and floats are IEEE doubles. Fixedpoints
+// follow the ones of PewPew: a 64-bit integer counting 1/4096ths, where a product is shifted back by 12
+// bits and a quotient is truncated towards zero.
+
+type constantKind int
+
+const (
+	integerConstant constantKind = iota
+	floatConstant
+	fixedConstant
+	textConstant
+	boolConstant
+)
+
+type constant struct {
+	kind    constantKind
+	integer int64
+	float   float64
+	// in 1/4096ths
+	fixed   int64
+	text    string
+	boolean bool
+}
+
+const fixedOne = 4096
+
+var (
+	minFixed = big.NewInt(math.MinInt64)
+	maxFixed = big.NewInt(math.MaxInt64)
+)
+
+// Returns the constant of a literal, looking through the groups around it
+func constantOf(node ast.Node) (constant, bool) {
+	for {
+		group, ok := node.(*ast.GroupExpr)
+		if !ok {
+			break
+		}
+		node = group.Expr
+	}
+	literal, ok := node.(*ast.LiteralExpr)
+	if !ok || literal.IsEnvPath {
+		return constant{}, false
+	}
+
+	switch literal.Token.Type {
+	case tokens.True, tokens.False:
+		return constant{kind: boolConstant, boolean: literal.Value == "true"}, true
+	case tokens.String:
+		return constant{kind: textConstant, text: literal.Value}, true
+	case tokens.Number:
+		if strings.ContainsAny(literal.Value, ".eE") {
+			float, err := strconv.ParseFloat(literal.Value, 64)
+			return constant{kind: floatConstant, float: float}, err == nil
+		}
+		// an integer too large for 64 bits is read as a float by Lua
+		integer, err := strconv.ParseInt(literal.Value, 10, 64)
+		return constant{kind: integerConstant, integer: integer}, err == nil
+	case tokens.Fixed, tokens.Radian, tokens.Degree:
+		float, err := strconv.ParseFloat(literal.Value, 64)
+		if err != nil {
+			return constant{}, false
+		}
+		if literal.Token.Type == tokens.Degree {
+			float = float * math.Pi / 180
+		}
+		// the same conversion as the one of the generator
+		abs := math.Abs(float)
+		integer := math.Floor(abs)
+		if integer >= 1<<51 {
+			return constant{}, false
+		}
+		fixed := int64(integer)*fixedOne + int64(math.Floor((abs-integer)*fixedOne))
+		if float < 0 {
+			fixed = -fixed
+		}
+		return constant{kind: fixedConstant, fixed: fixed}, true
+	case tokens.FixedPoint:
+		// the digits after the point are the 1/4096ths, the value of a folded literal can be negative
+		value, negative := strings.CutPrefix(literal.Value, "-")
+		integerPart, fractionPart, _ := strings.Cut(value, ".")
+		integer, err := strconv.ParseInt(integerPart, 10, 64)
+		if err != nil || integer > math.MaxInt64/fixedOne {
+			return constant{}, false
+		}
+		fraction := int64(0)
+		if fractionPart != "" {
+			fraction, err = strconv.ParseInt(fractionPart, 10, 64)
+			if err != nil || fraction >= fixedOne {
+				return constant{}, false
+			}
+		}
+		fixed := integer*fixedOne + fraction
+		if negative {
+			fixed = -fixed
+		}
+		return constant{kind: fixedConstant, fixed: fixed}, true
+	}
+	return constant{}, false
+}
+
+// Whether the source of the constant starts with a minus, which makes it bind looser than a '^'
+func (c constant) negative() bool {
+	switch c.kind {
+	case integerConstant:
+		return c.integer < 0
+	case floatConstant:
+		return math.Signbit(c.float)
+	case fixedConstant:
+		return c.fixed < 0
+	}
+	return false
+}
+
+// Returns the literal of the constant, placed at the token
+func (c constant) literal(at tokens.Token) *ast.LiteralExpr {
+	var tokenType tokens.TokenType
+	var value string
+	switch c.kind {
+	case integerConstant:
+		tokenType, value = tokens.Number, strconv.FormatInt(c.integer, 10)
+	case floatConstant:
+		tokenType, value = tokens.Number, strconv.FormatFloat(c.float, 'g', -1, 64)
+		// Lua reads a number without a point or an exponent as an integer
+		if !strings.ContainsAny(value, ".e") {
+			value += ".0"
+		}
+	case fixedConstant:
+		tokenType = tokens.FixedPoint
+		abs := new(big.Int).Abs(big.NewInt(c.fixed))
+		integer, fraction := new(big.Int).QuoRem(abs, big.NewInt(fixedOne), new(big.Int))
+		value = integer.String()
+		if fraction.Sign() != 0 {
+			value += "." + fraction.String()
+		}
+		if c.fixed < 0 {
+			value = "-" + value
+		}
+	case textConstant:
+		tokenType, value = tokens.String, c.text
+	case boolConstant:
+		tokenType, value = tokens.False, "false"
+		if c.boolean {
+			tokenType, value = tokens.True, "true"
+		}
+	}
+	token := tokens.NewToken(tokenType, value, value, at.Location)
+	return &ast.LiteralExpr{Value: value, Token: token}
+}
+
+// Replaces the expression with the literal of its result, when its operands are literals
+func (w *Walker) foldConstant(node *ast.Node) {
+	var result constant
+	var ok bool
+	switch expr := (*node).(type) {
+	case *ast.GroupExpr:
+		result, ok = constantOf(expr.Expr)
+		// the group keeps a negative result from binding to a following '^'
+		ok = ok && !result.negative()
+	case *ast.UnaryExpr:
+		var value constant
+		if value, ok = constantOf(expr.Value); ok {
+			result, ok = w.foldUnary(expr, value)
+		}
+	case *ast.BinaryExpr:
+		left, leftOk := constantOf(expr.Left)
+		right, rightOk := constantOf(expr.Right)
+		if leftOk && rightOk {
+			result, ok = w.foldBinary(expr, left, right)
+		}
+	}
+	if ok {
+		*node = result.literal(firstToken(*node))
+	}
+}
+
+func firstToken(node ast.Node) tokens.Token {
+	switch node := node.(type) {
+	case *ast.BinaryExpr:
+		return firstToken(node.Left)
+	case *ast.UnaryExpr:
+		return node.Operator
+	}
+	return node.GetToken()
+}
+
+func (w *Walker) foldUnary(expr *ast.UnaryExpr, value constant) (constant, bool) {
+	switch expr.Operator.Type {
+	case tokens.Bang:
+		if value.kind == boolConstant {
+			return constant{kind: boolConstant, boolean: !value.boolean}, true
+		}
+	case tokens.Minus:
+		switch value.kind {
+		case integerConstant:
+			// wraps around like in Lua
+			return constant{kind: integerConstant, integer: -value.integer}, true
+		case floatConstant:
+			return constant{kind: floatConstant, float: -value.float}, true
+		case fixedConstant:
+			return w.fixedResult(expr.Operator, new(big.Int).Neg(big.NewInt(value.fixed)))
+		}
+	}
+	return constant{}, false
+}
+
+func (w *Walker) foldBinary(expr *ast.BinaryExpr, left, right constant) (constant, bool) {
+	op := expr.Operator
+	switch {
+	case left.kind == boolConstant && right.kind == boolConstant:
+		switch op.Type {
+		case tokens.And:
+			return constant{kind: boolConstant, boolean: left.boolean && right.boolean}, true
+		case tokens.Or:
+			return constant{kind: boolConstant, boolean: left.boolean || right.boolean}, true
+		case tokens.EqualEqual:
+			return constant{kind: boolConstant, boolean: left.boolean == right.boolean}, true
+		case tokens.BangEqual:
+			return constant{kind: boolConstant, boolean: left.boolean != right.boolean}, true
+		}
+	case left.kind == textConstant && right.kind == textConstant:
+		switch op.Type {
+		case tokens.Concat:
+			return constant{kind: textConstant, text: left.text + right.text}, true
+		}
+		// escape sequences can spell the same text differently
+		if strings.Contains(left.text+right.text, "\\") {
+			return constant{}, false
+		}
+		switch op.Type {
+		case tokens.EqualEqual:
+			return constant{kind: boolConstant, boolean: left.text == right.text}, true
+		case tokens.BangEqual:
+			return constant{kind: boolConstant, boolean: left.text != right.text}, true
+		}
+	case left.kind == fixedConstant && right.kind == fixedConstant:
+		return w.foldFixed(op, left.fixed, right.fixed)
+	case left.kind == integerConstant && right.kind == integerConstant:
+		return w.foldInteger(op, left.integer, right.integer)
+	case isNumber(left) && isNumber(right):
+		return w.foldFloat(op, left.asFloat(), right.asFloat())
+	}
+	return constant{}, false
+}
+
+func isNumber(c constant) bool {
+	return c.kind == integerConstant || c.kind == floatConstant
+}
+
+func (c constant) asFloat() float64 {
+	if c.kind == integerConstant {
+		return float64(c.integer)
+	}
+	return c.float
+}
+
+func compared[T int64 | float64](op tokens.TokenType, a, b T) (constant, bool) {
+	var result bool
+	switch op {
+	case tokens.EqualEqual:
+		result = a == b
+	case tokens.BangEqual:
+		result = a != b
+	case tokens.Less:
+		result = a < b
+	case tokens.LessEqual:
+		result = a <= b
+	case tokens.Greater:
+		result = a > b
+	case tokens.GreaterEqual:
+		result = a >= b
+	default:
+		return constant{}, false
+	}
+	return constant{kind: boolConstant, boolean: result}, true
+}
+
+func (w *Walker) foldInteger(op tokens.Token, a, b int64) (constant, bool) {
+	integer := func(value int64) (constant, bool) {
+		return constant{kind: integerConstant, integer: value}, true
+	}
+	switch op.Type {
+	case tokens.Plus, tokens.Minus, tokens.Star:
+		result := new(big.Int)
+		switch op.Type {
+		case tokens.Plus:
+			result.Add(big.NewInt(a), big.NewInt(b))
+		case tokens.Minus:
+			result.Sub(big.NewInt(a), big.NewInt(b))
+		case tokens.Star:
+			result.Mul(big.NewInt(a), big.NewInt(b))
+		}
+		if !result.IsInt64() {
+			w.AlertSingle(&alerts.ConstantOverflow{}, op, "integers", "-2^63 to 2^63-1")
+			return constant{}, false
+		}
+		return integer(result.Int64())
+	case tokens.BackSlash, tokens.Modulo:
+		if b == 0 {
+			w.AlertSingle(&alerts.ConstantDivisionByZero{}, op)
+			return constant{}, false
+		}
+		// the quotient rounds towards negative infinity, the remainder takes the sign of the divisor
+		if a == math.MinInt64 && b == -1 {
+			if op.Type == tokens.Modulo {
+				return integer(0)
+			}
+			return integer(math.MinInt64)
+		}
+		quotient, remainder := a/b, a%b
+		if remainder != 0 && (remainder < 0) != (b < 0) {
+			quotient--
+			remainder += b
+		}
+		if op.Type == tokens.Modulo {
+			return integer(remainder)
+		}
+		return integer(quotient)
+	case tokens.Slash, tokens.Caret:
+		return w.foldFloat(op, float64(a), float64(b))
+	case tokens.Ampersand:
+		return integer(a & b)
+	case tokens.Pipe:
+		return integer(a | b)
+	case tokens.Tilde:
+		return integer(a ^ b)
+	case tokens.LeftShift, tokens.RightShift:
+		if op.Type == tokens.RightShift {
+			b = -b
+		}
+		// shifts are logical and shift everything out past 63 bits
+		switch {
+		case b <= -64 || b >= 64:
+			return integer(0)
+		case b >= 0:
+			return integer(int64(uint64(a) << b))
+		default:
+			return integer(int64(uint64(a) >> -b))
+		}
+	}
+	return compared(op.Type, a, b)
+}
+
+func (w *Walker) foldFloat(op tokens.Token, a, b float64) (constant, bool) {
+	var result float64
+	switch op.Type {
+	case tokens.Plus:
+		result = a + b
+	case tokens.Minus:
+		result = a - b
+	case tokens.Star:
+		result = a * b
+	case tokens.Caret:
+		result = math.Pow(a, b)
+	case tokens.Slash, tokens.BackSlash, tokens.Modulo:
+		if b == 0 {
+			w.AlertSingle(&alerts.ConstantDivisionByZero{}, op)
+			return constant{}, false
+		}
+		switch op.Type {
+		case tokens.Slash:
+			result = a / b
+		case tokens.BackSlash:
+			result = math.Floor(a / b)
+		case tokens.Modulo:
+			result = math.Mod(a, b)
+			if result != 0 && (result < 0) != (b < 0) {
+				result += b
+			}
+		}
+	default:
+		return compared(op.Type, a, b)
+	}
+
+	if math.IsNaN(result) {
+		return constant{}, false
+	}
+	if math.IsInf(result, 0) && !math.IsInf(a, 0) && !math.IsInf(b, 0) {
+		w.AlertSingle(&alerts.ConstantOverflow{}, op, "floats", "-1.8e308 to 1.8e308")
+		return constant{}, false
+	}
+	return constant{kind: floatConstant, float: result}, true
+}
+
+func (w *Walker) foldFixed(op tokens.Token, a, b int64) (constant, bool) {
+	result := new(big.Int)
+	switch op.Type {
+	case tokens.Plus:
+		result.Add(big.NewInt(a), big.NewInt(b))
+	case tokens.Minus:
+		result.Sub(big.NewInt(a), big.NewInt(b))
+	case tokens.Star:
+		result.Mul(big.NewInt(a), big.NewInt(b))
+		result.Rsh(result, 12)
+	case tokens.Slash:
+		if b == 0 {
+			w.AlertSingle(&alerts.ConstantDivisionByZero{}, op)
+			return constant{}, false
+		}
+		result.Lsh(big.NewInt(a), 12)
+		result.Quo(result, big.NewInt(b))
+	default:
+		return compared(op.Type, a, b)
+	}
+	return w.fixedResult(op, result)
+}
+
+func (w *Walker) fixedResult(op tokens.Token, result *big.Int) (constant, bool) {
+	if result.Cmp(minFixed) < 0 || result.Cmp(maxFixed) > 0 {
+		w.AlertSingle(&alerts.ConstantOverflow{}, op, "fixedpoints", "-2^51 to 2^51")
+		return constant{}, false
+	}
+	return constant{kind: fixedConstant, fixed: result.Int64()}, true
+}
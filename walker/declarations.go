@@ -4,6 +4,8 @@ import (
 	"hybroid/alerts"
 	"hybroid/ast"
 	"hybroid/tokens"
+	"maps"
+	"slices"
 )
 
 // Rewrote
@@ -67,6 +69,7 @@ func (w *Walker) classDeclaration(node *ast.ClassDecl, scope *Scope) {
 		Token:   node.Name,
 		Type:    *NewNamedType(w.environment.Name, node.Name.Lexeme, ast.Class),
 		IsPub:   node.IsPub,
+		Doc:     node.Doc,
 		Fields:  make(map[string]Field),
 		Methods: map[string]*VariableVal{},
 		New:     NewFunction(nil),
@@ -75,6 +78,7 @@ func (w *Walker) classDeclaration(node *ast.ClassDecl, scope *Scope) {
 		generic := NewGeneric(param.Name.Lexeme)
 		classVal.Type.Generics = append(classVal.Type.Generics, GenericWithType{GenericName: generic.Name, Type: UnknownTyp})
 	}
+	interfaces := w.impls(node.Impls, &classVal.Type, scope)
 
 	// DECLARATIONS
 	w.declareClass(classVal)
@@ -88,6 +92,7 @@ func (w *Walker) classDeclaration(node *ast.ClassDecl, scope *Scope) {
 	for i := range node.Methods {
 		w.methodDeclaration(&node.Methods[i], classVal, classScope, true)
 	}
+	w.checkConformance(classVal, node.Name, interfaces)
 
 	if node.Constructor != nil {
 		constructor := ast.MethodDecl{
@@ -140,6 +145,11 @@ func (w *Walker) entityDeclaration(node *ast.EntityDecl, scope *Scope) {
 		generic := NewGeneric(param.Name.Lexeme)
 		entityVal.Type.Generics = append(entityVal.Type.Generics, GenericWithType{GenericName: generic.Name, Type: UnknownTyp})
 	}
+	interfaces := w.impls(node.Impls, &entityVal.Type, scope)
+	if len(interfaces) != 0 {
+		// the spawner registers the entity in the table HybroidCall dispatches with
+		scope.Environment.AddBuiltinVar("HybroidCall")
+	}
 
 	et.EntityVal = entityVal
 	w.declareEntity(entityVal)
@@ -153,6 +163,7 @@ func (w *Walker) entityDeclaration(node *ast.EntityDecl, scope *Scope) {
 	for i := range node.Methods {
 		w.methodDeclaration(&node.Methods[i], entityVal, entityScope, true)
 	}
+	w.checkConformance(entityVal, node.Name, interfaces)
 
 	fn := w.entityFunctionDeclaration(node.Destroyer, entityScope)
 	entityVal.Destroy = fn
@@ -182,6 +193,102 @@ func (w *Walker) entityDeclaration(node *ast.EntityDecl, scope *Scope) {
 	}
 }
 
+func (w *Walker) interfaceDeclaration(node *ast.InterfaceDecl, scope *Scope) {
+	if scope.Parent != nil {
+		w.AlertSingle(&alerts.InvalidStmtInLocalBlock{}, node.Token, "interface declaration")
+		return
+	}
+	if w.typeExists(node.Name.Lexeme) {
+		w.AlertSingle(&alerts.TypeRedeclaration{}, node.Name, node.Name.Lexeme)
+		return
+	}
+
+	interfaceVal := &InterfaceVal{
+		Token:   node.Name,
+		Type:    *NewNamedType(w.environment.Name, node.Name.Lexeme, ast.Interface),
+		IsPub:   node.IsPub,
+		Doc:     node.Doc,
+		Methods: map[string]*VariableVal{},
+	}
+
+	for _, method := range node.Methods {
+		if _, found := interfaceVal.ContainsMethod(method.Name.Lexeme); found {
+			w.AlertSingle(&alerts.DuplicateElement{}, method.Name, "interface method", method.Name.Lexeme)
+			continue
+		}
+
+		// the parameters are declared in a scope of their own, since there is no body to walk
+		ft := &FuncTag{}
+		fnScope := w.NewScope(scope, ft, ReturnAllowing)
+		ft.Generics = w.getGenericParams(method.Generics, scope)
+		ft.ReturnTypes = w.getReturns(method.Returns, fnScope)
+		params := w.getParameters(method.Params, fnScope)
+
+		paramNames := make([]string, len(method.Params))
+		for i, param := range method.Params {
+			paramNames[i] = param.Name.Lexeme
+		}
+
+		info := ast.NewMethodInfo(ast.InterfaceMethod, method.Name.Lexeme, node.Name.Lexeme, w.environment.Name)
+		variable := NewVariable(method.Name, NewMethod(info, paramNames, params...).
+			WithGenerics(ft.Generics...).
+			WithReturns(ft.ReturnTypes...), true)
+		variable.Doc = method.Doc
+		interfaceVal.AddMethod(variable)
+	}
+
+	w.environment.Interfaces[node.Name.Lexeme] = interfaceVal
+}
+
+// Resolves the interfaces of an impl clause and records them on the type that implements them
+func (w *Walker) impls(impls []*ast.TypeExpr, named *NamedType, scope *Scope) []*InterfaceVal {
+	interfaces := []*InterfaceVal{}
+	for _, impl := range impls {
+		typ := w.typeExpression(impl, scope)
+		iface, ok := typ.(*NamedType)
+		if !ok || iface.Pvt != ast.Interface {
+			if typ != InvalidType {
+				w.AlertSingle(&alerts.NotAnInterface{}, impl.GetToken(), typ.String())
+			}
+			continue
+		}
+		if named.Implements(iface) {
+			w.AlertSingle(&alerts.DuplicateElement{}, impl.GetToken(), "interface", iface.Name)
+			continue
+		}
+		named.Impls = append(named.Impls, iface)
+		declaring, _ := w.lookupWalker(iface.EnvName)
+		interfaces = append(interfaces, declaring.environment.Interfaces[iface.Name])
+	}
+	return interfaces
+}
+
+// Checks that the container declares every method of the interfaces, with the same signature
+func (w *Walker) checkConformance(container MethodContainer, name tokens.Token, interfaces []*InterfaceVal) {
+	for _, iface := range interfaces {
+		methodNames := slices.Sorted(maps.Keys(iface.Methods))
+		for _, methodName := range methodNames {
+			expected := iface.Methods[methodName].Value.(*FunctionVal)
+			expectedSign := NewFuncSignature(expected.Generics...).
+				WithParams(expected.Params...).
+				WithReturns(expected.Returns...)
+
+			method, found := container.ContainsMethod(methodName)
+			if !found {
+				w.AlertSingle(&alerts.MissingInterfaceMethod{}, name, name.Lexeme, methodName, iface.Type.Name, expectedSign)
+				continue
+			}
+			fn := method.Value.(*FunctionVal)
+			sign := NewFuncSignature(fn.Generics...).
+				WithParams(fn.Params...).
+				WithReturns(fn.Returns...)
+			if !sign.Equals(expectedSign) {
+				w.AlertSingle(&alerts.InterfaceMethodMismatch{}, method.Token, methodName, iface.Type.Name, expectedSign, sign)
+			}
+		}
+	}
+}
+
 func (w *Walker) entityFunctionDeclaration(node *ast.EntityFunctionDecl, scope *Scope) *FunctionVal {
 	ft := &FuncTag{
 		Return: false,
@@ -250,14 +357,18 @@ func (w *Walker) enumDeclaration(node *ast.EnumDecl, scope *Scope) {
 		Type:   NewEnumType(scope.Environment.Name, node.Name.Lexeme),
 		Fields: make(map[string]*VariableVal),
 		IsPub:  node.IsPub,
+		Doc:    node.Doc,
 	}
 
-	for _, v := range node.Fields {
+	for i, v := range node.Fields {
 		if _, _, found := enumVal.ContainsField(v.Name.Lexeme); found {
 			w.AlertSingle(&alerts.DuplicateElement{}, v.GetToken(), "enum field", v.Name.Lexeme)
 			continue
 		}
 		variable := NewVariable(v.Name, &EnumFieldVal{Type: enumVal.Type}, node.IsPub)
+		if i < len(node.FieldDocs) {
+			variable.Doc = node.FieldDocs[i]
+		}
 		enumVal.AddField(variable)
 	}
 
@@ -303,6 +414,9 @@ func (w *Walker) methodDeclaration(node *ast.MethodDecl, container MethodContain
 
 		fnScope := w.NewScope(scope, fnTag, ReturnAllowing)
 		w.RegisterScope(fnScope, node.Name, w.GetNodeEndToken(node))
+		if fn.IsTask {
+			fnScope.Attributes.Add(WaitAllowing)
+		}
 
 		for i := range node.Params {
 			param := &node.Params[i]
@@ -318,6 +432,8 @@ func (w *Walker) methodDeclaration(node *ast.MethodDecl, container MethodContain
 			Generics: node.Generics,
 			Body:     node.Body,
 			IsPub:    false,
+			IsTask:   node.IsTask,
+			Doc:      node.Doc,
 		}
 
 		variable := w.functionDeclaration(&funcExpr, scope, Method)
@@ -349,13 +465,23 @@ func (w *Walker) functionDeclaration(node *ast.FunctionDecl, scope *Scope, procT
 		paramNames[i] = param.Name.Lexeme
 	}
 
+	fn := NewFunction(paramNames, params...).
+		WithGenerics(ft.Generics...).
+		WithReturns(ft.ReturnTypes...)
+	if node.IsTask {
+		fn.IsTask = true
+		fnScope.Attributes.Add(WaitAllowing)
+		if w.environment.Type == ast.MeshEnv || w.environment.Type == ast.SoundEnv {
+			w.AlertSingle(&alerts.ForbiddenTypeInEnvironment{}, node.Token, "task", []string{"Mesh", "Sound"})
+		}
+	}
+
 	variable := &VariableVal{
-		Name: node.Name.Lexeme,
-		Value: NewFunction(paramNames, params...).
-			WithGenerics(ft.Generics...).
-			WithReturns(ft.ReturnTypes...),
+		Name:  node.Name.Lexeme,
+		Value: fn,
 		Token: node.Name,
 		IsPub: node.IsPub,
+		Doc:   node.Doc,
 	}
 
 	if _, success := w.declareVariable(scope, variable); !success {
@@ -369,6 +495,30 @@ func (w *Walker) functionDeclaration(node *ast.FunctionDecl, scope *Scope, procT
 	return variable
 }
 
+// Walks a test block like the body of a function without parameters nor returns
+func (w *Walker) testDeclaration(node *ast.TestDecl, scope *Scope) {
+	if scope.Parent != nil {
+		w.AlertSingle(&alerts.InvalidStmtInLocalBlock{}, node.Token, "test block")
+		return
+	}
+	for _, other := range w.program {
+		if other == ast.Node(node) {
+			break
+		}
+		if otherTest, ok := other.(*ast.TestDecl); ok && otherTest.Name.Literal == node.Name.Literal {
+			w.AlertSingle(&alerts.DuplicateElement{}, node.Name, "test", node.Name.Literal)
+			break
+		}
+	}
+
+	ft := &FuncTag{}
+	testScope := w.NewScope(scope, ft, ReturnAllowing)
+	w.RegisterScope(testScope, node.Token, w.GetNodeEndToken(node))
+	w.context.InTest = true
+	w.walkFuncBody(node, &node.Body, ft, testScope)
+	w.context.InTest = false
+}
+
 // Rewrote
 func (w *Walker) variableDeclaration(declaration *ast.VariableDecl, scope *Scope, allowUnitialized bool) {
 	//check if it's a public declaration in a local scope
@@ -393,6 +543,7 @@ func (w *Walker) variableDeclaration(declaration *ast.VariableDecl, scope *Scope
 		} else {
 			variable.IsPub = declaration.IsPub
 			variable.IsConst = declaration.IsConst
+			variable.Doc = declaration.Doc
 			variables = append(variables, variable)
 		}
 
@@ -416,13 +567,17 @@ func (w *Walker) variableDeclaration(declaration *ast.VariableDecl, scope *Scope
 			w.AlertSingle(&alerts.NoValueGivenForConstant{}, ident.Name)
 			continue
 		} else if declaration.Type == nil {
-			w.AlertSingle(&alerts.ExplicitTypeRequiredInDeclaration{}, ident.Name, "to infer the value")
+			inferredType := ""
+			if declaration.Token.Type == tokens.Let {
+				inferredType = sharedDefaultableType(values)
+			}
+			w.AlertSingle(&alerts.ExplicitTypeRequiredInDeclaration{}, ident.Name, "to infer the value", declaration.Token, inferredType)
 			continue
 		} else {
 			val := w.typeToValue(declType)
 			defaultVal := val.GetDefault()
 
-			if defaultVal.Value == "nil" && !allowUnitialized {
+			if defaultVal.Value == "nil" && !allowUnitialized && declType.GetType() != Optional {
 				w.AlertSingle(&alerts.ExplicitTypeNotAllowed{}, declaration.Type.GetToken(), declType.String())
 				continue
 			}
@@ -442,6 +597,11 @@ func (w *Walker) variableDeclaration(declaration *ast.VariableDecl, scope *Scope
 			scope.ConstValues[variable.Name] = declaration.Expressions[values[i].Index]
 			continue
 		}
+		if i < len(values) && values[i].Index < len(declaration.Expressions) {
+			if list, ok := declaration.Expressions[values[i].Index].(*ast.ListExpr); ok {
+				w.listLengths[variable] = listLength{scope: scope, length: len(list.List)}
+			}
+		}
 		if declType == nil {
 			continue
 		}
@@ -449,14 +609,21 @@ func (w *Walker) variableDeclaration(declaration *ast.VariableDecl, scope *Scope
 			w.AlertSingle(&alerts.InvalidType{}, declaration.Expressions[values[i].Index].GetToken(), "unknown", "as a variable value")
 			continue
 		}
+		if valType.PVT() == ast.Nil && declaration.Type == nil {
+			w.AlertSingle(&alerts.InvalidType{}, declaration.Expressions[values[i].Index].GetToken(), "nil", "as the value of a variable without an optional type")
+			continue
+		}
 		if declType.GetType() == RawEntity && valType.PVT() == ast.Number {
 			variable.Value = &RawEntityVal{}
-		} else if !TypeEquals(declType, valType) && declType != InvalidType && valType != InvalidType {
+		} else if !IsAssignable(declType, valType) && declType != InvalidType && valType != InvalidType {
 			w.AlertSingle(&alerts.ExplicitTypeMismatch{},
 				variable.Token,
 				declType.String(),
 				valType.String(),
 			)
+		} else if !TypeEquals(declType, valType) {
+			// the variable keeps its declared type, like an optional or an interface, whatever value it starts with
+			variable.Value = w.typeToValue(declType)
 		}
 	}
 
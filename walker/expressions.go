@@ -7,6 +7,7 @@ import (
 	"hybroid/generator/mapping"
 	"hybroid/tokens"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -27,6 +28,10 @@ func (w *Walker) structExpression(node *ast.StructExpr, scope *Scope) *StructVal
 		structTypeVal.AddField(NewVariable(fieldToken, val))
 	}
 
+	if w.environment.Type == ast.MeshEnv && TypeEquals(structTypeVal.GetType(), MeshType) {
+		w.validateMesh(node)
+	}
+
 	return structTypeVal
 }
 
@@ -50,8 +55,13 @@ func (w *Walker) matchExpression(node *ast.MatchExpr, scope *Scope) Value {
 
 	cases := matchStmt.Cases
 	casesLength := len(cases)
+	// the values of the default case the fix adds are only known once the other cases are walked
+	var missingDefault *alerts.DefaultCaseMissing
 	if !matchStmt.HasDefault {
-		w.AlertSingle(&alerts.DefaultCaseMissing{}, matchStmt.Token)
+		missingDefault = w.NewAlert(&alerts.DefaultCaseMissing{}, alerts.NewSingle(matchStmt.Token), matchStmt.ClosingBrace, "", "").(*alerts.DefaultCaseMissing)
+		if !w.ignoreAlerts {
+			w.AlertI(missingDefault)
+		}
 		if casesLength < 1 {
 			w.AlertSingle(&alerts.InsufficientCases{}, matchStmt.Token)
 		}
@@ -114,6 +124,12 @@ func (w *Walker) matchExpression(node *ast.MatchExpr, scope *Scope) Value {
 
 	yieldTypes := matchScope.Tag.(*MatchExprTag).YieldTypes
 	node.ReturnAmount = len(yieldTypes)
+	// the case is added on its own line, with the indentation of the first one
+	if missingDefault != nil && len(yieldTypes) != 0 && casesLength != 0 &&
+		w.GetNodeEndToken(cases[casesLength-1]).Line < matchStmt.ClosingBrace.Line {
+		missingDefault.Indentation = strings.Repeat(" ", cases[0].GetToken().Column.Start-1)
+		missingDefault.DefaultValues = defaultValuesSource(yieldTypes)
+	}
 
 	switch node.ReturnAmount {
 	case 0:
@@ -153,7 +169,7 @@ func (w *Walker) entityEvaluationExpression(node *ast.EntityEvaluationExpr, scop
 		}
 		entityVal := w.typeToValue(typ).(*EntityVal)
 		if node.ConvertedVarName != nil {
-			w.context.EntityCasts.Push(NewEntityCast(*node.ConvertedVarName, entityVal))
+			w.context.SmartCasts.Push(NewEntityCast(*node.ConvertedVarName, entityVal))
 		}
 		node.EntityName = entityVal.Type.Name
 		node.EnvName = entityVal.Type.EnvName
@@ -164,9 +180,70 @@ func (w *Walker) entityEvaluationExpression(node *ast.EntityEvaluationExpr, scop
 	return &BoolVal{}
 }
 
-func (w *Walker) binaryExpression(node *ast.BinaryExpr, scope *Scope) Value {
+func (w *Walker) nilCoalescingExpression(node *ast.NilCoalescingExpr, scope *Scope) Value {
 	left, right := w.GetActualNodeValue(&node.Left, scope), w.GetActualNodeValue(&node.Right, scope)
 	leftType, rightType := left.GetType(), right.GetType()
+	if leftType == InvalidType || rightType == InvalidType {
+		return &Invalid{}
+	}
+	optional, ok := left.(*OptionalVal)
+	if !ok {
+		w.AlertSingle(&alerts.TypeMismatch{}, node.Left.GetToken(), "an optional value", leftType, "in '??' expression")
+		return &Invalid{}
+	}
+	if !IsAssignable(leftType, rightType) {
+		w.AlertSingle(&alerts.TypeMismatch{}, node.Right.GetToken(), optional.Value.GetType(), rightType, "as fallback value in '??' expression")
+		return &Invalid{}
+	}
+	// `or` would skip a false value, so booleans need to be compared to nil
+	node.IsBool = optional.Value.GetType().PVT() == ast.Bool
+
+	// the fallback value can be nil as well, in chains like `a ?? b ?? c`
+	if rightType.GetType() == Optional || rightType.PVT() == ast.Nil {
+		return optional
+	}
+	return optional.Value
+}
+
+func (w *Walker) letUnwrapExpression(node *ast.LetUnwrapExpr, scope *Scope) Value {
+	if !slices.Contains(w.context.Unwraps, node) {
+		w.AlertSingle(&alerts.UnwrapOutsideIfCondition{}, node.Token)
+		w.GetActualNodeValue(&node.Expr, scope)
+		return &Invalid{}
+	}
+	val := w.GetActualNodeValue(&node.Expr, scope)
+	valType := val.GetType()
+	if valType == InvalidType {
+		w.context.SmartCasts.Push(NewUnwrapCast(node.Name, val))
+		return &BoolVal{}
+	}
+	optional, ok := val.(*OptionalVal)
+	if !ok {
+		w.AlertSingle(&alerts.TypeMismatch{}, node.Expr.GetToken(), "an optional value", valType, "in if let condition")
+		w.context.SmartCasts.Push(NewUnwrapCast(node.Name, val))
+		return &BoolVal{}
+	}
+
+	w.context.SmartCasts.Push(NewUnwrapCast(node.Name, optional.Value))
+	return &BoolVal{}
+}
+
+func (w *Walker) binaryExpression(node *ast.BinaryExpr, scope *Scope) Value {
+	left := w.GetActualNodeValue(&node.Left, scope)
+	// the right side of `x != nil and ...` only runs when x is not nil, and the same goes for `x == nil or ...`
+	rightScope := scope
+	if node.Operator.Type == tokens.And || node.Operator.Type == tokens.Or {
+		op := tokens.BangEqual
+		if node.Operator.Type == tokens.Or {
+			op = tokens.EqualEqual
+		}
+		if len(nilChecks(node.Left, op)) != 0 {
+			rightScope = w.NewScope(scope, &UntaggedTag{})
+			w.narrowNilChecks(node.Left, op, scope, rightScope)
+		}
+	}
+	right := w.GetActualNodeValue(&node.Right, rightScope)
+	leftType, rightType := left.GetType(), right.GetType()
 	op := node.Operator
 	switch op.Type {
 	case tokens.Plus, tokens.Minus, tokens.Caret, tokens.Star, tokens.Slash, tokens.Modulo, tokens.BackSlash:
@@ -179,7 +256,16 @@ func (w *Walker) binaryExpression(node *ast.BinaryExpr, scope *Scope) Value {
 			w.AlertSingle(&alerts.TypeMismatch{}, node.Right.GetToken(), "string", rightType, "in concatenation")
 		}
 		return &StringVal{}
-	case tokens.Greater, tokens.GreaterEqual, tokens.Less, tokens.LessEqual, tokens.BangEqual, tokens.EqualEqual:
+	case tokens.BangEqual, tokens.EqualEqual:
+		if leftType == InvalidType || rightType == InvalidType {
+			return &BoolVal{}
+		}
+		// optionals are compared to nil and to the values they can hold
+		if !IsAssignable(leftType, rightType) && !IsAssignable(rightType, leftType) {
+			w.AlertSingle(&alerts.TypesMismatch{}, node.Left.GetToken(), "left value", leftType, "right value", rightType)
+		}
+		return &BoolVal{}
+	case tokens.Greater, tokens.GreaterEqual, tokens.Less, tokens.LessEqual:
 		if leftType == InvalidType || rightType == InvalidType {
 			return &BoolVal{}
 		}
@@ -210,6 +296,13 @@ func (w *Walker) binaryExpression(node *ast.BinaryExpr, scope *Scope) Value {
 				w.AlertSingle(&alerts.EntityConversionWithOrCondition{}, operand.GetToken())
 				return &BoolVal{}
 			}
+			if node.Left.GetType() == ast.LetUnwrapExpression {
+				w.AlertSingle(&alerts.UnwrapWithOrCondition{}, node.Left.GetToken())
+				return &BoolVal{}
+			} else if node.Right.GetType() == ast.LetUnwrapExpression {
+				w.AlertSingle(&alerts.UnwrapWithOrCondition{}, node.Right.GetToken())
+				return &BoolVal{}
+			}
 		}
 
 		return w.validateConditionalOperands(left, right, node)
@@ -220,7 +313,7 @@ func (w *Walker) literalExpression(node *ast.LiteralExpr) Value {
 	// Handle literals that were originally environment identifiers (mutated during a previous Walk)
 	if node.IsEnvPath {
 		envName := node.Token.Lexeme
-		if walker, ok := w.walkers[envName]; ok {
+		if walker, ok := w.lookupWalker(envName); ok {
 			w.AddReference("env", envName, node.Token)
 			return NewPathVal(walker.environment.luaPath, walker.environment.Type, walker.environment.Name)
 		}
@@ -245,6 +338,8 @@ func (w *Walker) literalExpression(node *ast.LiteralExpr) Value {
 			// alert: float in level
 		}
 		return NewNumberVal(node.Value)
+	case tokens.Nil:
+		return &NilVal{}
 	default:
 		return &Invalid{}
 	}
@@ -271,7 +366,7 @@ check:
 			}
 		}
 
-		walker, found := w.walkers[ident.Name.Lexeme]
+		walker, found := w.lookupWalker(ident.Name.Lexeme)
 		if found {
 			*node = &ast.LiteralExpr{
 				Value:     "\"" + walker.environment.luaPath + "\"",
@@ -285,7 +380,8 @@ check:
 		if scope.Environment.Name != w.environment.Name {
 			context = "in the environment " + scope.Environment.Name
 		}
-		w.AlertSingle(&alerts.UndeclaredVariableAccess{}, identToken, identToken.Lexeme, context)
+		use, useAfter := w.useSuggestion(identToken.Lexeme)
+		w.AlertSingle(&alerts.UndeclaredVariableAccess{}, identToken, identToken.Lexeme, context, use, useAfter)
 		return &Invalid{}
 	}
 
@@ -295,6 +391,7 @@ check:
 	}
 
 	variable := w.getVariable(sc, ident.Name)
+	delete(w.listLengths, variable)
 	if val, ok := sc.ConstValues[variable.Name]; variable.IsConst && ok {
 		*node = val
 		ref := reflect.ValueOf(*node).Elem()
@@ -380,7 +477,7 @@ check:
 			panic(fmt.Sprintf("ResolveVariableScope stopped on an entity scope, but there was no field or method found. (identifier: %s, env: %s)", ident.Name.Lexeme, w.environment.Name))
 		}
 	} else if sc.Environment.Name == "Builtin" {
-		scope.Environment.AddBuiltinVar(ident.Name.Lexeme)
+		w.useBuiltin(scope, ident.Name.Lexeme)
 		ident.Type = ast.Raw
 	} else if sc.Environment.Name != w.environment.Name && scope != sc {
 		*node = &ast.EnvAccessExpr{
@@ -397,6 +494,11 @@ check:
 	if !w.context.DontSetToUsed {
 		w.SetVarToUsed(variable)
 		w.AddReference(sc.Environment.Name, variable.Name, ident.GetToken())
+		if narrowed, found := scope.resolveNarrowing(variable.Name, sc); found {
+			narrowedVar := *variable
+			narrowedVar.Value = narrowed
+			return &narrowedVar
+		}
 		return variable
 	}
 
@@ -454,7 +556,7 @@ func (w *Walker) environmentAccessExpression(expr *ast.Node) Value {
 		w.AddLibrary(ast.Table)
 		val = w.GetNodeValue(&accessed, &TableAPI.Scope)
 	default:
-		walker, found := w.walkers[envName]
+		walker, found := w.lookupWalker(envName)
 		if !found {
 			w.AlertSingle(&alerts.InvalidEnvironmentAccess{}, node.PathExpr.GetToken(), envName)
 			return &Invalid{}
@@ -489,7 +591,9 @@ func (w *Walker) environmentAccessExpression(expr *ast.Node) Value {
 			walker.Walk()
 		}
 
+		w.context.MacroAccess = node.FromMacro
 		val = w.GetNodeValue(&accessed, &walker.environment.Scope)
+		w.context.MacroAccess = false
 	}
 	// Record reference for the accessed symbol and the environment name
 	if val != nil {
@@ -507,12 +611,21 @@ func (w *Walker) groupExpression(node *ast.GroupExpr, scope *Scope) Value {
 func (w *Walker) callExpression(node *ast.Node, scope *Scope) Value {
 	call := (*node).(*ast.CallExpr)
 
+	soundUsed := slices.Contains(scope.Environment.UsedBuiltinVars, "ParseSound")
 	val := w.GetNodeValue(&call.Caller, scope)
 
 	valType := val.GetType()
 	if valType == InvalidType {
 		return &Invalid{}
 	}
+	if valType.GetType() == Optional {
+		w.AlertSingle(&alerts.PossiblyNilAccess{}, call.Caller.GetToken(), valType, "called")
+		if variable, ok := val.(*VariableVal); ok {
+			val = variable.Value
+		}
+		val = val.(*OptionalVal).Value
+		valType = val.GetType()
+	}
 	if valType.PVT() != ast.Func {
 		w.AlertSingle(&alerts.InvalidCallerType{}, call.GetToken(), valType)
 		return &Invalid{}
@@ -533,6 +646,13 @@ func (w *Walker) callExpression(node *ast.Node, scope *Scope) Value {
 
 	fn := *val.(*FunctionVal)
 
+	started := w.context.Started == *node
+	if fn.IsTask && !started && !scope.Is(WaitAllowing) {
+		w.AlertSingle(&alerts.TaskCallOutsideTask{}, call.Caller.GetToken(), call.Caller.GetToken().Lexeme)
+	} else if started && !fn.IsTask {
+		w.AlertSingle(&alerts.NotATask{}, call.Caller.GetToken(), fn.GetType())
+	}
+
 	nodeGenerics := call.GenericArgs
 	nodeArgs := call.Args
 	genericArgs := w.getGenerics(nodeGenerics, fn.Generics, scope)
@@ -542,6 +662,19 @@ func (w *Walker) callExpression(node *ast.Node, scope *Scope) Value {
 	}
 	w.validateArguments(genericArgs, args, &fn, call)
 
+	// literal links are parsed here, so the generated code only needs ParseSound for the others
+	if literal, ok := soundLink(call); ok && call.Caller.(*ast.IdentifierExpr).Type == ast.Raw {
+		if sound, ok := w.parseSoundLink(literal); ok {
+			if !soundUsed {
+				scope.Environment.UsedBuiltinVars = slices.DeleteFunc(scope.Environment.UsedBuiltinVars, func(name string) bool {
+					return name == "ParseSound"
+				})
+			}
+			*node = sound
+			return w.typeToValue(SoundType)
+		}
+	}
+
 	actualReturns := fn.Returns
 	returnLen := len(actualReturns)
 
@@ -598,10 +731,18 @@ func (w *Walker) accessExpression(_node *ast.Node, scope *Scope) Value {
 
 	prevNode := &node.Start
 	for i := range node.Accessed {
+		if variable, ok := val.(*VariableVal); ok {
+			val = variable.Value
+		}
 		valType := val.GetType()
 		if valType == InvalidType {
 			return &Invalid{}
 		}
+		if optional, ok := val.(*OptionalVal); ok {
+			w.AlertSingle(&alerts.PossiblyNilAccess{}, (*prevNode).GetToken(), valType, "accessed from")
+			val = optional.Value
+			valType = val.GetType()
+		}
 		scopedVal, scopeable := val.(ScopeableValue)
 
 		if valType.GetType() != Wrapper && !scopeable {
@@ -645,6 +786,10 @@ func (w *Walker) accessExpression(_node *ast.Node, scope *Scope) Value {
 			}
 
 			val = w.typeToValue(val.GetType().(*WrapperType).WrappedType)
+			// a map can miss the key, which gives nil
+			if valType.PVT() == ast.Map && val.GetType().GetType() != Optional {
+				val = &OptionalVal{Value: val}
+			}
 			prevNode = &node.Accessed[i]
 			continue
 		}
@@ -660,6 +805,7 @@ func (w *Walker) accessExpression(_node *ast.Node, scope *Scope) Value {
 			}
 
 			field := node.Accessed[i].(*ast.FieldExpr)
+			w.MemberOwners[token.Location] = val
 			w.ignoreAlerts = true
 			fieldVal := w.GetNodeValue(&field.Field, scopedVal.Scopify(w))
 			w.ignoreAlerts = false
@@ -679,6 +825,9 @@ func (w *Walker) accessExpression(_node *ast.Node, scope *Scope) Value {
 				field.Index = index
 			}
 			if fn, ok := innerVal.(*FunctionVal); ok && fn.ProcType == Method {
+				if fn.MethodType == ast.InterfaceMethod {
+					w.useBuiltin(scope, "HybroidCall")
+				}
 				newAccess := *node
 				methodExpr := &ast.MethodExpr{
 					MethodInfo: fn.MethodInfo,
@@ -836,6 +985,34 @@ func (w *Walker) unaryExpression(node *ast.UnaryExpr, scope *Scope) Value {
 	return val
 }
 
+func (w *Walker) findExpression(node *ast.FindExpr, scope *Scope) Value {
+	containerVal := w.GetActualNodeValue(&node.Container, scope)
+	containerType := containerVal.GetType()
+	val := w.GetActualNodeValue(&node.Value, scope)
+	valType := val.GetType()
+
+	if containerType == InvalidType {
+		return &Invalid{}
+	}
+	containerPVT := containerType.PVT()
+	if containerType.GetType() != Wrapper || (containerPVT != ast.List && containerPVT != ast.Map) {
+		w.AlertSingle(&alerts.TypeMismatch{}, node.Container.GetToken(), "list or map", containerType.String(), "in find expression")
+		return &Invalid{}
+	}
+
+	elemType := containerType.(*WrapperType).WrappedType
+	if valType != InvalidType && !IsAssignable(elemType, valType) {
+		w.AlertSingle(&alerts.TypesMismatch{}, node.Value.GetToken(), "value to find", valType, "container element", elemType)
+	}
+
+	// the index of a list is returned, the key for a map, and nil when nothing matches
+	if containerPVT == ast.Map {
+		node.InMap = true
+		return &OptionalVal{Value: &StringVal{}}
+	}
+	return &OptionalVal{Value: &NumberVal{}}
+}
+
 // Rewrote
 func (w *Walker) selfExpression(self *ast.SelfExpr, scope *Scope) Value {
 	if !scope.Is(SelfAllowing) {
@@ -883,6 +1060,17 @@ func (w *Walker) newExpression(new *ast.NewExpr, scope *Scope) Value {
 	return val
 }
 
+func (w *Walker) startExpression(node *ast.StartExpr, scope *Scope) Value {
+	w.useBuiltin(scope, "HybroidStart")
+
+	started := w.context.Started
+	w.context.Started = node.Call
+	w.GetNodeValue(&node.Call, scope)
+	w.context.Started = started
+
+	return &Unknown{}
+}
+
 func (w *Walker) spawnExpression(new *ast.SpawnExpr, scope *Scope) Value {
 	_type := w.typeExpression(new.Type, scope)
 
@@ -908,6 +1096,29 @@ func (w *Walker) spawnExpression(new *ast.SpawnExpr, scope *Scope) Value {
 	return val
 }
 
+func (w *Walker) macroCallExpression(macroCall *ast.MacroCallExpr, scope *Scope) Value {
+	alertCount := len(w.GetAlerts())
+	defer func() {
+		for _, alert := range w.GetAlerts()[alertCount:] {
+			if alert.AlertType() == alerts.Error {
+				w.AlertMulti(&alerts.ErrorInMacroExpansion{}, macroCall.Token, w.GetNodeEndToken(macroCall), macroCall.Macro.Name.Lexeme, macroCall.Macro.Name.Line)
+				break
+			}
+		}
+	}()
+
+	if macroCall.Macro.MacroType == ast.ExpressionExpansion {
+		return w.GetNodeValue(&macroCall.Expansion, scope)
+	}
+
+	pt := NewPathTag()
+	macroScope := w.NewScope(scope, pt)
+	w.walkBody(&macroCall.Body, pt, macroScope)
+	w.reportExits(pt, scope)
+
+	return &Invalid{}
+}
+
 func (w *Walker) ResolveImportCycle(walker *Walker) ([]string, bool) {
 	if walker == w {
 		return []string{}, false
@@ -933,6 +1144,21 @@ func (w *Walker) typeExpression(typee *ast.TypeExpr, scope *Scope) Type {
 		return typ
 	}
 
+	if typee.IsOptional {
+		inner := *typee
+		inner.IsOptional = false
+		inner.IsVariadic = false
+		typ = w.typeExpression(&inner, scope)
+		typee.Name = inner.Name
+		if typ != InvalidType && typ != UnknownTyp && typ.GetType() != Optional {
+			typ = NewOptionalType(typ)
+		}
+		if typee.IsVariadic {
+			return NewVariadicType(typ)
+		}
+		return typ
+	}
+
 	defer func() {
 		if typ == UnknownTyp || typ.GetType() == Wrapper {
 			return
@@ -953,7 +1179,7 @@ func (w *Walker) typeExpression(typee *ast.TypeExpr, scope *Scope) Type {
 		expr, _ := typee.Name.(*ast.EnvAccessExpr)
 		path := expr.PathExpr.Path
 
-		walker, found := w.walkers[path.Lexeme]
+		walker, found := w.lookupWalker(path.Lexeme)
 		var env *Environment
 		if !found {
 			switch path.Lexeme {
@@ -1043,6 +1269,7 @@ func (w *Walker) typeExpression(typee *ast.TypeExpr, scope *Scope) Type {
 		typ = &FunctionType{
 			Params:  params,
 			Returns: returns,
+			IsTask:  typee.IsTask,
 		}
 	case ast.Map, ast.List:
 		var wrapped Type = InvalidType
@@ -1062,14 +1289,14 @@ func (w *Walker) typeExpression(typee *ast.TypeExpr, scope *Scope) Type {
 
 		// check for types of the environment
 		if val, ok := scope.Environment.Enums[typeName]; ok {
-			val.Type.IsUsed = true
+			w.markUsed(&val.Type.IsUsed)
 			typ = val.Type
 			w.AddReference(scope.Environment.Name, typeName, typee.Name.GetToken())
 			w.checkAccessibility(scope, val.IsPub, typee.Name.GetToken())
 			break
 		}
 		if entityVal, found := scope.Environment.Entities[typeName]; found {
-			entityVal.Type.IsUsed = true
+			w.markUsed(&entityVal.Type.IsUsed)
 			val := CopyEntityVal(entityVal)
 			typ = &val.Type
 			w.FillGenericsInNamedType(&val.Type, typee, scope)
@@ -1078,7 +1305,7 @@ func (w *Walker) typeExpression(typee *ast.TypeExpr, scope *Scope) Type {
 			break
 		}
 		if classVal, found := scope.Environment.Classes[typeName]; found {
-			classVal.Type.IsUsed = true
+			w.markUsed(&classVal.Type.IsUsed)
 			val := CopyClassVal(classVal)
 			typ = &val.Type
 			w.FillGenericsInNamedType(&val.Type, typee, scope)
@@ -1086,8 +1313,15 @@ func (w *Walker) typeExpression(typee *ast.TypeExpr, scope *Scope) Type {
 			w.checkAccessibility(scope, val.IsPub, typee.Name.GetToken())
 			break
 		}
+		if interfaceVal, found := scope.Environment.Interfaces[typeName]; found {
+			w.markUsed(&interfaceVal.Type.IsUsed)
+			typ = &interfaceVal.Type
+			w.AddReference(scope.Environment.Name, typeName, typee.Name.GetToken())
+			w.checkAccessibility(scope, interfaceVal.IsPub, typee.Name.GetToken())
+			break
+		}
 		if aliasType, found := scope.resolveAlias(typeName); found {
-			aliasType.IsUsed = true
+			w.markUsed(&aliasType.IsUsed)
 			typ = aliasType.UnderlyingType
 			w.AddReference(scope.Environment.Name, typeName, typee.Name.GetToken())
 			w.checkAccessibility(scope, aliasType.IsPub, typee.Name.GetToken())
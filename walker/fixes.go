@@ -0,0 +1,108 @@
+package walker
+
+import (
+	"hybroid/ast"
+	"hybroid/tokens"
+	"slices"
+	"strings"
+)
+
+// Helpers finding what the fixes of some alerts need. An empty result means the alert gets no fix
+
+// Returns the environment a use statement would have to bring in to declare the name, and the token the
+// statement goes after: the last use statement or the environment declaration
+func (w *Walker) useSuggestion(name string) (string, tokens.Token) {
+	var after tokens.Token
+	for _, node := range w.program {
+		switch node := node.(type) {
+		case *ast.EnvironmentDecl:
+			after = node.EnvType.Token
+		case *ast.UseStmt:
+			after = node.PathExpr.Path
+		}
+	}
+	if after == (tokens.Token{}) {
+		return "", after
+	}
+
+	envType := w.environment.Type
+	for _, library := range []ast.Library{ast.Pewpew, ast.Fmath, ast.Math, ast.String, ast.Table} {
+		if slices.Contains(w.environment.ImportedLibraries, library) {
+			continue
+		}
+		switch library {
+		case ast.Pewpew, ast.Fmath:
+			if envType != ast.LevelEnv {
+				continue
+			}
+		case ast.Math:
+			if envType == ast.LevelEnv {
+				continue
+			}
+		}
+		if _, found := BuiltinLibraries[library].Scope.Variables[name]; found {
+			return BuiltinLibraries[library].Name, after
+		}
+	}
+
+	// the walkers are kept by path too, only the ones kept by the name of their environment are looked at
+	envNames := make([]string, 0)
+	for key, walker := range w.walkers {
+		if walker.environment.Name == key && key != w.environment.Name {
+			envNames = append(envNames, key)
+		}
+	}
+	slices.Sort(envNames)
+	for _, envName := range envNames {
+		env := w.walkers[envName].environment
+		if slices.ContainsFunc(w.environment.imports, func(i Import) bool { return i.environment == env }) {
+			continue
+		}
+		if env.Type != ast.SharedEnv && (envType == ast.MeshEnv || envType == ast.SoundEnv) ||
+			envType == ast.LevelEnv && (env.Type == ast.MeshEnv || env.Type == ast.SoundEnv) {
+			continue
+		}
+		if variable, found := env.Scope.Variables[name]; found && variable.IsPub {
+			return envName, after
+		}
+	}
+	return "", after
+}
+
+// Returns the source of the values an added default case yields, as in `0, false`
+func defaultValuesSource(types []Type) string {
+	values := make([]string, 0, len(types))
+	for _, typ := range types {
+		value, ok := literalDefaults[typ.PVT()]
+		if !ok {
+			return ""
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, ", ")
+}
+
+var literalDefaults = map[ast.PrimitiveValueType]string{
+	ast.Number: "0",
+	ast.Fixed:  "0f",
+	ast.Text:   `""`,
+	ast.Bool:   "false",
+}
+
+// Returns the type of all the values, when they share one that the identifiers left without a value can
+// get the default of
+func sharedDefaultableType(values []Value2) string {
+	if len(values) == 0 {
+		return ""
+	}
+	typ := values[0].Value.GetType()
+	if _, ok := literalDefaults[typ.PVT()]; !ok {
+		return ""
+	}
+	for _, value := range values[1:] {
+		if !TypeEquals(value.Value.GetType(), typ) {
+			return ""
+		}
+	}
+	return typ.String()
+}
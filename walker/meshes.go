@@ -0,0 +1,68 @@
+package walker
+
+import (
+	"hybroid/alerts"
+	"hybroid/ast"
+	"hybroid/tokens"
+	"strconv"
+)
+
+// Checks the parts of a mesh literal that are known while compiling: segments index existing
+// vertexes, there is one color per vertex and the vertexes are all 2D or all 3D
+func (w *Walker) validateMesh(node *ast.StructExpr) {
+	fields := map[string]ast.Node{}
+	for i, field := range node.Fields {
+		fields[field.Name.Lexeme] = node.Expressions[i]
+	}
+
+	vertexes, ok := fields["vertexes"].(*ast.ListExpr)
+	if !ok {
+		return
+	}
+	dimensions := 0
+	for _, vertex := range vertexes.List {
+		vertex, ok := vertex.(*ast.ListExpr)
+		if !ok {
+			continue
+		}
+		if dimensions == 0 {
+			dimensions = len(vertex.List)
+		} else if len(vertex.List) != dimensions {
+			w.AlertSingle(&alerts.MixedVertexDimensions{}, vertex.Token, len(vertex.List), dimensions)
+		}
+	}
+
+	count := len(vertexes.List)
+	if segments, ok := fields["segments"].(*ast.ListExpr); ok {
+		for _, segment := range segments.List {
+			segment, ok := segment.(*ast.ListExpr)
+			if !ok {
+				continue
+			}
+			for _, index := range segment.List {
+				if lexeme, ok := meshIndex(index); ok {
+					if n, err := strconv.ParseInt(lexeme, 0, 64); err != nil || n < 0 || n >= int64(count) {
+						w.AlertSingle(&alerts.SegmentIndexOutOfRange{}, index.GetToken(), lexeme, count)
+					}
+				}
+			}
+		}
+	}
+
+	if colors, ok := fields["colors"].(*ast.ListExpr); ok && len(colors.List) != count {
+		w.AlertSingle(&alerts.MeshColorCountMismatch{}, colors.Token, len(colors.List), count)
+	}
+}
+
+// Returns the number a segment index is written as, if it is a literal
+func meshIndex(node ast.Node) (string, bool) {
+	if unary, ok := node.(*ast.UnaryExpr); ok && unary.Operator.Type == tokens.Minus {
+		lexeme, ok := meshIndex(unary.Value)
+		return "-" + lexeme, ok
+	}
+	literal, ok := node.(*ast.LiteralExpr)
+	if !ok || literal.Token.Type != tokens.Number {
+		return "", false
+	}
+	return literal.Value, true
+}
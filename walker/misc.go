@@ -10,7 +10,7 @@ import (
 )
 
 func (w *Walker) checkAccessibility(s *Scope, isPub bool, token tokens.Token) {
-	if s.Environment.Name != w.environment.Name && !isPub {
+	if s.Environment.Name != w.environment.Name && !isPub && !w.context.MacroAccess {
 		w.AlertSingle(&alerts.ForeignLocalVariableAccess{}, token, token.Lexeme)
 	}
 }
@@ -42,6 +42,9 @@ func (w *Walker) typeExists(name string) bool {
 	if _, found := w.environment.Enums[name]; found {
 		return true
 	}
+	if _, found := w.environment.Interfaces[name]; found {
+		return true
+	}
 	if w.getTypeFromString(name) != ast.Invalid {
 		return true
 	}
@@ -203,7 +206,7 @@ func (w *Walker) validateArguments(generics map[string]Type, args []Value, fn *F
 				genericArg = resolveGenericArgType(param, argType)
 				generics[typFound.Name] = genericArg
 				param = argType
-			} else if !TypeEquals(genericArg, argType) {
+			} else if !IsAssignable(genericArg, argType) {
 				w.AlertSingle(&alerts.TypesMismatch{}, nodeArgs[i].GetToken(),
 					"generic argument", genericArg,
 					"function argument", argType,
@@ -216,7 +219,7 @@ func (w *Walker) validateArguments(generics map[string]Type, args []Value, fn *F
 			continue
 		}
 
-		if !TypeEquals(param, argType) {
+		if !IsAssignable(param, argType) {
 			w.AlertSingle(&alerts.InvalidArgumentType{}, nodeArgs[i].GetToken(), argType.String(), param.String())
 			return
 		}
@@ -386,7 +389,7 @@ func (w *Walker) validateReturnValues(returnArgs []ast.Node, _return []Value2, e
 		if _return[i].GetType() == InvalidType || expectReturn[i] == InvalidType {
 			continue
 		}
-		if !TypeEquals(_return[i].GetType(), expectReturn[i]) {
+		if !IsAssignable(expectReturn[i], _return[i].GetType()) {
 			w.AlertSingle(&alerts.TypeMismatch{}, returnArgs[_return[i].Index].GetToken(),
 				expectReturn[i],
 				_return[i].GetType(),
@@ -397,7 +400,10 @@ func (w *Walker) validateReturnValues(returnArgs []ast.Node, _return []Value2, e
 }
 
 func (w *Walker) ifCondition(node *ast.Node, scope *Scope) {
+	prevUnwraps := w.context.Unwraps
+	w.context.Unwraps = conditionUnwraps(*node)
 	condition := w.GetActualNodeValue(node, scope)
+	w.context.Unwraps = prevUnwraps
 	if condition.GetType() == InvalidType {
 		return
 	}
@@ -408,6 +414,77 @@ func (w *Walker) ifCondition(node *ast.Node, scope *Scope) {
 	}
 }
 
+// Gives the let unwraps that are the operands of a condition, through groups and logical operators.
+// The ones with `or` are allowed here so that binaryExpression reports them
+func conditionUnwraps(node ast.Node) []*ast.LetUnwrapExpr {
+	switch n := node.(type) {
+	case *ast.LetUnwrapExpr:
+		return []*ast.LetUnwrapExpr{n}
+	case *ast.GroupExpr:
+		return conditionUnwraps(n.Expr)
+	case *ast.BinaryExpr:
+		if n.Operator.Type == tokens.And || n.Operator.Type == tokens.Or {
+			return append(conditionUnwraps(n.Left), conditionUnwraps(n.Right)...)
+		}
+	}
+	return nil
+}
+
+// Gives the variables a condition compares to nil with the operator. With `!=` these are the
+// variables that are not nil when the condition is true, which `and` keeps. With `==` these
+// are the variables that are not nil when the condition is false, which `or` keeps
+func nilChecks(node ast.Node, op tokens.TokenType) []tokens.Token {
+	if group, ok := node.(*ast.GroupExpr); ok {
+		return nilChecks(group.Expr, op)
+	}
+	binary, ok := node.(*ast.BinaryExpr)
+	if !ok {
+		return nil
+	}
+	chain := tokens.And
+	if op == tokens.EqualEqual {
+		chain = tokens.Or
+	}
+	if binary.Operator.Type == chain {
+		return append(nilChecks(binary.Left, op), nilChecks(binary.Right, op)...)
+	}
+	if binary.Operator.Type != op {
+		return nil
+	}
+
+	checked, other := binary.Left, binary.Right
+	if isNilLiteral(checked) {
+		checked, other = other, checked
+	}
+	ident, ok := checked.(*ast.IdentifierExpr)
+	if !ok || !isNilLiteral(other) {
+		return nil
+	}
+	return []tokens.Token{ident.Name}
+}
+
+func isNilLiteral(node ast.Node) bool {
+	literal, ok := node.(*ast.LiteralExpr)
+	return ok && literal.Token.Type == tokens.Nil
+}
+
+// Narrows the optional variables the condition compares to nil to their inner value in the scope `into`
+func (w *Walker) narrowNilChecks(condition ast.Node, op tokens.TokenType, scope *Scope, into *Scope) {
+	for _, name := range nilChecks(condition, op) {
+		sc := w.resolveVariable(scope, name)
+		if sc == nil {
+			continue
+		}
+		variable, found := sc.Variables[name.Lexeme]
+		if !found {
+			continue
+		}
+		if optional, ok := variable.Value.(*OptionalVal); ok {
+			into.narrow(name.Lexeme, optional.Value)
+		}
+	}
+}
+
 func (w *Walker) getParameters(parameters []ast.FunctionParam, scope *Scope) []Type {
 	variadicParams := make(map[tokens.Token]int)
 	params := make([]Type, 0)
@@ -553,6 +630,7 @@ func (w *Walker) typeToValue(_type Type) Value {
 		return &FunctionVal{
 			Params:  ft.Params,
 			Returns: ft.Returns,
+			IsTask:  ft.IsTask,
 		}
 	case ast.Text:
 		return &StringVal{}
@@ -608,6 +686,9 @@ func (w *Walker) typeToValue(_type Type) Value {
 		}
 		val.Type.Generics = named.Generics
 		return &val
+	case ast.Interface:
+		named := _type.(*NamedType)
+		return w.walkers[named.EnvName].environment.Interfaces[named.Name]
 	case ast.Struct:
 		return &StructVal{
 			Fields: _type.(*StructType).Fields,
@@ -682,6 +763,10 @@ func (w *Walker) typeToValue(_type Type) Value {
 	case ast.Path:
 		pathType := _type.(*PathType)
 		return NewPathVal("", pathType.Env, "")
+	case ast.Optional:
+		return &OptionalVal{Value: w.typeToValue(UnwrapOptional(_type))}
+	case ast.Nil:
+		return &NilVal{}
 	default:
 		return &Invalid{}
 	}
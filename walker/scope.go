@@ -7,15 +7,27 @@ import (
 	"slices"
 )
 
-type EntityCast struct {
-	Name   tokens.Token
-	Entity *EntityVal
+// A name a condition gives to a value of a more specific type, like the entity of
+// `x is Entity as e` or the unwrapped value of `if let x = maybe`
+type SmartCast struct {
+	Name  tokens.Token
+	Value Value
+	// the name only exists in the body of the branch, since outside of it the value can be nil
+	BranchOnly bool
+}
+
+func NewEntityCast(name tokens.Token, val *EntityVal) SmartCast {
+	return SmartCast{
+		Name:  name,
+		Value: val,
+	}
 }
 
-func NewEntityCast(name tokens.Token, val *EntityVal) EntityCast {
-	return EntityCast{
-		Name:   name,
-		Entity: val,
+func NewUnwrapCast(name tokens.Token, val Value) SmartCast {
+	return SmartCast{
+		Name:       name,
+		Value:      val,
+		BranchOnly: true,
 	}
 }
 
@@ -25,13 +37,21 @@ type Binding struct {
 }
 
 type Context struct {
-	EntityCasts   core.Queue[EntityCast]
+	SmartCasts    core.Queue[SmartCast]
 	DontSetToUsed bool
+	// the call of the start expression being walked, which can call a task outside of one
+	Started ast.Node
+	// the let unwraps of the if condition being walked, the only ones that are allowed
+	Unwraps []*ast.LetUnwrapExpr
+	// set while walking a test block, whose builtins only the test bundles define
+	InTest bool
+	// set while walking a variable qualified by a macro, which can be private to the environment of the macro
+	MacroAccess bool
 }
 
 func (c *Context) Clear() {
 	c.DontSetToUsed = false
-	c.EntityCasts.Clear()
+	c.SmartCasts.Clear()
 }
 
 type ScopeTagType int
@@ -190,6 +210,7 @@ const (
 	SelfAllowing
 	BreakAllowing
 	ContinueAllowing
+	WaitAllowing
 )
 
 type ScopeAttributes []ScopeAttribute
@@ -224,10 +245,44 @@ type Scope struct {
 	Variables   map[string]*VariableVal
 	AliasTypes  map[string]*AliasType
 	ConstValues map[string]ast.Node
+	// the values of the variables that are known to be more specific in the scope,
+	// like an optional that was compared to nil
+	Narrowings map[string]Value
 
 	Body *[]*ast.Node
 }
 
+func (sc *Scope) narrow(name string, value Value) {
+	if sc.Narrowings == nil {
+		sc.Narrowings = map[string]Value{}
+	}
+	sc.Narrowings[name] = value
+}
+
+// Looks for the narrowed value of a variable declared in the scope `declared`, from the scope outwards.
+// Functions stop the search, since they can be called after the variable changed
+func (sc *Scope) resolveNarrowing(name string, declared *Scope) (Value, bool) {
+	for s := sc; s != nil; s = s.Parent {
+		if value, found := s.Narrowings[name]; found {
+			return value, true
+		}
+		if s == declared || (s.Tag != nil && s.Tag.GetType() == Func) {
+			break
+		}
+	}
+	return nil, false
+}
+
+// Forgets the narrowed value of a variable after it was assigned to
+func (sc *Scope) clearNarrowing(name string, declared *Scope) {
+	for s := sc; s != nil; s = s.Parent {
+		delete(s.Narrowings, name)
+		if s == declared || (s.Tag != nil && s.Tag.GetType() == Func) {
+			break
+		}
+	}
+}
+
 func (sc *Scope) resolveAlias(typeName string) (*AliasType, bool) {
 	if alias, found := sc.AliasTypes[typeName]; found {
 		return alias, true
@@ -296,6 +351,10 @@ func (w *Walker) NewScope(parent *Scope, tag ScopeTag, extraAttrs ...ScopeAttrib
 	} else {
 		attrs = append(attrs, parent.Attributes...)
 	}
+	// a function declared in a task runs outside of it, so it cannot wait
+	if _, ok := tag.(*FuncTag); ok {
+		attrs.Remove(WaitAllowing)
+	}
 	for _, v := range extraAttrs {
 		attrs.Add(v)
 	}
@@ -0,0 +1,188 @@
+package walker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hybroid/alerts"
+	"hybroid/ast"
+	"hybroid/tokens"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The ranges JFXR allows for the number fields of a sound
+var soundRanges = map[string][2]float64{
+	"attack":               {0, 5},
+	"sustain":              {0, 5},
+	"sustainPunch":         {0, 100},
+	"decay":                {0, 5},
+	"tremoloDepth":         {0, 100},
+	"tremoloFrequency":     {1, 1000},
+	"frequency":            {10, 10000},
+	"frequencySweep":       {-10000, 10000},
+	"frequencyDeltaSweep":  {-10000, 10000},
+	"repeatFrequency":      {0, 100},
+	"frequencyJump1Onset":  {0, 100},
+	"frequencyJump1Amount": {-100, 100},
+	"frequencyJump2Onset":  {0, 100},
+	"frequencyJump2Amount": {-100, 100},
+	"harmonics":            {0, 5},
+	"harmonicsFalloff":     {0, 1},
+	"vibratoDepth":         {0, 1000},
+	"vibratoFrequency":     {1, 1000},
+	"squareDuty":           {0, 100},
+	"squareDutySweep":      {-100, 100},
+	"flangerOffset":        {0, 50},
+	"flangerOffsetSweep":   {-50, 50},
+	"bitCrush":             {1, 16},
+	"bitCrushSweep":        {-16, 16},
+	"lowPassCutoff":        {0, 22050},
+	"lowPassCutoffSweep":   {-22050, 22050},
+	"highPassCutoff":       {0, 22050},
+	"highPassCutoffSweep":  {-22050, 22050},
+	"compression":          {0, 5},
+	"amplification":        {0, 500},
+}
+
+var soundWaveforms = []string{
+	"sine", "triangle", "sawtooth", "square", "tangent",
+	"whistle", "breaker", "whitenoise", "pinknoise", "brownnoise",
+}
+
+// Returns the link of a ParseSound call whose only argument is a string literal
+func soundLink(call *ast.CallExpr) (*ast.LiteralExpr, bool) {
+	ident, ok := call.Caller.(*ast.IdentifierExpr)
+	if !ok || ident.Name.Lexeme != "ParseSound" || len(call.Args) != 1 {
+		return nil, false
+	}
+	literal, ok := call.Args[0].(*ast.LiteralExpr)
+	if !ok || literal.Token.Type != tokens.String {
+		return nil, false
+	}
+	return literal, true
+}
+
+// Parses a JFXR link into the table ParseSound would return at runtime, alerting on the fields
+// sounds do not have and on the values JFXR would not produce
+func (w *Walker) parseSoundLink(literal *ast.LiteralExpr) (*ast.StructExpr, bool) {
+	_, fragment, found := strings.Cut(literal.Value, "#")
+	if !found {
+		w.AlertSingle(&alerts.InvalidSoundLink{}, literal.Token, "the link has no '#' followed by the sound")
+		return nil, false
+	}
+	content, err := url.PathUnescape(fragment)
+	if err != nil {
+		w.AlertSingle(&alerts.InvalidSoundLink{}, literal.Token, err.Error())
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewBufferString(content))
+	decoder.UseNumber()
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		w.AlertSingle(&alerts.InvalidSoundLink{}, literal.Token, "the sound is not a JSON object")
+		return nil, false
+	}
+
+	sound := &ast.StructExpr{Token: literal.Token}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			w.AlertSingle(&alerts.InvalidSoundLink{}, literal.Token, err.Error())
+			return nil, false
+		}
+		key := token.(string)
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			w.AlertSingle(&alerts.InvalidSoundLink{}, literal.Token, err.Error())
+			return nil, false
+		}
+
+		// _version, _name, _locked and the like only matter to JFXR
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		field, ok := SoundType.Fields[key]
+		if !ok {
+			w.AlertSingle(&alerts.UnknownSoundField{}, literal.Token, key)
+			continue
+		}
+
+		expr, ok := w.soundFieldValue(literal, key, field, value)
+		if !ok {
+			continue
+		}
+		sound.Fields = append(sound.Fields, &ast.IdentifierExpr{
+			Name: tokens.NewToken(tokens.Identifier, key, "", literal.Token.Location),
+			Type: ast.Raw,
+		})
+		sound.Expressions = append(sound.Expressions, expr)
+	}
+	if _, err := decoder.Token(); err != nil {
+		w.AlertSingle(&alerts.InvalidSoundLink{}, literal.Token, err.Error())
+		return nil, false
+	}
+
+	return sound, true
+}
+
+func (w *Walker) soundFieldValue(literal *ast.LiteralExpr, key string, field StructField, value any) (ast.Node, bool) {
+	location := literal.Token.Location
+
+	switch field.Var.Value.(type) {
+	case *NumberVal:
+		expected := "a number"
+		bounds, ranged := soundRanges[key]
+		if ranged {
+			expected = fmt.Sprintf("a number from %v to %v", bounds[0], bounds[1])
+		}
+		number, ok := value.(json.Number)
+		if !ok {
+			w.AlertSingle(&alerts.InvalidSoundValue{}, literal.Token, key, formatSoundValue(value), expected)
+			return nil, false
+		}
+		n, err := number.Float64()
+		if err != nil || (ranged && (n < bounds[0] || n > bounds[1])) {
+			w.AlertSingle(&alerts.InvalidSoundValue{}, literal.Token, key, number.String(), expected)
+			return nil, false
+		}
+		lexeme := number.String()
+		// the runtime helper does the same
+		if key == "amplification" {
+			lexeme = strconv.FormatFloat(n/100, 'f', -1, 64)
+		}
+		return &ast.LiteralExpr{Value: lexeme, Token: tokens.NewToken(tokens.Number, lexeme, lexeme, location)}, true
+	case *BoolVal:
+		boolean, ok := value.(bool)
+		if !ok {
+			w.AlertSingle(&alerts.InvalidSoundValue{}, literal.Token, key, formatSoundValue(value), "true or false")
+			return nil, false
+		}
+		typ := tokens.False
+		if boolean {
+			typ = tokens.True
+		}
+		lexeme := strconv.FormatBool(boolean)
+		return &ast.LiteralExpr{Value: lexeme, Token: tokens.NewToken(typ, lexeme, lexeme, location)}, true
+	default:
+		waveform, ok := value.(string)
+		if !ok || !slices.Contains(soundWaveforms, waveform) {
+			w.AlertSingle(&alerts.InvalidSoundValue{}, literal.Token, key, formatSoundValue(value), "one of "+strings.Join(soundWaveforms, ", "))
+			return nil, false
+		}
+		return &ast.LiteralExpr{Value: waveform, Token: tokens.NewToken(tokens.String, waveform, waveform, location)}, true
+	}
+}
+
+func formatSoundValue(value any) string {
+	switch value := value.(type) {
+	case string:
+		return fmt.Sprintf("'%s'", value)
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(value)
+	}
+}
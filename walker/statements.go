@@ -5,36 +5,35 @@ import (
 	"hybroid/ast"
 	"hybroid/tokens"
 	"slices"
+	"strconv"
 	"strings"
 )
 
 func (w *Walker) ifStatement(node *ast.IfStmt, scope *Scope) {
-	w.context.EntityCasts.Clear()
+	w.context.SmartCasts.Clear()
 
 	w.ifCondition(&node.BoolExpr, scope)
 
-	for w.context.EntityCasts.Count() != 0 {
-		cast := w.context.EntityCasts.Pop()
-		w.declareVariable(scope, NewVariable(cast.Name, cast.Entity))
-	}
-
 	pt := NewPathTag()
 	ifScope := w.NewScope(scope, pt)
 	w.RegisterScope(ifScope, node.Token, w.GetBodyEndToken(&node.Body))
+	w.declareSmartCasts(scope, ifScope)
+	w.narrowNilChecks(node.BoolExpr, tokens.BangEqual, scope, ifScope)
 
 	w.walkBody(&node.Body, pt, ifScope)
 
+	// whether every branch leaves the block, so the code after the if only runs when all conditions were false
+	allLeave := pt.GetIfExits(ControlFlow) || pt.GetIfExits(Yield)
 	prevPathTag := *pt
 	for i := range node.Elseifs {
 		w.ifCondition(&node.Elseifs[i].BoolExpr, scope)
 		pt := NewPathTag()
 		ifScope := w.NewScope(scope, pt)
 		w.RegisterScope(ifScope, node.Elseifs[i].Token, w.GetBodyEndToken(&node.Elseifs[i].Body))
-		for w.context.EntityCasts.Count() != 0 {
-			cast := w.context.EntityCasts.Pop()
-			w.declareVariable(scope, NewVariable(cast.Name, cast.Entity))
-		}
+		w.declareSmartCasts(scope, ifScope)
+		w.narrowNilChecks(node.Elseifs[i].BoolExpr, tokens.BangEqual, scope, ifScope)
 		w.walkBody(&node.Elseifs[i].Body, pt, ifScope)
+		allLeave = allLeave && (pt.GetIfExits(ControlFlow) || pt.GetIfExits(Yield))
 		prevPathTag.SetAllExitAND(pt)
 	}
 
@@ -42,15 +41,37 @@ func (w *Walker) ifStatement(node *ast.IfStmt, scope *Scope) {
 		pt := NewPathTag()
 		elseScope := w.NewScope(scope, pt)
 		w.RegisterScope(elseScope, node.Else.Token, w.GetBodyEndToken(&node.Else.Body))
+		w.narrowNilChecks(node.BoolExpr, tokens.EqualEqual, scope, elseScope)
+		for i := range node.Elseifs {
+			w.narrowNilChecks(node.Elseifs[i].BoolExpr, tokens.EqualEqual, scope, elseScope)
+		}
 		w.walkBody(&node.Else.Body, pt, elseScope)
 		prevPathTag.SetAllExitAND(pt)
 	} else {
+		if allLeave {
+			w.narrowNilChecks(node.BoolExpr, tokens.EqualEqual, scope, scope)
+			for i := range node.Elseifs {
+				w.narrowNilChecks(node.Elseifs[i].BoolExpr, tokens.EqualEqual, scope, scope)
+			}
+		}
 		prevPathTag.SetAllFalse()
 	}
 
 	w.reportExits(&prevPathTag, scope)
 }
 
+// Declares the names the last condition gave with `as` or `if let`
+func (w *Walker) declareSmartCasts(scope *Scope, branchScope *Scope) {
+	for w.context.SmartCasts.Count() != 0 {
+		cast := w.context.SmartCasts.Pop()
+		if cast.BranchOnly {
+			w.declareVariable(branchScope, NewVariable(cast.Name, cast.Value))
+		} else {
+			w.declareVariable(scope, NewVariable(cast.Name, cast.Value))
+		}
+	}
+}
+
 // Rewrote
 func (w *Walker) assignmentStatement(assignStmt *ast.AssignmentStmt, scope *Scope) {
 	values := []Value2{}
@@ -77,6 +98,11 @@ func (w *Walker) assignmentStatement(assignStmt *ast.AssignmentStmt, scope *Scop
 		if !variable.IsInit {
 			variable.IsInit = true
 		}
+		if ident, ok := idents[i].(*ast.IdentifierExpr); ok {
+			if sc := w.resolveVariable(scope, ident.Name); sc != nil {
+				scope.clearNarrowing(variable.Name, sc)
+			}
+		}
 		variableType := variable.GetType()
 
 		var valType Type
@@ -122,6 +148,11 @@ func (w *Walker) assignmentStatement(assignStmt *ast.AssignmentStmt, scope *Scop
 				continue
 			}
 		} else if assignOp.Type != tokens.Equal {
+			// the elements of maps are optional too, as compound assigning to a missing key fails when the game runs
+			if variableType.GetType() == Optional {
+				w.AlertSingle(&alerts.PossiblyNilAccess{}, idents[i].GetToken(), variableType, "compound assigned")
+				continue
+			}
 			if !isNumerical(valType.PVT()) {
 				w.AlertSingle(&alerts.InvalidTypeInCompoundAssignment{}, exprs[values[i].Index].GetToken(), valType)
 				continue
@@ -132,7 +163,7 @@ func (w *Walker) assignmentStatement(assignStmt *ast.AssignmentStmt, scope *Scop
 			}
 		}
 
-		if !TypeEquals(variableType, valType) {
+		if !IsAssignable(variableType, valType) {
 			w.AlertSingle(&alerts.AssignmentTypeMismatch{}, exprs[values[i].Index].GetToken(),
 				variableType.String(),
 				valType.String(),
@@ -288,6 +319,8 @@ func (w *Walker) tickStatement(node *ast.TickStmt, scope *Scope) {
 	w.RegisterScope(tickScope, node.Token, w.GetBodyEndToken(&node.Body))
 	tt := NewPathTag()
 	tickScope.Tag = tt
+	// the body runs in its own callback, outside of the task around it
+	tickScope.Attributes.Remove(WaitAllowing)
 
 	if node.Variable != nil {
 		w.declareVariable(tickScope, NewVariable(node.Variable.Name, &NumberVal{}))
@@ -297,6 +330,25 @@ func (w *Walker) tickStatement(node *ast.TickStmt, scope *Scope) {
 	w.reportExits(tt, scope)
 }
 
+func (w *Walker) waitStatement(node *ast.WaitStmt, scope *Scope) {
+	if !scope.Is(WaitAllowing) {
+		w.AlertSingle(&alerts.WaitOutsideTask{}, node.Token)
+	}
+
+	if node.Until != nil {
+		valType := w.GetActualNodeValue(&node.Until, scope).GetType()
+		if valType != InvalidType && valType.PVT() != ast.Bool {
+			w.AlertSingle(&alerts.TypeMismatch{}, node.Until.GetToken(), "bool", valType, "in wait statement")
+		}
+		return
+	}
+
+	valType := w.GetActualNodeValue(&node.Ticks, scope).GetType()
+	if valType != InvalidType && valType.PVT() != ast.Number {
+		w.AlertSingle(&alerts.TypeMismatch{}, node.Ticks.GetToken(), "number", valType, "in wait statement")
+	}
+}
+
 func (w *Walker) matchStatement(node *ast.MatchStmt, scope *Scope) {
 	val := w.GetNodeValue(&node.ExprToMatch, scope)
 	valType := val.GetType()
@@ -499,7 +551,7 @@ func (w *Walker) useStatement(node *ast.UseStmt, scope *Scope) {
 		return
 	}
 
-	walker, found := w.walkers[envName]
+	walker, found := w.lookupWalker(envName)
 	if !found {
 		w.AlertSingle(&alerts.InvalidEnvironmentAccess{}, node.PathExpr.Path, envName)
 		return
@@ -572,3 +624,72 @@ func (w *Walker) destroyStatement(node *ast.DestroyStmt, scope *Scope) {
 	suppliedGenerics := w.getGenerics(node.GenericsArgs, entityVal.Destroy.Generics, scope)
 	w.validateArguments(suppliedGenerics, args, entityVal.Destroy, node)
 }
+
+func (w *Walker) removeStatement(node *ast.RemoveStmt, scope *Scope) {
+	// the length of a list variable is taken before the variable is resolved, which forgets it
+	var variable *VariableVal
+	known, knownOk := listLength{}, false
+	if ident, ok := node.Container.(*ast.IdentifierExpr); ok {
+		if sc := w.resolveVariable(scope, ident.Name); sc != nil {
+			variable = w.getVariable(sc, ident.Name)
+			known, knownOk = w.listLengths[variable]
+			knownOk = knownOk && known.scope == scope
+		}
+	}
+
+	containerVal := w.GetActualNodeValue(&node.Container, scope)
+	containerType := containerVal.GetType()
+	indexVal := w.GetActualNodeValue(&node.Index, scope)
+	indexPVT := indexVal.GetType().PVT()
+
+	if containerType == InvalidType {
+		return
+	}
+	containerPVT := containerType.PVT()
+	if containerType.GetType() != Wrapper || (containerPVT != ast.List && containerPVT != ast.Map) {
+		w.AlertSingle(&alerts.TypeMismatch{}, node.Container.GetToken(), "list or map", containerType.String(), "in remove statement")
+		return
+	}
+
+	listLiteral, isLiteral := node.Container.(*ast.ListExpr)
+	w.ConvertToGroupIf(&node.Container, ast.MapExpression, ast.ListExpression)
+
+	if containerPVT == ast.Map {
+		node.InMap = true
+		if indexPVT != ast.Text && indexPVT != ast.Invalid {
+			w.AlertSingle(&alerts.TypeMismatch{}, node.Index.GetToken(), "text", indexVal.GetType().String(), "as the key to remove from a map")
+		}
+		return
+	}
+
+	if indexPVT != ast.Number {
+		if indexPVT != ast.Invalid {
+			w.AlertSingle(&alerts.TypeMismatch{}, node.Index.GetToken(), "number", indexVal.GetType().String(), "as the index to remove from a list")
+		}
+		return
+	}
+
+	num, ok := indexVal.(*NumberVal)
+	if !ok || num.Value == "" {
+		return
+	}
+	n, err := strconv.ParseFloat(num.Value, 64)
+	if err != nil {
+		return
+	}
+	length := -1
+	if isLiteral {
+		length = len(listLiteral.List)
+	} else if knownOk {
+		length = known.length
+	}
+	if n < float64(1) {
+		w.AlertSingle(&alerts.ListIndexOutOfBounds{}, node.Index.GetToken())
+	} else if n != float64(int64(n)) {
+		w.AlertSingle(&alerts.InvalidListIndex{}, node.Index.GetToken())
+	} else if length != -1 && int(n) > length {
+		w.AlertSingle(&alerts.ListIndexPastLength{}, node.Index.GetToken(), num.Value, length)
+	} else if knownOk {
+		w.listLengths[variable] = listLength{scope: scope, length: length - 1}
+	}
+}
@@ -29,6 +29,7 @@ const (
 	Variadic
 	Generic
 	Path
+	Optional
 	NA
 )
 
@@ -59,6 +60,44 @@ func (vt *VariadicType) String() string {
 	return "..." + vt.Type.String()
 }
 
+// The type of the values that can also be nil, written `T?`
+type OptionalType struct {
+	Type Type
+}
+
+func NewOptionalType(typ Type) *OptionalType {
+	return &OptionalType{
+		Type: typ,
+	}
+}
+
+func (ot *OptionalType) PVT() ast.PrimitiveValueType {
+	return ast.Optional
+}
+
+func (ot *OptionalType) GetType() ValueType {
+	return Optional
+}
+
+func (ot *OptionalType) _eq(other Type) bool {
+	return TypeEquals(ot.Type, other.(*OptionalType).Type)
+}
+
+func (ot *OptionalType) String() string {
+	return ot.Type.String() + "?"
+}
+
+// Returns the type an optional type wraps, or the type itself if it is not optional
+func UnwrapOptional(typ Type) Type {
+	if alias, ok := typ.(*AliasType); ok {
+		return UnwrapOptional(alias.UnderlyingType)
+	}
+	if optional, ok := typ.(*OptionalType); ok {
+		return optional.Type
+	}
+	return typ
+}
+
 type PathType struct {
 	Env ast.Env
 }
@@ -123,6 +162,7 @@ type FunctionType struct {
 	Params     []Type
 	Returns    []Type
 	ProcType   ProcedureType
+	IsTask     bool
 }
 
 func NewFunctionType(params []Type, returns []Type, names []string, procType ...ProcedureType) *FunctionType {
@@ -148,6 +188,9 @@ func (ft *FunctionType) GetType() ValueType {
 
 func (ft *FunctionType) _eq(other Type) bool {
 	otherFT := other.(*FunctionType)
+	if ft.IsTask != otherFT.IsTask {
+		return false
+	}
 	if len(ft.Params) != len(otherFT.Params) {
 		return false
 	}
@@ -171,6 +214,9 @@ func (ft *FunctionType) _eq(other Type) bool {
 func (ft *FunctionType) String() string {
 	src := core.StringBuilder{}
 
+	if ft.IsTask {
+		src.Write("task ")
+	}
 	src.Write("fn(")
 
 	length := len(ft.Params)
@@ -366,6 +412,8 @@ type NamedType struct {
 	Name     string
 	IsUsed   bool
 	Generics []GenericWithType
+	// The interfaces a class or an entity implements
+	Impls []*NamedType
 }
 
 func NewNamedType(envName string, name string, primitive ast.PrimitiveValueType) *NamedType {
@@ -398,6 +446,16 @@ func (nt *NamedType) _eq(othr Type) bool {
 	return nt.Name == other.Name
 }
 
+// Whether the type is a class or an entity that implements the interface
+func (nt *NamedType) Implements(iface *NamedType) bool {
+	for _, impl := range nt.Impls {
+		if impl.Name == iface.Name && impl.EnvName == iface.EnvName {
+			return true
+		}
+	}
+	return false
+}
+
 func (nt *NamedType) String() string {
 	if len(nt.Generics) == 0 {
 		return nt.Name
@@ -613,4 +671,33 @@ func TypeEquals(t Type, other Type) bool {
 	return t._eq(other)
 }
 
+// Whether a value of the type from can be stored where the type to is expected. Unlike TypeEquals
+// it goes one way: a T fits where a T? is expected and nil fits any optional, but not the other way around
+func IsAssignable(to Type, from Type) bool {
+	if to.PVT() == ast.Object || from.PVT() == ast.Object {
+		return true
+	}
+	if from.PVT() == ast.Nil {
+		return to.GetType() == Optional || to.PVT() == ast.Nil
+	}
+	if to.GetType() == Optional {
+		return IsAssignable(UnwrapOptional(to), UnwrapOptional(from))
+	}
+	if iface, ok := to.(*NamedType); ok && iface.Pvt == ast.Interface {
+		if named, ok := from.(*NamedType); ok && named.Implements(iface) {
+			return true
+		}
+	}
+	// a list<Pylon> fits where a list<Damageable> is expected
+	if wrapper, ok := to.(*WrapperType); ok && wrapper.WrappedType.PVT() == ast.Interface {
+		if other, ok := from.(*WrapperType); ok && TypeEquals(wrapper.Type, other.Type) {
+			return IsAssignable(wrapper.WrappedType, other.WrappedType)
+		}
+	}
+	return TypeEquals(to, from)
+}
+
 var InvalidType = NewBasicType(ast.Invalid)
+
+// The type of the nil literal, which only fits where an optional value is expected
+var NilType = NewBasicType(ast.Nil)
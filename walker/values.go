@@ -57,18 +57,20 @@ type VariableVal struct {
 	IsInit  bool
 	IsConst bool
 	Token   tokens.Token
+	// The doc comment of the declaration, in markdown
+	Doc string
 }
 
 func (w *Walker) SetVarToUsed(v *VariableVal) {
-	v.IsUsed = true
+	w.markUsed(&v.IsUsed)
 	if fn, ok := v.Value.(*FunctionVal); ok && fn.ProcType == Method {
 		if fn.MethodName == "spawn" && fn.MethodType == ast.EntityMethod {
 			val := w.walkers[fn.EnvName].environment.Entities[fn.TypeName]
-			val.Type.IsUsed = true
+			w.markUsed(&val.Type.IsUsed)
 			w.walkers[fn.EnvName].environment.Entities[fn.TypeName] = val
 		} else if fn.MethodName == "new" && fn.MethodType == ast.ClassMethod {
 			val := w.walkers[fn.EnvName].environment.Classes[fn.TypeName]
-			val.Type.IsUsed = true
+			w.markUsed(&val.Type.IsUsed)
 			w.walkers[fn.EnvName].environment.Classes[fn.TypeName] = val
 		}
 	} else if num, ok := v.Value.(*NumberVal); ok {
@@ -212,6 +214,7 @@ type EnumVal struct {
 	Type   *EnumType
 	Fields map[string]*VariableVal
 	IsPub  bool
+	Doc    string
 }
 
 func NewEnumVal(envName string, name string, isPub bool, fields ...string) *EnumVal {
@@ -317,6 +320,7 @@ type EntityVal struct {
 	Token   tokens.Token
 	Type    NamedType
 	IsPub   bool
+	Doc     string
 	Fields  map[string]Field
 	Methods map[string]*VariableVal
 
@@ -343,6 +347,7 @@ func NewEntityVal(envName string, node *ast.EntityDecl) *EntityVal {
 		Token:   node.Name,
 		Type:    *NewNamedType(envName, name, ast.Entity),
 		IsPub:   node.IsPub,
+		Doc:     node.Doc,
 		Methods: make(map[string]*VariableVal),
 		Fields:  make(map[string]Field, 0),
 		Destroy: NewMethod(ast.NewMethodInfo(ast.EntityMethod, "destroy", name, envName), nil),
@@ -404,6 +409,7 @@ type ClassVal struct {
 	Token       tokens.Token
 	Type        NamedType
 	IsPub       bool
+	Doc         string
 	Fields      map[string]Field
 	Methods     map[string]*VariableVal
 	GenericArgs []Type
@@ -474,6 +480,52 @@ func (cv *ClassVal) Scopify(w *Walker) *Scope {
 	return scope
 }
 
+// The declaration of an interface, whose methods only have a signature
+type InterfaceVal struct {
+	Token   tokens.Token
+	Type    NamedType
+	IsPub   bool
+	Doc     string
+	Methods map[string]*VariableVal
+}
+
+func (iv *InterfaceVal) GetType() Type {
+	return &iv.Type
+}
+
+func (iv *InterfaceVal) GetDefault() *ast.LiteralExpr {
+	return &ast.LiteralExpr{Value: "nil"}
+}
+
+// Container
+func (iv *InterfaceVal) AddField(variable *VariableVal) {}
+
+func (iv *InterfaceVal) AddMethod(variable *VariableVal) {
+	iv.Methods[variable.Name] = variable
+}
+
+func (iv *InterfaceVal) ContainsField(name string) (*VariableVal, int, bool) {
+	return nil, -1, false
+}
+
+func (iv *InterfaceVal) ContainsMethod(name string) (*VariableVal, bool) {
+	if variable, found := iv.Methods[name]; found {
+		return variable, true
+	}
+
+	return nil, false
+}
+
+func (iv *InterfaceVal) Scopify(w *Walker) *Scope {
+	scope := w.NewScope(nil, &UntaggedTag{})
+
+	for _, v := range iv.Methods {
+		scope.Variables[v.Name] = v
+	}
+
+	return scope
+}
+
 type MapVal struct {
 	MemberType Type
 }
@@ -571,11 +623,13 @@ func TypesToString(types []Type) string {
 var EmptyReturn = []Type{}
 
 type FunctionVal struct {
-	Generics       []*GenericType
-	Params         []Type
-	ParamNames     []string
-	Returns        []Type
-	ProcType       ProcedureType
+	Generics   []*GenericType
+	Params     []Type
+	ParamNames []string
+	Returns    []Type
+	ProcType   ProcedureType
+	// tasks can wait, so they run with `start` or from other tasks
+	IsTask         bool
 	ast.MethodInfo // check if ProcType == Method before accessing this
 }
 
@@ -609,7 +663,9 @@ func (fn FunctionVal) WithGenerics(generics ...*GenericType) *FunctionVal {
 }
 
 func (f *FunctionVal) GetType() Type {
-	return NewFunctionType(f.Params, f.Returns, f.ParamNames, f.ProcType)
+	typ := NewFunctionType(f.Params, f.Returns, f.ParamNames, f.ProcType)
+	typ.IsTask = f.IsTask
+	return typ
 }
 
 func (f *FunctionVal) GetReturns() []Type {
@@ -669,6 +725,29 @@ func (gv *GenericVal) GetDefault() *ast.LiteralExpr {
 	return &ast.LiteralExpr{Value: "nil"}
 }
 
+// A value that can also be nil, its type is optional
+type OptionalVal struct {
+	Value Value
+}
+
+func (ov *OptionalVal) GetType() Type {
+	return NewOptionalType(ov.Value.GetType())
+}
+
+func (ov *OptionalVal) GetDefault() *ast.LiteralExpr {
+	return &ast.LiteralExpr{Value: "nil"}
+}
+
+type NilVal struct{}
+
+func (nv *NilVal) GetType() Type {
+	return NilType
+}
+
+func (nv *NilVal) GetDefault() *ast.LiteralExpr {
+	return &ast.LiteralExpr{Value: "nil"}
+}
+
 type Invalid struct{}
 
 func (u *Invalid) GetType() Type {
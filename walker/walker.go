@@ -53,10 +53,12 @@ type Environment struct {
 	UsedLibraries     []ast.Library
 	ImportedLibraries []ast.Library // Only libraries imported via 'use' statements
 	UsedBuiltinVars   []string
+	TestBuiltinVars   []string // Only used by test blocks
 
-	Classes  map[string]*ClassVal
-	Entities map[string]*EntityVal
-	Enums    map[string]*EnumVal
+	Classes    map[string]*ClassVal
+	Entities   map[string]*EntityVal
+	Enums      map[string]*EnumVal
+	Interfaces map[string]*InterfaceVal
 
 	_envStmt *ast.EnvironmentDecl
 }
@@ -114,6 +116,18 @@ func (e *Environment) AddBuiltinVar(name string) {
 	e.UsedBuiltinVars = append(e.UsedBuiltinVars, name)
 }
 
+// Records a builtin the code uses, apart from the other ones when it is used in a test block,
+// so that builds leaving the test blocks out do not define it
+func (w *Walker) useBuiltin(scope *Scope, name string) {
+	if !w.context.InTest {
+		scope.Environment.AddBuiltinVar(name)
+		return
+	}
+	if !slices.Contains(scope.Environment.TestBuiltinVars, name) {
+		scope.Environment.TestBuiltinVars = append(scope.Environment.TestBuiltinVars, name)
+	}
+}
+
 func NewEnvironment(hybroidPath, luaPath string) *Environment {
 	scope := Scope{
 		Tag:         &UntaggedTag{},
@@ -131,6 +145,7 @@ func NewEnvironment(hybroidPath, luaPath string) *Environment {
 		Classes:           map[string]*ClassVal{},
 		Entities:          map[string]*EntityVal{},
 		Enums:             map[string]*EnumVal{},
+		Interfaces:        map[string]*InterfaceVal{},
 	}
 
 	global.Scope.Environment = global
@@ -151,6 +166,21 @@ type Walker struct {
 
 	ScopeMap     []ScopeRange
 	ReferenceMap map[string][]Reference // key: "envName:varName", value: list of reference locations
+	// the value every field or method after a '.' was accessed from, by the location of its name
+	MemberOwners map[tokens.Location]Value
+
+	// Recorded during the walk, so that the walker can be post walked again without being walked
+	usages       []*bool         // every usage flag the walker has set, including ones of other environments
+	walkAlerts   []alerts.Alert  // the alerts the walker had before its PostWalk
+	dependencies map[string]bool // every environment name the walker has looked up
+	listLengths  map[*VariableVal]listLength
+}
+
+// The length of a list variable declared with a list literal, known until the variable is used
+// for anything other than removing from it in the scope it was declared in
+type listLength struct {
+	scope  *Scope
+	length int
 }
 
 func (w *Walker) Alert(alertType alerts.Alert, args ...any) {
@@ -182,12 +212,15 @@ func NewWalker(hybroidPath, luaPath string) *Walker {
 		environment: NewEnvironment(hybroidPath, luaPath),
 		program:     []ast.Node{},
 		context: Context{
-			EntityCasts: core.NewQueue[EntityCast]("EntityCasts"),
+			SmartCasts: core.NewQueue[SmartCast]("SmartCasts"),
 		},
 		Collector:    alerts.NewCollector(),
 		ScopeMap:     make([]ScopeRange, 0),
 		ReferenceMap: make(map[string][]Reference),
+		MemberOwners: make(map[tokens.Location]Value),
 		walkers:      make(map[string]*Walker),
+		dependencies: make(map[string]bool),
+		listLengths:  make(map[*VariableVal]listLength),
 	}
 }
 
@@ -248,6 +281,58 @@ func (w *Walker) Env() *Environment {
 	return w.environment
 }
 
+func (w *Walker) lookupWalker(envName string) (*Walker, bool) {
+	w.dependencies[envName] = true
+	walker, found := w.walkers[envName]
+	return walker, found
+}
+
+// DependsOn reports whether the walk of the walker looked up the given environment
+func (w *Walker) DependsOn(envName string) bool {
+	return w.dependencies[envName]
+}
+
+func (w *Walker) markUsed(flag *bool) {
+	*flag = true
+	w.usages = append(w.usages, flag)
+}
+
+// Rewind brings a walked walker back to the state it had right before its PostWalk:
+// its alerts are restored and the usage flags of its environment are cleared,
+// so they can be set again by ReplayUsages and by the walkers that are walked anew
+func (w *Walker) Rewind() {
+	w.Collector = alerts.NewCollector()
+	for _, alert := range w.walkAlerts {
+		w.AlertI(alert)
+	}
+
+	for _, v := range w.environment.Scope.Variables {
+		v.IsUsed = false
+	}
+	for _, v := range w.environment.Entities {
+		v.Type.IsUsed = false
+	}
+	for _, v := range w.environment.Classes {
+		v.Type.IsUsed = false
+	}
+	for _, v := range w.environment.Enums {
+		v.Type.IsUsed = false
+	}
+	for _, v := range w.environment.Interfaces {
+		v.Type.IsUsed = false
+	}
+	for _, v := range w.environment.Scope.AliasTypes {
+		v.IsUsed = false
+	}
+}
+
+// ReplayUsages sets again every usage flag the walker has set during its walk
+func (w *Walker) ReplayUsages() {
+	for _, flag := range w.usages {
+		*flag = true
+	}
+}
+
 func (w *Walker) SetProgram(program []ast.Node) {
 	w.program = program
 }
@@ -261,16 +346,23 @@ func (w *Walker) Reset() {
 	w.Collector = alerts.NewCollector()
 	w.ScopeMap = make([]ScopeRange, 0)
 	w.ReferenceMap = make(map[string][]Reference)
+	w.MemberOwners = make(map[tokens.Location]Value)
+	w.usages = nil
+	w.walkAlerts = nil
+	w.dependencies = make(map[string]bool)
+	w.listLengths = make(map[*VariableVal]listLength)
 	// Preserve hybroidPath and luaPath, but clear name and other state
 	w.environment.Name = ""
 	w.environment.Type = ast.InvalidEnv
 	w.environment.UsedBuiltinVars = make([]string, 0)
+	w.environment.TestBuiltinVars = make([]string, 0)
 	w.environment.UsedLibraries = make([]ast.Library, 0)
 	w.environment.ImportedLibraries = make([]ast.Library, 0)
 	w.environment.imports = make([]Import, 0)
 	w.environment.Classes = map[string]*ClassVal{}
 	w.environment.Entities = map[string]*EntityVal{}
 	w.environment.Enums = map[string]*EnumVal{}
+	w.environment.Interfaces = map[string]*InterfaceVal{}
 	w.environment.Scope = Scope{
 		Tag:         &UntaggedTag{},
 		Variables:   map[string]*VariableVal{},
@@ -324,6 +416,8 @@ func (w *Walker) Walk() {
 }
 
 func (w *Walker) PostWalk() {
+	w.walkAlerts = append([]alerts.Alert{}, w.GetAlerts()...)
+
 	for _, v := range w.environment.Scope.Variables {
 		if !v.IsUsed {
 			w.AlertSingle(&alerts.UnusedElement{}, v.Token, "variable")
@@ -344,6 +438,11 @@ func (w *Walker) PostWalk() {
 			w.AlertSingle(&alerts.UnusedElement{}, v.Token, "enum type")
 		}
 	}
+	for _, v := range w.environment.Interfaces {
+		if !v.Type.IsUsed {
+			w.AlertSingle(&alerts.UnusedElement{}, v.Token, "interface type")
+		}
+	}
 	for _, v := range w.environment.Scope.AliasTypes {
 		if !v.IsUsed {
 			w.AlertSingle(&alerts.UnusedElement{}, v.Token, "alias type")
@@ -351,6 +450,33 @@ func (w *Walker) PostWalk() {
 	}
 }
 
+// Returns the program without the private functions, classes and entities PostWalk reported as unused.
+// Enums need no pruning, their members are never generated
+func (w *Walker) PrunedProgram() []ast.Node {
+	pruned := make([]ast.Node, 0, len(w.program))
+	for _, node := range w.program {
+		if !w.isUnusedDeclaration(node) {
+			pruned = append(pruned, node)
+		}
+	}
+	return pruned
+}
+
+func (w *Walker) isUnusedDeclaration(node ast.Node) bool {
+	switch node := node.(type) {
+	case *ast.FunctionDecl:
+		variable, found := w.environment.Scope.Variables[node.Name.Lexeme]
+		return !node.IsPub && found && !variable.IsUsed
+	case *ast.ClassDecl:
+		class, found := w.environment.Classes[node.Name.Lexeme]
+		return !node.IsPub && found && !class.Type.IsUsed
+	case *ast.EntityDecl:
+		entity, found := w.environment.Entities[node.Name.Lexeme]
+		return !node.IsPub && found && !entity.Type.IsUsed
+	}
+	return false
+}
+
 func (w *Walker) CheckUniqueVariables() {
 	if w.environment.Type == ast.MeshEnv {
 		variable, ok := w.environment.Scope.Variables["meshes"]
@@ -358,7 +484,7 @@ func (w *Walker) CheckUniqueVariables() {
 			w.AlertSingle(&alerts.MissingPewpewVariable{}, w.environment._envStmt.GetToken(), "meshes", "Mesh")
 			return
 		}
-		variable.IsUsed = true
+		w.markUsed(&variable.IsUsed)
 		if !variable.IsPub || !TypeEquals(variable.Value.GetType(), MeshesType) {
 			w.AlertSingle(&alerts.InvalidPewpewVariable{}, variable.Token, "meshes", MeshType)
 		}
@@ -370,7 +496,7 @@ func (w *Walker) CheckUniqueVariables() {
 			w.AlertSingle(&alerts.MissingPewpewVariable{}, w.environment._envStmt.GetToken(), "sounds", "Sound")
 			return
 		}
-		variable.IsUsed = true
+		w.markUsed(&variable.IsUsed)
 		if !variable.IsPub || !TypeEquals(variable.Value.GetType(), SoundsType) {
 			w.AlertSingle(&alerts.InvalidPewpewVariable{}, variable.Token, "sounds", SoundType)
 		}
@@ -402,12 +528,16 @@ func (w *Walker) walkNode(node *ast.Node, scope *Scope) {
 		w.forStatement(newNode, scope)
 	case *ast.TickStmt:
 		w.tickStatement(newNode, scope)
+	case *ast.WaitStmt:
+		w.waitStatement(newNode, scope)
 	case *ast.CallExpr:
 		w.callExpression(node, scope)
 	case *ast.ClassDecl:
 		w.classDeclaration(newNode, scope)
 	case *ast.EnumDecl:
 		w.enumDeclaration(newNode, scope)
+	case *ast.InterfaceDecl:
+		w.interfaceDeclaration(newNode, scope)
 	case *ast.MatchStmt:
 		w.matchStatement(newNode, scope)
 	case *ast.AssignmentStmt:
@@ -416,16 +546,25 @@ func (w *Walker) walkNode(node *ast.Node, scope *Scope) {
 		w.useStatement(newNode, scope)
 	case *ast.DestroyStmt:
 		w.destroyStatement(newNode, scope)
+	case *ast.RemoveStmt:
+		w.removeStatement(newNode, scope)
 	case *ast.SpawnExpr:
 		w.spawnExpression(newNode, scope)
+	case *ast.StartExpr:
+		w.startExpression(newNode, scope)
 	case *ast.NewExpr:
 		w.newExpression(newNode, scope)
 	case *ast.AliasDecl:
 		w.aliasDeclaration(newNode, scope)
+	case *ast.MacroDecl:
+	case *ast.MacroCallExpr:
+		w.macroCallExpression(newNode, scope)
 	case *ast.Improper:
 		// w.Error(newNode.GetToken(), "Improper statement: parser fault")
 	case *ast.EntityDecl:
 		w.entityDeclaration(newNode, scope)
+	case *ast.TestDecl:
+		w.testDeclaration(newNode, scope)
 	default:
 		// w.Error(newNode.GetToken(), "Expected statement")
 	}
@@ -475,17 +614,32 @@ func (w *Walker) GetNodeValue(node *ast.Node, scope *Scope) Value {
 		val = w.selfExpression(newNode, scope)
 	case *ast.MatchExpr:
 		val = w.matchExpression(newNode, scope)
+	case *ast.FindExpr:
+		val = w.findExpression(newNode, scope)
 	case *ast.EntityEvaluationExpr:
 		val = w.entityEvaluationExpression(newNode, scope)
+	case *ast.NilCoalescingExpr:
+		val = w.nilCoalescingExpression(newNode, scope)
+	case *ast.LetUnwrapExpr:
+		val = w.letUnwrapExpression(newNode, scope)
 	case *ast.EnvAccessExpr:
 		val = w.environmentAccessExpression(node)
 	case *ast.SpawnExpr:
 		val = w.spawnExpression(newNode, scope)
+	case *ast.StartExpr:
+		val = w.startExpression(newNode, scope)
+	case *ast.MacroCallExpr:
+		val = w.macroCallExpression(newNode, scope)
 	default:
 		// w.Error(newNode.GetToken(), "Expected expression")
 		return &Invalid{}
 	}
 
+	switch (*node).(type) {
+	case *ast.BinaryExpr, *ast.UnaryExpr, *ast.GroupExpr:
+		w.foldConstant(node)
+	}
+
 	return val
 }
 func (w *Walker) walkBody(body *ast.Body, tag ExitableTag, scope *Scope) {
@@ -493,7 +647,7 @@ func (w *Walker) walkBody(body *ast.Body, tag ExitableTag, scope *Scope) {
 	bodySlice := *body
 	for i := range bodySlice {
 		if tag.GetIfExits(ControlFlow) || tag.GetIfExits(Yield) {
-			w.AlertMulti(&alerts.UnreachableCode{}, bodySlice[i].GetToken(), bodySlice[body.Size()-1].GetToken())
+			w.AlertMulti(&alerts.UnreachableCode{}, bodySlice[i].GetToken(), w.GetNodeEndToken(bodySlice[body.Size()-1]))
 			endIndex = i
 			break
 		}
@@ -565,6 +719,10 @@ func (w *Walker) GetNodeEndToken(node ast.Node) tokens.Token {
 		if tok := w.GetBodyEndToken(&n.Body); (tok != tokens.Token{}) {
 			return tok
 		}
+	case *ast.TestDecl:
+		if tok := w.GetBodyEndToken(&n.Body); (tok != tokens.Token{}) {
+			return tok
+		}
 	case *ast.ForStmt:
 		if tok := w.GetBodyEndToken(&n.Body); (tok != tokens.Token{}) {
 			return tok
@@ -597,6 +755,25 @@ func (w *Walker) GetNodeEndToken(node ast.Node) tokens.Token {
 		if len(n.Args) > 0 {
 			return w.GetNodeEndToken(n.Args[len(n.Args)-1])
 		}
+	case *ast.YieldStmt:
+		if len(n.Args) > 0 {
+			return w.GetNodeEndToken(n.Args[len(n.Args)-1])
+		}
+	case *ast.WaitStmt:
+		if n.Until != nil {
+			return w.GetNodeEndToken(n.Until)
+		}
+		return w.GetNodeEndToken(n.Ticks)
+	case *ast.StartExpr:
+		return w.GetNodeEndToken(n.Call)
+	case *ast.AssignmentStmt:
+		if len(n.Values) > 0 {
+			return w.GetNodeEndToken(n.Values[len(n.Values)-1])
+		}
+	case *ast.RemoveStmt:
+		return w.GetNodeEndToken(n.Container)
+	case *ast.MacroCallExpr:
+		return w.GetNodeEndToken(n.Caller)
 	case *ast.CallExpr:
 		if len(n.Args) > 0 {
 			return w.GetNodeEndToken(n.Args[len(n.Args)-1])
@@ -629,8 +806,14 @@ func (w *Walker) GetNodeEndToken(node ast.Node) tokens.Token {
 		}
 	case *ast.BinaryExpr:
 		return w.GetNodeEndToken(n.Right)
+	case *ast.NilCoalescingExpr:
+		return w.GetNodeEndToken(n.Right)
+	case *ast.LetUnwrapExpr:
+		return w.GetNodeEndToken(n.Expr)
 	case *ast.UnaryExpr:
 		return w.GetNodeEndToken(n.Value)
+	case *ast.FindExpr:
+		return w.GetNodeEndToken(n.Container)
 	case *ast.AccessExpr:
 		if len(n.Accessed) > 0 {
 			return w.GetNodeEndToken(n.Accessed[len(n.Accessed)-1])